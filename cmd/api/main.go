@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/VATUSA/primary-api/pkg/config"
 	"github.com/VATUSA/primary-api/pkg/cookie"
 	"github.com/VATUSA/primary-api/pkg/database"
 	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/discord"
 	gochi "github.com/VATUSA/primary-api/pkg/go-chi"
+	"github.com/VATUSA/primary-api/pkg/jobs"
+	"github.com/VATUSA/primary-api/pkg/notify"
 	"github.com/VATUSA/primary-api/pkg/oauth"
+	"github.com/VATUSA/primary-api/pkg/scheduler"
 	"github.com/VATUSA/primary-api/pkg/storage"
 	"github.com/VATUSA/primary-api/views"
+	discord_views "github.com/VATUSA/primary-api/views/v3/discord"
 	"github.com/joho/godotenv"
 	log "github.com/sirupsen/logrus"
 	"net/http"
@@ -24,16 +30,44 @@ func main() {
 	oauth.OAuthConfig = oauth.InitializeVATSIM(config.Cfg)
 	oauth.DiscordOAuthConfig = oauth.InitializeDiscord(config.Cfg)
 
+	provider, err := oauth.InitializeProvider(config.Cfg)
+	if err != nil {
+		panic(err)
+	}
+	oauth.Provider = provider
+
 	bucket, err := storage.NewS3Client(config.Cfg.S3)
 	if err != nil {
 		panic(err)
 	}
 
 	storage.PublicBucket = bucket
+	storage.PrivateBucket = bucket.WithBucket(config.Cfg.S3.PrivateBucket)
 	database.DB = database.Connect(config.Cfg.Database)
 	cookie.CookieStore = cookie.New(config.Cfg)
 	models.AutoMigrate()
 
+	// Notifications are always stored in-app, email and Discord are the additional channels
+	channels := []notify.Channel{notify.NewEmailChannel(config.Cfg.SMTP)}
+
+	discordClient := discord.NewHTTPClient(config.Cfg.Discord)
+	if config.Cfg.Discord.BotToken != "" {
+		discord.DefaultSyncer = discord.NewSyncer(discordClient)
+		channels = append(channels, notify.NewDiscordChannel(discordClient))
+
+		if config.Cfg.Discord.ApplicationID != "" {
+			if err := discord_views.RegisterCommands(context.Background(), discordClient, config.Cfg.Discord.ApplicationID); err != nil {
+				log.WithError(err).Error("Error registering Discord slash commands")
+			}
+		}
+	}
+
+	notify.Default = notify.NewDispatcher(channels...)
+
+	s := scheduler.NewScheduler()
+	jobs.Register(s)
+	defer s.Stop()
+
 	r := gochi.New(config.Cfg)
 	views.Router(r, config.Cfg)
 	log.Fatalf("Err starting http server: %s", http.ListenAndServe(fmt.Sprintf(":%s", config.Cfg.API.Port), r))
@@ -16,6 +16,9 @@ type Config struct {
 	S3           *S3Config
 	OAuth        *OAuth
 	DiscordOAuth *OAuth
+	SMTP         *SMTPConfig
+	Discord      *DiscordConfig
+	OIDC         *OIDCConfig
 }
 
 func New() *Config {
@@ -27,6 +30,9 @@ func New() *Config {
 		S3:           NewS3Config(),
 		OAuth:        NewOAuth(),
 		DiscordOAuth: NewDiscordOAuth(),
+		SMTP:         NewSMTPConfig(),
+		Discord:      NewDiscordConfig(),
+		OIDC:         NewOIDCConfig(),
 	}
 }
 
@@ -62,12 +68,13 @@ func defaultConfig() *Config {
 			Domain:   "vatusa.net",
 		},
 		S3: &S3Config{
-			Endpoint:  "https://digitaloceanspaces.com",
-			Region:    "nyc3",
-			Bucket:    "vatusa",
-			AccessKey: "",
-			SecretKey: "",
-			BaseURL:   "https://cdn.vatusa.net",
+			Endpoint:      "https://digitaloceanspaces.com",
+			Region:        "nyc3",
+			Bucket:        "vatusa",
+			PrivateBucket: "vatusa-private",
+			AccessKey:     "",
+			SecretKey:     "",
+			BaseURL:       "https://cdn.vatusa.net",
 		},
 		OAuth: &OAuth{
 			BaseURL:      "https://auth.vatusa.net",
@@ -81,5 +88,23 @@ func defaultConfig() *Config {
 			ClientID:     "",
 			ClientSecret: "",
 		},
+		SMTP: &SMTPConfig{
+			Host:     "localhost",
+			Port:     "1025",
+			User:     "",
+			Password: "",
+			From:     "VATUSA <no-reply@vatusa.net>",
+		},
+		Discord: &DiscordConfig{
+			BaseURL:       "https://discord.com/api/v10",
+			BotToken:      "",
+			ApplicationID: "",
+			PublicKey:     "",
+			WebsiteURL:    "https://vatusa.net",
+		},
+		OIDC: &OIDCConfig{
+			SigningKey: "",
+			KeyID:      "vatusa-1",
+		},
 	}
 }
@@ -0,0 +1,21 @@
+package config
+
+type DiscordConfig struct {
+	BaseURL       string
+	BotToken      string
+	ApplicationID string
+	// PublicKey is the hex encoded Ed25519 key Discord signs interactions with
+	PublicKey string
+	// WebsiteURL is where links in Discord messages point to
+	WebsiteURL string
+}
+
+func NewDiscordConfig() *DiscordConfig {
+	return &DiscordConfig{
+		BaseURL:       EnvOrDefault("DISCORD_BASE_URL", defaultCfg.Discord.BaseURL),
+		BotToken:      EnvOrDefault("DISCORD_BOT_TOKEN", defaultCfg.Discord.BotToken),
+		ApplicationID: EnvOrDefault("DISCORD_APPLICATION_ID", defaultCfg.Discord.ApplicationID),
+		PublicKey:     EnvOrDefault("DISCORD_PUBLIC_KEY", defaultCfg.Discord.PublicKey),
+		WebsiteURL:    EnvOrDefault("DISCORD_WEBSITE_URL", defaultCfg.Discord.WebsiteURL),
+	}
+}
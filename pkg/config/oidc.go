@@ -0,0 +1,18 @@
+package config
+
+type OIDCConfig struct {
+	// Issuer identifies the API to relying parties, it defaults to the API's OAuth routes
+	Issuer string
+	// SigningKey is the PEM encoded RSA private key ID and access tokens are signed with. A key is generated on start
+	// when it is not set, which logs everyone out of relying parties on every restart.
+	SigningKey string
+	KeyID      string
+}
+
+func NewOIDCConfig() *OIDCConfig {
+	return &OIDCConfig{
+		Issuer:     EnvOrDefault("OIDC_ISSUER", EnvOrDefault("API_BASE_URL", defaultCfg.API.BaseURL)+"/v3/oauth"),
+		SigningKey: EnvOrDefault("OIDC_SIGNING_KEY", defaultCfg.OIDC.SigningKey),
+		KeyID:      EnvOrDefault("OIDC_KEY_ID", defaultCfg.OIDC.KeyID),
+	}
+}
@@ -6,16 +6,19 @@ type S3Config struct {
 	AccessKey string
 	SecretKey string
 	Bucket    string
-	BaseURL   string
+	// PrivateBucket is never served publicly, files in it are downloaded through the API
+	PrivateBucket string
+	BaseURL       string
 }
 
 func NewS3Config() *S3Config {
 	return &S3Config{
-		Endpoint:  EnvOrDefault("S3_ENDPOINT", defaultCfg.S3.Endpoint),
-		Region:    EnvOrDefault("S3_REGION", defaultCfg.S3.Region),
-		Bucket:    EnvOrDefault("S3_BUCKET", defaultCfg.S3.Bucket),
-		AccessKey: EnvOrDefault("S3_ACCESS", defaultCfg.S3.AccessKey),
-		SecretKey: EnvOrDefault("S3_SECRET", defaultCfg.S3.SecretKey),
-		BaseURL:   EnvOrDefault("S3_BASE_URL", defaultCfg.S3.BaseURL),
+		Endpoint:      EnvOrDefault("S3_ENDPOINT", defaultCfg.S3.Endpoint),
+		Region:        EnvOrDefault("S3_REGION", defaultCfg.S3.Region),
+		Bucket:        EnvOrDefault("S3_BUCKET", defaultCfg.S3.Bucket),
+		PrivateBucket: EnvOrDefault("S3_PRIVATE_BUCKET", defaultCfg.S3.PrivateBucket),
+		AccessKey:     EnvOrDefault("S3_ACCESS", defaultCfg.S3.AccessKey),
+		SecretKey:     EnvOrDefault("S3_SECRET", defaultCfg.S3.SecretKey),
+		BaseURL:       EnvOrDefault("S3_BASE_URL", defaultCfg.S3.BaseURL),
 	}
 }
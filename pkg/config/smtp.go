@@ -0,0 +1,19 @@
+package config
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	From     string
+}
+
+func NewSMTPConfig() *SMTPConfig {
+	return &SMTPConfig{
+		Host:     EnvOrDefault("SMTP_HOST", defaultCfg.SMTP.Host),
+		Port:     EnvOrDefault("SMTP_PORT", defaultCfg.SMTP.Port),
+		User:     EnvOrDefault("SMTP_USER", defaultCfg.SMTP.User),
+		Password: EnvOrDefault("SMTP_PASSWORD", defaultCfg.SMTP.Password),
+		From:     EnvOrDefault("SMTP_FROM", defaultCfg.SMTP.From),
+	}
+}
@@ -100,3 +100,27 @@ func (r ATCRating) Long() string {
 	}
 	return "Unknown"
 }
+
+// promotionPath maps each rating to the one the division can promote it to. Ratings above C1 are not granted
+// through facility training.
+var promotionPath = map[ATCRating]ATCRating{
+	ObserverRating: Student1Rating,
+	Student1Rating: Student2Rating,
+	Student2Rating: Student3Rating,
+	Student3Rating: ControllerRating,
+}
+
+// NextRating returns the rating a controller holding r is promoted to, false when the division cannot promote r
+func (r ATCRating) NextRating() (ATCRating, bool) {
+	next, ok := promotionPath[r]
+	return next, ok
+}
+
+// MinimumDaysAtRating is how long a controller must hold their current rating before they can be promoted to the
+// rating it is keyed by. Facilities can require longer.
+var MinimumDaysAtRating = map[ATCRating]uint{
+	Student1Rating:   0,
+	Student2Rating:   30,
+	Student3Rating:   30,
+	ControllerRating: 60,
+}
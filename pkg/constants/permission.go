@@ -0,0 +1,182 @@
+package constants
+
+import "slices"
+
+// Permission is something a principal can be allowed to do at a facility. Roles are granted permissions through
+// their groups in GroupPermissions and their own Role.Permissions.
+type Permission string
+
+const (
+	EditFacilityPermission        Permission = "facility.edit"
+	EditRosterPermission          Permission = "roster.edit"
+	EditRosterRequestPermission   Permission = "roster_request.edit"
+	EditNewsPermission            Permission = "news.edit"
+	EditFAQPermission             Permission = "faq.edit"
+	ViewFeedbackPermission        Permission = "feedback.view"
+	EditFeedbackPermission        Permission = "feedback.edit"
+	EditEventPermission           Permission = "event.edit"
+	ManageEventSignupsPermission  Permission = "event_signup.manage"
+	ViewFacilityLogPermission     Permission = "facility_log.view"
+	EditFacilityLogPermission     Permission = "facility_log.edit"
+	ViewUserPermission            Permission = "user.view"
+	EditUserPermission            Permission = "user.edit"
+	ViewActionLogPermission       Permission = "action_log.view"
+	EditActionLogPermission       Permission = "action_log.edit"
+	ViewDisciplinaryLogPermission Permission = "disciplinary_log.view"
+	// ViewVATUSADisciplinaryLogPermission covers entries only the division can see
+	ViewVATUSADisciplinaryLogPermission Permission = "disciplinary_log.view_vatusa"
+	EditDisciplinaryLogPermission       Permission = "disciplinary_log.edit"
+	ViewRatingChangePermission          Permission = "rating_change.view"
+	ViewUserFlagPermission              Permission = "user_flag.view"
+	EditUserFlagPermission              Permission = "user_flag.edit"
+	ViewNotificationsPermission         Permission = "notification.view"
+	EditNotificationsPermission         Permission = "notification.edit"
+	SendBroadcastsPermission            Permission = "broadcast.send"
+	ViewTrainingPermission              Permission = "training.view"
+	EditTrainingPermission              Permission = "training.edit"
+	ConductOTSPermission                Permission = "training.ots"
+	// RecommendPromotionPermission lets the TA recommend a promotion, which division training staff then approve
+	RecommendPromotionPermission Permission = "promotion.recommend"
+	// ViewAuditLogPermission is not granted to any group, only division staff hold it
+	ViewAuditLogPermission Permission = "audit_log.view"
+)
+
+// Permissions describes every permission
+var Permissions = map[Permission]string{
+	EditFacilityPermission:              "Edit the facility's details, API keys, OAuth clients and Discord server",
+	EditRosterPermission:                "Add and remove controllers from the roster",
+	EditRosterRequestPermission:         "Accept and reject roster requests",
+	EditNewsPermission:                  "Post and edit news",
+	EditFAQPermission:                   "Edit the FAQ",
+	ViewFeedbackPermission:              "View all feedback",
+	EditFeedbackPermission:              "Accept, reject and edit feedback",
+	EditEventPermission:                 "Create and edit events, positions and templates",
+	ManageEventSignupsPermission:        "Sign controllers up for events and remove signups",
+	ViewFacilityLogPermission:           "View the facility log",
+	EditFacilityLogPermission:           "Write to the facility log",
+	ViewUserPermission:                  "View rostered users' profiles",
+	EditUserPermission:                  "Edit any user's profile",
+	ViewActionLogPermission:             "View rostered users' action logs",
+	EditActionLogPermission:             "Write to rostered users' action logs",
+	ViewDisciplinaryLogPermission:       "View rostered users' disciplinary logs",
+	ViewVATUSADisciplinaryLogPermission: "View disciplinary log entries restricted to the division",
+	EditDisciplinaryLogPermission:       "Write to disciplinary logs",
+	ViewRatingChangePermission:          "View rostered users' rating changes",
+	ViewUserFlagPermission:              "View rostered users' flags",
+	EditUserFlagPermission:              "Edit user flags",
+	ViewNotificationsPermission:         "View other users' notifications",
+	EditNotificationsPermission:         "Edit other users' notifications",
+	SendBroadcastsPermission:            "Send broadcasts",
+	ViewTrainingPermission:              "View rostered users' training sessions and OTS exams",
+	EditTrainingPermission:              "Record and edit training sessions",
+	ConductOTSPermission:                "Record and edit OTS exam results",
+	RecommendPromotionPermission:        "Recommend controllers for promotion after a passed OTS exam",
+	ViewAuditLogPermission:              "View the audit log of every change made through the API",
+}
+
+// facilityStaffPermissions are held by every member of a facility's staff
+var facilityStaffPermissions = []Permission{
+	EditNewsPermission,
+	ViewFacilityLogPermission,
+	ViewUserPermission,
+	ViewActionLogPermission,
+	ViewRatingChangePermission,
+}
+
+// seniorStaffPermissions are held by the ATM, DATM and TA, everything short of editing the facility itself
+var seniorStaffPermissions = append(slices.Clone(facilityStaffPermissions),
+	EditRosterPermission,
+	EditRosterRequestPermission,
+	EditFAQPermission,
+	ViewFeedbackPermission,
+	EditFeedbackPermission,
+	EditEventPermission,
+	ManageEventSignupsPermission,
+	EditActionLogPermission,
+	ViewDisciplinaryLogPermission,
+	ViewUserFlagPermission,
+	ViewTrainingPermission,
+)
+
+// trainingStaffPermissions are held by the facility's instructors and mentors. OTS exams are left to instructors and
+// the TA.
+var trainingStaffPermissions = []Permission{
+	ViewTrainingPermission,
+	EditTrainingPermission,
+}
+
+// GroupPermissions grants permissions to every role in a group. Division staff are not listed, anyone on the ZHQ
+// roster holds every permission at every facility.
+var GroupPermissions = map[GroupID][]Permission{
+	FacilityManagement:  {EditFacilityPermission, SendBroadcastsPermission},
+	FacilitySeniorStaff: seniorStaffPermissions,
+	FacilityStaff:       append(slices.Clone(facilityStaffPermissions), SendBroadcastsPermission),
+	FacilityEvents: {
+		EditEventPermission,
+		ManageEventSignupsPermission,
+	},
+	FacilityTraining: trainingStaffPermissions,
+}
+
+// FacilityKeyPermissions are held by facility API keys at their own facility
+var FacilityKeyPermissions = []Permission{
+	EditFacilityPermission,
+	EditRosterPermission,
+	EditRosterRequestPermission,
+	EditNewsPermission,
+	ViewFeedbackPermission,
+	EditFeedbackPermission,
+	EditEventPermission,
+	ManageEventSignupsPermission,
+	ViewFacilityLogPermission,
+	ViewUserPermission,
+	ViewDisciplinaryLogPermission,
+	ViewUserFlagPermission,
+	ViewTrainingPermission,
+	EditTrainingPermission,
+}
+
+func (p Permission) IsValidPermission() bool {
+	_, ok := Permissions[p]
+	return ok
+}
+
+// HasPermission checks if the role is granted p, by itself or through one of its groups, regardless of the rating
+// the role requires
+func (r RoleID) HasPermission(p Permission) bool {
+	if !r.IsValidRole() {
+		return false
+	}
+
+	role := Roles[r]
+	if slices.Contains(role.Permissions, p) {
+		return true
+	}
+	for _, group := range role.Groups {
+		if slices.Contains(GroupPermissions[group], p) {
+			return true
+		}
+	}
+	return false
+}
+
+// GrantsPermission checks if the role grants p to a holder of rating. Roles restricted to some ratings, like the
+// instructor role, grant nothing to anyone else.
+func (r RoleID) GrantsPermission(p Permission, rating ATCRating) bool {
+	if ratings := Roles[r].Ratings; len(ratings) > 0 && !slices.Contains(ratings, rating) {
+		return false
+	}
+	return r.HasPermission(p)
+}
+
+// Permissions lists every permission the role is granted, by itself or through its groups
+func (r RoleID) Permissions() []Permission {
+	perms := []Permission{}
+	for p := range Permissions {
+		if r.HasPermission(p) {
+			perms = append(perms, p)
+		}
+	}
+	slices.Sort(perms)
+	return perms
+}
@@ -5,9 +5,11 @@ type GroupID string
 
 type Role struct {
 	Name         string
-	Groups       []GroupID // Groups this role is a part of
-	RolesCanAdd  []RoleID  // Roles this role can be added by
-	GroupsCanAdd []GroupID // Groups this role can be added by
+	Groups       []GroupID    // Groups this role is a part of
+	RolesCanAdd  []RoleID     // Roles this role can be added by
+	GroupsCanAdd []GroupID    // Groups this role can be added by
+	Permissions  []Permission // Permissions granted on top of the ones from Groups
+	Ratings      []ATCRating  // When set, only holders of one of these ratings are granted the role's permissions
 }
 
 const (
@@ -59,6 +61,7 @@ const (
 
 	// Facility Groups
 	FacilityManagement  GroupID = "fac_mgmt"
+	FacilitySeniorStaff GroupID = "fac_senior_staff"
 	FacilityStaff       GroupID = "fac_staff"
 	FacilityEvents      GroupID = "fac_events"
 	FacilityTraining    GroupID = "fac_training"
@@ -75,6 +78,7 @@ var Roles = map[RoleID]Role{
 		Name: "Air Traffic Manager",
 		Groups: []GroupID{
 			FacilityManagement,
+			FacilitySeniorStaff,
 		},
 		RolesCanAdd: []RoleID{},
 		GroupsCanAdd: []GroupID{
@@ -86,6 +90,7 @@ var Roles = map[RoleID]Role{
 		Name: "Deputy Air Traffic Manager",
 		Groups: []GroupID{
 			FacilityManagement,
+			FacilitySeniorStaff,
 		},
 		RolesCanAdd: []RoleID{
 			AirTrafficManagerRole,
@@ -97,8 +102,11 @@ var Roles = map[RoleID]Role{
 		},
 	},
 	TrainingAdministratorRole: {
-		Name:        "Training Administrator",
-		Groups:      []GroupID{},
+		Name: "Training Administrator",
+		Groups: []GroupID{
+			FacilitySeniorStaff,
+			FacilityTraining,
+		},
 		RolesCanAdd: []RoleID{},
 		GroupsCanAdd: []GroupID{
 			DivisionManagement,
@@ -106,6 +114,10 @@ var Roles = map[RoleID]Role{
 			DivisionTraining,
 			FacilityManagement,
 		},
+		Permissions: []Permission{
+			ConductOTSPermission,
+			RecommendPromotionPermission,
+		},
 	},
 	EventCoordinatorRole: {
 		Name: "Event Coordinator",
@@ -174,6 +186,9 @@ var Roles = map[RoleID]Role{
 			DivisionDevelopment,
 			FacilityManagement,
 		},
+		Permissions: []Permission{
+			EditFacilityPermission,
+		},
 	},
 	AssistantWebMasterRole: {
 		Name: "Assistant Webmaster",
@@ -201,6 +216,14 @@ var Roles = map[RoleID]Role{
 			DivisionTraining,
 			DivisionDevelopment,
 		},
+		Permissions: []Permission{
+			ViewRatingChangePermission,
+			ConductOTSPermission,
+		},
+		Ratings: []ATCRating{
+			InstructorRating,
+			SeniorInstructorRating,
+		},
 	},
 	MentorRole: {
 		Name: "Mentor",
@@ -470,25 +493,38 @@ func (r RoleID) InGroup(group GroupID) bool {
 	return false
 }
 
-// CanAddRole checks if a user with the given roles can add the target role
-func CanAddRole(userRoles []RoleID, targetRole RoleID) bool {
+// RoleGrant is a held role that allows assigning another role, either by being listed in its RolesCanAdd or through
+// Group being listed in its GroupsCanAdd
+type RoleGrant struct {
+	Role  RoleID  `json:"role" example:"ATM"`
+	Group GroupID `json:"group,omitempty" example:"fac_mgmt"`
+}
+
+// AddRoleGrants lists which of userRoles allow adding the target role, and why
+func AddRoleGrants(userRoles []RoleID, targetRole RoleID) []RoleGrant {
+	grants := []RoleGrant{}
 	if !targetRole.IsValidRole() {
-		return false
+		return grants
 	}
 
 	targettedRole := Roles[targetRole]
 	for _, role := range userRoles {
 		for _, r := range targettedRole.RolesCanAdd {
 			if role == r {
-				return true
+				grants = append(grants, RoleGrant{Role: role})
 			}
 		}
 		for _, group := range targettedRole.GroupsCanAdd {
 			if role.InGroup(group) {
-				return true
+				grants = append(grants, RoleGrant{Role: role, Group: group})
 			}
 		}
 	}
 
-	return false
+	return grants
+}
+
+// CanAddRole checks if a user with the given roles can add the target role
+func CanAddRole(userRoles []RoleID, targetRole RoleID) bool {
+	return len(AddRoleGrants(userRoles, targetRole)) > 0
 }
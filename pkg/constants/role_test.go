@@ -0,0 +1,167 @@
+package constants
+
+import (
+	"slices"
+	"testing"
+)
+
+// divisionAdders are the roles that can add every role through the division management and development groups
+var divisionAdders = []RoleID{
+	DeveloperTeamRole, DivisionDirectorRole, AirTrafficServicesRole, TrainingServicesRole, SupportServicesRole,
+	TechnicalManagerRole,
+}
+
+// wantSeniorStaffPermissions are granted to the ATM, DATM and TA through the facility senior staff group
+var wantSeniorStaffPermissions = []Permission{
+	EditActionLogPermission, ViewActionLogPermission, ViewDisciplinaryLogPermission, EditEventPermission,
+	ManageEventSignupsPermission, ViewFacilityLogPermission, EditFAQPermission, EditFeedbackPermission,
+	ViewFeedbackPermission, EditNewsPermission, ViewRatingChangePermission, EditRosterPermission,
+	EditRosterRequestPermission, ViewTrainingPermission, ViewUserPermission, ViewUserFlagPermission,
+}
+
+// wantFacilityStaffPermissions are granted to every facility staff role through the facility staff group
+var wantFacilityStaffPermissions = []Permission{
+	ViewActionLogPermission, SendBroadcastsPermission, ViewFacilityLogPermission, EditNewsPermission,
+	ViewRatingChangePermission, ViewUserPermission,
+}
+
+func TestRoles(t *testing.T) {
+	with := func(roles []RoleID, more ...RoleID) []RoleID {
+		return append(slices.Clone(roles), more...)
+	}
+	withPermissions := func(perms []Permission, more ...Permission) []Permission {
+		return append(slices.Clone(perms), more...)
+	}
+
+	trainingAdders := with(divisionAdders, TrainingServicesManagerRole, TrainingContentManagerRole)
+
+	// Division roles grant no permissions themselves, their holders are on the ZHQ roster which grants everything
+	tests := []struct {
+		role        RoleID
+		adders      []RoleID
+		permissions []Permission
+	}{
+		{role: AirTrafficManagerRole, adders: divisionAdders,
+			permissions: withPermissions(wantSeniorStaffPermissions, SendBroadcastsPermission, EditFacilityPermission)},
+		{role: DeputyAirTrafficManagerRole, adders: with(divisionAdders, AirTrafficManagerRole, DeputyAirTrafficManagerRole),
+			permissions: withPermissions(wantSeniorStaffPermissions, SendBroadcastsPermission, EditFacilityPermission)},
+		{role: TrainingAdministratorRole, adders: with(divisionAdders, AirTrafficManagerRole, DeputyAirTrafficManagerRole,
+			TrainingServicesManagerRole, TrainingContentManagerRole),
+			permissions: withPermissions(wantSeniorStaffPermissions, EditTrainingPermission, ConductOTSPermission,
+				RecommendPromotionPermission)},
+		{role: EventCoordinatorRole, adders: with(divisionAdders, AirTrafficManagerRole, DeputyAirTrafficManagerRole,
+			EventsManagerRole),
+			permissions: withPermissions(wantFacilityStaffPermissions, EditEventPermission, ManageEventSignupsPermission)},
+		{role: AssistantEventCoordinator, adders: []RoleID{AirTrafficManagerRole, DeputyAirTrafficManagerRole,
+			EventCoordinatorRole, DivisionDirectorRole, AirTrafficServicesRole, TrainingServicesRole, SupportServicesRole,
+			EventsManagerRole},
+			permissions: []Permission{EditEventPermission, ManageEventSignupsPermission}},
+		{role: FacilityEngineerRole, adders: with(divisionAdders, AirTrafficManagerRole, DeputyAirTrafficManagerRole),
+			permissions: wantFacilityStaffPermissions},
+		{role: AssistantFacilityEngineer, adders: with(divisionAdders, AirTrafficManagerRole, DeputyAirTrafficManagerRole,
+			FacilityEngineerRole)},
+		{role: WebMasterRole, adders: with(divisionAdders, AirTrafficManagerRole, DeputyAirTrafficManagerRole),
+			permissions: withPermissions(wantFacilityStaffPermissions, EditFacilityPermission)},
+		{role: AssistantWebMasterRole, adders: with(divisionAdders, AirTrafficManagerRole, DeputyAirTrafficManagerRole,
+			WebMasterRole, AssistantWebMasterRole)},
+		{role: InstructorRole, adders: trainingAdders,
+			permissions: []Permission{ViewRatingChangePermission, ViewTrainingPermission, EditTrainingPermission,
+				ConductOTSPermission}},
+		{role: MentorRole, adders: with(trainingAdders, AirTrafficManagerRole, DeputyAirTrafficManagerRole,
+			TrainingAdministratorRole),
+			permissions: []Permission{ViewTrainingPermission, EditTrainingPermission}},
+
+		{role: DivisionDirectorRole, adders: divisionAdders},
+		{role: AirTrafficServicesRole, adders: divisionAdders},
+		{role: TrainingServicesRole, adders: divisionAdders},
+		{role: SupportServicesRole, adders: divisionAdders},
+		{role: EventsManagerRole, adders: divisionAdders},
+		{role: TechnicalManagerRole, adders: divisionAdders},
+		{role: StaffDevelopmentManagerRole, adders: divisionAdders},
+		{role: TrainingServicesManagerRole, adders: trainingAdders},
+		{role: TrainingContentManagerRole, adders: trainingAdders},
+
+		{role: DeveloperTeamRole, adders: divisionAdders},
+		{role: AceTeamRole, adders: []RoleID{DeveloperTeamRole, DivisionDirectorRole, AirTrafficServicesRole,
+			TrainingServicesRole, SupportServicesRole, EventsManagerRole, TechnicalManagerRole}},
+		{role: NTMSRole, adders: with(divisionAdders, EventsManagerRole)},
+		{role: NTMTRole, adders: with(divisionAdders, EventsManagerRole)},
+		{role: SocialMediaTeam, adders: divisionAdders},
+		{role: TrainingContentTeam, adders: trainingAdders},
+		{role: AcademyMaterialEditor, adders: trainingAdders},
+		{role: FacilityMaterialEditor, adders: with(trainingAdders, AirTrafficManagerRole, DeputyAirTrafficManagerRole,
+			TrainingAdministratorRole)},
+
+		{role: EmailUser, adders: []RoleID{AirTrafficManagerRole, DeputyAirTrafficManagerRole, EventCoordinatorRole,
+			FacilityEngineerRole, WebMasterRole, DivisionDirectorRole, AirTrafficServicesRole, TrainingServicesRole,
+			SupportServicesRole, EventsManagerRole, TechnicalManagerRole, StaffDevelopmentManagerRole,
+			TrainingServicesManagerRole, TrainingContentManagerRole}},
+	}
+
+	tested := map[RoleID]bool{}
+	for _, tt := range tests {
+		tested[tt.role] = true
+		t.Run(string(tt.role), func(t *testing.T) {
+			want := slices.Clone(tt.adders)
+			slices.Sort(want)
+			got := []RoleID{}
+			for holder := range Roles {
+				if CanAddRole([]RoleID{holder}, tt.role) {
+					got = append(got, holder)
+				}
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Errorf("roles that can add %s = %v, want %v", tt.role, got, want)
+			}
+
+			wantPerms := slices.Clone(tt.permissions)
+			if wantPerms == nil {
+				wantPerms = []Permission{}
+			}
+			slices.Sort(wantPerms)
+			if got := tt.role.Permissions(); !slices.Equal(got, wantPerms) {
+				t.Errorf("%s permissions = %v, want %v", tt.role, got, wantPerms)
+			}
+		})
+	}
+
+	for role := range Roles {
+		if !tested[role] {
+			t.Errorf("role %s is not covered by TestRoles", role)
+		}
+	}
+}
+
+func TestAddRoleGrants(t *testing.T) {
+	tests := []struct {
+		name      string
+		userRoles []RoleID
+		target    RoleID
+		want      []RoleGrant
+	}{
+		{name: "listed role and group", userRoles: []RoleID{AirTrafficManagerRole}, target: DeputyAirTrafficManagerRole,
+			want: []RoleGrant{{Role: AirTrafficManagerRole}, {Role: AirTrafficManagerRole, Group: FacilityManagement}}},
+		{name: "listed role", userRoles: []RoleID{TrainingAdministratorRole}, target: MentorRole,
+			want: []RoleGrant{{Role: TrainingAdministratorRole}}},
+		{name: "group", userRoles: []RoleID{FacilityEngineerRole}, target: EmailUser,
+			want: []RoleGrant{{Role: FacilityEngineerRole, Group: FacilityStaff}}},
+		{name: "only the granting roles", userRoles: []RoleID{MentorRole, WebMasterRole}, target: AssistantWebMasterRole,
+			want: []RoleGrant{{Role: WebMasterRole}, {Role: WebMasterRole, Group: FacilityDevelopment}}},
+		{name: "no grant", userRoles: []RoleID{MentorRole, InstructorRole}, target: AirTrafficManagerRole,
+			want: []RoleGrant{}},
+		{name: "unknown role", userRoles: []RoleID{DivisionDirectorRole}, target: "XYZ", want: []RoleGrant{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AddRoleGrants(tt.userRoles, tt.target)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("AddRoleGrants(%v, %s) = %v, want %v", tt.userRoles, tt.target, got, tt.want)
+			}
+			if can := CanAddRole(tt.userRoles, tt.target); can != (len(tt.want) > 0) {
+				t.Errorf("CanAddRole(%v, %s) = %v, want %v", tt.userRoles, tt.target, can, len(tt.want) > 0)
+			}
+		})
+	}
+}
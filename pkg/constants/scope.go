@@ -0,0 +1,43 @@
+package constants
+
+// Scope limits what a personal access token can be used for. Write scopes include the matching read scope.
+type Scope string
+
+const (
+	ReadRosterScope         Scope = "read:roster"
+	WriteRosterScope        Scope = "write:roster"
+	ReadEventsScope         Scope = "read:events"
+	WriteEventsScope        Scope = "write:events"
+	ReadFeedbackScope       Scope = "read:feedback"
+	WriteFeedbackScope      Scope = "write:feedback"
+	ReadUsersScope          Scope = "read:users"
+	WriteUsersScope         Scope = "write:users"
+	ReadNotificationsScope  Scope = "read:notifications"
+	WriteNotificationsScope Scope = "write:notifications"
+	ReadTrainingScope       Scope = "read:training"
+	WriteTrainingScope      Scope = "write:training"
+	ReadFacilityScope       Scope = "read:facility"
+	WriteFacilityScope      Scope = "write:facility"
+)
+
+var Scopes = map[Scope]string{
+	ReadRosterScope:         "View rosters and roster requests",
+	WriteRosterScope:        "Manage rosters and roster requests",
+	ReadEventsScope:         "View events and event staffing",
+	WriteEventsScope:        "Manage events, positions, shifts and signups",
+	ReadFeedbackScope:       "View feedback",
+	WriteFeedbackScope:      "Leave and manage feedback",
+	ReadUsersScope:          "View user profiles, flags and roles",
+	WriteUsersScope:         "Manage user profiles, flags and roles",
+	ReadNotificationsScope:  "View notifications",
+	WriteNotificationsScope: "Mark notifications read and dismiss them",
+	ReadTrainingScope:       "View training sessions and OTS exams",
+	WriteTrainingScope:      "Record training sessions and OTS exams",
+	ReadFacilityScope:       "View facility settings, logs and email templates",
+	WriteFacilityScope:      "Manage facility settings, news, FAQ, logs, Discord and email templates",
+}
+
+func (s Scope) IsValidScope() bool {
+	_, ok := Scopes[s]
+	return ok
+}
@@ -3,10 +3,49 @@ package cookie
 import (
 	"github.com/VATUSA/primary-api/pkg/config"
 	"github.com/gorilla/securecookie"
+	"net/http"
+	"time"
 )
 
+// SessionLifetime is how long a login lasts
+const SessionLifetime = 24 * time.Hour
+
 var CookieStore *securecookie.SecureCookie
 
 func New(cfg *config.Config) *securecookie.SecureCookie {
 	return securecookie.New(cfg.Cookie.HashKey, cfg.Cookie.BlockKey)
 }
+
+// SetSession sets the VATUSA cookie to the session token
+func SetSession(w http.ResponseWriter, token string) error {
+	encoded, err := CookieStore.Encode("VATUSA", map[string]string{
+		"session": token,
+	})
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "VATUSA",
+		Value:    encoded,
+		Path:     "/",
+		MaxAge:   int(SessionLifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		Domain:   config.Cfg.Cookie.Domain,
+	})
+	return nil
+}
+
+// ClearSession deletes the VATUSA cookie
+func ClearSession(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "VATUSA",
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		Domain:   config.Cfg.Cookie.Domain,
+		Expires:  time.Unix(0, 0),
+	})
+}
@@ -0,0 +1,145 @@
+// Package dbtest opens throwaway in-memory databases for tests of code that goes through database.DB.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const driverName = "sqlite-dbtest"
+
+var register sync.Once
+
+// Open replaces database.DB with a fresh in-memory SQLite database holding the tables of models, and restores the
+// previous database.DB when the test finishes.
+//
+// SQLite differs from MySQL in two ways the models rely on: it has no enum column type, so enum columns are created
+// as text, and it returns text as strings where MySQL returns []byte, which the Scan methods in pkg/database/types
+// expect, so text values are handed out as []byte like MySQL does.
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	register.Do(func() {
+		db, err := sql.Open(sqlite.DriverName, ":memory:")
+		if err != nil {
+			panic(err)
+		}
+		sql.Register(driverName, &bytesDriver{Driver: db.Driver()})
+		_ = db.Close()
+	})
+
+	db, err := gorm.Open(&sqlite.Dialector{DriverName: driverName, DSN: ":memory:"}, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+		NamingStrategy: schema.NamingStrategy{
+			NameReplacer: strings.NewReplacer("CID", "Cid"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+
+	// Every connection to an in-memory database gets its own database, so keep a single one
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatalf("parsing %T: %v", model, err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if strings.HasPrefix(strings.ToLower(string(field.DataType)), "enum") {
+				field.DataType = "text"
+			}
+		}
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		_ = sqlDB.Close()
+	})
+
+	return db
+}
+
+// bytesDriver wraps the SQLite driver so text columns are scanned as []byte
+type bytesDriver struct {
+	driver.Driver
+}
+
+func (d *bytesDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &bytesConn{Conn: conn}, nil
+}
+
+type bytesConn struct {
+	driver.Conn
+}
+
+func (c *bytesConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *bytesConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &bytesStmt{Stmt: stmt}, nil
+}
+
+func (c *bytesConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+type bytesStmt struct {
+	driver.Stmt
+}
+
+func (s *bytesStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.Stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+}
+
+func (s *bytesStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return &bytesRows{Rows: rows}, nil
+}
+
+type bytesRows struct {
+	driver.Rows
+}
+
+func (r *bytesRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		return err
+	}
+	for i, v := range dest {
+		if s, ok := v.(string); ok {
+			dest[i] = []byte(s)
+		}
+	}
+	return nil
+}
@@ -12,9 +12,11 @@ import (
 // - Changes to Rating Changes Table (for given User)
 
 type ActionLogEntry struct {
-	ID        uint      `json:"id" gorm:"primaryKey" example:"1"`
-	CID       uint      `json:"cid" example:"1293257"`
-	Entry     string    `json:"entry" example:"Changed Preferred OIs to RP"`
+	ID    uint   `json:"id" gorm:"primaryKey" example:"1"`
+	CID   uint   `json:"cid" example:"1293257"`
+	Entry string `json:"entry" example:"Changed Preferred OIs to RP"`
+	// Changes is the structured form of Entry for entries written when a model is changed
+	Changes   Changes   `json:"changes,omitempty" gorm:"serializer:json"`
 	CreatedAt time.Time `json:"created_at" example:"2021-01-01T00:00:00Z"`
 	CreatedBy string    `json:"created_by" example:"'1234567' or 'System'"`
 	UpdatedAt time.Time `json:"updated_at" example:"2021-01-01T00:00:00Z"`
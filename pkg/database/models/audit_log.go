@@ -0,0 +1,123 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ErrAuditLogImmutable is returned when something tries to change or remove audit log entries
+var ErrAuditLogImmutable = errors.New("audit log entries cannot be changed")
+
+const (
+	AuditActorUser      = "user"
+	AuditActorFacility  = "facility"
+	AuditActorAnonymous = "anonymous"
+
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeDenied  = "denied"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditLogEntry records a mutating API call. Entries are append-only: there is no Update or Delete, and the hooks
+// below refuse saves of existing rows and deletes.
+type AuditLogEntry struct {
+	ID        uint   `json:"id" gorm:"primaryKey" example:"1"`
+	ActorType string `json:"actor_type" gorm:"index" example:"user"`
+	// ActorCID is the user the request acted as, 0 for facility API keys
+	ActorCID        uint                 `json:"actor_cid,omitempty" gorm:"index" example:"1293257"`
+	ImpersonatorCID uint                 `json:"impersonator_cid,omitempty" gorm:"index" example:"1234567"`
+	AccessTokenID   uint                 `json:"access_token_id,omitempty" example:"1"`
+	Facility        constants.FacilityID `json:"facility,omitempty" gorm:"index" example:"ZDV"`
+	APIKeyID        uint                 `json:"api_key_id,omitempty" gorm:"index" example:"1"`
+	Method          string               `json:"method" example:"PATCH"`
+	Route           string               `json:"route" gorm:"index" example:"/v3/facility/{FacilityID}/"`
+	Path            string               `json:"path" example:"/v3/facility/ZDV/"`
+	IP              string               `json:"ip" example:"127.0.0.1"`
+	StatusCode      int                  `json:"status_code" example:"200"`
+	Outcome         string               `json:"outcome" gorm:"index" example:"success"`
+	Resources       []AuditLogResource   `json:"resources" gorm:"foreignKey:AuditLogEntryID"`
+	CreatedAt       time.Time            `json:"created_at" gorm:"index" example:"2021-01-01T00:00:00Z"`
+}
+
+// AuditLogResource is a resource an audited request targeted, with its state before and after the request
+type AuditLogResource struct {
+	ID              uint            `json:"-" gorm:"primaryKey"`
+	AuditLogEntryID uint            `json:"-" gorm:"index"`
+	Type            string          `json:"type" gorm:"index:idx_audit_resource" example:"Facility"`
+	ResourceID      string          `json:"resource_id" gorm:"index:idx_audit_resource" example:"ZDV"`
+	Before          json.RawMessage `json:"before,omitempty" gorm:"type:json"`
+	After           json.RawMessage `json:"after,omitempty" gorm:"type:json"`
+	Changes         Changes         `json:"changes,omitempty" gorm:"serializer:json"`
+}
+
+func (e *AuditLogEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+func (e *AuditLogEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+func (r *AuditLogResource) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+func (r *AuditLogResource) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+func (e *AuditLogEntry) Create() error {
+	if e.ID != 0 {
+		return ErrAuditLogImmutable
+	}
+	return database.DB.Create(e).Error
+}
+
+func (e *AuditLogEntry) Get() error {
+	return database.DB.Preload("Resources").First(e, e.ID).Error
+}
+
+// AuditLogFilter narrows down GetAuditLogEntries. Zero fields are ignored.
+type AuditLogFilter struct {
+	ActorCID     uint
+	APIKeyID     uint
+	Facility     constants.FacilityID
+	ResourceType string
+	ResourceID   string
+	Before       time.Time
+}
+
+// GetAuditLogEntries returns up to limit entries matching filter, newest first
+func GetAuditLogEntries(filter AuditLogFilter, limit int) ([]AuditLogEntry, error) {
+	var entries []AuditLogEntry
+
+	query := database.DB.Preload("Resources").Order("created_at DESC, id DESC").Limit(limit)
+	if filter.ActorCID != 0 {
+		query = query.Where("actor_cid = ? OR impersonator_cid = ?", filter.ActorCID, filter.ActorCID)
+	}
+	if filter.APIKeyID != 0 {
+		query = query.Where("api_key_id = ?", filter.APIKeyID)
+	}
+	if filter.Facility != "" {
+		query = query.Where("facility = ?", filter.Facility)
+	}
+	if filter.ResourceType != "" || filter.ResourceID != "" {
+		resources := database.DB.Model(&AuditLogResource{}).Select("audit_log_entry_id")
+		if filter.ResourceType != "" {
+			resources = resources.Where("type = ?", filter.ResourceType)
+		}
+		if filter.ResourceID != "" {
+			resources = resources.Where("resource_id = ?", filter.ResourceID)
+		}
+		query = query.Where("id IN (?)", resources)
+	}
+	if !filter.Before.IsZero() {
+		query = query.Where("created_at < ?", filter.Before)
+	}
+
+	return entries, query.Find(&entries).Error
+}
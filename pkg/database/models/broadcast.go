@@ -0,0 +1,202 @@
+package models
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// Broadcast is a notification sent to every user matching its target, instead of one row per recipient. Facility
+// limits facility, group and rating targets to that facility's roster; Group is only used by group targets and
+// MinRating/MaxRating only by rating targets.
+type Broadcast struct {
+	ID        uint                  `json:"id" gorm:"primaryKey" example:"1"`
+	Target    types.BroadcastTarget `json:"target" gorm:"type:enum('division', 'facility', 'group', 'rating')" example:"facility"`
+	Facility  constants.FacilityID  `json:"facility" example:"ZDV"`
+	Group     constants.GroupID     `json:"group" example:"fac_staff"`
+	MinRating constants.ATCRating   `json:"min_rating" example:"1"`
+	MaxRating constants.ATCRating   `json:"max_rating" example:"12"`
+	Category  string                `json:"category" example:"Administration"`
+	Title     string                `json:"title" example:"Scheduled Maintenance"`
+	Body      string                `json:"body" example:"The website will be down for maintenance on Sunday."`
+	CreatedBy uint                  `json:"created_by" example:"1293257"`
+	PublishAt time.Time             `json:"publish_at" gorm:"index" example:"2021-01-01T00:00:00Z"`
+	ExpireAt  time.Time             `json:"expire_at" gorm:"index" example:"2021-01-01T00:00:00Z"`
+	CreatedAt time.Time             `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt time.Time             `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+// BroadcastReceipt is a user's read state of a broadcast. Receipts are only created once the user reads or dismisses
+// the broadcast, no receipt means unread.
+type BroadcastReceipt struct {
+	ID          uint       `json:"id" gorm:"primaryKey" example:"1"`
+	BroadcastID uint       `json:"broadcast_id" gorm:"uniqueIndex:idx_broadcast_receipt" example:"1"`
+	CID         uint       `json:"cid" gorm:"uniqueIndex:idx_broadcast_receipt" example:"1293257"`
+	ReadAt      *time.Time `json:"read_at" example:"2021-01-01T00:00:00Z"`
+	DismissedAt *time.Time `json:"dismissed_at" example:"2021-01-01T00:00:00Z"`
+}
+
+func (b *Broadcast) Create() error {
+	return database.DB.Create(b).Error
+}
+
+func (b *Broadcast) Update() error {
+	return database.DB.Save(b).Error
+}
+
+func (b *Broadcast) Delete() error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("broadcast_id = ?", b.ID).Delete(&BroadcastReceipt{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(b).Error
+	})
+}
+
+func (b *Broadcast) Get() error {
+	return database.DB.Where("id = ?", b.ID).First(b).Error
+}
+
+// GetBroadcasts returns every broadcast, limited to those scoped to facility unless facility is empty
+func GetBroadcasts(facility constants.FacilityID) ([]Broadcast, error) {
+	var broadcasts []Broadcast
+	query := database.DB.Order("publish_at DESC")
+	if facility != "" {
+		query = query.Where("facility = ?", facility)
+	}
+	return broadcasts, query.Find(&broadcasts).Error
+}
+
+// GetLiveBroadcasts returns the broadcasts that are published and not yet expired
+func GetLiveBroadcasts() ([]Broadcast, error) {
+	var broadcasts []Broadcast
+	now := time.Now()
+	return broadcasts, database.DB.Where("publish_at <= ? AND expire_at > ?", now, now).Order("publish_at DESC").Find(&broadcasts).Error
+}
+
+// IsLive reports whether the broadcast is published and not yet expired
+func (b *Broadcast) IsLive() bool {
+	now := time.Now()
+	return !b.PublishAt.After(now) && b.ExpireAt.After(now)
+}
+
+// AppliesTo reports whether user is a recipient of the broadcast. user's roster and roles must be loaded.
+func (b *Broadcast) AppliesTo(user *User) bool {
+	switch b.Target {
+	case types.BroadcastDivision:
+		return true
+	case types.BroadcastFacility:
+		return b.onRoster(user)
+	case types.BroadcastGroup:
+		for _, roster := range user.Roster {
+			if b.Facility != "" && roster.Facility != b.Facility {
+				continue
+			}
+			for _, role := range roster.Roles {
+				if role.RoleID.InGroup(b.Group) {
+					return true
+				}
+			}
+		}
+		return false
+	case types.BroadcastRating:
+		if user.ControllerRating < b.MinRating || user.ControllerRating > b.MaxRating {
+			return false
+		}
+		return b.Facility == "" || b.onRoster(user)
+	}
+	return false
+}
+
+func (b *Broadcast) onRoster(user *User) bool {
+	for _, roster := range user.Roster {
+		if roster.Facility == b.Facility {
+			return true
+		}
+	}
+	return false
+}
+
+// UserBroadcast is a live broadcast as seen by one user
+type UserBroadcast struct {
+	Broadcast
+	ReadAt *time.Time `json:"read_at" example:"2021-01-01T00:00:00Z"`
+}
+
+// GetUserBroadcasts returns the live broadcasts user is a recipient of and has not dismissed, newest first
+func GetUserBroadcasts(user *User) ([]UserBroadcast, error) {
+	broadcasts, err := GetLiveBroadcasts()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	for _, b := range broadcasts {
+		ids = append(ids, b.ID)
+	}
+
+	receipts := map[uint]BroadcastReceipt{}
+	if len(ids) > 0 {
+		var rows []BroadcastReceipt
+		if err := database.DB.Where("cid = ? AND broadcast_id IN ?", user.CID, ids).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, receipt := range rows {
+			receipts[receipt.BroadcastID] = receipt
+		}
+	}
+
+	list := []UserBroadcast{}
+	for _, b := range broadcasts {
+		if !b.AppliesTo(user) {
+			continue
+		}
+		receipt := receipts[b.ID]
+		if receipt.DismissedAt != nil {
+			continue
+		}
+		list = append(list, UserBroadcast{Broadcast: b, ReadAt: receipt.ReadAt})
+	}
+
+	return list, nil
+}
+
+// MarkBroadcastsRead records that cid read the given broadcasts
+func MarkBroadcastsRead(cid uint, ids []uint) error {
+	return upsertBroadcastReceipts(cid, ids, false)
+}
+
+// DismissBroadcasts records that cid dismissed the given broadcasts, which also marks them read
+func DismissBroadcasts(cid uint, ids []uint) error {
+	return upsertBroadcastReceipts(cid, ids, true)
+}
+
+func upsertBroadcastReceipts(cid uint, ids []uint, dismiss bool) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	receipts := make([]BroadcastReceipt, 0, len(ids))
+	for _, id := range ids {
+		receipt := BroadcastReceipt{BroadcastID: id, CID: cid, ReadAt: &now}
+		if dismiss {
+			receipt.DismissedAt = &now
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	// Keep the original read time when a read broadcast is read again or dismissed
+	assignments := clause.Set{{Column: clause.Column{Name: "read_at"}, Value: gorm.Expr("COALESCE(read_at, VALUES(read_at))")}}
+	if dismiss {
+		assignments = append(assignments, clause.Assignment{Column: clause.Column{Name: "dismissed_at"}, Value: now})
+	}
+
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "broadcast_id"}, {Name: "cid"}},
+		DoUpdates: assignments,
+	}).Create(&receipts).Error
+}
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Redacted stands in for the values of sensitive fields, which are tagged `diff:"redact"`
+const Redacted = "[redacted]"
+
+// Change is a field that differs between two versions of a model
+type Change struct {
+	Field string `json:"field" example:"url"`
+	Old   any    `json:"old" swaggertype:"string" example:"https://denartcc.org"`
+	New   any    `json:"new" swaggertype:"string" example:"https://zdvartcc.org"`
+}
+
+type Changes []Change
+
+// String describes the changes the way log entries are written by hand, e.g. "Change url from 'a' to 'b'"
+func (c Changes) String() string {
+	lines := make([]string, 0, len(c))
+	for _, change := range c {
+		switch {
+		case change.Old == Redacted || change.New == Redacted:
+			lines = append(lines, fmt.Sprintf("Change %s", change.Field))
+		case change.Old == nil:
+			lines = append(lines, fmt.Sprintf("Set %s to '%v'", change.Field, change.New))
+		case change.New == nil:
+			lines = append(lines, fmt.Sprintf("Clear %s from '%v'", change.Field, change.Old))
+		default:
+			lines = append(lines, fmt.Sprintf("Change %s from '%v' to '%v'", change.Field, change.Old, change.New))
+		}
+	}
+	return strings.Join(lines, "; ")
+}
+
+// Diff compares two versions of a model by their JSON. Pass nil as old for a created model and as updated for a
+// deleted one. Relations, timestamps GORM manages and fields hidden from JSON are skipped, as are fields left unset on
+// a created or deleted model. Redacted fields are reported without their values.
+func Diff[T any](old, updated *T) Changes {
+	changes := Changes{}
+	for _, change := range DiffJSON(diffJSON(old), diffJSON(updated)) {
+		if slices.Contains([]string{"created_at", "updated_at", "deleted_at"}, change.Field) ||
+			isDiffObject(change.Old) || isDiffObject(change.New) {
+			continue
+		}
+		if old == nil && isDiffZero(change.New) || updated == nil && isDiffZero(change.Old) {
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes.Redact(RedactedFields(new(T)))
+}
+
+// DiffJSON lists the top level fields whose JSON differs between before and after, by name. Missing and null fields
+// are nil. Values are left unredacted, see Changes.Redact.
+func DiffJSON(before, after json.RawMessage) Changes {
+	old, updated := decodeDiffJSON(before), decodeDiffJSON(after)
+
+	fields := map[string]bool{}
+	for field := range old {
+		fields[field] = true
+	}
+	for field := range updated {
+		fields[field] = true
+	}
+
+	changes := Changes{}
+	for field := range fields {
+		if !reflect.DeepEqual(old[field], updated[field]) {
+			changes = append(changes, Change{Field: field, Old: old[field], New: updated[field]})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+	return changes
+}
+
+// Redact hides the values of fields, keeping the record that they changed
+func (c Changes) Redact(fields []string) Changes {
+	for idx := range c {
+		if !slices.Contains(fields, c[idx].Field) {
+			continue
+		}
+		if c[idx].Old != nil {
+			c[idx].Old = Redacted
+		}
+		if c[idx].New != nil {
+			c[idx].New = Redacted
+		}
+	}
+	return c
+}
+
+// RedactedFields lists the JSON names of v's fields tagged `diff:"redact"`. Fields hidden from JSON never show up in a
+// diff, so they are left out.
+func RedactedFields(v any) []string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("diff") != "redact" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+func diffJSON[T any](v *T) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// decodeDiffJSON decodes the fields of a JSON object, keeping numbers as written so IDs and CIDs print in full
+func decodeDiffJSON(data json.RawMessage) map[string]any {
+	values := map[string]any{}
+	if len(data) == 0 {
+		return values
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return map[string]any{}
+	}
+	for field, value := range values {
+		if value == nil {
+			delete(values, field)
+		}
+	}
+	return values
+}
+
+func isDiffObject(v any) bool {
+	switch v.(type) {
+	case map[string]any, []any:
+		return true
+	}
+	return false
+}
+
+// isDiffZero checks if v is the JSON of a zero value
+func isDiffZero(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == "" || v == time.Time{}.Format(time.RFC3339)
+	case bool:
+		return !v
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f == 0
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type diffModel struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Secret    string     `json:"secret" diff:"redact"`
+	Hidden    string     `json:"-"`
+	Mentor    *uint      `json:"mentor_cid"`
+	Note      *string    `json:"note"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Roles     []UserRole `json:"roles"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func TestDiff(t *testing.T) {
+	mentor, other := uint(1293257), uint(1000000)
+	note := "ready for OTS"
+	expires := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	old := &diffModel{ID: 1, Name: "Denver", Secret: "a", Hidden: "a", Mentor: &mentor, CreatedAt: expires}
+	updated := &diffModel{ID: 1, Name: "Denver ARTCC", Secret: "b", Hidden: "b", Mentor: &other, Note: &note,
+		ExpiresAt: &expires, Roles: []UserRole{{ID: 1}}, CreatedAt: expires, UpdatedAt: time.Now()}
+
+	tests := []struct {
+		name         string
+		old, updated *diffModel
+		want         Changes
+	}{
+		{name: "updated", old: old, updated: updated, want: Changes{
+			{Field: "expires_at", Old: nil, New: "2024-01-01T00:00:00Z"},
+			{Field: "mentor_cid", Old: json.Number("1293257"), New: json.Number("1000000")},
+			{Field: "name", Old: "Denver", New: "Denver ARTCC"},
+			{Field: "note", Old: nil, New: "ready for OTS"},
+			{Field: "secret", Old: Redacted, New: Redacted},
+		}},
+		{name: "pointer cleared", old: updated, updated: &diffModel{ID: 1, Name: "Denver ARTCC", Secret: "b"}, want: Changes{
+			{Field: "expires_at", Old: "2024-01-01T00:00:00Z", New: nil},
+			{Field: "mentor_cid", Old: json.Number("1000000"), New: nil},
+			{Field: "note", Old: "ready for OTS", New: nil},
+		}},
+		{name: "created", old: nil, updated: old, want: Changes{
+			{Field: "id", Old: nil, New: json.Number("1")},
+			{Field: "mentor_cid", Old: nil, New: json.Number("1293257")},
+			{Field: "name", Old: nil, New: "Denver"},
+			{Field: "secret", Old: nil, New: Redacted},
+		}},
+		{name: "deleted", old: &diffModel{ID: 2, Name: "Salt Lake"}, updated: nil, want: Changes{
+			{Field: "id", Old: json.Number("2"), New: nil},
+			{Field: "name", Old: "Salt Lake", New: nil},
+		}},
+		{name: "unchanged", old: old, updated: old, want: Changes{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.old, tt.updated); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	before := json.RawMessage(`{"id": 1, "email": "a@vatusa.net", "roles": [{"id": 1}], "note": null}`)
+	after := json.RawMessage(`{"id": 1, "email": "b@vatusa.net", "roles": [], "discord_id": null}`)
+
+	got := DiffJSON(before, after).Redact([]string{"email"})
+	want := Changes{
+		{Field: "email", Old: Redacted, New: Redacted},
+		{Field: "roles", Old: []any{map[string]any{"id": json.Number("1")}}, New: []any{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffJSON() = %v, want %v", got, want)
+	}
+}
+
+func TestChangesString(t *testing.T) {
+	mentor := uint(1293257)
+	changes := Diff(&diffModel{Name: "Denver", Secret: "a"}, &diffModel{Name: "Denver ARTCC", Secret: "b", Mentor: &mentor})
+
+	want := "Set mentor_cid to '1293257'; Change name from 'Denver' to 'Denver ARTCC'; Change secret"
+	if got := changes.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database"
+	"time"
+)
+
+// DisciplinaryAttachmentDirectory is where attachments are kept in the private bucket
+const DisciplinaryAttachmentDirectory = "disciplinary"
+
+// DisciplinaryAttachment is a file attached to a disciplinary log entry. The file itself lives in the private bucket
+// under DisciplinaryAttachmentDirectory/StorageKey.
+type DisciplinaryAttachment struct {
+	ID          uint      `json:"id" gorm:"primaryKey" example:"1"`
+	EntryID     uint      `json:"entry_id" gorm:"index" example:"1"`
+	Filename    string    `json:"filename" example:"chat-log.png"`
+	ContentType string    `json:"content_type" example:"image/png"`
+	Size        int64     `json:"size" example:"52000"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	CreatedBy   string    `json:"created_by" example:"'1234567' or 'System'"`
+}
+
+func (a *DisciplinaryAttachment) Create() error {
+	return database.DB.Create(a).Error
+}
+
+func (a *DisciplinaryAttachment) Delete() error {
+	return database.DB.Delete(a).Error
+}
+
+func (a *DisciplinaryAttachment) Get() error {
+	return database.DB.Where("id = ?", a.ID).First(a).Error
+}
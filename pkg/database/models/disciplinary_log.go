@@ -1,7 +1,11 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"gorm.io/gorm"
 	"time"
 )
 
@@ -9,23 +13,63 @@ import (
 // - Adding any flags
 // - Changes to rating SUSPENDED
 
+// ErrRestrictionLifted is returned when lifting the restriction of an entry that has none left
+var ErrRestrictionLifted = errors.New("the entry has no restriction left to lift")
+
+// DisciplinaryLogEntry is a note or a disciplinary case. Cases can impose a UserFlag restriction, which is linked
+// back to the entry and lifted automatically at EffectiveUntil.
 type DisciplinaryLogEntry struct {
-	ID         uint      `json:"id" gorm:"primaryKey" example:"1"`
-	CID        uint      `json:"cid" example:"1293257"`
-	Entry      string    `json:"entry" example:"Changed Preferred OIs to RP"`
-	VATUSAOnly bool      `json:"vatusa_only" example:"true"`
-	CreatedAt  time.Time `json:"created_at" example:"2021-01-01T00:00:00Z"`
-	CreatedBy  string    `json:"created_by" example:"'1234567' or 'System'"`
-	UpdatedAt  time.Time `json:"updated_at" example:"2021-01-01T00:00:00Z"`
-	UpdatedBy  string    `json:"updated_by" example:"'1234567' or 'System'"`
+	ID         uint                         `json:"id" gorm:"primaryKey" example:"1"`
+	CID        uint                         `json:"cid" gorm:"index" example:"1293257"`
+	Type       types.DisciplinaryType       `json:"type" gorm:"type:enum('note', 'warning', 'suspension', 'removal');default:'note'" example:"suspension"`
+	Entry      string                       `json:"entry" example:"Changed Preferred OIs to RP"`
+	Visibility types.DisciplinaryVisibility `json:"visibility" gorm:"type:enum('vatusa', 'senior_staff', 'member');default:'senior_staff'" example:"senior_staff"`
+	VATUSAOnly bool                         `json:"-"` // Deprecated: replaced by Visibility, only read to migrate existing entries
+	// Restriction is the UserFlag restriction the case imposes, if any
+	Restriction    types.UserRestriction `json:"restriction,omitempty" gorm:"type:varchar(20)" example:"no_staff_role"`
+	EffectiveFrom  *time.Time            `json:"effective_from,omitempty" example:"2021-01-01T00:00:00Z"`
+	EffectiveUntil *time.Time            `json:"effective_until,omitempty" gorm:"index" example:"2021-02-01T00:00:00Z"`
+	LiftedAt       *time.Time            `json:"lifted_at,omitempty" example:"2021-02-01T00:00:00Z"`
+	LiftedBy       string                `json:"lifted_by,omitempty" example:"'1234567' or 'System'"`
+	// RelatedEntryID is the entry a note was written about, like the case whose restriction it records lifting
+	RelatedEntryID uint                     `json:"related_entry_id,omitempty" example:"1"`
+	Attachments    []DisciplinaryAttachment `json:"attachments" gorm:"foreignKey:EntryID"`
+	CreatedAt      time.Time                `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	CreatedBy      string                   `json:"created_by" example:"'1234567' or 'System'"`
+	UpdatedAt      time.Time                `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedBy      string                   `json:"updated_by" example:"'1234567' or 'System'"`
 }
 
 func (dle *DisciplinaryLogEntry) Create() error {
 	return database.DB.Create(dle).Error
 }
 
+// Update saves the entry. A change to the EffectiveUntil of a restriction still in place is carried over to the
+// user's flags, as long as the entry is still the one linked to the restriction.
 func (dle *DisciplinaryLogEntry) Update() error {
-	return database.DB.Save(dle).Error
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Attachments").Save(dle).Error; err != nil {
+			return err
+		}
+		if !dle.HasActiveRestriction() {
+			return nil
+		}
+
+		flag := &UserFlag{}
+		if err := tx.Where("cid = ?", dle.CID).First(flag).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if !flag.HasRestriction(dle.Restriction) || flag.RestrictionEntryID(dle.Restriction) != dle.ID {
+			return nil
+		}
+
+		fields := flag.restriction(dle.Restriction)
+		*fields.until = dle.EffectiveUntil
+		return tx.Save(flag).Error
+	})
 }
 
 func (dle *DisciplinaryLogEntry) Delete() error {
@@ -33,20 +77,105 @@ func (dle *DisciplinaryLogEntry) Delete() error {
 }
 
 func (dle *DisciplinaryLogEntry) Get() error {
-	return database.DB.Where("id = ?", dle.ID).First(dle).Error
+	return database.DB.Preload("Attachments").Where("id = ?", dle.ID).First(dle).Error
 }
 
-func GetAllDisciplinaryLogEntries(VATUSAOnly bool) ([]DisciplinaryLogEntry, error) {
-	var dle []DisciplinaryLogEntry
-	return dle, database.DB.Where("vatusa_only = ?", VATUSAOnly).Find(&dle).Error
+// HasActiveRestriction reports whether the entry imposed a restriction that has not been lifted yet
+func (dle *DisciplinaryLogEntry) HasActiveRestriction() bool {
+	return dle.Restriction != "" && dle.LiftedAt == nil
+}
+
+// CreateCase creates the entry and imposes its restriction on the user's flags
+func (dle *DisciplinaryLogEntry) CreateCase() error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(dle).Error; err != nil {
+			return err
+		}
+		if dle.Restriction == "" {
+			return nil
+		}
+
+		flag := &UserFlag{CID: dle.CID}
+		if err := tx.Where("cid = ?", dle.CID).FirstOrCreate(flag).Error; err != nil {
+			return err
+		}
+		flag.SetRestriction(dle.Restriction, dle.ID, dle.EffectiveUntil, dle.Entry)
+		return tx.Save(flag).Error
+	})
+}
+
+// LiftRestriction clears the entry's restriction from the user's flags, unless a later entry has taken it over, and
+// writes a note recording it
+func (dle *DisciplinaryLogEntry) LiftRestriction(by string) (*DisciplinaryLogEntry, error) {
+	if !dle.HasActiveRestriction() {
+		return nil, ErrRestrictionLifted
+	}
+
+	var note *DisciplinaryLogEntry
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		flag := &UserFlag{}
+		err := tx.Where("cid = ?", dle.CID).First(flag).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && flag.RestrictionEntryID(dle.Restriction) == dle.ID {
+			flag.ClearRestriction(dle.Restriction)
+			if err := tx.Save(flag).Error; err != nil {
+				return err
+			}
+		}
+
+		note, err = liftedRestriction(tx, dle, by)
+		return err
+	})
+	return note, err
+}
+
+// RecordRestrictionLifted marks the entry's restriction as lifted when it was cleared from the flags directly
+func (dle *DisciplinaryLogEntry) RecordRestrictionLifted(by string) (*DisciplinaryLogEntry, error) {
+	if !dle.HasActiveRestriction() {
+		return nil, ErrRestrictionLifted
+	}
+
+	var note *DisciplinaryLogEntry
+	err := database.DB.Transaction(func(tx *gorm.DB) (err error) {
+		note, err = liftedRestriction(tx, dle, by)
+		return err
+	})
+	return note, err
 }
 
-func GetAllDisciplinaryLogEntriesByCID(cid uint, VATUSAOnly bool) ([]DisciplinaryLogEntry, error) {
-	if VATUSAOnly {
-		var dle []DisciplinaryLogEntry
-		return dle, database.DB.Where("cid = ?", cid).Find(&dle).Error
+func liftedRestriction(tx *gorm.DB, dle *DisciplinaryLogEntry, by string) (*DisciplinaryLogEntry, error) {
+	now := time.Now()
+	dle.LiftedAt = &now
+	dle.LiftedBy = by
+	dle.UpdatedBy = by
+	if err := tx.Omit("Attachments").Save(dle).Error; err != nil {
+		return nil, err
 	}
 
+	note := &DisciplinaryLogEntry{
+		CID:            dle.CID,
+		Type:           types.DisciplinaryNote,
+		Entry:          fmt.Sprintf("Lifted the %s restriction imposed by entry %d.", dle.Restriction, dle.ID),
+		Visibility:     dle.Visibility,
+		RelatedEntryID: dle.ID,
+		CreatedBy:      by,
+		UpdatedBy:      by,
+	}
+	return note, tx.Create(note).Error
+}
+
+// GetDisciplinaryLogEntriesByCID returns the user's entries with one of visibilities, newest first
+func GetDisciplinaryLogEntriesByCID(cid uint, visibilities []types.DisciplinaryVisibility) ([]DisciplinaryLogEntry, error) {
+	var dle []DisciplinaryLogEntry
+	return dle, database.DB.Preload("Attachments").Where("cid = ? AND visibility IN ?", cid, visibilities).
+		Order("created_at DESC").Find(&dle).Error
+}
+
+// GetExpiredDisciplinaryRestrictions returns entries whose restriction is still in place after their EffectiveUntil
+func GetExpiredDisciplinaryRestrictions(now time.Time) ([]DisciplinaryLogEntry, error) {
 	var dle []DisciplinaryLogEntry
-	return dle, database.DB.Where("cid = ? AND vatusa_only = ?", cid, VATUSAOnly).Find(&dle).Error
+	return dle, database.DB.Where("restriction <> '' AND lifted_at IS NULL AND effective_until <= ?", now).
+		Find(&dle).Error
 }
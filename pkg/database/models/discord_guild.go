@@ -0,0 +1,81 @@
+package models
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"gorm.io/gorm"
+	"time"
+)
+
+// DiscordGuild is the Discord server of a facility, ZHQ being the division's server, along with the roles that are
+// kept in sync with VATUSA
+type DiscordGuild struct {
+	ID        uint                 `json:"id" gorm:"primaryKey" example:"1"`
+	Facility  constants.FacilityID `json:"facility" gorm:"uniqueIndex" example:"ZDV"`
+	GuildID   string               `json:"guild_id" gorm:"index" example:"1234567890"`
+	Roles     []DiscordRoleMapping `json:"roles" gorm:"foreignKey:DiscordGuildID"`
+	CreatedAt time.Time            `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt time.Time            `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+// DiscordRoleMapping grants a Discord role to users matching Source and Value. Only mapped roles are managed, any
+// other role in the guild is left alone.
+type DiscordRoleMapping struct {
+	ID             uint                    `json:"id" gorm:"primaryKey" example:"1"`
+	DiscordGuildID uint                    `json:"-" gorm:"index"`
+	Source         types.DiscordRoleSource `json:"source" gorm:"type:enum('rating', 'home', 'visiting', 'staff')" example:"rating"`
+	Value          string                  `json:"value" example:"S3"`
+	DiscordRoleID  string                  `json:"discord_role_id" example:"1234567890"`
+}
+
+func (g *DiscordGuild) Create() error {
+	return database.DB.Create(g).Error
+}
+
+// Update saves the guild and replaces its role mappings
+func (g *DiscordGuild) Update() error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Roles").Save(g).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("discord_guild_id = ?", g.ID).Delete(&DiscordRoleMapping{}).Error; err != nil {
+			return err
+		}
+		for idx := range g.Roles {
+			g.Roles[idx].ID = 0
+			g.Roles[idx].DiscordGuildID = g.ID
+		}
+		if len(g.Roles) == 0 {
+			return nil
+		}
+		return tx.Create(&g.Roles).Error
+	})
+}
+
+func (g *DiscordGuild) Delete() error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("discord_guild_id = ?", g.ID).Delete(&DiscordRoleMapping{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(g).Error
+	})
+}
+
+func (g *DiscordGuild) Get() error {
+	if g.Facility != "" {
+		return database.DB.Preload("Roles").Where("facility = ?", g.Facility).First(g).Error
+	}
+	return database.DB.Preload("Roles").Where("id = ?", g.ID).First(g).Error
+}
+
+func GetDiscordGuilds() ([]DiscordGuild, error) {
+	var guilds []DiscordGuild
+	return guilds, database.DB.Preload("Roles").Find(&guilds).Error
+}
+
+// GetDiscordLinkedUsers returns every user with a linked Discord account, with their roster and roles loaded
+func GetDiscordLinkedUsers() ([]User, error) {
+	var users []User
+	return users, database.DB.Preload("Roster.Roles").Where("discord_id <> ''").Find(&users).Error
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"time"
+)
+
+// EmailTemplate is a facility's override of one of the system email templates. Empty parts fall back to the
+// default template.
+type EmailTemplate struct {
+	ID        uint                 `json:"id" gorm:"primaryKey" example:"1"`
+	Facility  constants.FacilityID `json:"facility" gorm:"size:3;not null;uniqueIndex:idx_email_template" example:"ZDV"`
+	Name      string               `json:"name" gorm:"size:64;not null;uniqueIndex:idx_email_template" example:"event_assignment"`
+	Subject   string               `json:"subject" example:"{{.Data.event}} assignment"`
+	Text      string               `json:"text" gorm:"type:text" example:"You are working {{.Data.position}}."`
+	HTML      string               `json:"html" gorm:"type:text" example:"<p>You are working {{.Data.position}}.</p>"`
+	CreatedAt time.Time            `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt time.Time            `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+func (et *EmailTemplate) Create() error {
+	return database.DB.Create(et).Error
+}
+
+func (et *EmailTemplate) Update() error {
+	return database.DB.Save(et).Error
+}
+
+func (et *EmailTemplate) Delete() error {
+	return database.DB.Delete(et).Error
+}
+
+func (et *EmailTemplate) Get() error {
+	if et.ID != 0 {
+		return database.DB.First(et).Error
+	}
+	return database.DB.Where("facility = ? AND name = ?", et.Facility, et.Name).First(et).Error
+}
+
+func GetEmailTemplatesByFacility(facility constants.FacilityID) ([]EmailTemplate, error) {
+	var templates []EmailTemplate
+	return templates, database.DB.Where("facility = ?", facility).Find(&templates).Error
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"gorm.io/gorm"
 	"time"
 )
 
@@ -22,6 +24,19 @@ type Event struct {
 	Positions []EventPosition `json:"positions" gorm:"foreignKey:EventID"`
 	Routing   []EventRouting  `json:"routing" gorm:"foreignKey:EventID"`
 
+	StaffingStatus types.StaffingStatus `json:"staffing_status" gorm:"type:enum('draft', 'published', 'finalized');default:'draft'" example:"draft"`
+	PublishedAt    *time.Time           `json:"published_at" example:"2021-01-01T00:00:00Z"`
+	FinalizedAt    *time.Time           `json:"finalized_at" example:"2021-01-01T00:00:00Z"`
+
+	// RecurrenceRule is only set on the series definition, occurrences reference it through SeriesID
+	RecurrenceRule       string      `json:"recurrence_rule" example:"FREQ=WEEKLY;BYDAY=TU"`
+	RecurrenceExceptions []time.Time `json:"recurrence_exceptions" gorm:"serializer:json"`
+	SeriesID             *uint       `json:"series_id" gorm:"index" example:"1"`
+	OccurrenceStart      *time.Time  `json:"occurrence_start" example:"2021-01-01T00:00:00Z"`
+	Detached             bool        `json:"detached" gorm:"not null;default:false" example:"false"`
+	// TimeZone is the IANA time zone the recurrence rule is expanded in, empty means UTC
+	TimeZone string `json:"time_zone" example:"America/Denver"`
+
 	CreatedAt time.Time `json:"created_at" example:"2021-01-01T00:00:00Z"`
 	UpdatedAt time.Time `json:"updated_at" example:"2021-01-01T00:00:00Z"`
 }
@@ -31,7 +46,9 @@ func (e *Event) Create() error {
 }
 
 func (e *Event) Get() error {
-	return database.DB.Preload("Positions.Signups").Preload("Routing").First(e).Error
+	return database.DB.Preload("Positions.Shifts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("start_time ASC")
+	}).Preload("Positions.Shifts.Signups").Preload("Routing").First(e).Error
 }
 
 func (e *Event) Update() error {
@@ -42,10 +59,23 @@ func (e *Event) Delete() error {
 	return database.DB.Delete(e).Error
 }
 
+// Location returns the location of the event's TimeZone, UTC when it is not set or unknown
+func (e *Event) Location() *time.Location {
+	if e.TimeZone == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(e.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
 func GetEventsFiltered(page, pageSize int, facilityID constants.FacilityID, afterDate time.Time) ([]Event, error) {
 	var events []Event
 
-	query := database.DB.Preload("Positions").Preload("Routing")
+	query := database.DB.Preload("Positions.Shifts").Preload("Routing")
 	if !afterDate.IsZero() {
 		query = query.Where("end_date > ?", afterDate)
 	}
@@ -3,6 +3,7 @@ package models
 import (
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database"
+	"gorm.io/gorm"
 	"time"
 )
 
@@ -10,13 +11,10 @@ type EventPosition struct {
 	ID      uint `json:"id" gorm:"primaryKey" example:"1"`
 	EventID uint `json:"event_id" gorm:"not null" example:"1"`
 
-	Facility          constants.FacilityID `json:"facility" example:"ZDV"`
-	Position          string               `json:"position" gorm:"not null" example:"ZDV_APP"`
-	Assignee          uint                 `json:"assignee" example:"1293257"`
-	Shifts            bool                 `json:"shifts" gorm:"not null;default:false" example:"true"`
-	SecondaryAssignee uint                 `json:"secondary_assignee" example:"1293257"`
+	Facility constants.FacilityID `json:"facility" example:"ZDV"`
+	Position string               `json:"position" gorm:"not null" example:"ZDV_APP"`
 
-	Signups []EventSignup `json:"signups" gorm:"foreignKey:PositionID"`
+	Shifts []EventShift `json:"shifts" gorm:"foreignKey:PositionID"`
 
 	CreatedAt time.Time `json:"created_at" example:"2021-01-01T00:00:00Z"`
 	UpdatedAt time.Time `json:"updated_at" example:"2021-01-01T00:00:00Z"`
@@ -27,7 +25,9 @@ func (ep *EventPosition) Create() error {
 }
 
 func (ep *EventPosition) Get() error {
-	return database.DB.Preload("Signups").First(ep).Error
+	return database.DB.Preload("Shifts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("start_time ASC")
+	}).Preload("Shifts.Signups").First(ep).Error
 }
 
 func (ep *EventPosition) Update() error {
@@ -35,12 +35,22 @@ func (ep *EventPosition) Update() error {
 }
 
 func (ep *EventPosition) Delete() error {
-	return database.DB.Delete(ep).Error
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("position_id = ?", ep.ID).Delete(&EventSignup{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("position_id = ?", ep.ID).Delete(&EventShift{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(ep).Error
+	})
 }
 
 func GetEventPositionsFiltered(eventId uint, facilityID constants.FacilityID) ([]EventPosition, error) {
 	var positions []EventPosition
-	query := database.DB.Preload("Signups")
+	query := database.DB.Preload("Shifts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("start_time ASC")
+	}).Preload("Shifts.Signups")
 	if eventId != 0 {
 		query = query.Where("event_id = ?", eventId)
 	}
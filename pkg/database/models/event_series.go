@@ -0,0 +1,252 @@
+package models
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/recurrence"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// EventSeriesHorizon is how far ahead occurrences of a recurring event are generated
+const EventSeriesHorizon = 90 * 24 * time.Hour
+
+// IsSeries reports whether e is the definition of a recurring event series
+func (e *Event) IsSeries() bool {
+	return e.RecurrenceRule != "" && e.SeriesID == nil
+}
+
+func GetEventSeries() ([]Event, error) {
+	var series []Event
+	return series, database.DB.Preload("Positions.Shifts").Preload("Routing").
+		Where("recurrence_rule <> '' AND series_id IS NULL").Find(&series).Error
+}
+
+func GetSeriesOccurrences(seriesID uint) ([]Event, error) {
+	var occurrences []Event
+	return occurrences, database.DB.Preload("Positions.Shifts.Signups").
+		Where("series_id = ?", seriesID).Order("start_date ASC").Find(&occurrences).Error
+}
+
+// GenerateOccurrences creates every future occurrence of the series up to until that does not exist yet. Each
+// occurrence copies the series' details, positions (with their shifts moved to the occurrence) and routing.
+func (e *Event) GenerateOccurrences(until time.Time) error {
+	rule, err := recurrence.Parse(e.RecurrenceRule)
+	if err != nil {
+		return err
+	}
+
+	occurrences, err := GetSeriesOccurrences(e.ID)
+	if err != nil {
+		return err
+	}
+
+	// Occurrences are keyed by day so a kept occurrence is not duplicated when the series' time changes
+	loc := e.Location()
+	taken := map[string]bool{}
+	for _, o := range occurrences {
+		if o.OccurrenceStart != nil {
+			taken[occurrenceKey(*o.OccurrenceStart, loc)] = true
+		}
+	}
+	for _, t := range e.RecurrenceExceptions {
+		taken[occurrenceKey(t, loc)] = true
+	}
+
+	// The rule is expanded in the series' time zone so occurrences keep their local start time across DST
+	now := time.Now()
+	length := e.EndDate.Sub(e.StartDate)
+	for _, start := range rule.Occurrences(e.StartDate.In(loc), until)[1:] {
+		if !start.After(now) || taken[occurrenceKey(start, loc)] {
+			continue
+		}
+
+		if err := e.createOccurrence(start, length); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *Event) createOccurrence(start time.Time, length time.Duration) error {
+	seriesID := e.ID
+	occurrenceStart := start
+	offset := start.Sub(e.StartDate)
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		occurrence := &Event{
+			Title:           e.Title,
+			Description:     e.Description,
+			BannerURL:       e.BannerURL,
+			StartDate:       start,
+			EndDate:         start.Add(length),
+			Fields:          e.Fields,
+			Facilities:      e.Facilities,
+			TimeZone:        e.TimeZone,
+			StaffingStatus:  types.StaffingDraft,
+			SeriesID:        &seriesID,
+			OccurrenceStart: &occurrenceStart,
+		}
+		if err := tx.Omit(clause.Associations).Create(occurrence).Error; err != nil {
+			return err
+		}
+
+		for _, p := range e.Positions {
+			position := &EventPosition{
+				EventID:  occurrence.ID,
+				Facility: p.Facility,
+				Position: p.Position,
+			}
+			if err := tx.Omit(clause.Associations).Create(position).Error; err != nil {
+				return err
+			}
+
+			for _, s := range p.Shifts {
+				shift := &EventShift{
+					EventID:    occurrence.ID,
+					PositionID: position.ID,
+					StartTime:  s.StartTime.Add(offset),
+					EndTime:    s.EndTime.Add(offset),
+				}
+				if err := tx.Omit(clause.Associations).Create(shift).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, r := range e.Routing {
+			routing := &EventRouting{
+				EventID:     occurrence.ID,
+				Origin:      r.Origin,
+				Destination: r.Destination,
+				Routing:     r.Routing,
+				Notes:       r.Notes,
+			}
+			if err := tx.Create(routing).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// UpdateSeries carries changes to the series definition over to its future occurrences that were not edited on
+// their own. Occurrences nobody has signed up for or been assigned to yet are regenerated from the definition,
+// the rest keep their positions and only have their details updated.
+func (e *Event) UpdateSeries() error {
+	occurrences, err := GetSeriesOccurrences(e.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for idx := range occurrences {
+		o := &occurrences[idx]
+		if o.Detached || !o.StartDate.After(now) {
+			continue
+		}
+
+		if o.StaffingStatus == types.StaffingDraft && !o.hasStaffing() {
+			if err := o.deleteWithChildren(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		o.Title = e.Title
+		o.Description = e.Description
+		o.BannerURL = e.BannerURL
+		o.Fields = e.Fields
+		o.Facilities = e.Facilities
+		o.TimeZone = e.TimeZone
+		if err := database.DB.Omit(clause.Associations).Save(o).Error; err != nil {
+			return err
+		}
+	}
+
+	if e.RecurrenceRule == "" {
+		return nil
+	}
+
+	return e.GenerateOccurrences(now.Add(EventSeriesHorizon))
+}
+
+// DeleteSeries deletes the series definition and its future occurrences. Past occurrences are kept as standalone
+// events.
+func (e *Event) DeleteSeries() error {
+	occurrences, err := GetSeriesOccurrences(e.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for idx := range occurrences {
+		o := &occurrences[idx]
+		if o.StartDate.After(now) {
+			if err := o.deleteWithChildren(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := database.DB.Model(o).Update("series_id", nil).Error; err != nil {
+			return err
+		}
+	}
+
+	return e.deleteWithChildren()
+}
+
+// DeleteOccurrence deletes a single occurrence and records it as an exception on its series so it is not
+// generated again.
+func (e *Event) DeleteOccurrence() error {
+	if e.SeriesID != nil && e.OccurrenceStart != nil {
+		series := &Event{ID: *e.SeriesID}
+		if err := database.DB.First(series).Error; err != nil {
+			return err
+		}
+
+		series.RecurrenceExceptions = append(series.RecurrenceExceptions, *e.OccurrenceStart)
+		if err := database.DB.Omit(clause.Associations).Save(series).Error; err != nil {
+			return err
+		}
+	}
+
+	return e.deleteWithChildren()
+}
+
+func (e *Event) hasStaffing() bool {
+	for _, p := range e.Positions {
+		for _, s := range p.Shifts {
+			if s.Assignee != 0 || len(s.Signups) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *Event) deleteWithChildren() error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("event_id = ?", e.ID).Delete(&EventSignup{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id = ?", e.ID).Delete(&EventShift{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id = ?", e.ID).Delete(&EventPosition{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id = ?", e.ID).Delete(&EventRouting{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(e).Error
+	})
+}
+
+func occurrenceKey(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02")
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"time"
+)
+
+type EventShift struct {
+	ID         uint `json:"id" gorm:"primaryKey" example:"1"`
+	EventID    uint `json:"event_id" gorm:"not null" example:"1"`
+	PositionID uint `json:"position_id" gorm:"not null" example:"1"`
+
+	StartTime    time.Time `json:"start_time" gorm:"not null" example:"2021-01-01T23:00:00Z"`
+	EndTime      time.Time `json:"end_time" gorm:"not null" example:"2021-01-02T01:00:00Z"`
+	Assignee     uint      `json:"assignee" example:"1293257"`
+	AssigneeName string    `json:"assignee_name" gorm:"-" example:"John - JD"`
+
+	Signups []EventSignup `json:"signups" gorm:"foreignKey:ShiftID"`
+
+	CreatedAt time.Time `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+func (es *EventShift) Create() error {
+	return database.DB.Create(es).Error
+}
+
+func (es *EventShift) Get() error {
+	return database.DB.Preload("Signups").First(es).Error
+}
+
+func (es *EventShift) Update() error {
+	return database.DB.Save(es).Error
+}
+
+func (es *EventShift) Delete() error {
+	if err := database.DB.Where("shift_id = ?", es.ID).Delete(&EventSignup{}).Error; err != nil {
+		return err
+	}
+	return database.DB.Delete(es).Error
+}
+
+// CountEventShifts returns how many shifts an event position has
+func CountEventShifts(positionID uint) (int64, error) {
+	var count int64
+	return count, database.DB.Model(&EventShift{}).Where("position_id = ?", positionID).Count(&count).Error
+}
+
+func GetEventShiftsFiltered(positionID uint) ([]EventShift, error) {
+	var shifts []EventShift
+	query := database.DB.Preload("Signups")
+	if positionID != 0 {
+		query = query.Where("position_id = ?", positionID)
+	}
+	return shifts, query.Order("start_time ASC").Find(&shifts).Error
+}
+
+// EventAssignment is a shift a user is assigned to or signed up for, flattened with its event and position
+type EventAssignment struct {
+	ShiftID        uint                 `json:"shift_id"`
+	EventID        uint                 `json:"event_id"`
+	EventTitle     string               `json:"event_title"`
+	Facility       constants.FacilityID `json:"facility"`
+	Position       string               `json:"position"`
+	StartTime      time.Time            `json:"start_time"`
+	EndTime        time.Time            `json:"end_time"`
+	StaffingStatus types.StaffingStatus `json:"staffing_status"`
+	Assigned       bool                 `json:"assigned"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+}
+
+// GetEventAssignments returns the shifts ending after after that cid is assigned to on events whose assignments
+// were published, along with the shifts cid signed up for on events whose assignments are still drafts.
+func GetEventAssignments(cid uint, after time.Time) ([]EventAssignment, error) {
+	var assignments []EventAssignment
+
+	columns := "event_shifts.id AS shift_id, event_shifts.event_id, events.title AS event_title, " +
+		"event_positions.facility, event_positions.position, event_shifts.start_time, event_shifts.end_time, " +
+		"events.staffing_status, GREATEST(event_shifts.updated_at, events.updated_at) AS updated_at"
+
+	if err := database.DB.Table("event_shifts").Select(columns+", TRUE AS assigned").
+		Joins("JOIN events ON events.id = event_shifts.event_id").
+		Joins("JOIN event_positions ON event_positions.id = event_shifts.position_id").
+		Where("event_shifts.assignee = ? AND event_shifts.end_time > ? AND events.staffing_status <> ?", cid, after, types.StaffingDraft).
+		Scan(&assignments).Error; err != nil {
+		return nil, err
+	}
+
+	var signups []EventAssignment
+	if err := database.DB.Table("event_signups").Select(columns+", FALSE AS assigned").
+		Joins("JOIN event_shifts ON event_shifts.id = event_signups.shift_id").
+		Joins("JOIN events ON events.id = event_shifts.event_id").
+		Joins("JOIN event_positions ON event_positions.id = event_shifts.position_id").
+		Where("event_signups.cid = ? AND event_shifts.end_time > ? AND events.staffing_status = ?", cid, after, types.StaffingDraft).
+		Scan(&signups).Error; err != nil {
+		return nil, err
+	}
+
+	return append(assignments, signups...), nil
+}
+
+const (
+	// MaxEventShifts is the most shifts a single event position can be split into
+	MaxEventShifts = 12
+	// MinEventShiftLength is the shortest shift SplitEventShifts will create
+	MinEventShiftLength = 30 * time.Minute
+)
+
+var (
+	ErrEventShiftCount    = fmt.Errorf("shift count must be between 1 and %d", MaxEventShifts)
+	ErrEventShiftTooShort = fmt.Errorf("shifts must be at least %s long", MinEventShiftLength)
+	ErrEventShiftWindow   = errors.New("shifts must be within the start and end of the event")
+)
+
+// ValidateEventShift checks a shift between start and end against the bounds SplitEventShifts applies: it must be at
+// least MinEventShiftLength long and within the start and end of the event.
+func ValidateEventShift(ev *Event, start, end time.Time) error {
+	if end.Sub(start) < MinEventShiftLength {
+		return ErrEventShiftTooShort
+	}
+	if start.Before(ev.StartDate) || end.After(ev.EndDate) {
+		return ErrEventShiftWindow
+	}
+	return nil
+}
+
+// SplitEventShifts splits the window between start and end into count back-to-back shifts of equal length. count
+// must be between 1 and MaxEventShifts and every shift must be at least MinEventShiftLength long.
+func SplitEventShifts(eventID, positionID uint, start, end time.Time, count int) ([]EventShift, error) {
+	if count < 1 || count > MaxEventShifts {
+		return nil, ErrEventShiftCount
+	}
+	if end.Sub(start)/time.Duration(count) < MinEventShiftLength {
+		return nil, ErrEventShiftTooShort
+	}
+
+	return splitEventShifts(eventID, positionID, start, end, count), nil
+}
+
+func splitEventShifts(eventID, positionID uint, start, end time.Time, count int) []EventShift {
+	length := end.Sub(start) / time.Duration(count)
+	shifts := make([]EventShift, 0, count)
+	for i := 0; i < count; i++ {
+		shiftEnd := start.Add(length * time.Duration(i+1))
+		if i == count-1 {
+			shiftEnd = end
+		}
+
+		shifts = append(shifts, EventShift{
+			EventID:    eventID,
+			PositionID: positionID,
+			StartTime:  start.Add(length * time.Duration(i)),
+			EndTime:    shiftEnd,
+		})
+	}
+
+	return shifts
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSplitEventShifts(t *testing.T) {
+	start := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		length time.Duration
+		count  int
+		want   int
+		err    error
+	}{
+		{name: "single shift", length: 3 * time.Hour, count: 1, want: 1},
+		{name: "even split", length: 3 * time.Hour, count: 3, want: 3},
+		{name: "maximum shifts", length: 6 * time.Hour, count: MaxEventShifts, want: MaxEventShifts},
+		{name: "zero shifts", length: 3 * time.Hour, count: 0, err: ErrEventShiftCount},
+		{name: "too many shifts", length: 24 * time.Hour, count: MaxEventShifts + 1, err: ErrEventShiftCount},
+		{name: "shifts too short", length: time.Hour, count: 3, err: ErrEventShiftTooShort},
+		{name: "end before start", length: -time.Hour, count: 1, err: ErrEventShiftTooShort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end := start.Add(tt.length)
+			shifts, err := SplitEventShifts(1, 2, start, end, tt.count)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("SplitEventShifts() error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			if len(shifts) != tt.want {
+				t.Fatalf("SplitEventShifts() returned %d shifts, want %d", len(shifts), tt.want)
+			}
+			if !shifts[0].StartTime.Equal(start) || !shifts[len(shifts)-1].EndTime.Equal(end) {
+				t.Errorf("shifts cover %s - %s, want %s - %s", shifts[0].StartTime, shifts[len(shifts)-1].EndTime, start, end)
+			}
+			for i, shift := range shifts {
+				if shift.EventID != 1 || shift.PositionID != 2 {
+					t.Errorf("shift %d belongs to event %d position %d", i, shift.EventID, shift.PositionID)
+				}
+				if shift.EndTime.Sub(shift.StartTime) < MinEventShiftLength {
+					t.Errorf("shift %d is %s long", i, shift.EndTime.Sub(shift.StartTime))
+				}
+				if i > 0 && !shift.StartTime.Equal(shifts[i-1].EndTime) {
+					t.Errorf("shift %d starts at %s, previous shift ends at %s", i, shift.StartTime, shifts[i-1].EndTime)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateEventShift(t *testing.T) {
+	start := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	ev := &Event{StartDate: start, EndDate: start.Add(4 * time.Hour)}
+
+	tests := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+		err   error
+	}{
+		{name: "whole event", start: ev.StartDate, end: ev.EndDate},
+		{name: "minimum length", start: start.Add(time.Hour), end: start.Add(time.Hour + MinEventShiftLength)},
+		{name: "too short", start: start.Add(time.Hour), end: start.Add(time.Hour + MinEventShiftLength - time.Minute),
+			err: ErrEventShiftTooShort},
+		{name: "end before start", start: start.Add(2 * time.Hour), end: start.Add(time.Hour), err: ErrEventShiftTooShort},
+		{name: "starts before the event", start: start.Add(-time.Hour), end: start.Add(time.Hour), err: ErrEventShiftWindow},
+		{name: "ends after the event", start: start.Add(3 * time.Hour), end: start.Add(5 * time.Hour),
+			err: ErrEventShiftWindow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateEventShift(ev, tt.start, tt.end); !errors.Is(err, tt.err) {
+				t.Errorf("ValidateEventShift() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
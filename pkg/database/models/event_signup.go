@@ -10,9 +10,9 @@ type EventSignup struct {
 	EventID uint `json:"event_id" gorm:"not null" example:"1"`
 
 	PositionID uint   `json:"position_id" gorm:"not null" example:"1"`
+	ShiftID    uint   `json:"shift_id" gorm:"not null" example:"1"`
 	CID        uint   `json:"cid" gorm:"not null" example:"1293257"`
 	Name       string `json:"name" gorm:"-"`
-	Shift      uint   `json:"shift" gorm:"not null;default:1" example:"1"` // 1 = Primary, 2 = Secondary
 
 	CreatedAt time.Time `json:"created_at" example:"2021-01-01T00:00:00Z"`
 	UpdatedAt time.Time `json:"updated_at" example:"2021-01-01T00:00:00Z"`
@@ -3,6 +3,8 @@ package models
 import (
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"time"
 )
 
@@ -13,7 +15,7 @@ type EventTemplate struct {
 	Positions  []string               `json:"positions" gorm:"serializer:json" example:"[\"ZDV_APP\", \"ZDV_TWR\"]"`
 	Facilities []constants.FacilityID `json:"facilities" gorm:"serializer:json" example:"[\"ZDV\", \"ZAB\", \"ZLC\"]"`
 	Fields     []string               `json:"fields" gorm:"serializer:json" example:"[\"KDEN\", \"KBJC\", \"KAPA\"]"`
-	Shifts     bool                   `json:"shifts" gorm:"not null;default:false" example:"true"`
+	ShiftCount uint                   `json:"shift_count" gorm:"not null;default:1" example:"2"` // Number of equal length shifts per position
 
 	CreatedAt time.Time `json:"created_at" example:"2021-01-01T00:00:00Z"`
 	UpdatedAt time.Time `json:"updated_at" example:"2021-01-01T00:00:00Z"`
@@ -42,3 +44,40 @@ func GetEventTemplatesFiltered(facility constants.FacilityID) ([]EventTemplate,
 	}
 	return ets, nil
 }
+
+// CreateEvent creates ev along with a position (split into the template's shift count) for every position in the
+// template. Everything is created in a single transaction so a failure never leaves a half built event behind.
+func (et *EventTemplate) CreateEvent(ev *Event, facility constants.FacilityID) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(ev).Error; err != nil {
+			return err
+		}
+
+		ev.Positions = []EventPosition{}
+		for _, name := range et.Positions {
+			position := EventPosition{
+				EventID:  ev.ID,
+				Facility: facility,
+				Position: name,
+			}
+
+			if err := tx.Omit(clause.Associations).Create(&position).Error; err != nil {
+				return err
+			}
+
+			shifts, err := SplitEventShifts(ev.ID, position.ID, ev.StartDate, ev.EndDate, int(et.ShiftCount))
+			if err != nil {
+				return err
+			}
+
+			position.Shifts = shifts
+			if err := tx.Create(&position.Shifts).Error; err != nil {
+				return err
+			}
+
+			ev.Positions = append(ev.Positions, position)
+		}
+
+		return nil
+	})
+}
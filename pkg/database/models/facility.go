@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database"
+	"gorm.io/gorm"
 	"time"
 )
 
@@ -13,13 +14,19 @@ type Facility struct {
 	Name             string               `json:"name" example:"Denver ARTCC"`
 	About            string               `json:"about" example:"Denver ARTCC contains ZDV... etc. etc. etc."`
 	URL              string               `json:"url" example:"https://zdvartcc.org"`
-	APIKey           string               `json:"api_key" example:"1234567890"`
-	WebhookURL       string               `json:"webhook_url" example:""`
+	APIKey           string               `json:"-"` // Deprecated: replaced by FacilityAPIKey, only read to migrate existing keys
+	WebhookURL       string               `json:"webhook_url" diff:"redact" example:""`
 	FacilityLogEntry []FacilityLogEntry   `json:"-" gorm:"foreignKey:Facility"`
 	FAQ              []FAQ                `json:"-" gorm:"foreignKey:Facility"`
 	Document         []Document           `json:"-" gorm:"foreignKey:Facility"`
 	CreatedAt        time.Time            `json:"created_at" example:"2021-01-01T00:00:00Z"`
 	UpdatedAt        time.Time            `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+
+	// PromotionMinDaysAtRating raises the division's minimum time at rating before a promotion
+	PromotionMinDaysAtRating uint `json:"promotion_min_days_at_rating" example:"45"`
+	// PromotionMinTrainingSessions is how many training sessions at the facility a promotion needs since the
+	// controller's last rating change
+	PromotionMinTrainingSessions uint `json:"promotion_min_training_sessions" example:"5"`
 }
 
 func (f *Facility) Create() error {
@@ -38,13 +45,22 @@ func (f *Facility) Get() error {
 	return database.DB.Where("id = ?", f.ID).First(f).Error
 }
 
-func GetFacilityByAPIKey(key string) (*Facility, error) {
-	var f Facility
-	if err := database.DB.Where("api_key = ?", key).First(&f).Error; err != nil {
-		return nil, err
+// GetFacilityByAPIKey returns the facility a usable API key belongs to along with the key
+func GetFacilityByAPIKey(key string) (*Facility, *FacilityAPIKey, error) {
+	apiKey, err := GetFacilityAPIKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if apiKey.IsExpired() {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+
+	f := &Facility{ID: apiKey.Facility}
+	if err := f.Get(); err != nil {
+		return nil, nil, err
 	}
 
-	return &f, nil
+	return f, apiKey, nil
 }
 
 func IsValidFacility(id constants.FacilityID) bool {
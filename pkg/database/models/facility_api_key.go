@@ -0,0 +1,126 @@
+package models
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"net"
+	"time"
+)
+
+// FacilityAPIKey authenticates a facility's integrations through the x-api-key header. Only a hash of the key is
+// stored, Prefix is kept so keys can be told apart. A rotated key keeps working until ExpiresAt so integrations can
+// move over to its replacement.
+type FacilityAPIKey struct {
+	ID         uint                 `json:"id" gorm:"primaryKey" example:"1"`
+	Facility   constants.FacilityID `json:"facility" gorm:"index" example:"ZDV"`
+	Name       string               `json:"name" example:"Website"`
+	Prefix     string               `json:"prefix" example:"1a2b3c4d"`
+	KeyHash    string               `json:"-" gorm:"uniqueIndex;type:char(64)"`
+	Scopes     []constants.Scope    `json:"scopes" gorm:"serializer:json" example:"read:roster"`
+	AllowedIPs []string             `json:"allowed_ips" gorm:"serializer:json" example:"203.0.113.0/24"`
+	ExpiresAt  *time.Time           `json:"expires_at" example:"2021-01-01T00:00:00Z"`
+	LastUsedAt *time.Time           `json:"last_used_at" example:"2021-01-01T00:00:00Z"`
+	LastUsedIP string               `json:"last_used_ip" example:"203.0.113.10"`
+	CreatedBy  uint                 `json:"created_by" example:"1293257"`
+	CreatedAt  time.Time            `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt  time.Time            `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+func (k *FacilityAPIKey) Create() error {
+	return database.DB.Create(k).Error
+}
+
+func (k *FacilityAPIKey) Update() error {
+	return database.DB.Save(k).Error
+}
+
+func (k *FacilityAPIKey) Delete() error {
+	return database.DB.Delete(k).Error
+}
+
+func (k *FacilityAPIKey) Get() error {
+	return database.DB.Where("id = ?", k.ID).First(k).Error
+}
+
+// IsExpired reports whether the key can no longer be used
+func (k *FacilityAPIKey) IsExpired() bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now())
+}
+
+// AllowsIP reports whether the key can be used from ip. Keys without an allowlist can be used from anywhere.
+func (k *FacilityAPIKey) AllowsIP(ip string) bool {
+	if len(k.AllowedIPs) == 0 {
+		return true
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, allowed := range k.AllowedIPs {
+		if _, network, err := net.ParseCIDR(allowed); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(allowed); allowedIP != nil && allowedIP.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// TouchLastUsed records that the key was used from ip at now
+func (k *FacilityAPIKey) TouchLastUsed(now time.Time, ip string) error {
+	k.LastUsedAt = &now
+	k.LastUsedIP = ip
+	return database.DB.Model(k).UpdateColumns(map[string]interface{}{"last_used_at": now, "last_used_ip": ip}).Error
+}
+
+func GetFacilityAPIKeys(facility constants.FacilityID) ([]FacilityAPIKey, error) {
+	var keys []FacilityAPIKey
+	return keys, database.DB.Where("facility = ?", facility).Order("created_at DESC").Find(&keys).Error
+}
+
+// GetFacilityAPIKey looks a key up by its plaintext value
+func GetFacilityAPIKey(key string) (*FacilityAPIKey, error) {
+	k := &FacilityAPIKey{}
+	return k, database.DB.Where("key_hash = ?", HashToken(key)).First(k).Error
+}
+
+// AllScopes returns every scope, which keys migrated from the single facility key are granted
+func AllScopes() []constants.Scope {
+	scopes := make([]constants.Scope, 0, len(constants.Scopes))
+	for scope := range constants.Scopes {
+		scopes = append(scopes, scope)
+	}
+	return scopes
+}
+
+// apiKeyPrefix returns the part of a key that is kept in the clear to identify it
+func apiKeyPrefix(key string) string {
+	if len(key) < 8 {
+		return key
+	}
+	return key[:8]
+}
+
+// NewFacilityAPIKey generates a key for facility. The plaintext key is returned, only its hash is kept on the model.
+func NewFacilityAPIKey(facility constants.FacilityID, name string, scopes []constants.Scope, allowedIPs []string, createdBy uint) (*FacilityAPIKey, string, error) {
+	key, err := GenerateApiKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &FacilityAPIKey{
+		Facility:   facility,
+		Name:       name,
+		Prefix:     apiKeyPrefix(key),
+		KeyHash:    HashToken(key),
+		Scopes:     scopes,
+		AllowedIPs: allowedIPs,
+		CreatedBy:  createdBy,
+	}, key, nil
+}
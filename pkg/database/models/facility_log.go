@@ -14,13 +14,15 @@ import (
 // - Changes to gSuite Email (for given Facility)
 
 type FacilityLogEntry struct {
-	ID        uint                 `json:"id" gorm:"primaryKey" example:"1"`
-	Facility  constants.FacilityID `json:"facility" example:"ZDV"`
-	Entry     string               `json:"entry" example:"Change URL from 'denartcc.org' to 'zdvartcc.org'"`
-	CreatedAt time.Time            `json:"created_at" example:"2021-01-01T00:00:00Z"`
-	CreatedBy string               `json:"created_by" example:"'1234567' or 'System'"`
-	UpdatedAt time.Time            `json:"updated_at" example:"2021-01-01T00:00:00Z"`
-	UpdatedBy string               `json:"updated_by" example:"'1234567' or 'System'"`
+	ID       uint                 `json:"id" gorm:"primaryKey" example:"1"`
+	Facility constants.FacilityID `json:"facility" example:"ZDV"`
+	Entry    string               `json:"entry" example:"Change URL from 'denartcc.org' to 'zdvartcc.org'"`
+	// Changes is the structured form of Entry for entries written when a model is changed
+	Changes   Changes   `json:"changes,omitempty" gorm:"serializer:json"`
+	CreatedAt time.Time `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	CreatedBy string    `json:"created_by" example:"'1234567' or 'System'"`
+	UpdatedAt time.Time `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedBy string    `json:"updated_by" example:"'1234567' or 'System'"`
 }
 
 func (fle *FacilityLogEntry) Create() error {
@@ -1,20 +1,33 @@
 package models
 
 import (
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database"
+	"gorm.io/gorm"
 	"time"
 )
 
 // Expire Time can be the time of the session, or the time of the event
 
 type Notification struct {
-	ID        uint      `json:"id" gorm:"primaryKey" example:"1"`
-	CID       uint      `json:"cid" example:"1293257"`
-	Category  string    `json:"category" example:"Training"`
-	Title     string    `json:"title" example:"Upcoming Training Session"`
-	Body      string    `json:"body" example:"You have a training session coming up."`
-	ExpireAt  time.Time `json:"expire_at" example:"2021-01-01T00:00:00Z"`
-	CreatedAt time.Time `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	ID       uint      `json:"id" gorm:"primaryKey" example:"1"`
+	CID      uint      `json:"cid" gorm:"index:idx_notification_cid_created" example:"1293257"`
+	Category string    `json:"category" example:"Training"`
+	Title    string    `json:"title" example:"Upcoming Training Session"`
+	Body     string    `json:"body" example:"You have a training session coming up."`
+	ExpireAt time.Time `json:"expire_at" example:"2021-01-01T00:00:00Z"`
+
+	// Template is the email template used instead of Title and Body when the notification is emailed
+	Template     string               `json:"-"`
+	TemplateData map[string]string    `json:"-" gorm:"serializer:json"`
+	Facility     constants.FacilityID `json:"facility" example:"ZDV"`
+
+	Deliveries []NotificationDelivery `json:"deliveries" gorm:"foreignKey:NotificationID"`
+
+	ReadAt      *time.Time `json:"read_at" example:"2021-01-01T00:00:00Z"`
+	DismissedAt *time.Time `json:"-"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_notification_cid_created" example:"2021-01-01T00:00:00Z"`
 	UpdatedAt time.Time `json:"updated_at" example:"2021-01-01T00:00:00Z"`
 }
 
@@ -31,7 +44,7 @@ func (n *Notification) Delete() error {
 }
 
 func (n *Notification) Get() error {
-	return database.DB.Where("id = ?", n.ID).First(n).Error
+	return database.DB.Preload("Deliveries").Where("id = ?", n.ID).First(n).Error
 }
 
 func GetAllNotifications() ([]Notification, error) {
@@ -41,5 +54,60 @@ func GetAllNotifications() ([]Notification, error) {
 
 func GetAllActiveNotificationsByCID(cid uint) ([]Notification, error) {
 	var notifications []Notification
-	return notifications, database.DB.Where("cid = ? AND expire_at > ?", cid, time.Now()).Find(&notifications).Error
+	return notifications, activeNotifications(cid).Preload("Deliveries").Find(&notifications).Error
+}
+
+// NotificationCursor is where a page of notifications continues from: the CreatedAt and ID of the last notification
+// of the previous page. The ID breaks ties between notifications created at the same time.
+type NotificationCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// GetActiveNotificationsPage returns up to limit of cid's active notifications after cursor, newest first. A zero
+// cursor starts from the newest notification.
+func GetActiveNotificationsPage(cid uint, cursor NotificationCursor, limit int, unreadOnly bool) ([]Notification, error) {
+	var notifications []Notification
+
+	query := activeNotifications(cid).Preload("Deliveries")
+	if !cursor.CreatedAt.IsZero() {
+		query = query.Where("(created_at < ? OR (created_at = ? AND id < ?))", cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+	}
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	return notifications, query.Order("created_at DESC, id DESC").Limit(limit).Find(&notifications).Error
+}
+
+func CountUnreadNotifications(cid uint) (int64, error) {
+	var count int64
+	return count, activeNotifications(cid).Model(&Notification{}).Where("read_at IS NULL").Count(&count).Error
+}
+
+// MarkNotificationsRead marks cid's unread notifications as read, limited to ids unless ids is empty
+func MarkNotificationsRead(cid uint, ids []uint) (int64, error) {
+	query := activeNotifications(cid).Model(&Notification{}).Where("read_at IS NULL")
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+
+	result := query.Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// DismissNotifications hides cid's notifications from their list, limited to ids unless ids is empty
+func DismissNotifications(cid uint, ids []uint) (int64, error) {
+	query := activeNotifications(cid).Model(&Notification{})
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+
+	now := time.Now()
+	result := query.Updates(map[string]interface{}{"dismissed_at": now, "read_at": gorm.Expr("COALESCE(read_at, ?)", now)})
+	return result.RowsAffected, result.Error
+}
+
+func activeNotifications(cid uint) *gorm.DB {
+	return database.DB.Where("cid = ? AND expire_at > ? AND dismissed_at IS NULL", cid, time.Now())
 }
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"time"
+)
+
+// NotificationDelivery tracks the delivery of a Notification over a channel other than in-app
+type NotificationDelivery struct {
+	ID             uint                      `json:"id" gorm:"primaryKey" example:"1"`
+	NotificationID uint                      `json:"notification_id" gorm:"not null;index" example:"1"`
+	CID            uint                      `json:"cid" gorm:"not null" example:"1293257"`
+	Channel        types.NotificationChannel `json:"channel" gorm:"type:enum('email', 'discord');not null" example:"email"`
+	Status         types.DeliveryStatus      `json:"status" gorm:"type:enum('pending', 'sent', 'failed');default:'pending';index" example:"sent"`
+	Attempts       uint                      `json:"attempts" example:"1"`
+	LastError      string                    `json:"-"`
+	NextAttemptAt  time.Time                 `json:"next_attempt_at" example:"2021-01-01T00:00:00Z"`
+	SentAt         *time.Time                `json:"sent_at" example:"2021-01-01T00:00:00Z"`
+	CreatedAt      time.Time                 `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt      time.Time                 `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+func (nd *NotificationDelivery) Create() error {
+	return database.DB.Create(nd).Error
+}
+
+func (nd *NotificationDelivery) Update() error {
+	return database.DB.Save(nd).Error
+}
+
+func (nd *NotificationDelivery) Get() error {
+	return database.DB.First(nd).Error
+}
+
+func GetNotificationDeliveries(notificationID uint) ([]NotificationDelivery, error) {
+	var deliveries []NotificationDelivery
+	return deliveries, database.DB.Where("notification_id = ?", notificationID).Find(&deliveries).Error
+}
+
+// GetDueNotificationDeliveries returns the pending deliveries whose next attempt is due
+func GetDueNotificationDeliveries(now time.Time) ([]NotificationDelivery, error) {
+	var deliveries []NotificationDelivery
+	return deliveries, database.DB.Where("status = ? AND next_attempt_at <= ?", types.DeliveryPending, now).
+		Order("next_attempt_at ASC").Limit(100).Find(&deliveries).Error
+}
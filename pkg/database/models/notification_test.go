@@ -0,0 +1,46 @@
+package models
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database/dbtest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGetActiveNotificationsPage(t *testing.T) {
+	dbtest.Open(t, &Notification{}, &NotificationDelivery{})
+
+	// A broadcast creates many notifications in the same instant, so pages must not skip or repeat them
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var ids []uint
+	for _, at := range []time.Time{created.Add(time.Minute), created, created, created, created.Add(-time.Minute)} {
+		n := &Notification{CID: 1293257, Title: "Broadcast", ExpireAt: time.Now().Add(time.Hour), CreatedAt: at}
+		if err := n.Create(); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, n.ID)
+	}
+	// Newest first, and by descending ID within the same instant
+	want := []uint{ids[0], ids[3], ids[2], ids[1], ids[4]}
+
+	var got []uint
+	cursor := NotificationCursor{}
+	for page := 0; page < 10; page++ {
+		notifications, err := GetActiveNotificationsPage(1293257, cursor, 2, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notifications) == 0 {
+			break
+		}
+		for _, n := range notifications {
+			got = append(got, n.ID)
+		}
+		last := notifications[len(notifications)-1]
+		cursor = NotificationCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("paged notification IDs = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"gorm.io/gorm"
+	"slices"
+	"strings"
+	"time"
+)
+
+// OAuthClient is a facility website that signs its users in through the API's OAuth2 provider. Confidential clients
+// authenticate with a secret, only a hash of which is stored. Public clients have no secret and rely on PKCE alone.
+type OAuthClient struct {
+	ID           uint                 `json:"id" gorm:"primaryKey" example:"1"`
+	ClientID     string               `json:"client_id" gorm:"uniqueIndex;type:varchar(64)" example:"zdv_Ab12Cd34"`
+	SecretHash   string               `json:"-" gorm:"type:char(64)"`
+	Facility     constants.FacilityID `json:"facility" gorm:"index" example:"ZDV"`
+	Name         string               `json:"name" example:"Denver ARTCC Website"`
+	RedirectURIs []string             `json:"redirect_uris" gorm:"serializer:json" example:"https://zdvartcc.org/login/callback"`
+	Public       bool                 `json:"public" example:"false"`
+	CreatedBy    uint                 `json:"created_by" example:"1293257"`
+	CreatedAt    time.Time            `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt    time.Time            `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+func (c *OAuthClient) Create() error {
+	return database.DB.Create(c).Error
+}
+
+func (c *OAuthClient) Update() error {
+	return database.DB.Save(c).Error
+}
+
+// Delete removes the client along with every code and refresh token issued to it and the consent users gave it
+func (c *OAuthClient) Delete() error {
+	if err := database.DB.Where("client_id = ?", c.ClientID).Delete(&OAuthAuthorizationCode{}).Error; err != nil {
+		return err
+	}
+	if err := database.DB.Where("client_id = ?", c.ClientID).Delete(&OAuthConsent{}).Error; err != nil {
+		return err
+	}
+	if err := database.DB.Where("client_id = ?", c.ClientID).Delete(&OAuthRefreshToken{}).Error; err != nil {
+		return err
+	}
+	return database.DB.Delete(c).Error
+}
+
+func (c *OAuthClient) Get() error {
+	if c.ClientID != "" {
+		return database.DB.Where("client_id = ?", c.ClientID).First(c).Error
+	}
+	return database.DB.Where("id = ?", c.ID).First(c).Error
+}
+
+// AllowsRedirect reports whether uri exactly matches one of the client's registered redirect URIs
+func (c *OAuthClient) AllowsRedirect(uri string) bool {
+	return slices.Contains(c.RedirectURIs, uri)
+}
+
+// CheckSecret reports whether secret is the client's secret. Public clients have no secret to check.
+func (c *OAuthClient) CheckSecret(secret string) bool {
+	if c.Public {
+		return secret == ""
+	}
+	return secret != "" && HashToken(secret) == c.SecretHash
+}
+
+func GetOAuthClientsByFacility(facility constants.FacilityID) ([]OAuthClient, error) {
+	var clients []OAuthClient
+	return clients, database.DB.Where("facility = ?", facility).Order("created_at DESC").Find(&clients).Error
+}
+
+// OAuthConsent records the scopes a user approved for a client, so they are only asked once per client and scope
+type OAuthConsent struct {
+	ID        uint   `gorm:"primaryKey"`
+	CID       uint   `gorm:"uniqueIndex:idx_oauth_consent"`
+	ClientID  string `gorm:"uniqueIndex:idx_oauth_consent;type:varchar(64)"`
+	Scope     string `gorm:"type:varchar(255)"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// GetOAuthConsent returns the consent cid gave clientID, with no scopes when they haven't approved the client yet
+func GetOAuthConsent(cid uint, clientID string) (*OAuthConsent, error) {
+	c := &OAuthConsent{}
+	err := database.DB.Where("cid = ? AND client_id = ?", cid, clientID).First(c).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &OAuthConsent{CID: cid, ClientID: clientID}, nil
+	}
+	return c, err
+}
+
+// Covers reports whether every scope in scopes has been approved
+func (c *OAuthConsent) Covers(scopes []string) bool {
+	approved := strings.Fields(c.Scope)
+	for _, s := range scopes {
+		if !slices.Contains(approved, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// Grant adds scopes to the approved scopes and saves the consent
+func (c *OAuthConsent) Grant(scopes []string) error {
+	approved := strings.Fields(c.Scope)
+	for _, s := range scopes {
+		if !slices.Contains(approved, s) {
+			approved = append(approved, s)
+		}
+	}
+	c.Scope = strings.Join(approved, " ")
+	return database.DB.Save(c).Error
+}
+
+// OAuthAuthorizationCode is a single use code handed to a client's redirect URI, exchanged at the token endpoint
+type OAuthAuthorizationCode struct {
+	ID            uint      `gorm:"primaryKey"`
+	CodeHash      string    `gorm:"uniqueIndex;type:char(64)"`
+	ClientID      string    `gorm:"index;type:varchar(64)"`
+	CID           uint      `gorm:"index"`
+	RedirectURI   string    `gorm:"type:varchar(512)"`
+	Scope         string    `gorm:"type:varchar(255)"`
+	Nonce         string    `gorm:"type:varchar(255)"`
+	CodeChallenge string    `gorm:"type:varchar(128)"`
+	AuthTime      time.Time `gorm:"not null"`
+	ExpiresAt     time.Time `gorm:"not null"`
+	CreatedAt     time.Time
+}
+
+func (c *OAuthAuthorizationCode) Create() error {
+	return database.DB.Create(c).Error
+}
+
+// ConsumeOAuthAuthorizationCode looks a code up by its plaintext value and deletes it, so it can only be used once
+func ConsumeOAuthAuthorizationCode(code string) (*OAuthAuthorizationCode, error) {
+	c := &OAuthAuthorizationCode{}
+	if err := database.DB.Where("code_hash = ?", HashToken(code)).First(c).Error; err != nil {
+		return nil, err
+	}
+
+	res := database.DB.Where("id = ?", c.ID).Delete(&OAuthAuthorizationCode{})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		// Lost the race against a concurrent exchange of the same code
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return c, nil
+}
+
+// OAuthRefreshToken lets a client get new tokens without sending the user through the login again. Refresh tokens are
+// rotated on use.
+type OAuthRefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	TokenHash string    `gorm:"uniqueIndex;type:char(64)"`
+	ClientID  string    `gorm:"index;type:varchar(64)"`
+	CID       uint      `gorm:"index"`
+	Scope     string    `gorm:"type:varchar(255)"`
+	AuthTime  time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (t *OAuthRefreshToken) Create() error {
+	return database.DB.Create(t).Error
+}
+
+func (t *OAuthRefreshToken) Delete() error {
+	return database.DB.Delete(t).Error
+}
+
+// Rotate replaces the refresh token with next in one transaction, so a token is never lost between deleting the old
+// one and creating the new one, and a token used by two concurrent requests is only rotated once
+func (t *OAuthRefreshToken) Rotate(next *OAuthRefreshToken) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ?", t.ID).Delete(&OAuthRefreshToken{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// Lost the race against a concurrent refresh with the same token
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(next).Error
+	})
+}
+
+// GetOAuthRefreshToken looks a refresh token up by its plaintext value
+func GetOAuthRefreshToken(token string) (*OAuthRefreshToken, error) {
+	t := &OAuthRefreshToken{}
+	return t, database.DB.Where("token_hash = ?", HashToken(token)).First(t).Error
+}
+
+// DeleteExpiredOAuthGrants removes codes and refresh tokens that can no longer be used
+func DeleteExpiredOAuthGrants(now time.Time) error {
+	if err := database.DB.Where("expires_at <= ?", now).Delete(&OAuthAuthorizationCode{}).Error; err != nil {
+		return err
+	}
+	return database.DB.Where("expires_at <= ?", now).Delete(&OAuthRefreshToken{}).Error
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/database/dbtest"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestOAuthRefreshTokenRotate(t *testing.T) {
+	dbtest.Open(t, &OAuthRefreshToken{})
+
+	old := &OAuthRefreshToken{TokenHash: HashToken("old"), ClientID: "zdv", CID: 1293257, AuthTime: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour)}
+	if err := old.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	next := &OAuthRefreshToken{TokenHash: HashToken("next"), ClientID: "zdv", CID: 1293257, AuthTime: old.AuthTime,
+		ExpiresAt: time.Now().Add(time.Hour)}
+	if err := old.Rotate(next); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if _, err := GetOAuthRefreshToken("old"); err == nil {
+		t.Error("old refresh token is still valid after rotation")
+	}
+	if _, err := GetOAuthRefreshToken("next"); err != nil {
+		t.Errorf("new refresh token was not stored: %v", err)
+	}
+
+	// A second rotation of the same token, e.g. a concurrent refresh, must not issue another token
+	again := &OAuthRefreshToken{TokenHash: HashToken("again"), ClientID: "zdv", CID: 1293257, AuthTime: old.AuthTime,
+		ExpiresAt: time.Now().Add(time.Hour)}
+	if err := old.Rotate(again); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Rotate() of a used token error = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := GetOAuthRefreshToken("again"); err == nil {
+		t.Error("rotating a used token issued a new one")
+	}
+
+	// A failure creating the new token keeps the old one
+	other := &OAuthRefreshToken{TokenHash: HashToken("other"), ClientID: "zdv", CID: 1293257, AuthTime: old.AuthTime,
+		ExpiresAt: time.Now().Add(time.Hour)}
+	if err := other.Create(); err != nil {
+		t.Fatal(err)
+	}
+	duplicate := &OAuthRefreshToken{TokenHash: other.TokenHash, ClientID: "zdv", CID: 1293257, AuthTime: old.AuthTime,
+		ExpiresAt: time.Now().Add(time.Hour)}
+	if err := next.Rotate(duplicate); err == nil {
+		t.Fatal("Rotate() with a duplicate token hash succeeded")
+	}
+	if _, err := GetOAuthRefreshToken("next"); err != nil {
+		t.Errorf("failed rotation deleted the current token: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"gorm.io/gorm"
+	"time"
+)
+
+// OTSExam is the result of an over-the-shoulder exam for Rating
+type OTSExam struct {
+	ID            uint                 `json:"id" gorm:"primaryKey" example:"1"`
+	StudentCID    uint                 `json:"student_cid" gorm:"index" example:"1293257"`
+	InstructorCID uint                 `json:"instructor_cid" gorm:"index" example:"1293257"`
+	Facility      constants.FacilityID `json:"facility" gorm:"index" example:"ZDV"`
+	Position      string               `json:"position" example:"DEN_APP"`
+	Rating        constants.ATCRating  `json:"rating" example:"4"`
+	Result        types.OTSResult      `json:"result" gorm:"type:enum('pass', 'fail')" example:"pass"`
+	ExamDate      time.Time            `json:"exam_date" example:"2021-01-01T00:00:00Z"`
+	Notes         string               `json:"notes" gorm:"type:text" example:"Handled the traffic load well."`
+	CreatedAt     time.Time            `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt     time.Time            `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+// Create records the exam, refused with a RestrictionError when the student may not train
+func (o *OTSExam) Create() error {
+	if err := CheckUserRestriction(o.StudentCID, types.RestrictionNoTraining); err != nil {
+		return err
+	}
+	return database.DB.Create(o).Error
+}
+
+func (o *OTSExam) Update() error {
+	return database.DB.Save(o).Error
+}
+
+func (o *OTSExam) Delete() error {
+	return database.DB.Delete(o).Error
+}
+
+func (o *OTSExam) Get() error {
+	return database.DB.Where("id = ?", o.ID).First(o).Error
+}
+
+// GetOTSExams returns the exams matching filter, most recent first
+func GetOTSExams(filter TrainingFilter) ([]OTSExam, error) {
+	var exams []OTSExam
+	return exams, filter.apply(database.DB).Order("exam_date DESC, id DESC").Find(&exams).Error
+}
+
+// GetLatestOTSExam returns the user's most recent exam for rating at facility since the given time, whatever its
+// result, nil if there is none
+func GetLatestOTSExam(cid uint, facility constants.FacilityID, rating constants.ATCRating, since time.Time) (*OTSExam, error) {
+	return getLatestOTSExam(database.DB, cid, facility, rating, since)
+}
+
+func getLatestOTSExam(tx *gorm.DB, cid uint, facility constants.FacilityID, rating constants.ATCRating, since time.Time) (*OTSExam, error) {
+	var exams []OTSExam
+	err := tx.Where("student_cid = ? AND facility = ? AND rating = ? AND exam_date >= ?", cid, facility, rating, since).
+		Order("exam_date DESC, id DESC").Limit(1).Find(&exams).Error
+	if err != nil || len(exams) == 0 {
+		return nil, err
+	}
+	return &exams[0], nil
+}
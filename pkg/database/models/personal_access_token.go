@@ -0,0 +1,72 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"time"
+)
+
+// PersonalAccessTokenPrefix starts every personal access token, telling them apart from other bearer tokens
+const PersonalAccessTokenPrefix = "vatusa_pat_"
+
+// PersonalAccessToken lets a user authenticate scripts with a bearer token instead of their session cookie. Only a
+// hash of the token is stored, Prefix is kept so users can tell their tokens apart.
+type PersonalAccessToken struct {
+	ID         uint              `json:"id" gorm:"primaryKey" example:"1"`
+	CID        uint              `json:"cid" gorm:"index" example:"1293257"`
+	Name       string            `json:"name" example:"Roster sync script"`
+	Prefix     string            `json:"prefix" example:"vatusa_pat_Ab12"`
+	TokenHash  string            `json:"-" gorm:"uniqueIndex;type:char(64)"`
+	Scopes     []constants.Scope `json:"scopes" gorm:"serializer:json" example:"read:roster"`
+	ExpiresAt  *time.Time        `json:"expires_at" example:"2021-01-01T00:00:00Z"`
+	LastUsedAt *time.Time        `json:"last_used_at" example:"2021-01-01T00:00:00Z"`
+	CreatedAt  time.Time         `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt  time.Time         `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+func (t *PersonalAccessToken) Create() error {
+	return database.DB.Create(t).Error
+}
+
+func (t *PersonalAccessToken) Update() error {
+	return database.DB.Save(t).Error
+}
+
+func (t *PersonalAccessToken) Delete() error {
+	return database.DB.Delete(t).Error
+}
+
+func (t *PersonalAccessToken) Get() error {
+	return database.DB.Where("id = ?", t.ID).First(t).Error
+}
+
+// IsExpired reports whether the token can no longer be used
+func (t *PersonalAccessToken) IsExpired() bool {
+	return t.ExpiresAt != nil && !t.ExpiresAt.After(time.Now())
+}
+
+// TouchLastUsed records that the token was used at now
+func (t *PersonalAccessToken) TouchLastUsed(now time.Time) error {
+	t.LastUsedAt = &now
+	return database.DB.Model(t).UpdateColumn("last_used_at", now).Error
+}
+
+func GetPersonalAccessTokensByCID(cid uint) ([]PersonalAccessToken, error) {
+	var tokens []PersonalAccessToken
+	return tokens, database.DB.Where("cid = ?", cid).Order("created_at DESC").Find(&tokens).Error
+}
+
+// GetPersonalAccessToken looks a token up by its plaintext value
+func GetPersonalAccessToken(token string) (*PersonalAccessToken, error) {
+	t := &PersonalAccessToken{}
+	return t, database.DB.Where("token_hash = ?", HashToken(token)).First(t).Error
+}
+
+// HashToken returns the hex encoded SHA-256 hash tokens are stored as. Tokens are long random strings, so a fast
+// hash is enough.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,283 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPromotionDecided = errors.New("promotion has already been decided")
+	// ErrPromotionStale is returned when the controller's rating changed after the promotion was recommended
+	ErrPromotionStale = errors.New("controller rating changed since the promotion was recommended")
+)
+
+// PromotionIneligibleError is returned when approving a promotion whose controller no longer meets every
+// eligibility check, e.g. because they were restricted or left the roster after the recommendation
+type PromotionIneligibleError struct {
+	Reasons []string
+}
+
+func (e *PromotionIneligibleError) Error() string {
+	return strings.Join(e.Reasons, "; ")
+}
+
+// Promotion is a TA's recommendation to promote a controller after a passed OTS exam. Division training staff
+// approve or reject it, approving records the RatingChange and updates the controller's rating.
+type Promotion struct {
+	ID               uint                 `json:"id" gorm:"primaryKey" example:"1"`
+	CID              uint                 `json:"cid" gorm:"index" example:"1293257"`
+	Facility         constants.FacilityID `json:"facility" gorm:"index" example:"ZDV"`
+	FromRating       constants.ATCRating  `json:"from_rating" example:"2"`
+	ToRating         constants.ATCRating  `json:"to_rating" example:"3"`
+	OTSExamID        uint                 `json:"ots_exam_id" example:"1"`
+	RecommendedByCID uint                 `json:"recommended_by_cid" example:"1293257"`
+	Comment          string               `json:"comment" example:"Ready for S2, passed the OTS on the first attempt."`
+	Status           types.StatusType     `json:"status" gorm:"type:enum('pending', 'accepted', 'rejected');default:'pending'" example:"pending"`
+	DecidedByCID     uint                 `json:"decided_by_cid" example:"1293257"`
+	DecidedAt        *time.Time           `json:"decided_at" example:"2021-01-01T00:00:00Z"`
+	DecisionComment  string               `json:"decision_comment" example:"Approved"`
+	RatingChangeID   uint                 `json:"rating_change_id" example:"1"`
+	// PushedAt is when the new rating was applied on VATSIM, nil until then
+	PushedAt  *time.Time `json:"pushed_at" example:"2021-01-01T00:00:00Z"`
+	CreatedAt time.Time  `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt time.Time  `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+func (p *Promotion) Create() error {
+	return database.DB.Create(p).Error
+}
+
+func (p *Promotion) Update() error {
+	return database.DB.Save(p).Error
+}
+
+func (p *Promotion) Delete() error {
+	return database.DB.Delete(p).Error
+}
+
+func (p *Promotion) Get() error {
+	return database.DB.Where("id = ?", p.ID).First(p).Error
+}
+
+// Approve records the rating change, updates the controller's rating and marks the promotion accepted. The
+// controller's eligibility is checked again, returning a PromotionIneligibleError when they no longer qualify.
+func (p *Promotion) Approve(by uint, comment string) (*RatingChange, error) {
+	if p.Status != types.Pending {
+		return nil, ErrPromotionDecided
+	}
+
+	rc := &RatingChange{
+		CID:          p.CID,
+		OldRating:    p.FromRating,
+		NewRating:    p.ToRating,
+		CreatedByCID: by,
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		user := &User{}
+		if err := tx.Where("cid = ?", p.CID).First(user).Error; err != nil {
+			return err
+		}
+		if user.ControllerRating != p.FromRating {
+			return ErrPromotionStale
+		}
+
+		facility := &Facility{}
+		if err := tx.Where("id = ?", p.Facility).First(facility).Error; err != nil {
+			return err
+		}
+		eligibility, err := checkPromotionEligibility(tx, p.CID, facility, p.ID)
+		if err != nil {
+			return err
+		}
+		if !eligibility.Eligible {
+			return &PromotionIneligibleError{Reasons: eligibility.Reasons}
+		}
+
+		if err := tx.Create(rc).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(user).Update("controller_rating", p.ToRating).Error; err != nil {
+			return err
+		}
+
+		p.decide(types.Accepted, by, comment)
+		p.RatingChangeID = rc.ID
+		return tx.Save(p).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+// Reject marks the promotion rejected, the controller keeps their rating
+func (p *Promotion) Reject(by uint, comment string) error {
+	if p.Status != types.Pending {
+		return ErrPromotionDecided
+	}
+
+	p.decide(types.Rejected, by, comment)
+	return p.Update()
+}
+
+func (p *Promotion) decide(status types.StatusType, by uint, comment string) {
+	now := time.Now()
+	p.Status = status
+	p.DecidedByCID = by
+	p.DecidedAt = &now
+	p.DecisionComment = comment
+}
+
+// PromotionFilter narrows down promotions, zero values are ignored
+type PromotionFilter struct {
+	CID      uint
+	Facility constants.FacilityID
+	Status   types.StatusType
+}
+
+// GetPromotions returns the promotions matching filter, most recent first
+func GetPromotions(filter PromotionFilter) ([]Promotion, error) {
+	return getPromotions(database.DB, filter)
+}
+
+func getPromotions(tx *gorm.DB, filter PromotionFilter) ([]Promotion, error) {
+	var promotions []Promotion
+
+	query := tx.Order("created_at DESC, id DESC")
+	if filter.CID != 0 {
+		query = query.Where("cid = ?", filter.CID)
+	}
+	if filter.Facility != "" {
+		query = query.Where("facility = ?", filter.Facility)
+	}
+	if filter.Status != types.All {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	return promotions, query.Find(&promotions).Error
+}
+
+// PromotionEligibility is whether a controller can be recommended for promotion at a facility, and why not
+type PromotionEligibility struct {
+	CID                      uint                 `json:"cid" example:"1293257"`
+	Facility                 constants.FacilityID `json:"facility" example:"ZDV"`
+	FromRating               constants.ATCRating  `json:"from_rating" example:"2"`
+	ToRating                 constants.ATCRating  `json:"to_rating" example:"3"`
+	RatingSince              time.Time            `json:"rating_since" example:"2021-01-01T00:00:00Z"`
+	DaysAtRating             uint                 `json:"days_at_rating" example:"42"`
+	RequiredDaysAtRating     uint                 `json:"required_days_at_rating" example:"30"`
+	TrainingSessions         uint                 `json:"training_sessions" example:"6"`
+	RequiredTrainingSessions uint                 `json:"required_training_sessions" example:"5"`
+	OTSExamID                uint                 `json:"ots_exam_id" example:"1"`
+	Eligible                 bool                 `json:"eligible" example:"true"`
+	Reasons                  []string             `json:"reasons" example:"No passed S2 OTS exam at ZDV since 2021-01-01"`
+}
+
+// CheckPromotionEligibility checks the controller can be promoted to their next rating at facility. They must be on
+// the facility's home roster, not be restricted from training, have held their rating for the division's and the
+// facility's minimum time, have trained at the facility as often as it requires and have passed their latest OTS exam
+// there for the next rating, all since their last rating change. Only one promotion can be pending at a time.
+func CheckPromotionEligibility(cid uint, facility *Facility) (*PromotionEligibility, error) {
+	return checkPromotionEligibility(database.DB, cid, facility, 0)
+}
+
+// checkPromotionEligibility checks eligibility through tx, ignoring the pending promotion being approved
+func checkPromotionEligibility(tx *gorm.DB, cid uint, facility *Facility, approving uint) (*PromotionEligibility, error) {
+	user := &User{}
+	if err := tx.Where("cid = ?", cid).First(user).Error; err != nil {
+		return nil, err
+	}
+
+	e := &PromotionEligibility{
+		CID:                      cid,
+		Facility:                 facility.ID,
+		FromRating:               user.ControllerRating,
+		RatingSince:              user.CreatedAt,
+		RequiredTrainingSessions: facility.PromotionMinTrainingSessions,
+		Reasons:                  []string{},
+	}
+
+	next, ok := user.ControllerRating.NextRating()
+	if !ok {
+		e.Reasons = append(e.Reasons, fmt.Sprintf("%s cannot be promoted by the division", user.ControllerRating.Short()))
+		return e, nil
+	}
+	e.ToRating = next
+
+	roster, err := getRosterByFacilityAndCID(tx, facility.ID, cid)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err != nil || !roster.Home {
+		e.Reasons = append(e.Reasons, fmt.Sprintf("Not on the %s home roster", facility.ID))
+	}
+
+	if err := checkUserRestriction(tx, cid, types.RestrictionNoTraining); err != nil {
+		var restrictionErr *RestrictionError
+		if !errors.As(err, &restrictionErr) {
+			return nil, err
+		}
+		e.Reasons = append(e.Reasons, "Restricted from training")
+	}
+
+	ratingChange, err := getLatestRatingChange(tx, cid)
+	if err != nil {
+		return nil, err
+	}
+	if ratingChange != nil {
+		e.RatingSince = ratingChange.CreatedAt
+	}
+
+	e.DaysAtRating = uint(time.Since(e.RatingSince).Hours() / 24)
+	e.RequiredDaysAtRating = max(constants.MinimumDaysAtRating[next], facility.PromotionMinDaysAtRating)
+	if e.DaysAtRating < e.RequiredDaysAtRating {
+		e.Reasons = append(e.Reasons, fmt.Sprintf("Held %s for %d of the required %d days", user.ControllerRating.Short(),
+			e.DaysAtRating, e.RequiredDaysAtRating))
+	}
+
+	sessions, err := countTrainingSessions(tx, cid, facility.ID, e.RatingSince)
+	if err != nil {
+		return nil, err
+	}
+	e.TrainingSessions = uint(sessions)
+	if e.TrainingSessions < e.RequiredTrainingSessions {
+		e.Reasons = append(e.Reasons, fmt.Sprintf("Completed %d of the required %d training sessions at %s",
+			e.TrainingSessions, e.RequiredTrainingSessions, facility.ID))
+	}
+
+	exam, err := getLatestOTSExam(tx, cid, facility.ID, next, e.RatingSince)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case exam == nil:
+		e.Reasons = append(e.Reasons, fmt.Sprintf("No passed %s OTS exam at %s since %s", next.Short(), facility.ID,
+			e.RatingSince.Format("2006-01-02")))
+	case exam.Result != types.OTSPass:
+		e.Reasons = append(e.Reasons, fmt.Sprintf("Failed the latest %s OTS exam at %s on %s", next.Short(), facility.ID,
+			exam.ExamDate.Format("2006-01-02")))
+	default:
+		e.OTSExamID = exam.ID
+	}
+
+	pending, err := getPromotions(tx, PromotionFilter{CID: cid, Status: types.Pending})
+	if err != nil {
+		return nil, err
+	}
+	for _, promotion := range pending {
+		if promotion.ID != approving {
+			e.Reasons = append(e.Reasons, fmt.Sprintf("Promotion %d is already pending", promotion.ID))
+			break
+		}
+	}
+
+	e.Eligible = len(e.Reasons) == 0
+	return e, nil
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database"
+	"gorm.io/gorm"
 	"time"
 )
 
@@ -41,3 +42,17 @@ func GetAllRatingChangesByCID(cid uint) ([]RatingChange, error) {
 	var ratingChanges []RatingChange
 	return ratingChanges, database.DB.Where("cid = ?", cid).Find(&ratingChanges).Error
 }
+
+// GetLatestRatingChange returns the user's most recent rating change, nil if their rating never changed
+func GetLatestRatingChange(cid uint) (*RatingChange, error) {
+	return getLatestRatingChange(database.DB, cid)
+}
+
+func getLatestRatingChange(tx *gorm.DB, cid uint) (*RatingChange, error) {
+	var ratingChanges []RatingChange
+	err := tx.Where("cid = ?", cid).Order("created_at DESC, id DESC").Limit(1).Find(&ratingChanges).Error
+	if err != nil || len(ratingChanges) == 0 {
+		return nil, err
+	}
+	return &ratingChanges[0], nil
+}
@@ -4,22 +4,26 @@ import (
 	"errors"
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
 	"gorm.io/gorm"
 	"time"
 )
 
 type Roster struct {
-	ID        uint                 `json:"id" gorm:"primaryKey" example:"1"`
-	CID       uint                 `json:"cid" example:"1293257"`
-	Facility  constants.FacilityID `json:"facility" example:"ZDV"`
-	OIs       string               `json:"operating_initials" gorm:"column:ois" example:"RP"`
-	Home      bool                 `json:"home" example:"true"`
-	Visiting  bool                 `json:"visiting" example:"false"`
-	Status    string               `json:"status" example:"Active"` // Active, LOA
-	Roles     []UserRole           `json:"roles" gorm:"foreignKey:RosterID"`
-	CreatedAt time.Time            `json:"created_at" example:"2021-01-01T00:00:00Z"`
-	UpdatedAt time.Time            `json:"updated_at" example:"2021-01-01T00:00:00Z"`
-	DeletedAt gorm.DeletedAt       `json:"deleted_at" example:"2021-01-01T00:00:00Z"` // Soft Deletes for logging
+	ID       uint                 `json:"id" gorm:"primaryKey" example:"1"`
+	CID      uint                 `json:"cid" example:"1293257"`
+	Facility constants.FacilityID `json:"facility" example:"ZDV"`
+	OIs      string               `json:"operating_initials" gorm:"column:ois" example:"RP"`
+	Home     bool                 `json:"home" example:"true"`
+	Visiting bool                 `json:"visiting" example:"false"`
+	Status   string               `json:"status" example:"Active"` // Active, LOA
+	// LOAStart and LOAEnd bound the leave of absence while Status is LOA
+	LOAStart  *time.Time     `json:"loa_start" example:"2021-01-01T00:00:00Z"`
+	LOAEnd    *time.Time     `json:"loa_end" example:"2021-03-01T00:00:00Z"`
+	Roles     []UserRole     `json:"roles" gorm:"foreignKey:RosterID"`
+	CreatedAt time.Time      `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt time.Time      `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" example:"2021-01-01T00:00:00Z"` // Soft Deletes for logging
 }
 
 func (r *Roster) BeforeCreate(tx *gorm.DB) error {
@@ -49,6 +53,10 @@ func (r *Roster) Create() error {
 		return errors.New("user not found")
 	}
 
+	if err := r.checkRestrictions(); err != nil {
+		return err
+	}
+
 	// See if preferred OIs are already taken
 	if err := database.DB.Where("ois = ? AND facility = ?", user.PreferredOIs, r.Facility).First(&Roster{}).Error; err == nil {
 		// OIs are taken so try first and last initial
@@ -64,6 +72,27 @@ func (r *Roster) Create() error {
 	return database.DB.Create(r).Error
 }
 
+// checkRestrictions refuses visiting rosters to users restricted from visiting, and home rosters to users restricted
+// from transferring who already have a home roster elsewhere
+func (r *Roster) checkRestrictions() error {
+	if r.Visiting {
+		return CheckUserRestriction(r.CID, types.RestrictionNoVisiting)
+	}
+	if !r.Home {
+		return nil
+	}
+
+	var count int64
+	if err := database.DB.Model(&Roster{}).Where("cid = ? AND home = ? AND facility <> ?", r.CID, true, r.Facility).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	return CheckUserRestriction(r.CID, types.RestrictionNoTransferring)
+}
+
 func (r *Roster) Update() error {
 	return database.DB.Save(r).Error
 }
@@ -77,8 +106,12 @@ func (r *Roster) Get() error {
 }
 
 func GetRosterByFacilityAndCID(facility constants.FacilityID, cid uint) (Roster, error) {
+	return getRosterByFacilityAndCID(database.DB, facility, cid)
+}
+
+func getRosterByFacilityAndCID(tx *gorm.DB, facility constants.FacilityID, cid uint) (Roster, error) {
 	var roster Roster
-	return roster, database.DB.Where("facility = ? AND cid = ?", facility, cid).First(&roster).Error
+	return roster, tx.Where("facility = ? AND cid = ?", facility, cid).First(&roster).Error
 }
 
 func GetRosters() ([]Roster, error) {
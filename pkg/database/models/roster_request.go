@@ -18,10 +18,21 @@ type RosterRequest struct {
 	UpdatedAt   time.Time            `json:"updated_at" example:"2021-01-01T00:00:00Z"`
 }
 
+// Create files the request, refused with a RestrictionError when the user may not visit or transfer
 func (rr *RosterRequest) Create() error {
+	if restriction, ok := rosterRequestRestriction[rr.RequestType]; ok {
+		if err := CheckUserRestriction(rr.CID, restriction); err != nil {
+			return err
+		}
+	}
 	return database.DB.Create(rr).Error
 }
 
+var rosterRequestRestriction = map[types.RequestType]types.UserRestriction{
+	types.Visiting:     types.RestrictionNoVisiting,
+	types.Transferring: types.RestrictionNoTransferring,
+}
+
 func (rr *RosterRequest) Update() error {
 	return database.DB.Save(rr).Error
 }
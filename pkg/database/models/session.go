@@ -0,0 +1,130 @@
+package models
+
+import (
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Session is a login from a browser. The VATUSA cookie only carries the session's token, so a session can be revoked
+// by deleting its row. Only a hash of the token is stored.
+type Session struct {
+	ID         uint      `json:"id" gorm:"primaryKey" example:"1"`
+	TokenHash  string    `json:"-" gorm:"uniqueIndex;type:char(64)"`
+	CID        uint      `json:"cid" gorm:"index" example:"1293257"`
+	UserAgent  string    `json:"user_agent" gorm:"type:varchar(512)" example:"Mozilla/5.0 ..."`
+	IP         string    `json:"ip" example:"203.0.113.10"`
+	LastSeenAt time.Time `json:"last_seen_at" example:"2021-01-01T00:00:00Z"`
+	LastSeenIP string    `json:"last_seen_ip" example:"203.0.113.10"`
+	ExpiresAt  time.Time `json:"expires_at" gorm:"index" example:"2021-01-01T00:00:00Z"`
+	CreatedAt  time.Time `json:"created_at" example:"2021-01-01T00:00:00Z"`
+
+	// ImpersonatingCID is set while a staff member is viewing the API as another user with this session
+	ImpersonatingCID       *uint      `json:"impersonating_cid" example:"1293257"`
+	ImpersonationReadOnly  bool       `json:"impersonation_read_only" example:"true"`
+	ImpersonationExpiresAt *time.Time `json:"impersonation_expires_at" example:"2021-01-01T00:00:00Z"`
+}
+
+func (s *Session) Create() error {
+	return database.DB.Create(s).Error
+}
+
+func (s *Session) Delete() error {
+	return database.DB.Delete(s).Error
+}
+
+func (s *Session) Get() error {
+	return database.DB.Where("id = ?", s.ID).First(s).Error
+}
+
+// IsExpired reports whether the session can no longer be used
+func (s *Session) IsExpired() bool {
+	return !s.ExpiresAt.After(time.Now())
+}
+
+// TouchLastSeen records that the session was used at now from ip
+func (s *Session) TouchLastSeen(now time.Time, ip string) error {
+	s.LastSeenAt = now
+	s.LastSeenIP = ip
+	return database.DB.Model(s).UpdateColumns(map[string]interface{}{"last_seen_at": now, "last_seen_ip": ip}).Error
+}
+
+// IsImpersonating reports whether the session is currently acting as another user
+func (s *Session) IsImpersonating() bool {
+	return s.ImpersonatingCID != nil && s.ImpersonationExpiresAt != nil && s.ImpersonationExpiresAt.After(time.Now())
+}
+
+// StartImpersonation makes the session act as target until the given time. The start is logged on the target.
+func (s *Session) StartImpersonation(target uint, readOnly bool, until time.Time, reason string) error {
+	entry := fmt.Sprintf("Impersonation started by %d", s.CID)
+	if readOnly {
+		entry += " (read-only)"
+	}
+	if reason != "" {
+		entry += ": " + reason
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(s).Select("impersonating_cid", "impersonation_read_only", "impersonation_expires_at").
+			Updates(&Session{ImpersonatingCID: &target, ImpersonationReadOnly: readOnly, ImpersonationExpiresAt: &until}).Error; err != nil {
+			return err
+		}
+		return tx.Create(impersonationLogEntry(target, s.CID, entry)).Error
+	})
+}
+
+// EndImpersonation returns the session to its own user. The end is logged on the target along with why it ended.
+func (s *Session) EndImpersonation(why string) error {
+	if s.ImpersonatingCID == nil {
+		return nil
+	}
+	target := *s.ImpersonatingCID
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(s).Select("impersonating_cid", "impersonation_read_only", "impersonation_expires_at").
+			Updates(&Session{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(impersonationLogEntry(target, s.CID, fmt.Sprintf("Impersonation by %d ended: %s", s.CID, why))).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.ImpersonatingCID = nil
+	s.ImpersonationReadOnly = false
+	s.ImpersonationExpiresAt = nil
+	return nil
+}
+
+func impersonationLogEntry(target, staff uint, entry string) *ActionLogEntry {
+	return &ActionLogEntry{
+		CID:       target,
+		Entry:     entry,
+		CreatedBy: fmt.Sprint(staff),
+		UpdatedBy: fmt.Sprint(staff),
+	}
+}
+
+// GetSession looks a session up by its plaintext token
+func GetSession(token string) (*Session, error) {
+	s := &Session{}
+	return s, database.DB.Where("token_hash = ?", HashToken(token)).First(s).Error
+}
+
+// GetActiveSessionsByCID returns the user's unexpired sessions, most recently used first
+func GetActiveSessionsByCID(cid uint) ([]Session, error) {
+	var sessions []Session
+	return sessions, database.DB.Where("cid = ? AND expires_at > ?", cid, time.Now()).
+		Order("last_seen_at DESC").Find(&sessions).Error
+}
+
+// RevokeSessionsByCID logs the user out everywhere
+func RevokeSessionsByCID(cid uint) error {
+	return database.DB.Where("cid = ?", cid).Delete(&Session{}).Error
+}
+
+func DeleteExpiredSessions(now time.Time) error {
+	return database.DB.Where("expires_at <= ?", now).Delete(&Session{}).Error
+}
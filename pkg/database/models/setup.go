@@ -2,6 +2,8 @@ package models
 
 import (
 	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"gorm.io/gorm"
 	"log"
 )
 
@@ -10,21 +12,41 @@ func AutoMigrate() {
 		&Facility{},
 		&User{},
 		&ActionLogEntry{},
+		&AuditLogEntry{},
+		&AuditLogResource{},
 		&DisciplinaryLogEntry{},
+		&DisciplinaryAttachment{},
+		&DiscordGuild{},
+		&DiscordRoleMapping{},
+		&Broadcast{},
+		&BroadcastReceipt{},
 		&Document{},
+		&EmailTemplate{},
 		&Event{},
 		&EventPosition{},
+		&EventShift{},
 		&EventSignup{},
 		&EventRouting{},
 		&EventTemplate{},
+		&FacilityAPIKey{},
 		&FacilityLogEntry{},
 		&FAQ{},
 		&Feedback{},
 		&News{},
+		&OTSExam{},
 		&Notification{},
+		&NotificationDelivery{},
+		&OAuthClient{},
+		&OAuthAuthorizationCode{},
+		&OAuthConsent{},
+		&OAuthRefreshToken{},
+		&PersonalAccessToken{},
+		&Promotion{},
 		&RatingChange{},
 		&Roster{},
 		&RosterRequest{},
+		&Session{},
+		&TrainingSession{},
 		&UserNotification{},
 		&UserFlag{},
 		&UserRole{},
@@ -32,6 +54,18 @@ func AutoMigrate() {
 	if err != nil {
 		log.Fatal("[Database] Migration Error:", err)
 	}
+
+	if err := migrateLegacyEventShifts(); err != nil {
+		log.Fatal("[Database] Event Shift Migration Error:", err)
+	}
+
+	if err := migrateLegacyFacilityAPIKeys(); err != nil {
+		log.Fatal("[Database] Facility API Key Migration Error:", err)
+	}
+
+	if err := migrateLegacyDisciplinaryVisibility(); err != nil {
+		log.Fatal("[Database] Disciplinary Log Migration Error:", err)
+	}
 }
 
 func DropTables() {
@@ -39,21 +73,41 @@ func DropTables() {
 		&Facility{},
 		&User{},
 		&ActionLogEntry{},
+		&AuditLogEntry{},
+		&AuditLogResource{},
 		&DisciplinaryLogEntry{},
+		&DisciplinaryAttachment{},
+		&DiscordGuild{},
+		&DiscordRoleMapping{},
+		&Broadcast{},
+		&BroadcastReceipt{},
 		&Document{},
+		&EmailTemplate{},
 		&EventPosition{},
+		&EventShift{},
 		&EventSignup{},
 		&EventRouting{},
 		&EventTemplate{},
 		&Event{},
+		&FacilityAPIKey{},
 		&FacilityLogEntry{},
 		&FAQ{},
 		&Feedback{},
 		&News{},
+		&OTSExam{},
 		&Notification{},
+		&NotificationDelivery{},
+		&OAuthClient{},
+		&OAuthAuthorizationCode{},
+		&OAuthConsent{},
+		&OAuthRefreshToken{},
+		&PersonalAccessToken{},
+		&Promotion{},
 		&RatingChange{},
 		&Roster{},
 		&RosterRequest{},
+		&Session{},
+		&TrainingSession{},
 		&UserNotification{},
 		&UserFlag{},
 		&UserRole{},
@@ -62,3 +116,144 @@ func DropTables() {
 		log.Fatal("[Database] Drop Table Error:", err)
 	}
 }
+
+type legacyEventPosition struct {
+	ID                uint
+	EventID           uint
+	Shifts            bool
+	Assignee          uint
+	SecondaryAssignee uint
+}
+
+// legacyEventShiftColumns are the primary/secondary shift columns replaced by EventShift rows
+var legacyEventShiftColumns = []struct {
+	model  interface{}
+	column string
+}{
+	{&EventSignup{}, "shift"},
+	{&EventTemplate{}, "shifts"},
+	{&EventPosition{}, "assignee"},
+	{&EventPosition{}, "secondary_assignee"},
+	{&EventPosition{}, "shifts"},
+}
+
+// migrateLegacyEventShifts converts the primary/secondary shift columns that used to live on event positions,
+// signups and templates into EventShift rows. The data is moved in a transaction, the legacy columns are dropped
+// after it commits since MySQL commits DDL implicitly. Both steps can be re-run safely if the other one failed.
+func migrateLegacyEventShifts() error {
+	migrator := database.DB.Migrator()
+	if migrator.HasColumn(&EventPosition{}, "shifts") {
+		log.Println("[Database] Migrating legacy event shifts")
+
+		if err := database.DB.Transaction(moveLegacyEventShifts); err != nil {
+			return err
+		}
+	}
+
+	for _, legacy := range legacyEventShiftColumns {
+		if !migrator.HasColumn(legacy.model, legacy.column) {
+			continue
+		}
+		if err := migrator.DropColumn(legacy.model, legacy.column); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// moveLegacyEventShifts creates the EventShift rows for every legacy position and points its signups at them.
+// Positions that already have shifts were moved by an earlier run and are skipped.
+func moveLegacyEventShifts(tx *gorm.DB) error {
+	var positions []legacyEventPosition
+	if err := tx.Table("event_positions").Select("id, event_id, shifts, assignee, secondary_assignee").Scan(&positions).Error; err != nil {
+		return err
+	}
+
+	for _, position := range positions {
+		var existing int64
+		if err := tx.Model(&EventShift{}).Where("position_id = ?", position.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			continue
+		}
+
+		ev := &Event{ID: position.EventID}
+		if err := tx.First(ev).Error; err != nil {
+			log.Printf("[Database] Skipping event position %d, event %d not found", position.ID, position.EventID)
+			continue
+		}
+
+		count := 1
+		if position.Shifts {
+			count = 2
+		}
+
+		// Legacy positions are converted as they are, even when their halves are shorter than new shifts may be
+		shifts := splitEventShifts(ev.ID, position.ID, ev.StartDate, ev.EndDate, count)
+		shifts[0].Assignee = position.Assignee
+		if position.Shifts {
+			shifts[1].Assignee = position.SecondaryAssignee
+		}
+
+		if err := tx.Create(&shifts).Error; err != nil {
+			return err
+		}
+
+		// Secondary signups only exist on positions that were split, everything else belongs to the first shift
+		if err := tx.Table("event_signups").Where("position_id = ?", position.ID).Update("shift_id", shifts[0].ID).Error; err != nil {
+			return err
+		}
+		if position.Shifts {
+			if err := tx.Table("event_signups").Where("position_id = ? AND shift = ?", position.ID, 2).Update("shift_id", shifts[1].ID).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if !tx.Migrator().HasColumn(&EventTemplate{}, "shifts") {
+		return nil
+	}
+	return tx.Exec("UPDATE event_templates SET shift_count = 2 WHERE shifts = ?", true).Error
+}
+
+// migrateLegacyFacilityAPIKeys moves the single plaintext Facility.APIKey of each facility into a hashed
+// FacilityAPIKey with every scope, so existing integrations keep working
+func migrateLegacyFacilityAPIKeys() error {
+	var facilities []Facility
+	if err := database.DB.Where("api_key <> ''").Find(&facilities).Error; err != nil {
+		return err
+	}
+	if len(facilities) == 0 {
+		return nil
+	}
+
+	log.Println("[Database] Migrating legacy facility API keys")
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, f := range facilities {
+			key := &FacilityAPIKey{
+				Facility: f.ID,
+				Name:     "Legacy key",
+				Prefix:   apiKeyPrefix(f.APIKey),
+				KeyHash:  HashToken(f.APIKey),
+				Scopes:   AllScopes(),
+			}
+			if err := tx.Create(key).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&Facility{}).Where("id = ?", f.ID).UpdateColumn("api_key", "").Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migrateLegacyDisciplinaryVisibility moves disciplinary log entries marked VATUSAOnly to the VATUSA-only visibility.
+// Everything else keeps the senior staff default, which is who could see those entries before.
+func migrateLegacyDisciplinaryVisibility() error {
+	return database.DB.Model(&DisciplinaryLogEntry{}).Where("vatusa_only = ?", true).
+		Updates(map[string]interface{}{"visibility": types.DisciplinaryVATUSAOnly, "vatusa_only": false}).Error
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"gorm.io/gorm"
+	"time"
+)
+
+type TrainingSession struct {
+	ID            uint                      `json:"id" gorm:"primaryKey" example:"1"`
+	StudentCID    uint                      `json:"student_cid" gorm:"index" example:"1293257"`
+	InstructorCID uint                      `json:"instructor_cid" gorm:"index" example:"1293257"`
+	Facility      constants.FacilityID      `json:"facility" gorm:"index" example:"ZDV"`
+	Position      string                    `json:"position" example:"DEN_APP"`
+	Type          types.TrainingSessionType `json:"type" gorm:"type:enum('classroom', 'sweatbox', 'live')" example:"sweatbox"`
+	SessionDate   time.Time                 `json:"session_date" example:"2021-01-01T00:00:00Z"`
+	// Duration is in minutes
+	Duration uint `json:"duration" example:"90"`
+	// Score is the instructor's assessment from 1 to 5, 0 when the session was not scored
+	Score     uint      `json:"score" example:"4"`
+	Notes     string    `json:"notes" gorm:"type:text" example:"Good progress on vectoring for the ILS."`
+	CreatedAt time.Time `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+// Create records the session, refused with a RestrictionError when the student may not train
+func (ts *TrainingSession) Create() error {
+	if err := CheckUserRestriction(ts.StudentCID, types.RestrictionNoTraining); err != nil {
+		return err
+	}
+	return database.DB.Create(ts).Error
+}
+
+func (ts *TrainingSession) Update() error {
+	return database.DB.Save(ts).Error
+}
+
+func (ts *TrainingSession) Delete() error {
+	return database.DB.Delete(ts).Error
+}
+
+func (ts *TrainingSession) Get() error {
+	return database.DB.Where("id = ?", ts.ID).First(ts).Error
+}
+
+// TrainingFilter narrows down training sessions and OTS exams, zero values are ignored
+type TrainingFilter struct {
+	StudentCID    uint
+	InstructorCID uint
+	Facility      constants.FacilityID
+	Position      string
+}
+
+func (f TrainingFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.StudentCID != 0 {
+		query = query.Where("student_cid = ?", f.StudentCID)
+	}
+	if f.InstructorCID != 0 {
+		query = query.Where("instructor_cid = ?", f.InstructorCID)
+	}
+	if f.Facility != "" {
+		query = query.Where("facility = ?", f.Facility)
+	}
+	if f.Position != "" {
+		query = query.Where("position = ?", f.Position)
+	}
+	return query
+}
+
+// GetTrainingSessions returns the sessions matching filter, most recent first
+func GetTrainingSessions(filter TrainingFilter) ([]TrainingSession, error) {
+	var sessions []TrainingSession
+	return sessions, filter.apply(database.DB).Order("session_date DESC, id DESC").Find(&sessions).Error
+}
+
+// CountTrainingSessions counts the user's sessions at facility since the given time
+func CountTrainingSessions(cid uint, facility constants.FacilityID, since time.Time) (int64, error) {
+	return countTrainingSessions(database.DB, cid, facility, since)
+}
+
+func countTrainingSessions(tx *gorm.DB, cid uint, facility constants.FacilityID, since time.Time) (int64, error) {
+	var count int64
+	return count, tx.Model(&TrainingSession{}).
+		Where("student_cid = ? AND facility = ? AND session_date >= ?", cid, facility, since).Count(&count).Error
+}
@@ -13,13 +13,14 @@ type User struct {
 	LastName             string                 `json:"last_name" example:"Patel" gorm:"index:idx_last_name"`
 	PreferredName        string                 `json:"preferred_name" example:"Raaj" gorm:"index:idx_pref_name"`
 	PrefNameEnabled      bool                   `json:"pref_name_enabled" example:"true"`
-	Email                string                 `json:"email" example:"vatusa6@vatusa.net"`
+	Email                string                 `json:"email" diff:"redact" example:"vatusa6@vatusa.net"`
 	PreferredOIs         string                 `json:"preferred_ois" gorm:"column:preferred_ois" example:"RP"`
 	PilotRating          constants.PilotRating  `json:"pilot_rating" example:"1"`
 	ControllerRating     constants.ATCRating    `json:"controller_rating" example:"1"`
 	DiscordID            string                 `json:"discord_id" example:"1234567890"`
 	LastLogin            time.Time              `json:"last_login" example:"2021-01-01T00:00:00Z"`
 	LastCertSync         time.Time              `json:"last_cert_sync" example:"2021-01-01T00:00:00Z"`
+	CalendarTokenHash    *string                `json:"-" gorm:"uniqueIndex;size:64"`
 	Flags                UserFlag               `json:"flags" gorm:"foreignKey:CID"`
 	RatingChanges        []RatingChange         `json:"-" gorm:"foreignKey:CID"`
 	RosterRequest        []RosterRequest        `json:"-" gorm:"foreignKey:CID"`
@@ -56,6 +57,14 @@ func (un *User) Get() error {
 	return database.DB.Preload("Roster.Roles").First(un).Error
 }
 
+func GetUsersByCIDs(cids []uint) ([]User, error) {
+	var users []User
+	if len(cids) == 0 {
+		return users, nil
+	}
+	return users, database.DB.Where("cid IN ?", cids).Find(&users).Error
+}
+
 func GetAllUsers() ([]User, error) {
 	var users []User
 	return users, database.DB.Find(&users).Error
@@ -80,6 +89,12 @@ func SearchUsersByName(query string) ([]User, error) {
 	return users, nil
 }
 
+// GetUserByCalendarToken returns the user whose calendar feed token is token, only its hash is stored
+func GetUserByCalendarToken(token string) (*User, error) {
+	user := &User{}
+	return user, database.DB.Where("calendar_token_hash = ?", HashToken(token)).First(user).Error
+}
+
 func IsValidUser(cid uint) bool {
 	var user User
 	if err := database.DB.Where("cid = ?", cid).First(&user).Error; err != nil {
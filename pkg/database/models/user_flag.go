@@ -1,23 +1,56 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"gorm.io/gorm"
 	"time"
 )
 
+// UserFlag holds the restrictions on a user. Each restriction can be linked to the disciplinary log entry that
+// imposed it, carry a reason and expire, after which it no longer applies and is cleared by the lifting job.
 type UserFlag struct {
-	CID                      uint      `json:"cid" gorm:"primaryKey" example:"1293257"`
-	NoStaffRole              bool      `json:"no_staff_role" gorm:"not null,default:false" example:"false"`
-	NoStaffLogEntryID        uint      `json:"no_staff_log_entry_id" example:"1"`
-	NoVisiting               bool      `json:"no_visiting" gorm:"not null,default:false" example:"false"`
-	NoVisitingLogEntryID     uint      `json:"no_visiting_log_entry_id" example:"1"`
-	NoTransferring           bool      `json:"no_transferring" gorm:"not null,default:false" example:"false"`
-	NoTransferringLogEntryID uint      `json:"no_transferring_log_entry_id" example:"1"`
-	NoTraining               bool      `json:"no_training" gorm:"not null,default:false" example:"false"`
-	NoTrainingLogEntryID     uint      `json:"no_training_log_entry_id" example:"1"`
-	UsedTransferOverride     bool      `json:"used_transfer_override" gorm:"not null,default:false" example:"false"`
-	CreatedAt                time.Time `json:"created_at" example:"2021-01-01T00:00:00Z"`
-	UpdatedAt                time.Time `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+	CID                      uint       `json:"cid" gorm:"primaryKey" example:"1293257"`
+	NoStaffRole              bool       `json:"no_staff_role" gorm:"not null,default:false" example:"false"`
+	NoStaffLogEntryID        uint       `json:"no_staff_log_entry_id" example:"1"`
+	NoStaffRoleUntil         *time.Time `json:"no_staff_role_until" example:"2021-01-01T00:00:00Z"`
+	NoStaffRoleReason        string     `json:"no_staff_role_reason" example:"Suspended"`
+	NoVisiting               bool       `json:"no_visiting" gorm:"not null,default:false" example:"false"`
+	NoVisitingLogEntryID     uint       `json:"no_visiting_log_entry_id" example:"1"`
+	NoVisitingUntil          *time.Time `json:"no_visiting_until" example:"2021-01-01T00:00:00Z"`
+	NoVisitingReason         string     `json:"no_visiting_reason" example:"Removed from ZDV visiting roster"`
+	NoTransferring           bool       `json:"no_transferring" gorm:"not null,default:false" example:"false"`
+	NoTransferringLogEntryID uint       `json:"no_transferring_log_entry_id" example:"1"`
+	NoTransferringUntil      *time.Time `json:"no_transferring_until" example:"2021-01-01T00:00:00Z"`
+	NoTransferringReason     string     `json:"no_transferring_reason" example:"Transferred within the last 90 days"`
+	NoTraining               bool       `json:"no_training" gorm:"not null,default:false" example:"false"`
+	NoTrainingLogEntryID     uint       `json:"no_training_log_entry_id" example:"1"`
+	NoTrainingUntil          *time.Time `json:"no_training_until" example:"2021-01-01T00:00:00Z"`
+	NoTrainingReason         string     `json:"no_training_reason" example:"Missed three sessions"`
+	UsedTransferOverride     bool       `json:"used_transfer_override" gorm:"not null,default:false" example:"false"`
+	CreatedAt                time.Time  `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt                time.Time  `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+// RestrictionError is returned when one of the user's restrictions blocks an action
+type RestrictionError struct {
+	CID         uint
+	Restriction types.UserRestriction
+	Until       *time.Time
+	Reason      string
+}
+
+func (e *RestrictionError) Error() string {
+	msg := fmt.Sprintf("user %d is restricted from %s", e.CID, e.Restriction.DisplayName())
+	if e.Until != nil {
+		msg += " until " + e.Until.UTC().Format(time.RFC3339)
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
 }
 
 func (f *UserFlag) Create() error {
@@ -40,3 +73,178 @@ func GetAllFlags() ([]UserFlag, error) {
 	var flags []UserFlag
 	return flags, database.DB.Find(&flags).Error
 }
+
+// GetOrCreateUserFlag returns the user's flags, creating an empty row for users who have none yet
+func GetOrCreateUserFlag(cid uint) (*UserFlag, error) {
+	f := &UserFlag{CID: cid}
+	return f, database.DB.Where("cid = ?", cid).FirstOrCreate(f).Error
+}
+
+type restrictionFields struct {
+	on      *bool
+	entryID *uint
+	until   **time.Time
+	reason  *string
+}
+
+func (f *UserFlag) restriction(r types.UserRestriction) restrictionFields {
+	switch r {
+	case types.RestrictionNoStaffRole:
+		return restrictionFields{&f.NoStaffRole, &f.NoStaffLogEntryID, &f.NoStaffRoleUntil, &f.NoStaffRoleReason}
+	case types.RestrictionNoVisiting:
+		return restrictionFields{&f.NoVisiting, &f.NoVisitingLogEntryID, &f.NoVisitingUntil, &f.NoVisitingReason}
+	case types.RestrictionNoTransferring:
+		return restrictionFields{&f.NoTransferring, &f.NoTransferringLogEntryID, &f.NoTransferringUntil, &f.NoTransferringReason}
+	case types.RestrictionNoTraining:
+		return restrictionFields{&f.NoTraining, &f.NoTrainingLogEntryID, &f.NoTrainingUntil, &f.NoTrainingReason}
+	}
+	return restrictionFields{new(bool), new(uint), new(*time.Time), new(string)}
+}
+
+// HasRestriction reports whether r is set, even if it has expired and not been cleared yet
+func (f *UserFlag) HasRestriction(r types.UserRestriction) bool {
+	return *f.restriction(r).on
+}
+
+// IsRestricted reports whether r is set and has not expired at now
+func (f *UserFlag) IsRestricted(r types.UserRestriction, now time.Time) bool {
+	fields := f.restriction(r)
+	return *fields.on && (*fields.until == nil || (*fields.until).After(now))
+}
+
+// ExpiredRestrictions lists the restrictions that are still set but expired at now
+func (f *UserFlag) ExpiredRestrictions(now time.Time) []types.UserRestriction {
+	expired := []types.UserRestriction{}
+	for _, r := range types.UserRestrictions {
+		if f.HasRestriction(r) && !f.IsRestricted(r, now) {
+			expired = append(expired, r)
+		}
+	}
+	return expired
+}
+
+// RestrictionEntryID is the disciplinary log entry that imposed r, 0 if none is linked
+func (f *UserFlag) RestrictionEntryID(r types.UserRestriction) uint {
+	return *f.restriction(r).entryID
+}
+
+// SetRestriction imposes r until the given time, or indefinitely when until is nil. entryID links the disciplinary
+// log entry that imposed it, 0 for none.
+func (f *UserFlag) SetRestriction(r types.UserRestriction, entryID uint, until *time.Time, reason string) {
+	fields := f.restriction(r)
+	*fields.on = true
+	*fields.entryID = entryID
+	*fields.until = until
+	*fields.reason = reason
+}
+
+func (f *UserFlag) ClearRestriction(r types.UserRestriction) {
+	fields := f.restriction(r)
+	*fields.on = false
+	*fields.entryID = 0
+	*fields.until = nil
+	*fields.reason = ""
+}
+
+// Check returns a RestrictionError when r applies at now
+func (f *UserFlag) Check(r types.UserRestriction, now time.Time) error {
+	if !f.IsRestricted(r, now) {
+		return nil
+	}
+
+	fields := f.restriction(r)
+	return &RestrictionError{CID: f.CID, Restriction: r, Until: *fields.until, Reason: *fields.reason}
+}
+
+// CheckUserRestriction returns a RestrictionError when r currently applies to the user. Users without flags have no
+// restrictions.
+func CheckUserRestriction(cid uint, r types.UserRestriction) error {
+	return checkUserRestriction(database.DB, cid, r)
+}
+
+func checkUserRestriction(tx *gorm.DB, cid uint, r types.UserRestriction) error {
+	flag := &UserFlag{}
+	if err := tx.Where("cid = ?", cid).First(flag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return flag.Check(r, time.Now())
+}
+
+// LiftUserRestriction clears r from the user's flags. When a disciplinary log entry imposed it, the entry is marked as
+// lifted and a note recording it is written.
+func LiftUserRestriction(cid uint, r types.UserRestriction, by string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		flag := &UserFlag{}
+		if err := tx.Where("cid = ?", cid).First(flag).Error; err != nil {
+			return err
+		}
+		if !flag.HasRestriction(r) {
+			return nil
+		}
+
+		entryID := flag.RestrictionEntryID(r)
+		flag.ClearRestriction(r)
+		if err := tx.Save(flag).Error; err != nil {
+			return err
+		}
+		if entryID == 0 {
+			return nil
+		}
+
+		dle := &DisciplinaryLogEntry{}
+		if err := tx.Where("id = ?", entryID).First(dle).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if dle.Restriction != r || !dle.HasActiveRestriction() {
+			return nil
+		}
+		_, err := liftedRestriction(tx, dle, by)
+		return err
+	})
+}
+
+// GetUserFlagsWithExpiredRestrictions returns the flags holding a restriction that expired at now
+func GetUserFlagsWithExpiredRestrictions(now time.Time) ([]UserFlag, error) {
+	var flags []UserFlag
+	return flags, database.DB.
+		Where("no_staff_role = ? AND no_staff_role_until <= ?", true, now).
+		Or("no_visiting = ? AND no_visiting_until <= ?", true, now).
+		Or("no_transferring = ? AND no_transferring_until <= ?", true, now).
+		Or("no_training = ? AND no_training_until <= ?", true, now).
+		Find(&flags).Error
+}
+
+// RecordRestrictionsLifted writes the lifting note of every restriction that a disciplinary log entry imposed on
+// before and that after no longer holds. Pass nil as after when the flags were deleted.
+func RecordRestrictionsLifted(before, after *UserFlag, by string) error {
+	for _, restriction := range types.UserRestrictions {
+		entryID := before.RestrictionEntryID(restriction)
+		if !before.HasRestriction(restriction) || entryID == 0 {
+			continue
+		}
+		if after != nil && after.HasRestriction(restriction) && after.RestrictionEntryID(restriction) == entryID {
+			continue
+		}
+
+		dle := &DisciplinaryLogEntry{ID: entryID}
+		if err := dle.Get(); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+		if dle.Restriction != restriction || !dle.HasActiveRestriction() {
+			continue
+		}
+		if _, err := dle.RecordRestrictionLifted(by); err != nil {
+			return err
+		}
+	}
+	return nil
+}
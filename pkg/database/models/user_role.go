@@ -3,6 +3,7 @@ package models
 import (
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/types"
 	"time"
 )
 
@@ -16,7 +17,11 @@ type UserRole struct {
 	UpdatedAt  time.Time            `json:"updated_at" example:"2021-01-01T00:00:00Z"`
 }
 
+// Create assigns the role, refused with a RestrictionError when the user may not hold staff roles
 func (ur *UserRole) Create() error {
+	if err := CheckUserRestriction(ur.CID, types.RestrictionNoStaffRole); err != nil {
+		return err
+	}
 	return database.DB.Create(ur).Error
 }
 
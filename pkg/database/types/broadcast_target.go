@@ -0,0 +1,41 @@
+package types
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+type BroadcastTarget string
+
+const (
+	BroadcastDivision BroadcastTarget = "division"
+	BroadcastFacility BroadcastTarget = "facility"
+	BroadcastGroup    BroadcastTarget = "group"
+	BroadcastRating   BroadcastTarget = "rating"
+)
+
+func (t BroadcastTarget) IsValid() bool {
+	switch t {
+	case BroadcastDivision, BroadcastFacility, BroadcastGroup, BroadcastRating:
+		return true
+	}
+	return false
+}
+
+func (t *BroadcastTarget) Scan(value interface{}) error {
+	bytesValue, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to scan BroadcastTarget: expected []byte, got %T", value)
+	}
+
+	strValue := string(bytesValue)
+	if !BroadcastTarget(strValue).IsValid() {
+		return fmt.Errorf("invalid BroadcastTarget value: %s", strValue)
+	}
+	*t = BroadcastTarget(strValue)
+	return nil
+}
+
+func (t *BroadcastTarget) Value() (driver.Value, error) {
+	return string(*t), nil
+}
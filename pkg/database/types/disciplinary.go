@@ -0,0 +1,81 @@
+package types
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// DisciplinaryType is what kind of action a disciplinary log entry records
+type DisciplinaryType string
+
+const (
+	// DisciplinaryNote is a free-text entry, including the ones written when a restriction is lifted
+	DisciplinaryNote       DisciplinaryType = "note"
+	DisciplinaryWarning    DisciplinaryType = "warning"
+	DisciplinarySuspension DisciplinaryType = "suspension"
+	DisciplinaryRemoval    DisciplinaryType = "removal"
+)
+
+func (t DisciplinaryType) IsValid() bool {
+	switch t {
+	case DisciplinaryNote, DisciplinaryWarning, DisciplinarySuspension, DisciplinaryRemoval:
+		return true
+	}
+	return false
+}
+
+func (t *DisciplinaryType) Scan(value interface{}) error {
+	bytesValue, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to scan DisciplinaryType: expected []byte, got %T", value)
+	}
+
+	strValue := string(bytesValue)
+	if !DisciplinaryType(strValue).IsValid() {
+		return fmt.Errorf("invalid DisciplinaryType value: %s", strValue)
+	}
+	*t = DisciplinaryType(strValue)
+	return nil
+}
+
+func (t *DisciplinaryType) Value() (driver.Value, error) {
+	return string(*t), nil
+}
+
+// DisciplinaryVisibility is who can see a disciplinary log entry. Each tier can also see the ones below it.
+type DisciplinaryVisibility string
+
+const (
+	// DisciplinaryVATUSAOnly entries are only visible to division staff
+	DisciplinaryVATUSAOnly DisciplinaryVisibility = "vatusa"
+	// DisciplinarySeniorStaff entries are visible to senior staff of the member's facilities
+	DisciplinarySeniorStaff DisciplinaryVisibility = "senior_staff"
+	// DisciplinaryMember entries are also visible to the member themselves
+	DisciplinaryMember DisciplinaryVisibility = "member"
+)
+
+func (v DisciplinaryVisibility) IsValid() bool {
+	switch v {
+	case DisciplinaryVATUSAOnly, DisciplinarySeniorStaff, DisciplinaryMember:
+		return true
+	}
+	return false
+}
+
+func (v *DisciplinaryVisibility) Scan(value interface{}) error {
+	bytesValue, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to scan DisciplinaryVisibility: expected []byte, got %T", value)
+	}
+
+	strValue := string(bytesValue)
+	if !DisciplinaryVisibility(strValue).IsValid() {
+		return fmt.Errorf("invalid DisciplinaryVisibility value: %s", strValue)
+	}
+	*v = DisciplinaryVisibility(strValue)
+	return nil
+}
+
+func (v *DisciplinaryVisibility) Value() (driver.Value, error) {
+	return string(*v), nil
+}
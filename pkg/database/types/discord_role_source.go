@@ -0,0 +1,46 @@
+package types
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// DiscordRoleSource is what grants a mapped Discord role
+type DiscordRoleSource string
+
+const (
+	// DiscordSourceRating grants the role to controllers holding the rating named by the mapping's value, e.g. S3
+	DiscordSourceRating DiscordRoleSource = "rating"
+	// DiscordSourceHome grants the role to controllers on the guild facility's home roster
+	DiscordSourceHome DiscordRoleSource = "home"
+	// DiscordSourceVisiting grants the role to controllers on the guild facility's visiting roster
+	DiscordSourceVisiting DiscordRoleSource = "visiting"
+	// DiscordSourceStaff grants the role to holders of the role or role group named by the mapping's value
+	DiscordSourceStaff DiscordRoleSource = "staff"
+)
+
+func (s DiscordRoleSource) IsValid() bool {
+	switch s {
+	case DiscordSourceRating, DiscordSourceHome, DiscordSourceVisiting, DiscordSourceStaff:
+		return true
+	}
+	return false
+}
+
+func (s *DiscordRoleSource) Scan(value interface{}) error {
+	bytesValue, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to scan DiscordRoleSource: expected []byte, got %T", value)
+	}
+
+	strValue := string(bytesValue)
+	if !DiscordRoleSource(strValue).IsValid() {
+		return fmt.Errorf("invalid DiscordRoleSource value: %s", strValue)
+	}
+	*s = DiscordRoleSource(strValue)
+	return nil
+}
+
+func (s *DiscordRoleSource) Value() (driver.Value, error) {
+	return string(*s), nil
+}
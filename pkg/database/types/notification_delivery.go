@@ -0,0 +1,61 @@
+package types
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+type NotificationChannel string
+
+const (
+	ChannelEmail   NotificationChannel = "email"
+	ChannelDiscord NotificationChannel = "discord"
+)
+
+func (c *NotificationChannel) Scan(value interface{}) error {
+	bytesValue, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to scan NotificationChannel: expected []byte, got %T", value)
+	}
+
+	strValue := string(bytesValue)
+	switch NotificationChannel(strValue) {
+	case ChannelEmail, ChannelDiscord:
+		*c = NotificationChannel(strValue)
+	default:
+		return fmt.Errorf("invalid NotificationChannel value: %s", strValue)
+	}
+	return nil
+}
+
+func (c *NotificationChannel) Value() (driver.Value, error) {
+	return string(*c), nil
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending DeliveryStatus = "pending"
+	DeliverySent    DeliveryStatus = "sent"
+	DeliveryFailed  DeliveryStatus = "failed"
+)
+
+func (s *DeliveryStatus) Scan(value interface{}) error {
+	bytesValue, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to scan DeliveryStatus: expected []byte, got %T", value)
+	}
+
+	strValue := string(bytesValue)
+	switch DeliveryStatus(strValue) {
+	case DeliveryPending, DeliverySent, DeliveryFailed:
+		*s = DeliveryStatus(strValue)
+	default:
+		return fmt.Errorf("invalid DeliveryStatus value: %s", strValue)
+	}
+	return nil
+}
+
+func (s *DeliveryStatus) Value() (driver.Value, error) {
+	return string(*s), nil
+}
@@ -0,0 +1,34 @@
+package types
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+type StaffingStatus string
+
+const (
+	StaffingDraft     StaffingStatus = "draft"
+	StaffingPublished StaffingStatus = "published"
+	StaffingFinalized StaffingStatus = "finalized"
+)
+
+func (s *StaffingStatus) Scan(value interface{}) error {
+	bytesValue, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to scan StaffingStatus: expected []byte, got %T", value)
+	}
+
+	strValue := string(bytesValue)
+	switch StaffingStatus(strValue) {
+	case StaffingDraft, StaffingPublished, StaffingFinalized:
+		*s = StaffingStatus(strValue)
+	default:
+		return fmt.Errorf("invalid StaffingStatus value: %s", strValue)
+	}
+	return nil
+}
+
+func (s *StaffingStatus) Value() (driver.Value, error) {
+	return string(*s), nil
+}
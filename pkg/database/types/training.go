@@ -0,0 +1,75 @@
+package types
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// TrainingSessionType is where a training session took place
+type TrainingSessionType string
+
+const (
+	TrainingClassroom TrainingSessionType = "classroom"
+	TrainingSweatbox  TrainingSessionType = "sweatbox"
+	TrainingLive      TrainingSessionType = "live"
+)
+
+func (t TrainingSessionType) IsValid() bool {
+	switch t {
+	case TrainingClassroom, TrainingSweatbox, TrainingLive:
+		return true
+	}
+	return false
+}
+
+func (t *TrainingSessionType) Scan(value interface{}) error {
+	bytesValue, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to scan TrainingSessionType: expected []byte, got %T", value)
+	}
+
+	strValue := string(bytesValue)
+	if !TrainingSessionType(strValue).IsValid() {
+		return fmt.Errorf("invalid TrainingSessionType value: %s", strValue)
+	}
+	*t = TrainingSessionType(strValue)
+	return nil
+}
+
+func (t *TrainingSessionType) Value() (driver.Value, error) {
+	return string(*t), nil
+}
+
+// OTSResult is the outcome of an over-the-shoulder exam
+type OTSResult string
+
+const (
+	OTSPass OTSResult = "pass"
+	OTSFail OTSResult = "fail"
+)
+
+func (o OTSResult) IsValid() bool {
+	switch o {
+	case OTSPass, OTSFail:
+		return true
+	}
+	return false
+}
+
+func (o *OTSResult) Scan(value interface{}) error {
+	bytesValue, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to scan OTSResult: expected []byte, got %T", value)
+	}
+
+	strValue := string(bytesValue)
+	if !OTSResult(strValue).IsValid() {
+		return fmt.Errorf("invalid OTSResult value: %s", strValue)
+	}
+	*o = OTSResult(strValue)
+	return nil
+}
+
+func (o *OTSResult) Value() (driver.Value, error) {
+	return string(*o), nil
+}
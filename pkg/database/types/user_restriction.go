@@ -0,0 +1,42 @@
+package types
+
+// UserRestriction is one of the restrictions a UserFlag can hold
+type UserRestriction string
+
+const (
+	RestrictionNoStaffRole    UserRestriction = "no_staff_role"
+	RestrictionNoVisiting     UserRestriction = "no_visiting"
+	RestrictionNoTransferring UserRestriction = "no_transferring"
+	RestrictionNoTraining     UserRestriction = "no_training"
+)
+
+// UserRestrictions lists every restriction
+var UserRestrictions = []UserRestriction{
+	RestrictionNoStaffRole,
+	RestrictionNoVisiting,
+	RestrictionNoTransferring,
+	RestrictionNoTraining,
+}
+
+func (r UserRestriction) IsValid() bool {
+	switch r {
+	case RestrictionNoStaffRole, RestrictionNoVisiting, RestrictionNoTransferring, RestrictionNoTraining:
+		return true
+	}
+	return false
+}
+
+// DisplayName describes what the restriction keeps the user from
+func (r UserRestriction) DisplayName() string {
+	switch r {
+	case RestrictionNoStaffRole:
+		return "holding staff roles"
+	case RestrictionNoVisiting:
+		return "visiting"
+	case RestrictionNoTransferring:
+		return "transferring"
+	case RestrictionNoTraining:
+		return "training"
+	}
+	return string(r)
+}
@@ -0,0 +1,128 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/config"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Client is the subset of the Discord bot API used by the API. It is an interface so a Fake can stand in for
+// Discord during development.
+type Client interface {
+	SendDirectMessage(ctx context.Context, userID, content string) error
+	// GetMemberRoles returns the roles of a guild member, or ErrNotMember if the user is not in the guild
+	GetMemberRoles(ctx context.Context, guildID, userID string) ([]string, error)
+	AddMemberRole(ctx context.Context, guildID, userID, roleID string) error
+	RemoveMemberRole(ctx context.Context, guildID, userID, roleID string) error
+	// RegisterCommands replaces the application's global slash commands
+	RegisterCommands(ctx context.Context, applicationID string, commands []ApplicationCommand) error
+}
+
+var (
+	ErrNotMember        = errors.New("user is not a member of the guild")
+	errInvalidPublicKey = errors.New("invalid Ed25519 public key")
+)
+
+// APIError is returned when Discord responds with an error status
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("discord %s %s returned %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+type HTTPClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func NewHTTPClient(cfg *config.DiscordConfig) *HTTPClient {
+	return &HTTPClient{
+		baseURL: cfg.BaseURL,
+		token:   cfg.BotToken,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *HTTPClient) SendDirectMessage(ctx context.Context, userID, content string) error {
+	channel := struct {
+		ID string `json:"id"`
+	}{}
+	if err := c.do(ctx, http.MethodPost, "/users/@me/channels", map[string]string{"recipient_id": userID}, &channel); err != nil {
+		return err
+	}
+
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/channels/%s/messages", channel.ID), map[string]string{"content": content}, nil)
+}
+
+func (c *HTTPClient) GetMemberRoles(ctx context.Context, guildID, userID string) ([]string, error) {
+	member := struct {
+		Roles []string `json:"roles"`
+	}{}
+
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/guilds/%s/members/%s", guildID, userID), nil, &member)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, ErrNotMember
+	}
+	return member.Roles, err
+}
+
+func (c *HTTPClient) AddMemberRole(ctx context.Context, guildID, userID, roleID string) error {
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/guilds/%s/members/%s/roles/%s", guildID, userID, roleID), nil, nil)
+}
+
+func (c *HTTPClient) RemoveMemberRole(ctx context.Context, guildID, userID, roleID string) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/guilds/%s/members/%s/roles/%s", guildID, userID, roleID), nil, nil)
+}
+
+func (c *HTTPClient) RegisterCommands(ctx context.Context, applicationID string, commands []ApplicationCommand) error {
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/applications/%s/commands", applicationID), commands, nil)
+}
+
+func (c *HTTPClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &APIError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: string(msg)}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,95 @@
+package discord
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+type Message struct {
+	UserID  string
+	Content string
+}
+
+// Fake is an in-memory Client that records what would have been sent to Discord
+type Fake struct {
+	mu       sync.Mutex
+	Messages []Message
+	// Members holds the roles of each member, by guild ID and then user ID
+	Members map[string]map[string][]string
+	// Commands holds the registered slash commands by application ID
+	Commands map[string][]ApplicationCommand
+	// Err, when set, is returned from every call instead of recording it
+	Err error
+}
+
+func (f *Fake) SendDirectMessage(_ context.Context, userID, content string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return f.Err
+	}
+	f.Messages = append(f.Messages, Message{UserID: userID, Content: content})
+	return nil
+}
+
+func (f *Fake) GetMemberRoles(_ context.Context, guildID, userID string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	roles, ok := f.Members[guildID][userID]
+	if !ok {
+		return nil, ErrNotMember
+	}
+	return slices.Clone(roles), nil
+}
+
+func (f *Fake) AddMemberRole(_ context.Context, guildID, userID, roleID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return f.Err
+	}
+	roles, ok := f.Members[guildID][userID]
+	if !ok {
+		return ErrNotMember
+	}
+	if !slices.Contains(roles, roleID) {
+		f.Members[guildID][userID] = append(roles, roleID)
+	}
+	return nil
+}
+
+func (f *Fake) RemoveMemberRole(_ context.Context, guildID, userID, roleID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return f.Err
+	}
+	roles, ok := f.Members[guildID][userID]
+	if !ok {
+		return ErrNotMember
+	}
+	f.Members[guildID][userID] = slices.DeleteFunc(roles, func(r string) bool { return r == roleID })
+	return nil
+}
+
+func (f *Fake) RegisterCommands(_ context.Context, applicationID string, commands []ApplicationCommand) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return f.Err
+	}
+	if f.Commands == nil {
+		f.Commands = map[string][]ApplicationCommand{}
+	}
+	f.Commands[applicationID] = commands
+	return nil
+}
@@ -0,0 +1,158 @@
+package discord
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+)
+
+// Interaction types
+const (
+	InteractionPing               = 1
+	InteractionApplicationCommand = 2
+)
+
+// Interaction response types
+const (
+	ResponsePong           = 1
+	ResponseChannelMessage = 4
+)
+
+// FlagEphemeral shows a message only to the user that ran the command
+const FlagEphemeral = 1 << 6
+
+// Application command option types
+const (
+	OptionString  = 3
+	OptionInteger = 4
+	OptionUser    = 6
+)
+
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+type Member struct {
+	User *User `json:"user"`
+}
+
+// Interaction is a request Discord sends to the interactions endpoint
+type Interaction struct {
+	ID      string       `json:"id"`
+	Type    int          `json:"type"`
+	GuildID string       `json:"guild_id"`
+	Member  *Member      `json:"member"`
+	User    *User        `json:"user"`
+	Data    *CommandData `json:"data"`
+}
+
+// Invoker returns the user that triggered the interaction, in a guild or a DM
+func (i *Interaction) Invoker() *User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+type CommandData struct {
+	ID      string          `json:"id"`
+	Name    string          `json:"name"`
+	Options []CommandOption `json:"options"`
+}
+
+type CommandOption struct {
+	Name  string          `json:"name"`
+	Type  int             `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// String returns the value of the named option, user options hold the user's ID
+func (d *CommandData) String(name string) string {
+	for _, o := range d.Options {
+		if o.Name != name {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(o.Value, &s); err == nil {
+			return s
+		}
+		return string(o.Value)
+	}
+	return ""
+}
+
+// Uint returns the value of the named integer option, or 0 when it is missing
+func (d *CommandData) Uint(name string) uint {
+	v, err := strconv.ParseUint(d.String(name), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(v)
+}
+
+type InteractionResponse struct {
+	Type int          `json:"type"`
+	Data *MessageData `json:"data,omitempty"`
+}
+
+type MessageData struct {
+	Content string  `json:"content,omitempty"`
+	Embeds  []Embed `json:"embeds,omitempty"`
+	Flags   int     `json:"flags,omitempty"`
+}
+
+type Embed struct {
+	Title       string       `json:"title,omitempty"`
+	Description string       `json:"description,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Color       int          `json:"color,omitempty"`
+	Fields      []EmbedField `json:"fields,omitempty"`
+}
+
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// ApplicationCommand is a slash command definition as registered with Discord
+type ApplicationCommand struct {
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	Options     []ApplicationCommandOption `json:"options,omitempty"`
+}
+
+type ApplicationCommandOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// VerifySignature reports whether signature, the hex encoded X-Signature-Ed25519 header, signs timestamp followed by
+// body with publicKey
+func VerifySignature(publicKey ed25519.PublicKey, signature, timestamp string, body []byte) bool {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize || len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+
+	msg := make([]byte, 0, len(timestamp)+len(body))
+	msg = append(msg, timestamp...)
+	msg = append(msg, body...)
+	return ed25519.Verify(publicKey, msg, sig)
+}
+
+// ParsePublicKey decodes a hex encoded Ed25519 public key
+func ParsePublicKey(key string) (ed25519.PublicKey, error) {
+	raw, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, errInvalidPublicKey
+	}
+	return raw, nil
+}
@@ -0,0 +1,175 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	log "github.com/sirupsen/logrus"
+	"slices"
+)
+
+// divisionFacility is the facility of the division's own guild, whose mappings apply across every facility
+const divisionFacility = constants.FacilityID("ZHQ")
+
+// GuildRoles is the set of mapped roles a user should hold in a guild
+type GuildRoles struct {
+	Facility constants.FacilityID `json:"facility" example:"ZDV"`
+	GuildID  string               `json:"guild_id" example:"1234567890"`
+	Roles    []string             `json:"roles"`
+}
+
+// DesiredRoles returns the mapped roles user should hold in guild. user's roster and roles must be loaded.
+func DesiredRoles(user *models.User, guild *models.DiscordGuild) []string {
+	roles := []string{}
+	for _, m := range guild.Roles {
+		if grants(user, guild.Facility, &m) && !slices.Contains(roles, m.DiscordRoleID) {
+			roles = append(roles, m.DiscordRoleID)
+		}
+	}
+	return roles
+}
+
+func grants(user *models.User, facility constants.FacilityID, m *models.DiscordRoleMapping) bool {
+	switch m.Source {
+	case types.DiscordSourceRating:
+		return user.ControllerRating.Short() == m.Value
+	case types.DiscordSourceHome, types.DiscordSourceVisiting:
+		for _, roster := range user.Roster {
+			if facility != divisionFacility && roster.Facility != facility {
+				continue
+			}
+			if (m.Source == types.DiscordSourceHome && roster.Home) || (m.Source == types.DiscordSourceVisiting && roster.Visiting) {
+				return true
+			}
+		}
+	case types.DiscordSourceStaff:
+		for _, roster := range user.Roster {
+			if facility != divisionFacility && roster.Facility != facility {
+				continue
+			}
+			for _, role := range roster.Roles {
+				if string(role.RoleID) == m.Value || role.RoleID.InGroup(constants.GroupID(m.Value)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// managedRoles returns every role the guild's mappings can grant
+func managedRoles(guild *models.DiscordGuild) []string {
+	roles := []string{}
+	for _, m := range guild.Roles {
+		if !slices.Contains(roles, m.DiscordRoleID) {
+			roles = append(roles, m.DiscordRoleID)
+		}
+	}
+	return roles
+}
+
+// Syncer keeps the mapped roles of linked users in sync across the configured guilds
+type Syncer struct {
+	client Client
+}
+
+// DefaultSyncer is used when users link and unlink their Discord account, it is nil when role sync is disabled
+var DefaultSyncer *Syncer
+
+func NewSyncer(client Client) *Syncer {
+	return &Syncer{client: client}
+}
+
+// DesiredGuildRoles returns the mapped roles user should hold in every configured guild
+func DesiredGuildRoles(user *models.User) ([]GuildRoles, error) {
+	guilds, err := models.GetDiscordGuilds()
+	if err != nil {
+		return nil, err
+	}
+
+	list := []GuildRoles{}
+	for idx := range guilds {
+		list = append(list, GuildRoles{
+			Facility: guilds[idx].Facility,
+			GuildID:  guilds[idx].GuildID,
+			Roles:    DesiredRoles(user, &guilds[idx]),
+		})
+	}
+	return list, nil
+}
+
+// SyncUser applies the difference between user's desired and current mapped roles in every configured guild the
+// user is a member of
+func (s *Syncer) SyncUser(ctx context.Context, user *models.User) error {
+	if user.DiscordID == "" {
+		return nil
+	}
+
+	guilds, err := models.GetDiscordGuilds()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for idx := range guilds {
+		errs = append(errs, s.apply(ctx, &guilds[idx], user.DiscordID, DesiredRoles(user, &guilds[idx])))
+	}
+	return errors.Join(errs...)
+}
+
+// RevokeUser removes every mapped role from discordID, used when a user unlinks their Discord account
+func (s *Syncer) RevokeUser(ctx context.Context, discordID string) error {
+	guilds, err := models.GetDiscordGuilds()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for idx := range guilds {
+		errs = append(errs, s.apply(ctx, &guilds[idx], discordID, nil))
+	}
+	return errors.Join(errs...)
+}
+
+// SyncAll syncs every linked user, logging failures
+func (s *Syncer) SyncAll(ctx context.Context) {
+	users, err := models.GetDiscordLinkedUsers()
+	if err != nil {
+		log.WithError(err).Error("Error getting users with a linked Discord account")
+		return
+	}
+
+	for idx := range users {
+		if err := s.SyncUser(ctx, &users[idx]); err != nil {
+			log.WithError(err).Errorf("Error syncing Discord roles for user %d", users[idx].CID)
+		}
+	}
+}
+
+func (s *Syncer) apply(ctx context.Context, guild *models.DiscordGuild, discordID string, desired []string) error {
+	if guild.GuildID == "" {
+		return nil
+	}
+
+	current, err := s.client.GetMemberRoles(ctx, guild.GuildID, discordID)
+	if errors.Is(err, ErrNotMember) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, role := range managedRoles(guild) {
+		has, wants := slices.Contains(current, role), slices.Contains(desired, role)
+		switch {
+		case wants && !has:
+			errs = append(errs, s.client.AddMemberRole(ctx, guild.GuildID, discordID, role))
+		case has && !wants:
+			errs = append(errs, s.client.RemoveMemberRole(ctx, guild.GuildID, discordID, role))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,251 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/config"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/dbtest"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// fakeServer serves the guild member endpoints of the Discord API from memory
+type fakeServer struct {
+	mu sync.Mutex
+	// members holds the roles of each member, by guild ID and then user ID
+	members map[string]map[string][]string
+	// calls records every role change as "METHOD guild/user/role"
+	calls []string
+}
+
+func newFakeServer(t *testing.T, members map[string]map[string][]string) (*fakeServer, *HTTPClient) {
+	t.Helper()
+
+	f := &fakeServer{members: members}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /guilds/{guild}/members/{user}", f.getMember)
+	mux.HandleFunc("PUT /guilds/{guild}/members/{user}/roles/{role}", f.changeRole)
+	mux.HandleFunc("DELETE /guilds/{guild}/members/{user}/roles/{role}", f.changeRole)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bot token" {
+			http.Error(w, `{"message": "401: Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return f, NewHTTPClient(&config.DiscordConfig{BaseURL: srv.URL, BotToken: "token"})
+}
+
+func (f *fakeServer) getMember(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	roles, ok := f.members[r.PathValue("guild")][r.PathValue("user")]
+	if !ok {
+		http.Error(w, `{"message": "Unknown Member"}`, http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string][]string{"roles": roles})
+}
+
+func (f *fakeServer) changeRole(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	guild, user, role := r.PathValue("guild"), r.PathValue("user"), r.PathValue("role")
+	roles, ok := f.members[guild][user]
+	if !ok {
+		http.Error(w, `{"message": "Unknown Member"}`, http.StatusNotFound)
+		return
+	}
+
+	f.calls = append(f.calls, r.Method+" "+guild+"/"+user+"/"+role)
+	if r.Method == http.MethodPut {
+		if !slices.Contains(roles, role) {
+			f.members[guild][user] = append(roles, role)
+		}
+	} else {
+		f.members[guild][user] = slices.DeleteFunc(roles, func(r string) bool { return r == role })
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeServer) roles(guild, user string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	roles := slices.Clone(f.members[guild][user])
+	slices.Sort(roles)
+	return roles
+}
+
+func TestHTTPClientErrors(t *testing.T) {
+	_, client := newFakeServer(t, map[string]map[string][]string{"g1": {"u1": {"r1"}}})
+	ctx := context.Background()
+
+	roles, err := client.GetMemberRoles(ctx, "g1", "u1")
+	if err != nil || !slices.Equal(roles, []string{"r1"}) {
+		t.Errorf("GetMemberRoles() = %v, %v, want [r1]", roles, err)
+	}
+
+	if _, err := client.GetMemberRoles(ctx, "g1", "u2"); !errors.Is(err, ErrNotMember) {
+		t.Errorf("GetMemberRoles() for a non-member error = %v, want ErrNotMember", err)
+	}
+
+	var apiErr *APIError
+	if err := client.AddMemberRole(ctx, "g1", "u2", "r1"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("AddMemberRole() for a non-member error = %v, want a 404 APIError", err)
+	}
+
+	client.token = "wrong"
+	if err := client.AddMemberRole(ctx, "g1", "u1", "r2"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("AddMemberRole() with a bad token error = %v, want a 401 APIError", err)
+	}
+}
+
+func testGuilds() []models.DiscordGuild {
+	return []models.DiscordGuild{
+		{
+			Facility: "ZDV",
+			GuildID:  "zdv",
+			Roles: []models.DiscordRoleMapping{
+				{Source: types.DiscordSourceRating, Value: "C1", DiscordRoleID: "c1"},
+				{Source: types.DiscordSourceHome, Value: "", DiscordRoleID: "home"},
+				{Source: types.DiscordSourceVisiting, Value: "", DiscordRoleID: "visitor"},
+				{Source: types.DiscordSourceStaff, Value: string(constants.FacilityManagement), DiscordRoleID: "management"},
+			},
+		},
+		{
+			Facility: "ZHQ",
+			GuildID:  "zhq",
+			Roles: []models.DiscordRoleMapping{
+				{Source: types.DiscordSourceHome, Value: "", DiscordRoleID: "member"},
+				{Source: types.DiscordSourceVisiting, Value: "", DiscordRoleID: "member"},
+				{Source: types.DiscordSourceStaff, Value: string(constants.AirTrafficManagerRole), DiscordRoleID: "atm"},
+			},
+		},
+		{
+			Facility: "ZLC",
+			GuildID:  "zlc",
+			Roles: []models.DiscordRoleMapping{
+				{Source: types.DiscordSourceHome, Value: "", DiscordRoleID: "home"},
+			},
+		},
+	}
+}
+
+func testUser() *models.User {
+	return &models.User{
+		CID:              1293257,
+		ControllerRating: constants.ControllerRating,
+		DiscordID:        "u1",
+		Roster: []models.Roster{
+			{Facility: "ZDV", Home: true, Roles: []models.UserRole{{RoleID: constants.AirTrafficManagerRole, FacilityID: "ZDV"}}},
+		},
+	}
+}
+
+func TestDesiredRoles(t *testing.T) {
+	guilds := testGuilds()
+
+	visitor := testUser()
+	visitor.ControllerRating = constants.Student3Rating
+	visitor.Roster = []models.Roster{{Facility: "ZLC", Visiting: true}}
+
+	tests := []struct {
+		name  string
+		user  *models.User
+		guild *models.DiscordGuild
+		want  []string
+	}{
+		{name: "facility guild", user: testUser(), guild: &guilds[0], want: []string{"c1", "home", "management"}},
+		{name: "division guild covers every facility", user: testUser(), guild: &guilds[1], want: []string{"member", "atm"}},
+		{name: "other facility guild", user: testUser(), guild: &guilds[2], want: []string{}},
+		{name: "visitor in facility guild", user: visitor, guild: &guilds[0], want: []string{}},
+		{name: "visitor in division guild", user: visitor, guild: &guilds[1], want: []string{"member"}},
+		{name: "visitor not home", user: visitor, guild: &guilds[2], want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DesiredRoles(tt.user, tt.guild); !slices.Equal(got, tt.want) {
+				t.Errorf("DesiredRoles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func openGuilds(t *testing.T) {
+	t.Helper()
+
+	dbtest.Open(t, &models.DiscordGuild{}, &models.DiscordRoleMapping{})
+	for _, guild := range testGuilds() {
+		if err := guild.Create(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSyncerSyncUser(t *testing.T) {
+	openGuilds(t)
+	f, client := newFakeServer(t, map[string]map[string][]string{
+		"zdv": {"u1": {"visitor", "unmanaged"}},
+		"zhq": {"u1": {"atm"}},
+	})
+
+	if err := NewSyncer(client).SyncUser(context.Background(), testUser()); err != nil {
+		t.Fatalf("SyncUser() error = %v", err)
+	}
+
+	if got, want := f.roles("zdv", "u1"), []string{"c1", "home", "management", "unmanaged"}; !slices.Equal(got, want) {
+		t.Errorf("ZDV roles = %v, want %v", got, want)
+	}
+	if got, want := f.roles("zhq", "u1"), []string{"atm", "member"}; !slices.Equal(got, want) {
+		t.Errorf("ZHQ roles = %v, want %v", got, want)
+	}
+
+	// Only the difference is applied, and guilds the user is not in are skipped
+	slices.Sort(f.calls)
+	want := []string{"DELETE zdv/u1/visitor", "PUT zdv/u1/c1", "PUT zdv/u1/home", "PUT zdv/u1/management", "PUT zhq/u1/member"}
+	if !slices.Equal(f.calls, want) {
+		t.Errorf("role changes = %v, want %v", f.calls, want)
+	}
+
+	// A second sync has nothing left to do
+	f.calls = nil
+	if err := NewSyncer(client).SyncUser(context.Background(), testUser()); err != nil {
+		t.Fatalf("SyncUser() error = %v", err)
+	}
+	if len(f.calls) != 0 {
+		t.Errorf("repeated sync made role changes: %v", f.calls)
+	}
+}
+
+func TestSyncerRevokeUser(t *testing.T) {
+	openGuilds(t)
+	f, client := newFakeServer(t, map[string]map[string][]string{
+		"zdv": {"u1": {"c1", "home", "unmanaged"}},
+		"zhq": {"u1": {"member"}},
+	})
+
+	if err := NewSyncer(client).RevokeUser(context.Background(), "u1"); err != nil {
+		t.Fatalf("RevokeUser() error = %v", err)
+	}
+
+	if got, want := f.roles("zdv", "u1"), []string{"unmanaged"}; !slices.Equal(got, want) {
+		t.Errorf("ZDV roles = %v, want %v", got, want)
+	}
+	if got := f.roles("zhq", "u1"); len(got) != 0 {
+		t.Errorf("ZHQ roles = %v, want none", got)
+	}
+}
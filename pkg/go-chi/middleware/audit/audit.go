@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"slices"
+)
+
+// Log writes an audit log entry for every POST, PUT, PATCH and DELETE once it has been handled. It has to run before
+// the auth middlewares so requests they reject are recorded too.
+func Log(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains([]string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}, r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &utils.AuditRecord{}
+		r = r.WithContext(context.WithValue(r.Context(), utils.AuditKey{}, rec))
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		entry := NewEntry(r, rec, ww.Status())
+		if err := entry.Create(); err != nil {
+			log.WithError(err).Errorf("Error writing audit log entry for %s %s", entry.Method, entry.Path)
+		}
+	})
+}
+
+// NewEntry builds the audit log entry of a handled request. The principal is read from the last request rendered,
+// which has passed through the auth middlewares, falling back to the request as it arrived.
+func NewEntry(r *http.Request, rec *utils.AuditRecord, status int) *models.AuditLogEntry {
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	handled := r
+	if rec.Request != nil {
+		handled = rec.Request
+	}
+
+	entry := &models.AuditLogEntry{
+		ActorType:  models.AuditActorAnonymous,
+		Method:     r.Method,
+		Route:      chi.RouteContext(r.Context()).RoutePattern(),
+		Path:       r.URL.Path,
+		IP:         utils.ClientIP(r),
+		StatusCode: status,
+		Outcome:    outcome(status),
+		Resources:  []models.AuditLogResource{},
+	}
+
+	if user := utils.GetXUser(handled); user != nil {
+		entry.ActorType = models.AuditActorUser
+		entry.ActorCID = user.CID
+		if impersonator := utils.GetXImpersonator(handled); impersonator != nil {
+			entry.ImpersonatorCID = impersonator.CID
+		}
+		if pat := utils.GetXAccessToken(handled); pat != nil {
+			entry.AccessTokenID = pat.ID
+		}
+	}
+	if key := utils.GetXFacilityAPIKey(handled); key != nil {
+		if entry.ActorType == models.AuditActorAnonymous {
+			entry.ActorType = models.AuditActorFacility
+		}
+		entry.Facility = key.Facility
+		entry.APIKeyID = key.ID
+	}
+
+	for _, resource := range rec.Resources {
+		entry.Resources = append(entry.Resources, newResource(resource, r.Method == http.MethodDelete && entry.Outcome == models.AuditOutcomeSuccess))
+	}
+
+	return entry
+}
+
+func newResource(resource *utils.AuditResource, deleted bool) models.AuditLogResource {
+	res := models.AuditLogResource{
+		Type:       resource.Type,
+		ResourceID: resourceID(resource.Before),
+		Before:     resource.Before,
+	}
+
+	if !deleted {
+		after, err := json.Marshal(resource.Value)
+		if err != nil {
+			log.WithError(err).Errorf("Error marshalling %s %s for the audit log", res.Type, res.ResourceID)
+		} else {
+			res.After = after
+		}
+	}
+
+	res.Changes = models.DiffJSON(res.Before, res.After)
+	redact(&res, models.RedactedFields(resource.Value))
+	return res
+}
+
+// redact hides the values of sensitive fields, once the changes to them have been found
+func redact(res *models.AuditLogResource, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+
+	res.Changes = res.Changes.Redact(fields)
+	res.Before = redactJSON(res.Before, fields)
+	res.After = redactJSON(res.After, fields)
+}
+
+func redactJSON(data json.RawMessage, fields []string) json.RawMessage {
+	var values map[string]any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return data
+	}
+
+	for _, field := range fields {
+		if value, ok := values[field]; ok && value != nil {
+			values[field] = models.Redacted
+		}
+	}
+
+	redacted, err := json.Marshal(values)
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+// resourceID picks the ID out of a resource's JSON, its id or, for users, cid
+func resourceID(data json.RawMessage) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ""
+	}
+
+	for _, key := range []string{"id", "cid"} {
+		raw, ok := fields[key]
+		if !ok {
+			continue
+		}
+		var id any
+		if err := json.Unmarshal(raw, &id); err != nil {
+			continue
+		}
+		if f, ok := id.(float64); ok {
+			return fmt.Sprintf("%.0f", f)
+		}
+		return fmt.Sprint(id)
+	}
+	return ""
+}
+
+func outcome(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return models.AuditOutcomeDenied
+	case status >= http.StatusBadRequest:
+		return models.AuditOutcomeFailure
+	default:
+		return models.AuditOutcomeSuccess
+	}
+}
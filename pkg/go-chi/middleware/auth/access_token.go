@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// lastUsedResolution is how stale a token's last used time can get before it is written again, so busy scripts do
+// not write on every request
+const lastUsedResolution = time.Minute
+
+// HasAccessToken authenticates requests carrying a personal access token as "Authorization: Bearer <token>". The
+// token's user is set as the request's user, just like a session cookie, along with the token's scopes.
+func HasAccessToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !strings.HasPrefix(token, models.PersonalAccessTokenPrefix) {
+			// Not ours, OAuth access tokens are checked by the routes that accept them
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		pat, err := models.GetPersonalAccessToken(token)
+		if err != nil || pat.IsExpired() {
+			utils.Render(w, r, utils.ErrUnauthorized)
+			return
+		}
+
+		user := &models.User{CID: pat.CID}
+		if err := user.Get(); err != nil {
+			utils.Render(w, r, utils.ErrUnauthorized)
+			return
+		}
+
+		if now := time.Now(); pat.LastUsedAt == nil || now.Sub(*pat.LastUsedAt) > lastUsedResolution {
+			if err := pat.TouchLastUsed(now); err != nil {
+				log.WithError(err).Errorf("Error updating last used time of access token %d", pat.ID)
+			}
+		}
+
+		scopes := pat.Scopes
+		if scopes == nil {
+			scopes = []constants.Scope{}
+		}
+
+		ctx := context.WithValue(r.Context(), utils.XUser{}, user)
+		ctx = context.WithValue(ctx, utils.XGuest{}, false)
+		ctx = context.WithValue(ctx, utils.XScopes{}, scopes)
+		ctx = context.WithValue(ctx, utils.XAccessToken{}, pat)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireScope limits the routes below it to access tokens and facility API keys with the read scope for safe
+// methods and the write scope for everything else. Write scopes include read access. Cookie sessions are not
+// affected.
+func RequireScope(read, write constants.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, ok := utils.GetXScopes(r)
+			if !ok && utils.GetXUser(r) == nil {
+				// The facility API key is only what the request acts as when no user is logged in
+				scopes, ok = utils.GetXFacilityScopes(r)
+			}
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed := slices.Contains(scopes, write)
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				allowed = allowed || slices.Contains(scopes, read)
+			}
+
+			if !allowed {
+				utils.Render(w, r, utils.ErrForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), utils.XScopeChecked{}, true)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// NoAccessToken rejects requests authenticated with a personal access token, for routes that must only be used from
+// a session such as managing the tokens themselves
+func NoAccessToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := utils.GetXScopes(r); ok {
+			utils.Render(w, r, utils.ErrForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
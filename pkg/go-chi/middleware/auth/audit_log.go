@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"net/http"
+)
+
+// CanViewAuditLog allows users holding the audit log permission at ZHQ, which only division staff do
+func CanViewAuditLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := utils.GetPrincipal(r)
+		if principal.User != nil && principal.Can(constants.ViewAuditLogPermission, "ZHQ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		denyPermission(w, r, constants.ViewAuditLogPermission, "audit log")
+	})
+}
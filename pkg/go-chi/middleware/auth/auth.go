@@ -3,20 +3,24 @@ package middleware
 import (
 	"context"
 	"errors"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/cookie"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	"github.com/VATUSA/primary-api/pkg/utils"
+	log "github.com/sirupsen/logrus"
 	"net/http"
-	"strconv"
+	"time"
 )
 
+// HasCookie authenticates requests carrying a VATUSA session cookie. The cookie holds a session token, sessions that
+// were revoked or have expired are treated as guests and the cookie is cleared.
 func HasCookie(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		guest := context.WithValue(r.Context(), utils.XGuest{}, true)
+
 		authCookie, err := r.Cookie("VATUSA")
 		if errors.Is(err, http.ErrNoCookie) {
-			// Set x-guest context to true
-			ctx := context.WithValue(r.Context(), utils.XGuest{}, true)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(guest))
 			return
 		}
 
@@ -26,22 +30,36 @@ func HasCookie(next http.Handler) http.Handler {
 			return
 		}
 
-		cid, err := strconv.ParseUint(auth["cid"], 10, 64)
-		if err != nil {
-			utils.Render(w, r, utils.ErrBadRequest)
+		session, err := models.GetSession(auth["session"])
+		if auth["session"] == "" || err != nil || session.IsExpired() {
+			// Cookies from before server-side sessions only carry a CID and can't be revoked, so they are not honored
+			cookie.ClearSession(w)
+			next.ServeHTTP(w, r.WithContext(guest))
 			return
 		}
 
-		user := &models.User{CID: uint(cid)}
+		user := &models.User{CID: session.CID}
 		if err := user.Get(); err != nil {
 			utils.Render(w, r, utils.ErrBadRequest)
 			return
 		}
 
+		if now := time.Now(); now.Sub(session.LastSeenAt) > lastUsedResolution {
+			if err := session.TouchLastSeen(now, utils.ClientIP(r)); err != nil {
+				log.WithError(err).Errorf("Error updating last seen time of session %d", session.ID)
+			}
+		}
+
 		ctx := context.WithValue(r.Context(), utils.XUser{}, user)
 		ctx = context.WithValue(ctx, utils.XGuest{}, false)
+		ctx = context.WithValue(ctx, utils.XSession{}, session)
 
-		next.ServeHTTP(w, r.WithContext(ctx))
+		r, ok := impersonate(w, r.WithContext(ctx), session, user)
+		if !ok {
+			return
+		}
+
+		next.ServeHTTP(w, r)
 	})
 
 }
@@ -54,14 +72,34 @@ func HasAPIKey(next http.Handler) http.Handler {
 			return
 		}
 
-		facility, err := models.GetFacilityByAPIKey(apiKey)
+		facility, key, err := models.GetFacilityByAPIKey(apiKey)
 		if err != nil {
 			next.ServeHTTP(w, r.WithContext(r.Context()))
 			return
 		}
 
+		ip := utils.ClientIP(r)
+		if !key.AllowsIP(ip) {
+			log.Warnf("Facility API Key %d of %s used from %s, which is not in its allowlist.", key.ID, key.Facility, ip)
+			utils.Render(w, r, utils.ErrForbidden)
+			return
+		}
+
+		if now := time.Now(); key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedResolution {
+			if err := key.TouchLastUsed(now, ip); err != nil {
+				log.WithError(err).Errorf("Error updating last used time of facility API key %d", key.ID)
+			}
+		}
+
+		scopes := key.Scopes
+		if scopes == nil {
+			scopes = []constants.Scope{}
+		}
+
 		ctx := context.WithValue(r.Context(), utils.XFacility{}, facility)
 		ctx = context.WithValue(ctx, utils.XGuest{}, false)
+		ctx = context.WithValue(ctx, utils.XFacilityScopes{}, scopes)
+		ctx = context.WithValue(ctx, utils.XFacilityAPIKey{}, key)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -74,6 +112,26 @@ func NotGuest(next http.Handler) http.Handler {
 			utils.Render(w, r, utils.ErrUnauthorized)
 			return
 		}
+
+		// Access tokens and facility API keys can only be used on routes that declare the scope they need
+		_, token := utils.GetXScopes(r)
+		_, key := utils.GetXFacilityScopes(r)
+		if (token || key && utils.GetXUser(r) == nil) && !utils.GetXScopeChecked(r) {
+			utils.Render(w, r, utils.ErrForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireUser rejects requests that are only authenticated by a facility API key
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if utils.GetXUser(r) == nil {
+			utils.Render(w, r, utils.ErrForbidden)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
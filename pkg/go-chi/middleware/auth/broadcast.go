@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// CanSendBroadcasts allows users who can send broadcasts at any facility, the broadcast itself is checked with
+// utils.CanBroadcast once its target is known
+func CanSendBroadcasts(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := utils.GetPrincipal(r)
+		if principal.User != nil && principal.CanAnywhere(constants.SendBroadcastsPermission) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		denyPermission(w, r, constants.SendBroadcastsPermission, "broadcasts")
+	})
+}
+
+func CanEditBroadcast(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		credentials := GetCredentials(r)
+		b := utils.GetBroadcastCtx(r)
+
+		if credentials.User != nil {
+			if utils.CanBroadcast(credentials.User, b) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log.Warnf("User %d, attempted to edit broadcast %d. No permissions.", credentials.User.CID, b.ID)
+		}
+
+		utils.Render(w, r, utils.ErrForbidden)
+	})
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"encoding/json"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/types"
 	"github.com/VATUSA/primary-api/pkg/utils"
 	log "github.com/sirupsen/logrus"
@@ -11,86 +12,38 @@ import (
 
 func CanViewFeedback(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		credentials := GetCredentials(r)
-
 		targetFacility := utils.GetFacilityCtx(r)
 
-		if credentials.User != nil {
-			if utils.IsVATUSAStaff(credentials.User) {
-				next.ServeHTTP(w, r)
-				return
-			}
+		principal := utils.GetPrincipal(r)
+		if principal.Can(constants.ViewFeedbackPermission, targetFacility.ID) {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-			if utils.IsFacilitySeniorStaff(credentials.User, targetFacility.ID) {
-				next.ServeHTTP(w, r)
+		// Controllers can see their own accepted feedback
+		if cid := r.URL.Query().Get("cid"); cid != "" && principal.User != nil {
+			cidInt, err := strconv.Atoi(cid)
+			if err != nil {
+				utils.Render(w, r, utils.ErrInvalidRequest(err))
 				return
 			}
 
-			cid := r.URL.Query().Get("cid")
-			if cid != "" {
-				cidInt, err := strconv.Atoi(cid)
-				if err != nil {
-					utils.Render(w, r, utils.ErrInvalidRequest(err))
-					return
-				}
-
-				if credentials.User.CID == uint(cidInt) {
-					q := r.URL.Query()
-					q.Set("cid", cid)           // Preserve the original 'cid'
-					q.Set("status", "accepted") // Set the 'status' to 'accepted'
-					r.URL.RawQuery = q.Encode()
-					next.ServeHTTP(w, r)
-					return
-				}
-			}
-
-			log.Warnf("User %d, attempted to view feedback for facility: %s. No permissions.", credentials.User.CID, targetFacility.ID)
-		}
-
-		if credentials.Facility != nil {
-			if credentials.Facility.ID == targetFacility.ID {
+			if principal.User.CID == uint(cidInt) {
+				q := r.URL.Query()
+				q.Set("cid", cid)           // Preserve the original 'cid'
+				q.Set("status", "accepted") // Set the 'status' to 'accepted'
+				r.URL.RawQuery = q.Encode()
 				next.ServeHTTP(w, r)
 				return
 			}
-
-			log.Warnf("Facility %s, attempted to view feedback for facility: %s. No permissions.", credentials.Facility.ID, targetFacility.ID)
 		}
 
-		utils.Render(w, r, utils.ErrForbidden)
+		denyPermission(w, r, constants.ViewFeedbackPermission, "facility "+string(targetFacility.ID))
 	})
 }
 
 func CanEditFeedback(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		credentials := GetCredentials(r)
-
-		targetFacility := utils.GetFacilityCtx(r)
-
-		if credentials.User != nil {
-			if utils.IsVATUSAStaff(credentials.User) {
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			if utils.IsFacilitySeniorStaff(credentials.User, targetFacility.ID) {
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			log.Warnf("User %d, attempted to edit feedback for facility: %s. No permissions.", credentials.User.CID, targetFacility.ID)
-		}
-
-		if credentials.Facility != nil {
-			if credentials.Facility.ID == targetFacility.ID {
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			log.Warnf("Facility %s, attempted to edit feedback for facility: %s. No permissions.", credentials.Facility.ID, targetFacility.ID)
-		}
-
-		utils.Render(w, r, utils.ErrForbidden)
-	})
+	return RequirePermission(constants.EditFeedbackPermission)(next)
 }
 
 type Request struct {
@@ -120,7 +73,7 @@ func CanLeaveFeedback(next http.Handler) http.Handler {
 
 		if credentials.User != nil {
 			if req.Status != types.Pending {
-				log.Error("User %d, attempted to create feedback with status: %s. No permissions.", credentials.User.CID, req.Status)
+				log.Errorf("User %d attempted to create feedback with status: %s. No permissions.", credentials.User.CID, req.Status)
 				utils.Render(w, r, utils.ErrForbidden)
 				return
 			}
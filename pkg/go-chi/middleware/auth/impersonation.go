@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+// ImpersonationRoute is the route pattern of the impersonation endpoints. Writes to them, like stopping the
+// impersonation, must still be allowed during a read-only impersonation.
+const ImpersonationRoute = "/v3/user/impersonation"
+
+// impersonate switches the request over to the user the session is impersonating. Every impersonated request is
+// logged and tagged with the X-Impersonated-By header, and writes are refused when the impersonation is read-only.
+// It returns false when the request has already been answered.
+func impersonate(w http.ResponseWriter, r *http.Request, session *models.Session, staff *models.User) (*http.Request, bool) {
+	if !session.IsImpersonating() {
+		if session.ImpersonatingCID != nil {
+			if err := session.EndImpersonation("expired"); err != nil {
+				log.WithError(err).Errorf("Error ending expired impersonation of session %d", session.ID)
+			}
+		}
+		return r, true
+	}
+
+	target := &models.User{CID: *session.ImpersonatingCID}
+	if err := target.Get(); err != nil {
+		utils.Render(w, r, utils.ErrInternalServer)
+		return r, false
+	}
+
+	log.WithFields(log.Fields{
+		"impersonator": staff.CID,
+		"cid":          target.CID,
+		"read_only":    session.ImpersonationReadOnly,
+	}).Infof("Impersonated request %s %s", r.Method, r.URL.Path)
+	w.Header().Set("X-Impersonated-By", fmt.Sprint(staff.CID))
+
+	if session.ImpersonationReadOnly && !isSafeMethod(r.Method) && !isImpersonationRoute(r) {
+		utils.Render(w, r, utils.ErrForbidden)
+		return r, false
+	}
+
+	ctx := context.WithValue(r.Context(), utils.XUser{}, target)
+	ctx = context.WithValue(ctx, utils.XImpersonator{}, staff)
+	return r.WithContext(ctx), true
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// isImpersonationRoute reports whether r is routed to one of the impersonation endpoints. Sessions are loaded before
+// the request is routed, so the route is looked up here, which treats paths with and without a trailing slash alike.
+func isImpersonationRoute(r *http.Request) bool {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return false
+	}
+
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+
+	match := chi.NewRouteContext()
+	if !rctx.Routes.Match(match, r.Method, path) {
+		return false
+	}
+	pattern := match.RoutePattern()
+	return pattern == ImpersonationRoute || strings.HasPrefix(pattern, ImpersonationRoute+"/")
+}
+
+// NoImpersonation rejects requests made while impersonating, for routes that would let the impersonator act as the
+// user beyond the impersonation, like creating tokens
+func NoImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if utils.GetXImpersonator(r) != nil {
+			utils.Render(w, r, utils.ErrForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsImpersonationRoute(t *testing.T) {
+	var matched bool
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			matched = isImpersonationRoute(r)
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Route("/v3/user", func(r chi.Router) {
+		r.Route("/impersonation", func(r chi.Router) {
+			r.Get("/", ok)
+			r.Post("/", ok)
+			r.Delete("/", ok)
+			r.Delete("/{ID}", ok)
+		})
+		r.Post("/impersonations", ok)
+		r.Delete("/{CID}", ok)
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{method: http.MethodDelete, path: "/v3/user/impersonation", want: true},
+		{method: http.MethodDelete, path: "/v3/user/impersonation/", want: true},
+		{method: http.MethodDelete, path: "/v3/user/impersonation/5", want: true},
+		{method: http.MethodPost, path: "/v3/user/impersonation", want: true},
+		{method: http.MethodDelete, path: "/v3/user/1293257", want: false},
+		{method: http.MethodPost, path: "/v3/user/impersonations", want: false},
+		{method: http.MethodDelete, path: "/v3/user/impersonation/5/extra", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			matched = false
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+			if matched != tt.want {
+				t.Errorf("isImpersonationRoute() = %v, want %v", matched, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// RequirePermission allows principals holding perm at the route's facility
+func RequirePermission(perm constants.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			targetFacility := utils.GetFacilityCtx(r)
+			if utils.GetPrincipal(r).Can(perm, targetFacility.ID) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			denyPermission(w, r, perm, "facility "+string(targetFacility.ID))
+		})
+	}
+}
+
+// RequireUserPermission allows principals holding perm at a facility the route's user is rostered at, and the user
+// themselves when allowSelf is set
+func RequireUserPermission(perm constants.Permission, allowSelf bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			targetUser := utils.GetUserCtx(r)
+			principal := utils.GetPrincipal(r)
+			if allowSelf && principal.User != nil && principal.User.CID == targetUser.CID {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if principal.CanForUser(perm, targetUser) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			denyPermission(w, r, perm, fmt.Sprintf("user %d", targetUser.CID))
+		})
+	}
+}
+
+func denyPermission(w http.ResponseWriter, r *http.Request, perm constants.Permission, target string) {
+	principal := utils.GetPrincipal(r)
+	if principal.User != nil {
+		log.Warnf("User %d, attempted %s on %s. No permissions.", principal.User.CID, perm, target)
+	} else if principal.Facility != nil {
+		log.Warnf("Facility API Key %s, attempted %s on %s. No permissions.", principal.Facility.ID, perm, target)
+	}
+
+	utils.Render(w, r, utils.ErrForbidden)
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func rosteredUser(cid uint, rating constants.ATCRating, facility constants.FacilityID, roles ...constants.RoleID) *models.User {
+	roster := models.Roster{CID: cid, Facility: facility, Home: true}
+	for _, role := range roles {
+		roster.Roles = append(roster.Roles, models.UserRole{CID: cid, RoleID: role, FacilityID: facility})
+	}
+	return &models.User{CID: cid, ControllerRating: rating, Roster: []models.Roster{roster}}
+}
+
+// serve runs the request through mw as principal and reports whether it was let through
+func serve(t *testing.T, mw func(http.Handler) http.Handler, principal *models.User, apiKey *models.Facility, ctx func(context.Context) context.Context) bool {
+	t.Helper()
+
+	passed := false
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { passed = true }))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	c := ctx(r.Context())
+	if principal != nil {
+		c = context.WithValue(c, utils.XUser{}, principal)
+	}
+	if apiKey != nil {
+		c = context.WithValue(c, utils.XFacility{}, apiKey)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r.WithContext(c))
+
+	if !passed && w.Code != http.StatusForbidden {
+		t.Errorf("denied request returned %d, want 403", w.Code)
+	}
+	return passed
+}
+
+func TestRequirePermission(t *testing.T) {
+	zdv := func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, utils.FacilityKey{}, &models.Facility{ID: "ZDV"})
+	}
+
+	tests := []struct {
+		name      string
+		perm      constants.Permission
+		principal *models.User
+		apiKey    *models.Facility
+		want      bool
+	}{
+		{name: "ATM edits the roster", perm: constants.EditRosterPermission, principal: rosteredUser(1, constants.ControllerRating, "ZDV", constants.AirTrafficManagerRole), want: true},
+		{name: "TA edits the roster", perm: constants.EditRosterPermission, principal: rosteredUser(1, constants.ControllerRating, "ZDV", constants.TrainingAdministratorRole), want: true},
+		{name: "EC can't edit the roster", perm: constants.EditRosterPermission, principal: rosteredUser(1, constants.ControllerRating, "ZDV", constants.EventCoordinatorRole)},
+		{name: "another facility's ATM", perm: constants.EditRosterPermission, principal: rosteredUser(1, constants.ControllerRating, "ZLC", constants.AirTrafficManagerRole)},
+		{name: "division staff", perm: constants.EditRosterPermission, principal: rosteredUser(1, constants.ControllerRating, "ZHQ", constants.SupportServicesRole), want: true},
+		{name: "controller", perm: constants.EditRosterPermission, principal: rosteredUser(1, constants.ControllerRating, "ZDV")},
+		{name: "guest", perm: constants.EditRosterPermission},
+		{name: "facility API key", perm: constants.EditRosterPermission, apiKey: &models.Facility{ID: "ZDV"}, want: true},
+		{name: "another facility's API key", perm: constants.EditRosterPermission, apiKey: &models.Facility{ID: "ZLC"}},
+		{name: "WM edits the facility", perm: constants.EditFacilityPermission, principal: rosteredUser(1, constants.ControllerRating, "ZDV", constants.WebMasterRole), want: true},
+		{name: "TA can't edit the facility", perm: constants.EditFacilityPermission, principal: rosteredUser(1, constants.ControllerRating, "ZDV", constants.TrainingAdministratorRole)},
+		{name: "AEC edits events", perm: constants.EditEventPermission, principal: rosteredUser(1, constants.ControllerRating, "ZDV", constants.AssistantEventCoordinator), want: true},
+		{name: "FE can't edit events", perm: constants.EditEventPermission, principal: rosteredUser(1, constants.ControllerRating, "ZDV", constants.FacilityEngineerRole)},
+		{name: "mentor records training", perm: constants.EditTrainingPermission, principal: rosteredUser(1, constants.ControllerRating, "ZDV", constants.MentorRole), want: true},
+		{name: "TA records training", perm: constants.EditTrainingPermission, principal: rosteredUser(1, constants.ControllerRating, "ZDV", constants.TrainingAdministratorRole), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(t, RequirePermission(tt.perm), tt.principal, tt.apiKey, zdv); got != tt.want {
+				t.Errorf("RequirePermission(%s) let the request through = %v, want %v", tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireUserPermission(t *testing.T) {
+	target := rosteredUser(1293257, constants.Student2Rating, "ZDV")
+	targetCtx := func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, utils.UserKey{}, target)
+	}
+
+	tests := []struct {
+		name      string
+		allowSelf bool
+		principal *models.User
+		want      bool
+	}{
+		{name: "self", allowSelf: true, principal: target, want: true},
+		{name: "self not allowed", principal: target},
+		{name: "staff of the user's facility", principal: rosteredUser(1, constants.ControllerRating, "ZDV", constants.FacilityEngineerRole), want: true},
+		{name: "staff of another facility", principal: rosteredUser(1, constants.ControllerRating, "ZLC", constants.FacilityEngineerRole)},
+		{name: "I1 instructor", principal: rosteredUser(1, constants.InstructorRating, "ZDV", constants.InstructorRole), want: true},
+		{name: "instructor role below I1", principal: rosteredUser(1, constants.SeniorControllerRating, "ZDV", constants.InstructorRole)},
+		{name: "mentor", principal: rosteredUser(1, constants.SeniorControllerRating, "ZDV", constants.MentorRole)},
+		{name: "division staff", principal: rosteredUser(1, constants.ControllerRating, "ZHQ"), want: true},
+		{name: "guest", allowSelf: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := RequireUserPermission(constants.ViewRatingChangePermission, tt.allowSelf)
+			if got := serve(t, mw, tt.principal, nil, targetCtx); got != tt.want {
+				t.Errorf("RequireUserPermission() let the request through = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func CanRecommendPromotion(next http.Handler) http.Handler {
+	return RequirePermission(constants.RecommendPromotionPermission)(next)
+}
+
+// CanApprovePromotions allows division training staff, see utils.CanApprovePromotions
+func CanApprovePromotions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := utils.GetXUser(r)
+		if user != nil && utils.CanApprovePromotions(user) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if user != nil {
+			log.Warnf("User %d, attempted to decide promotions. No permissions.", user.CID)
+		}
+		utils.Render(w, r, utils.ErrForbidden)
+	})
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"net/http"
+)
+
+func CanViewTraining(next http.Handler) http.Handler {
+	return RequirePermission(constants.ViewTrainingPermission)(next)
+}
+
+func CanViewUserTraining(next http.Handler) http.Handler {
+	return RequireUserPermission(constants.ViewTrainingPermission, true)(next)
+}
+
+func CanEditTraining(next http.Handler) http.Handler {
+	return RequirePermission(constants.EditTrainingPermission)(next)
+}
+
+// CanConductOTS allows the facility's TA and its instructors, who must hold an I1 or I3 rating to conduct OTS exams
+func CanConductOTS(next http.Handler) http.Handler {
+	return RequirePermission(constants.ConductOTSPermission)(next)
+}
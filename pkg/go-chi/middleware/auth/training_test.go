@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"testing"
+)
+
+func TestCanConductOTS(t *testing.T) {
+	zdv := func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, utils.FacilityKey{}, &models.Facility{ID: "ZDV"})
+	}
+
+	tests := []struct {
+		name      string
+		principal *models.User
+		want      bool
+	}{
+		{name: "I1 instructor", principal: rosteredUser(1, constants.InstructorRating, "ZDV", constants.InstructorRole), want: true},
+		{name: "I3 instructor", principal: rosteredUser(1, constants.SeniorInstructorRating, "ZDV", constants.InstructorRole), want: true},
+		{name: "C3 instructor", principal: rosteredUser(1, constants.SeniorControllerRating, "ZDV", constants.InstructorRole)},
+		{name: "C1 instructor", principal: rosteredUser(1, constants.ControllerRating, "ZDV", constants.InstructorRole)},
+		{name: "SUP instructor", principal: rosteredUser(1, constants.SupervisorRating, "ZDV", constants.InstructorRole)},
+		{name: "instructor of another facility", principal: rosteredUser(1, constants.InstructorRating, "ZLC", constants.InstructorRole)},
+		{name: "TA", principal: rosteredUser(1, constants.ControllerRating, "ZDV", constants.TrainingAdministratorRole), want: true},
+		{name: "I1 mentor", principal: rosteredUser(1, constants.InstructorRating, "ZDV", constants.MentorRole)},
+		{name: "ATM", principal: rosteredUser(1, constants.InstructorRating, "ZDV", constants.AirTrafficManagerRole)},
+		{name: "division staff", principal: rosteredUser(1, constants.ControllerRating, "ZHQ", constants.TrainingServicesRole), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(t, CanConductOTS, tt.principal, nil, zdv); got != tt.want {
+				t.Errorf("CanConductOTS let the request through = %v, want %v", got, tt.want)
+			}
+			if got := (utils.Principal{User: tt.principal}).Can(constants.ConductOTSPermission, "ZDV"); got != tt.want {
+				t.Errorf("Principal.Can(%s) = %v, want %v", constants.ConductOTSPermission, got, tt.want)
+			}
+		})
+	}
+}
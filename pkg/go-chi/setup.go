@@ -2,6 +2,7 @@ package go_chi
 
 import (
 	"github.com/VATUSA/primary-api/pkg/config"
+	"github.com/VATUSA/primary-api/pkg/go-chi/middleware/audit"
 	auth "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -21,8 +22,11 @@ func New(cfg *config.Config) *chi.Mux {
 
 	r.Use(cors.Handler(NewCors(cfg)))
 
+	r.Use(audit.Log)
+
 	r.Use(auth.HasCookie)
 	r.Use(auth.HasAPIKey)
+	r.Use(auth.HasAccessToken)
 
 	r.Route("/ping", func(r chi.Router) {
 		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,131 @@
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusTentative = "TENTATIVE"
+	StatusCancelled = "CANCELLED"
+)
+
+const (
+	timeFormat = "20060102T150405Z"
+	lineLength = 75
+)
+
+// Calendar is an RFC 5545 VCALENDAR containing VEVENTs
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Event is a single VEVENT. UID must stay the same for the lifetime of the event so calendar clients update it in
+// place, and Sequence must increase whenever it changes.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Status      string
+	Start       time.Time
+	End         time.Time
+	Modified    time.Time
+	Sequence    int64
+}
+
+// Bytes encodes the calendar, all times are written in UTC
+func (c *Calendar) Bytes() []byte {
+	buf := &bytes.Buffer{}
+	write := func(name, value string) {
+		fold(buf, name+":"+value)
+	}
+
+	now := time.Now()
+	write("BEGIN", "VCALENDAR")
+	write("VERSION", "2.0")
+	write("PRODID", "-//VATUSA//Primary API//EN")
+	write("CALSCALE", "GREGORIAN")
+	write("METHOD", "PUBLISH")
+	if c.Name != "" {
+		write("X-WR-CALNAME", escape(c.Name))
+	}
+	write("X-WR-TIMEZONE", "UTC")
+
+	for _, e := range c.Events {
+		modified := e.Modified
+		if modified.IsZero() {
+			modified = now
+		}
+
+		write("BEGIN", "VEVENT")
+		write("UID", e.UID)
+		write("DTSTAMP", now.UTC().Format(timeFormat))
+		write("DTSTART", e.Start.UTC().Format(timeFormat))
+		write("DTEND", e.End.UTC().Format(timeFormat))
+		write("LAST-MODIFIED", modified.UTC().Format(timeFormat))
+		write("SEQUENCE", fmt.Sprintf("%d", e.Sequence))
+		write("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			write("DESCRIPTION", escape(e.Description))
+		}
+		if e.Location != "" {
+			write("LOCATION", escape(e.Location))
+		}
+		if e.URL != "" {
+			write("URL", e.URL)
+		}
+		if e.Status != "" {
+			write("STATUS", e.Status)
+		}
+		write("END", "VEVENT")
+	}
+
+	write("END", "VCALENDAR")
+	return buf.Bytes()
+}
+
+// Serve writes the calendar as a text/calendar response
+func Serve(w http.ResponseWriter, c *Calendar) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(c.Bytes())
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// fold writes line terminated by CRLF, folding it so no line is longer than 75 octets without splitting a UTF-8
+// character
+func fold(buf *bytes.Buffer, line string) {
+	limit := lineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards their length
+		limit = lineLength - 1
+	}
+
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
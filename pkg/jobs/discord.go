@@ -0,0 +1,15 @@
+package jobs
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/discord"
+)
+
+// SyncDiscordRoles reconciles the mapped Discord roles of every linked user, catching rating, roster and staff role
+// changes made since the last run
+func SyncDiscordRoles() {
+	if discord.DefaultSyncer == nil {
+		return
+	}
+	discord.DefaultSyncer.SyncAll(context.Background())
+}
@@ -0,0 +1,23 @@
+package jobs
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// GenerateEventOccurrences creates the upcoming occurrences of every recurring event series
+func GenerateEventOccurrences() {
+	series, err := models.GetEventSeries()
+	if err != nil {
+		log.WithError(err).Error("Error getting event series")
+		return
+	}
+
+	until := time.Now().Add(models.EventSeriesHorizon)
+	for idx := range series {
+		if err := series[idx].GenerateOccurrences(until); err != nil {
+			log.WithError(err).Errorf("Error generating occurrences for event series %d", series[idx].ID)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"github.com/VATUSA/primary-api/pkg/scheduler"
+	"time"
+)
+
+// Register adds every recurring background job to s
+func Register(s *scheduler.Scheduler) {
+	s.AddTask(&scheduler.Task{
+		ID:       1,
+		Name:     "Generate event series occurrences",
+		TaskFunc: GenerateEventOccurrences,
+		Interval: time.Hour,
+	})
+
+	s.AddTask(&scheduler.Task{
+		ID:       2,
+		Name:     "Retry notification deliveries",
+		TaskFunc: RetryNotificationDeliveries,
+		Interval: time.Minute,
+	})
+
+	s.AddTask(&scheduler.Task{
+		ID:       3,
+		Name:     "Sync Discord roles",
+		TaskFunc: SyncDiscordRoles,
+		Interval: 6 * time.Hour,
+	})
+
+	s.AddTask(&scheduler.Task{
+		ID:       4,
+		Name:     "Delete expired OAuth grants",
+		TaskFunc: DeleteExpiredOAuthGrants,
+		Interval: 24 * time.Hour,
+	})
+
+	s.AddTask(&scheduler.Task{
+		ID:       5,
+		Name:     "Delete expired sessions",
+		TaskFunc: DeleteExpiredSessions,
+		Interval: 24 * time.Hour,
+	})
+
+	s.AddTask(&scheduler.Task{
+		ID:       6,
+		Name:     "Lift expired restrictions",
+		TaskFunc: LiftExpiredRestrictions,
+		Interval: 15 * time.Minute,
+	})
+}
@@ -0,0 +1,11 @@
+package jobs
+
+import "github.com/VATUSA/primary-api/pkg/notify"
+
+// RetryNotificationDeliveries retries the notification deliveries that failed and are due another attempt
+func RetryNotificationDeliveries() {
+	if notify.Default == nil {
+		return
+	}
+	notify.Default.Retry()
+}
@@ -0,0 +1,14 @@
+package jobs
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// DeleteExpiredOAuthGrants clears out authorization codes and refresh tokens that were never used before expiring
+func DeleteExpiredOAuthGrants() {
+	if err := models.DeleteExpiredOAuthGrants(time.Now()); err != nil {
+		log.WithError(err).Error("Error deleting expired OAuth grants")
+	}
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/notify"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// LiftExpiredRestrictions clears the user flag restrictions whose expiry has passed and notifies the members, then
+// lifts the restrictions of disciplinary cases whose effective period has ended
+func LiftExpiredRestrictions() {
+	now := time.Now()
+
+	flags, err := models.GetUserFlagsWithExpiredRestrictions(now)
+	if err != nil {
+		log.WithError(err).Error("Error fetching user flags with expired restrictions")
+		return
+	}
+
+	for idx := range flags {
+		flag := &flags[idx]
+		for _, restriction := range flag.ExpiredRestrictions(now) {
+			if err := models.LiftUserRestriction(flag.CID, restriction, "System"); err != nil {
+				log.WithError(err).Errorf("Error lifting the %s restriction of %d", restriction, flag.CID)
+				continue
+			}
+
+			notify.Send(&models.Notification{
+				CID:      flag.CID,
+				Category: notify.CategoryAdministration,
+				Title:    "Restriction Lifted",
+				Body:     "Your restriction from " + restriction.DisplayName() + " has expired and been lifted.",
+				ExpireAt: now.AddDate(0, 0, 30),
+			})
+		}
+	}
+
+	entries, err := models.GetExpiredDisciplinaryRestrictions(now)
+	if err != nil {
+		log.WithError(err).Error("Error fetching expired disciplinary restrictions")
+		return
+	}
+
+	for idx := range entries {
+		dle := &entries[idx]
+		if _, err := dle.LiftRestriction("System"); err != nil {
+			log.WithError(err).Errorf("Error lifting the restriction of disciplinary log entry %d", dle.ID)
+		}
+	}
+}
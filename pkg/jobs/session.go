@@ -0,0 +1,14 @@
+package jobs
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// DeleteExpiredSessions clears out sessions whose cookie has expired
+func DeleteExpiredSessions() {
+	if err := models.DeleteExpiredSessions(time.Now()); err != nil {
+		log.WithError(err).Error("Error deleting expired sessions")
+	}
+}
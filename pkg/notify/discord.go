@@ -0,0 +1,30 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/discord"
+)
+
+// DiscordChannel delivers notifications as direct messages from the VATUSA bot
+type DiscordChannel struct {
+	client discord.Client
+}
+
+func NewDiscordChannel(client discord.Client) *DiscordChannel {
+	return &DiscordChannel{client: client}
+}
+
+func (c *DiscordChannel) Name() types.NotificationChannel {
+	return types.ChannelDiscord
+}
+
+func (c *DiscordChannel) Enabled(user *models.User, prefs *models.UserNotification) bool {
+	return prefs.DiscordEnabled && user.DiscordID != ""
+}
+
+func (c *DiscordChannel) Send(ctx context.Context, user *models.User, n *models.Notification) error {
+	return c.client.SendDirectMessage(ctx, user.DiscordID, fmt.Sprintf("**%s**\n%s", n.Title, n.Body))
+}
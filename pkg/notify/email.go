@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/config"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// EmailChannel delivers notifications over SMTP. In development SMTP_HOST and SMTP_PORT can point at a local sink
+// such as MailHog, which the defaults (localhost:1025) already do.
+type EmailChannel struct {
+	cfg *config.SMTPConfig
+}
+
+func NewEmailChannel(cfg *config.SMTPConfig) *EmailChannel {
+	return &EmailChannel{cfg: cfg}
+}
+
+func (c *EmailChannel) Name() types.NotificationChannel {
+	return types.ChannelEmail
+}
+
+func (c *EmailChannel) Enabled(user *models.User, prefs *models.UserNotification) bool {
+	return prefs.EmailEnabled && user.Email != ""
+}
+
+func (c *EmailChannel) Send(ctx context.Context, user *models.User, n *models.Notification) error {
+	if n.Template == "" {
+		return c.SendEmail(ctx, user.Email, &Email{Subject: n.Title, Text: n.Body})
+	}
+
+	var facility *models.Facility
+	if n.Facility != "" {
+		facility = &models.Facility{ID: n.Facility}
+		if err := facility.Get(); err != nil {
+			return err
+		}
+	}
+
+	email, err := RenderEmail(n.Template, facility, user.FirstName, n.TemplateData)
+	if err != nil {
+		return err
+	}
+
+	return c.SendEmail(ctx, user.Email, email)
+}
+
+// Email is a message with a plain text body and an optional HTML alternative
+type Email struct {
+	Subject string `json:"subject" example:"Event assignment: ZDV FNO"`
+	Text    string `json:"text" example:"You have been assigned to DEN_I_APP (2300-0100z) for ZDV FNO."`
+	HTML    string `json:"html" example:"<p>You have been assigned to DEN_I_APP (2300-0100z) for ZDV FNO.</p>"`
+}
+
+func (c *EmailChannel) SendEmail(ctx context.Context, to string, email *Email) error {
+	from, err := mail.ParseAddress(c.cfg.From)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if c.cfg.User != "" {
+		auth = smtp.PlainAuth("", c.cfg.User, c.cfg.Password, c.cfg.Host)
+	}
+
+	msg, err := buildEmail(from.String(), to, email)
+	if err != nil {
+		return err
+	}
+
+	// smtp.SendMail does not take a context, so honor its deadline by running it in the background
+	errc := make(chan error, 1)
+	go func() {
+		errc <- smtp.SendMail(net.JoinHostPort(c.cfg.Host, c.cfg.Port), auth, from.Address, []string{to}, msg)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func buildEmail(from, to string, email *Email) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "From: %s\r\n", from)
+	fmt.Fprintf(buf, "To: %s\r\n", to)
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if email.HTML == "" {
+		if err := writePart(buf, "text/plain", email.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	boundary := fmt.Sprintf("vatusa-%d", time.Now().UnixNano())
+	fmt.Fprintf(buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	for _, part := range []struct{ contentType, body string }{{"text/plain", email.Text}, {"text/html", email.HTML}} {
+		fmt.Fprintf(buf, "--%s\r\n", boundary)
+		if err := writePart(buf, part.contentType, part.body); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(buf, "--%s--\r\n", boundary)
+
+	return buf.Bytes(), nil
+}
+
+func writePart(buf *bytes.Buffer, contentType, body string) error {
+	fmt.Fprintf(buf, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	w := quotedprintable.NewWriter(buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,213 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/stream"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// Notification categories, Events, Training and Feedback can be muted through models.UserNotification
+const (
+	CategoryAdministration = "Administration"
+	CategoryEvents         = "Events"
+	CategoryFeedback       = "Feedback"
+	CategoryRoster         = "Roster"
+	CategoryTraining       = "Training"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is attempted before it is marked as failed
+	MaxAttempts = 5
+	sendTimeout = 30 * time.Second
+)
+
+// Channel delivers notifications to users outside the API. Every notification is always stored in-app, channels
+// are the additional ways of reaching the user.
+type Channel interface {
+	Name() types.NotificationChannel
+	// Enabled reports whether the user can and wants to receive notifications over the channel
+	Enabled(user *models.User, prefs *models.UserNotification) bool
+	Send(ctx context.Context, user *models.User, n *models.Notification) error
+}
+
+// Default is the dispatcher used by Send
+var Default *Dispatcher
+
+type Dispatcher struct {
+	channels []Channel
+}
+
+func NewDispatcher(channels ...Channel) *Dispatcher {
+	return &Dispatcher{channels: channels}
+}
+
+// Send dispatches n through the Default dispatcher, logging any failure. Without a dispatcher the notification is
+// only stored in-app.
+func Send(n *models.Notification) {
+	if Default == nil {
+		if err := n.Create(); err != nil {
+			log.WithError(err).Errorf("Error creating notification for user %d", n.CID)
+			return
+		}
+		stream.Publish(n.CID, stream.TypeNotification, n)
+		return
+	}
+
+	if err := Default.Dispatch(n); err != nil {
+		log.WithError(err).Errorf("Error dispatching notification for user %d", n.CID)
+	}
+}
+
+// Dispatch stores n in-app, pushes it to the user's open streams and queues a delivery on every channel the user
+// has enabled for n's category. The first attempt is made straight away in the background, failed attempts are
+// picked up by Retry.
+func (d *Dispatcher) Dispatch(n *models.Notification) error {
+	if err := n.Create(); err != nil {
+		return err
+	}
+	stream.Publish(n.CID, stream.TypeNotification, n)
+
+	user := &models.User{CID: n.CID}
+	if err := user.Get(); err != nil {
+		return err
+	}
+
+	// Users that never saved their notification settings only receive in-app notifications
+	prefs := &models.UserNotification{CID: n.CID}
+	if err := prefs.Get(); err != nil || !CategoryEnabled(prefs, n.Category) {
+		return nil
+	}
+
+	var deliveries []*models.NotificationDelivery
+	for _, c := range d.channels {
+		if !c.Enabled(user, prefs) {
+			continue
+		}
+
+		delivery := &models.NotificationDelivery{
+			NotificationID: n.ID,
+			CID:            n.CID,
+			Channel:        c.Name(),
+			Status:         types.DeliveryPending,
+			// Leave the immediate attempt below time to finish before Retry considers the delivery
+			NextAttemptAt: time.Now().Add(backoff(0)),
+		}
+		if err := delivery.Create(); err != nil {
+			return err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if len(deliveries) > 0 {
+		go func() {
+			for _, delivery := range deliveries {
+				d.deliver(delivery, user, n)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// Retry attempts every pending delivery that is due
+func (d *Dispatcher) Retry() {
+	deliveries, err := models.GetDueNotificationDeliveries(time.Now())
+	if err != nil {
+		log.WithError(err).Error("Error getting due notification deliveries")
+		return
+	}
+
+	for idx := range deliveries {
+		delivery := &deliveries[idx]
+
+		n := &models.Notification{ID: delivery.NotificationID}
+		if err := n.Get(); err != nil {
+			d.fail(delivery, err)
+			continue
+		}
+		if n.ExpireAt.Before(time.Now()) {
+			d.fail(delivery, errors.New("notification expired before it could be delivered"))
+			continue
+		}
+
+		user := &models.User{CID: delivery.CID}
+		if err := user.Get(); err != nil {
+			d.fail(delivery, err)
+			continue
+		}
+
+		d.deliver(delivery, user, n)
+	}
+}
+
+func (d *Dispatcher) deliver(delivery *models.NotificationDelivery, user *models.User, n *models.Notification) {
+	channel := d.channel(delivery.Channel)
+	if channel == nil {
+		d.fail(delivery, errors.New("channel is not configured"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	delivery.Attempts++
+	if err := channel.Send(ctx, user, n); err != nil {
+		log.WithError(err).Warnf("Error delivering notification %d over %s, attempt %d", n.ID, delivery.Channel, delivery.Attempts)
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= MaxAttempts {
+			delivery.Status = types.DeliveryFailed
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(backoff(delivery.Attempts))
+		}
+	} else {
+		now := time.Now()
+		delivery.Status = types.DeliverySent
+		delivery.SentAt = &now
+		delivery.LastError = ""
+	}
+
+	if err := delivery.Update(); err != nil {
+		log.WithError(err).Errorf("Error updating notification delivery %d", delivery.ID)
+	}
+}
+
+func (d *Dispatcher) fail(delivery *models.NotificationDelivery, err error) {
+	delivery.Status = types.DeliveryFailed
+	delivery.LastError = err.Error()
+	if err := delivery.Update(); err != nil {
+		log.WithError(err).Errorf("Error updating notification delivery %d", delivery.ID)
+	}
+}
+
+func (d *Dispatcher) channel(name types.NotificationChannel) Channel {
+	for _, c := range d.channels {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// CategoryEnabled reports whether prefs allow notifications of category outside the API. Categories without a
+// preference flag, such as Administration, cannot be muted.
+func CategoryEnabled(prefs *models.UserNotification, category string) bool {
+	switch category {
+	case CategoryEvents:
+		return prefs.Events
+	case CategoryTraining:
+		return prefs.Training
+	case CategoryFeedback:
+		return prefs.Feedback
+	default:
+		return true
+	}
+}
+
+// backoff is the delay before the next attempt after attempts failed attempts: 1, 2, 4, 8... minutes
+func backoff(attempts uint) time.Duration {
+	return time.Minute << attempts
+}
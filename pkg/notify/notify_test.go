@@ -0,0 +1,380 @@
+package notify
+
+import (
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/config"
+	"github.com/VATUSA/primary-api/pkg/database/dbtest"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/discord"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type smtpMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// smtpServer is a minimal in-process SMTP server that records the messages it receives
+type smtpServer struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	messages []smtpMessage
+	// failing makes the server reject every message with a temporary error
+	failing bool
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening for SMTP: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	s := &smtpServer{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) config() *config.SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return &config.SMTPConfig{Host: host, Port: port, From: "VATUSA <no-reply@vatusa.net>"}
+}
+
+func (s *smtpServer) setFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+func (s *smtpServer) received() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMessage(nil), s.messages...)
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	tp := textproto.NewConn(conn)
+	defer tp.Close()
+
+	_ = tp.PrintfLine("220 localhost ESMTP")
+	msg := smtpMessage{}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO", "RSET", "NOOP":
+			_ = tp.PrintfLine("250 localhost")
+		case "MAIL":
+			s.mu.Lock()
+			failing := s.failing
+			s.mu.Unlock()
+			if failing {
+				_ = tp.PrintfLine("451 Try again later")
+				continue
+			}
+			msg = smtpMessage{From: strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")}
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 Go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func setup(t *testing.T, prefs *models.UserNotification) (*Dispatcher, *smtpServer, *discord.Fake) {
+	t.Helper()
+
+	dbtest.Open(t, &models.User{}, &models.Roster{}, &models.UserRole{}, &models.UserNotification{}, &models.Notification{},
+		&models.NotificationDelivery{})
+
+	user := &models.User{CID: 1293257, FirstName: "John", LastName: "Doe", Email: "john@example.com", DiscordID: "1234567890"}
+	if err := user.Create(); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	if prefs != nil {
+		prefs.CID = user.CID
+		if err := prefs.Create(); err != nil {
+			t.Fatalf("creating notification settings: %v", err)
+		}
+	}
+
+	smtpServer := newSMTPServer(t)
+	fake := &discord.Fake{}
+	return NewDispatcher(NewEmailChannel(smtpServer.config()), NewDiscordChannel(fake)), smtpServer, fake
+}
+
+func dispatch(t *testing.T, d *Dispatcher, category string) *models.Notification {
+	t.Helper()
+
+	n := &models.Notification{
+		CID:      1293257,
+		Category: category,
+		Title:    "Event assignment",
+		Body:     "You have been assigned to DEN_I_APP for ZDV FNO.",
+		ExpireAt: time.Now().Add(24 * time.Hour),
+	}
+	if err := d.Dispatch(n); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	return n
+}
+
+// waitForAttempts waits until every delivery of n has been attempted at least once and returns them by channel
+func waitForAttempts(t *testing.T, n *models.Notification, want int) map[types.NotificationChannel]models.NotificationDelivery {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, err := models.GetNotificationDeliveries(n.ID)
+		if err != nil {
+			t.Fatalf("getting deliveries: %v", err)
+		}
+
+		attempted := 0
+		for _, delivery := range deliveries {
+			if delivery.Attempts > 0 {
+				attempted++
+			}
+		}
+		if len(deliveries) == want && attempted == want {
+			byChannel := map[types.NotificationChannel]models.NotificationDelivery{}
+			for _, delivery := range deliveries {
+				byChannel[delivery.Channel] = delivery
+			}
+			return byChannel
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d deliveries with %d attempted, want %d attempted", len(deliveries), attempted, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDispatchRouting(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefs    *models.UserNotification
+		category string
+		want     []types.NotificationChannel
+	}{
+		{
+			name:     "no settings are in-app only",
+			category: CategoryAdministration,
+		},
+		{
+			name:     "every enabled channel",
+			prefs:    &models.UserNotification{EmailEnabled: true, DiscordEnabled: true, Events: true},
+			category: CategoryEvents,
+			want:     []types.NotificationChannel{types.ChannelEmail, types.ChannelDiscord},
+		},
+		{
+			name:     "email only",
+			prefs:    &models.UserNotification{EmailEnabled: true, Training: true},
+			category: CategoryTraining,
+			want:     []types.NotificationChannel{types.ChannelEmail},
+		},
+		{
+			name:     "discord only",
+			prefs:    &models.UserNotification{DiscordEnabled: true, Feedback: true},
+			category: CategoryFeedback,
+			want:     []types.NotificationChannel{types.ChannelDiscord},
+		},
+		{
+			name:     "muted category",
+			prefs:    &models.UserNotification{EmailEnabled: true, DiscordEnabled: true, Events: false},
+			category: CategoryEvents,
+		},
+		{
+			name:     "administration cannot be muted",
+			prefs:    &models.UserNotification{EmailEnabled: true, DiscordEnabled: true},
+			category: CategoryAdministration,
+			want:     []types.NotificationChannel{types.ChannelEmail, types.ChannelDiscord},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, smtpServer, fake := setup(t, tt.prefs)
+			n := dispatch(t, d, tt.category)
+
+			deliveries := waitForAttempts(t, n, len(tt.want))
+			for _, channel := range tt.want {
+				delivery, ok := deliveries[channel]
+				if !ok {
+					t.Fatalf("no %s delivery", channel)
+				}
+				if delivery.Status != types.DeliverySent || delivery.SentAt == nil || delivery.Attempts != 1 {
+					t.Errorf("%s delivery is %s after %d attempts, want sent after 1", channel, delivery.Status, delivery.Attempts)
+				}
+			}
+
+			_, wantEmail := deliveries[types.ChannelEmail]
+			if got := len(smtpServer.received()); (got == 1) != wantEmail || got > 1 {
+				t.Errorf("SMTP server received %d messages, want email %v", got, wantEmail)
+			}
+			_, wantDiscord := deliveries[types.ChannelDiscord]
+			if got := len(fake.Messages); (got == 1) != wantDiscord || got > 1 {
+				t.Errorf("Discord received %d messages, want Discord %v", got, wantDiscord)
+			}
+		})
+	}
+}
+
+func TestDispatchContent(t *testing.T) {
+	d, smtpServer, fake := setup(t, &models.UserNotification{EmailEnabled: true, DiscordEnabled: true})
+	n := dispatch(t, d, CategoryAdministration)
+	waitForAttempts(t, n, 2)
+
+	messages := smtpServer.received()
+	if len(messages) != 1 {
+		t.Fatalf("SMTP server received %d messages, want 1", len(messages))
+	}
+	email := messages[0]
+	if email.From != "no-reply@vatusa.net" || len(email.To) != 1 || email.To[0] != "john@example.com" {
+		t.Errorf("email from %s to %v", email.From, email.To)
+	}
+	if !strings.Contains(email.Data, "Subject: Event assignment") || !strings.Contains(email.Data, n.Body) {
+		t.Errorf("email does not contain the notification:\n%s", email.Data)
+	}
+
+	if len(fake.Messages) != 1 {
+		t.Fatalf("Discord received %d messages, want 1", len(fake.Messages))
+	}
+	if dm := fake.Messages[0]; dm.UserID != "1234567890" || dm.Content != "**Event assignment**\n"+n.Body {
+		t.Errorf("Discord message = %+v", dm)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	d, smtpServer, fake := setup(t, &models.UserNotification{EmailEnabled: true, DiscordEnabled: true})
+	smtpServer.setFailing(true)
+	fake.Err = errors.New("discord is down")
+
+	start := time.Now()
+	n := dispatch(t, d, CategoryAdministration)
+	deliveries := waitForAttempts(t, n, 2)
+
+	for channel, delivery := range deliveries {
+		if delivery.Status != types.DeliveryPending || delivery.LastError == "" {
+			t.Errorf("%s delivery is %s with error %q, want pending with an error", channel, delivery.Status, delivery.LastError)
+		}
+		// One failed attempt backs off for two minutes
+		if next := delivery.NextAttemptAt.Sub(start); next < backoff(1) || next > backoff(1)+time.Minute {
+			t.Errorf("%s delivery is retried after %s, want %s", channel, next, backoff(1))
+		}
+	}
+
+	// Nothing is due until the backoff has passed
+	d.Retry()
+	for channel, delivery := range waitForAttempts(t, n, 2) {
+		if delivery.Attempts != 1 {
+			t.Errorf("%s delivery was attempted %d times before it was due", channel, delivery.Attempts)
+		}
+	}
+
+	// Email recovers, Discord keeps failing until it runs out of attempts
+	smtpServer.setFailing(false)
+	for attempt := 2; attempt <= MaxAttempts; attempt++ {
+		makeDue(t, n)
+		d.Retry()
+	}
+
+	deliveries = waitForAttempts(t, n, 2)
+	if email := deliveries[types.ChannelEmail]; email.Status != types.DeliverySent || email.Attempts != 2 || email.LastError != "" {
+		t.Errorf("email delivery is %s after %d attempts with error %q, want sent after 2", email.Status, email.Attempts, email.LastError)
+	}
+	if dm := deliveries[types.ChannelDiscord]; dm.Status != types.DeliveryFailed || dm.Attempts != MaxAttempts {
+		t.Errorf("Discord delivery is %s after %d attempts, want failed after %d", dm.Status, dm.Attempts, MaxAttempts)
+	}
+	if got := len(smtpServer.received()); got != 1 {
+		t.Errorf("SMTP server received %d messages, want 1", got)
+	}
+	if len(fake.Messages) != 0 {
+		t.Errorf("Discord received %d messages, want 0", len(fake.Messages))
+	}
+}
+
+func TestRetryExpired(t *testing.T) {
+	d, smtpServer, _ := setup(t, &models.UserNotification{EmailEnabled: true})
+	smtpServer.setFailing(true)
+
+	n := dispatch(t, d, CategoryAdministration)
+	waitForAttempts(t, n, 1)
+
+	n.ExpireAt = time.Now().Add(-time.Minute)
+	if err := n.Update(); err != nil {
+		t.Fatal(err)
+	}
+	makeDue(t, n)
+	d.Retry()
+
+	delivery := waitForAttempts(t, n, 1)[types.ChannelEmail]
+	if delivery.Status != types.DeliveryFailed || delivery.Attempts != 1 {
+		t.Errorf("expired delivery is %s after %d attempts, want failed after 1", delivery.Status, delivery.Attempts)
+	}
+}
+
+// makeDue moves the next attempt of every delivery of n into the past
+func makeDue(t *testing.T, n *models.Notification) {
+	t.Helper()
+
+	deliveries, err := models.GetNotificationDeliveries(n.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx := range deliveries {
+		deliveries[idx].NextAttemptAt = time.Now().Add(-time.Second)
+		if err := deliveries[idx].Update(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 16 * time.Minute}
+	for attempts, delay := range want {
+		if got := backoff(uint(attempts)); got != delay {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, delay)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// System email templates
+const (
+	TemplateRosterRequestReceived = "roster_request_received"
+	TemplateRosterRequestDecided  = "roster_request_decided"
+	TemplateRoleAssigned          = "role_assigned"
+	TemplateFeedbackAccepted      = "feedback_accepted"
+	TemplateEventAssignment       = "event_assignment"
+	TemplateLOAApproved           = "loa_approved"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+// EmailTemplate describes a system email template. Sample is the data used to preview it.
+type EmailTemplate struct {
+	Name        string            `json:"name" example:"event_assignment"`
+	Description string            `json:"description" example:"Sent when a controller is assigned to an event position"`
+	Sample      map[string]string `json:"sample"`
+}
+
+var emailTemplates = map[string]EmailTemplate{
+	TemplateRosterRequestReceived: {
+		Name:        TemplateRosterRequestReceived,
+		Description: "Sent to a controller when their visiting or transfer request is received",
+		Sample:      map[string]string{"request_type": "visiting"},
+	},
+	TemplateRosterRequestDecided: {
+		Name:        TemplateRosterRequestDecided,
+		Description: "Sent to a controller when their visiting or transfer request is accepted or rejected",
+		Sample:      map[string]string{"request_type": "visiting", "status": "accepted", "reason": "Welcome aboard!"},
+	},
+	TemplateRoleAssigned: {
+		Name:        TemplateRoleAssigned,
+		Description: "Sent to a user when they are assigned a role",
+		Sample:      map[string]string{"role": "EC"},
+	},
+	TemplateFeedbackAccepted: {
+		Name:        TemplateFeedbackAccepted,
+		Description: "Sent to a controller when feedback about them is accepted",
+		Sample: map[string]string{
+			"callsign": "DAL123",
+			"position": "DEN_I_APP",
+			"rating":   "excellent",
+			"feedback": "Great service into Denver tonight!",
+			"comment":  "Keep up the good work.",
+		},
+	},
+	TemplateEventAssignment: {
+		Name:        TemplateEventAssignment,
+		Description: "Sent to a controller when they are assigned to an event position",
+		Sample:      map[string]string{"event": "ZDV FNO", "position": "DEN_I_APP", "shift": "2300-0100z"},
+	},
+	TemplateLOAApproved: {
+		Name:        TemplateLOAApproved,
+		Description: "Sent to a controller when their leave of absence is approved",
+		Sample:      map[string]string{"start": "2021-01-01", "end": "2021-03-01"},
+	},
+}
+
+// EmailTemplates returns every system email template sorted by name
+func EmailTemplates() []EmailTemplate {
+	templates := make([]EmailTemplate, 0, len(emailTemplates))
+	for _, t := range emailTemplates {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+func GetEmailTemplate(name string) (EmailTemplate, bool) {
+	t, ok := emailTemplates[name]
+	return t, ok
+}
+
+// DefaultEmailTemplate returns the default subject, text body and HTML body of a template, in the same form a
+// facility override stores them
+func DefaultEmailTemplate(name string) (*models.EmailTemplate, error) {
+	if _, ok := emailTemplates[name]; !ok {
+		return nil, ErrUnknownTemplate
+	}
+
+	text, err := texttemplate.ParseFS(templateFS, "templates/"+name+".txt")
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.ParseFS(templateFS, "templates/"+name+".html")
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.EmailTemplate{
+		Name:    name,
+		Subject: text.Lookup("subject").Root.String(),
+		Text:    text.Lookup("body").Root.String(),
+		HTML:    html.Lookup("body").Tree.Root.String(),
+	}, nil
+}
+
+// Branding is the sender an email is branded as
+type Branding struct {
+	ID   string
+	Name string
+	URL  string
+}
+
+// TemplateData is what email templates are executed with
+type TemplateData struct {
+	Name     string
+	Subject  string
+	Facility Branding
+	Data     map[string]string
+}
+
+// RenderEmail renders a system email template for facility, using the facility's override when it has one.
+// Without a facility the email is branded as VATUSA.
+func RenderEmail(name string, facility *models.Facility, recipient string, data map[string]string) (*Email, error) {
+	var override *models.EmailTemplate
+	if facility != nil {
+		override = &models.EmailTemplate{Facility: facility.ID, Name: name}
+		if err := override.Get(); err != nil {
+			override = nil
+		}
+	}
+
+	return RenderEmailOverride(name, facility, override, recipient, data)
+}
+
+// RenderEmailOverride renders a system email template with the given override, which may be nil
+func RenderEmailOverride(name string, facility *models.Facility, override *models.EmailTemplate, recipient string, data map[string]string) (*Email, error) {
+	if _, ok := emailTemplates[name]; !ok {
+		return nil, ErrUnknownTemplate
+	}
+
+	text, err := texttemplate.ParseFS(templateFS, "templates/layout.txt", "templates/"+name+".txt")
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html")
+	if err != nil {
+		return nil, err
+	}
+
+	if override != nil {
+		if override.Subject != "" {
+			if _, err := text.New("subject").Parse(override.Subject); err != nil {
+				return nil, fmt.Errorf("invalid subject: %w", err)
+			}
+		}
+		if override.Text != "" {
+			if _, err := text.New("body").Parse(override.Text); err != nil {
+				return nil, fmt.Errorf("invalid text body: %w", err)
+			}
+		}
+		if override.HTML != "" {
+			if _, err := html.New("body").Parse(override.HTML); err != nil {
+				return nil, fmt.Errorf("invalid html body: %w", err)
+			}
+		}
+	}
+
+	td := &TemplateData{
+		Name:     recipient,
+		Facility: branding(facility),
+		Data:     data,
+	}
+
+	subject := &bytes.Buffer{}
+	if err := text.ExecuteTemplate(subject, "subject", td); err != nil {
+		return nil, err
+	}
+	td.Subject = strings.TrimSpace(subject.String())
+
+	body := &bytes.Buffer{}
+	if err := text.ExecuteTemplate(body, "layout", td); err != nil {
+		return nil, err
+	}
+
+	htmlBody := &bytes.Buffer{}
+	if err := html.ExecuteTemplate(htmlBody, "layout", td); err != nil {
+		return nil, err
+	}
+
+	return &Email{Subject: td.Subject, Text: body.String(), HTML: htmlBody.String()}, nil
+}
+
+func branding(facility *models.Facility) Branding {
+	if facility == nil {
+		return Branding{ID: "ZHQ", Name: "VATUSA", URL: "https://vatusa.net"}
+	}
+
+	b := Branding{ID: string(facility.ID), Name: facility.Name, URL: facility.URL}
+	if b.Name == "" {
+		b.Name = string(facility.ID)
+	}
+	if b.URL == "" {
+		b.URL = "https://vatusa.net"
+	}
+	return b
+}
@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestEmailTemplatesRender(t *testing.T) {
+	for _, tmpl := range EmailTemplates() {
+		t.Run(tmpl.Name, func(t *testing.T) {
+			email, err := RenderEmailOverride(tmpl.Name, nil, nil, "John", tmpl.Sample)
+			if err != nil {
+				t.Fatalf("RenderEmailOverride() error = %v", err)
+			}
+			if email.Subject == "" || email.Text == "" || email.HTML == "" {
+				t.Errorf("rendered email is missing a part: %+v", email)
+			}
+			if strings.Contains(email.Text, "<no value>") || strings.Contains(email.HTML, "<no value>") {
+				t.Errorf("sample data does not cover the template:\n%s", email.Text)
+			}
+
+			if _, err := DefaultEmailTemplate(tmpl.Name); err != nil {
+				t.Errorf("DefaultEmailTemplate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestEmailTemplateFilesRegistered(t *testing.T) {
+	files, err := fs.Glob(templateFS, "templates/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(file, "templates/"), ".txt"), ".html")
+		if name == "layout" {
+			continue
+		}
+		if _, ok := GetEmailTemplate(name); !ok {
+			t.Errorf("%s is not a registered email template", file)
+		}
+	}
+}
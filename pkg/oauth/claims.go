@@ -0,0 +1,131 @@
+package oauth
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenID Connect scopes relying parties can request
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+	// ScopeRoster adds the user's rosters and roles, which facility websites need to decide what a user can do
+	ScopeRoster = "vatusa_roster"
+)
+
+var SupportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopeRoster}
+
+// ParseScope splits a space separated scope parameter, dropping anything unsupported
+func ParseScope(scope string) []string {
+	var scopes []string
+	for _, s := range strings.Fields(scope) {
+		if slices.Contains(SupportedScopes, s) && !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+type RegisteredClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	ExpiresAt int64  `json:"exp"`
+	IssuedAt  int64  `json:"iat"`
+}
+
+// AccessTokenClaims are carried by the access tokens relying parties present to the userinfo endpoint
+type AccessTokenClaims struct {
+	RegisteredClaims
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+type RosterClaim struct {
+	Facility constants.FacilityID `json:"facility"`
+	Home     bool                 `json:"home"`
+	Visiting bool                 `json:"visiting"`
+	Status   string               `json:"status"`
+}
+
+type RoleClaim struct {
+	Facility constants.FacilityID `json:"facility"`
+	Role     constants.RoleID     `json:"role"`
+}
+
+// UserClaims describe a user, which scopes decide which are filled in
+type UserClaims struct {
+	CID         uint          `json:"cid"`
+	Rating      int           `json:"rating"`
+	RatingShort string        `json:"rating_short"`
+	Name        string        `json:"name,omitempty"`
+	GivenName   string        `json:"given_name,omitempty"`
+	FamilyName  string        `json:"family_name,omitempty"`
+	Email       string        `json:"email,omitempty"`
+	Rosters     []RosterClaim `json:"rosters,omitempty"`
+	Roles       []RoleClaim   `json:"roles,omitempty"`
+}
+
+type IDTokenClaims struct {
+	RegisteredClaims
+	AuthTime int64  `json:"auth_time,omitempty"`
+	Nonce    string `json:"nonce,omitempty"`
+	UserClaims
+}
+
+// NewUserClaims builds the claims of user granted by scopes. User must have been loaded with its rosters and roles.
+func NewUserClaims(user *models.User, scopes []string) UserClaims {
+	claims := UserClaims{
+		CID:         user.CID,
+		Rating:      user.ControllerRating.Int(),
+		RatingShort: user.ControllerRating.Short(),
+	}
+
+	if slices.Contains(scopes, ScopeProfile) {
+		claims.GivenName = user.FirstName
+		if user.PrefNameEnabled && user.PreferredName != "" {
+			claims.GivenName = user.PreferredName
+		}
+		claims.FamilyName = user.LastName
+		claims.Name = claims.GivenName + " " + claims.FamilyName
+	}
+
+	if slices.Contains(scopes, ScopeEmail) {
+		claims.Email = user.Email
+	}
+
+	if slices.Contains(scopes, ScopeRoster) {
+		claims.Rosters = []RosterClaim{}
+		claims.Roles = []RoleClaim{}
+		for _, roster := range user.Roster {
+			claims.Rosters = append(claims.Rosters, RosterClaim{
+				Facility: roster.Facility,
+				Home:     roster.Home,
+				Visiting: roster.Visiting,
+				Status:   roster.Status,
+			})
+			for _, role := range roster.Roles {
+				claims.Roles = append(claims.Roles, RoleClaim{Facility: role.FacilityID, Role: role.RoleID})
+			}
+		}
+	}
+
+	return claims
+}
+
+// NewRegisteredClaims fills in the claims every token carries
+func (s *TokenSigner) NewRegisteredClaims(cid uint, audience string, lifetime time.Duration) RegisteredClaims {
+	now := time.Now()
+	return RegisteredClaims{
+		Issuer:    s.Issuer,
+		Subject:   strconv.FormatUint(uint64(cid), 10),
+		Audience:  audience,
+		ExpiresAt: now.Add(lifetime).Unix(),
+		IssuedAt:  now.Unix(),
+	}
+}
@@ -0,0 +1,191 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Provider signs the ID and access tokens the API issues as an OpenID Connect provider for facility websites
+var Provider *TokenSigner
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+type TokenSigner struct {
+	Issuer string
+	KeyID  string
+	key    *rsa.PrivateKey
+}
+
+func InitializeProvider(cfg *config.Config) (*TokenSigner, error) {
+	var key *rsa.PrivateKey
+	if cfg.OIDC.SigningKey == "" {
+		log.Warn("OIDC_SIGNING_KEY is not set, generating a signing key. Tokens will not survive a restart.")
+
+		generated, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		key = generated
+	} else {
+		parsed, err := ParseSigningKey(cfg.OIDC.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		key = parsed
+	}
+
+	return &TokenSigner{
+		Issuer: strings.TrimSuffix(cfg.OIDC.Issuer, "/"),
+		KeyID:  cfg.OIDC.KeyID,
+		key:    key,
+	}, nil
+}
+
+// ParseSigningKey reads a PEM encoded PKCS #1 or PKCS #8 RSA private key
+func ParseSigningKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("signing key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("signing key is not an RSA key")
+	}
+	return key, nil
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// Sign encodes claims as a JWT signed with RS256
+func (s *TokenSigner) Sign(claims interface{}) (string, error) {
+	header, err := json.Marshal(jwtHeader{Algorithm: "RS256", Type: "JWT", KeyID: s.KeyID})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks a JWT issued by Sign and decodes its claims. The token must have been issued by this provider and not
+// have expired.
+func (s *TokenSigner) Verify(token string, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrInvalidToken
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	header := jwtHeader{}
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Algorithm != "RS256" {
+		return ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&s.key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		return ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	registered := RegisteredClaims{}
+	if err := json.Unmarshal(payload, &registered); err != nil || registered.Issuer != s.Issuer {
+		return ErrInvalidToken
+	}
+	if time.Now().Unix() >= registered.ExpiresAt {
+		return ErrExpiredToken
+	}
+
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of the signing key for relying parties to verify tokens with
+func (s *TokenSigner) JWKS() JWKS {
+	pub := s.key.PublicKey
+	return JWKS{Keys: []JWK{{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: "RS256",
+		KeyID:     s.KeyID,
+		Modulus:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}}
+}
+
+// VerifyPKCE checks a PKCE code verifier against the S256 challenge sent with the authorization request
+func VerifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
@@ -0,0 +1,299 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Day is a BYDAY entry, e.g. TU or 2TU (second Tuesday) or -1FR (last Friday)
+type Day struct {
+	Weekday time.Weekday
+	Nth     int
+}
+
+// Rule is the subset of an RFC 5545 RRULE supported for recurring events: FREQ (DAILY, WEEKLY, MONTHLY),
+// INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and WKST.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []Day
+	ByMonthDay []int
+	WeekStart  time.Weekday
+
+	// untilLocal is set when UNTIL has no trailing Z and is a wall clock time in the location of dtstart
+	untilLocal bool
+}
+
+// Parse parses an RRULE string such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU". A leading "RRULE:" is accepted.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, errors.New("empty recurrence rule")
+	}
+
+	rule := &Rule{Interval: 1, WeekStart: time.Monday}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid recurrence rule part: %s", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(value))
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return nil, fmt.Errorf("unsupported frequency: %s", value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("invalid interval: %s", value)
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid count: %s", value)
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = until
+			rule.untilLocal = !strings.HasSuffix(value, "Z")
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				day, err := parseDay(d)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				md, err := strconv.Atoi(d)
+				if err != nil || md == 0 || md < -31 || md > 31 {
+					return nil, fmt.Errorf("invalid month day: %s", d)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, md)
+			}
+		case "WKST":
+			weekday, ok := weekdays[strings.ToUpper(value)]
+			if !ok {
+				return nil, fmt.Errorf("invalid week start: %s", value)
+			}
+			rule.WeekStart = weekday
+		default:
+			return nil, fmt.Errorf("unsupported recurrence rule part: %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("recurrence rule is missing FREQ")
+	}
+	if rule.Count != 0 && !rule.Until.IsZero() {
+		return nil, errors.New("recurrence rule cannot have both COUNT and UNTIL")
+	}
+	for _, d := range rule.ByDay {
+		if d.Nth != 0 && rule.Freq != Monthly {
+			return nil, errors.New("ordinal BYDAY values are only supported with FREQ=MONTHLY")
+		}
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != Monthly {
+		return nil, errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid until: %s", value)
+}
+
+func parseDay(value string) (Day, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return Day{}, fmt.Errorf("invalid day: %s", value)
+	}
+
+	weekday, ok := weekdays[value[len(value)-2:]]
+	if !ok {
+		return Day{}, fmt.Errorf("invalid day: %s", value)
+	}
+
+	day := Day{Weekday: weekday}
+	if prefix := value[:len(value)-2]; prefix != "" {
+		nth, err := strconv.Atoi(prefix)
+		if err != nil || nth == 0 || nth < -5 || nth > 5 {
+			return Day{}, fmt.Errorf("invalid day: %s", value)
+		}
+		day.Nth = nth
+	}
+
+	return day, nil
+}
+
+// Occurrences returns the start times of every occurrence of the rule anchored at dtstart, up to and including
+// before. dtstart is always the first occurrence. The rule is expanded in the location of dtstart, so occurrences
+// keep the wall clock time of dtstart across daylight saving time changes in that location. A dtstart in UTC keeps
+// the same UTC time instead.
+func (r *Rule) Occurrences(dtstart, before time.Time) []time.Time {
+	until := r.Until
+	if r.untilLocal && !until.IsZero() {
+		until = time.Date(until.Year(), until.Month(), until.Day(), until.Hour(), until.Minute(), until.Second(), 0,
+			dtstart.Location())
+	}
+
+	occurrences := []time.Time{dtstart}
+
+	// Every candidate of a period is at or after its start, so expansion stops at the first period starting after
+	// before or UNTIL however far that is from dtstart
+	for period := 0; ; period++ {
+		start := r.periodStart(dtstart, period)
+		if start.After(before) || (!until.IsZero() && start.After(until)) {
+			return occurrences
+		}
+
+		for _, t := range r.candidates(dtstart, period) {
+			if !t.After(dtstart) {
+				continue
+			}
+			if t.After(before) || (!until.IsZero() && t.After(until)) {
+				return occurrences
+			}
+			if r.Count != 0 && len(occurrences) >= r.Count {
+				return occurrences
+			}
+			occurrences = append(occurrences, t)
+		}
+	}
+}
+
+// periodStart returns the start of the nth period after dtstart: dtstart itself for DAILY, the start of the week
+// for WEEKLY and midnight on the first of the month for MONTHLY
+func (r *Rule) periodStart(dtstart time.Time, period int) time.Time {
+	switch r.Freq {
+	case Weekly:
+		// Weeks start on WKST, which defaults to Monday. It decides which days share a week when INTERVAL > 1.
+		return dtstart.AddDate(0, 0, period*r.Interval*7-r.weekdayOffset(dtstart.Weekday()))
+	case Monthly:
+		return time.Date(dtstart.Year(), dtstart.Month(), 1, 0, 0, 0, 0, dtstart.Location()).AddDate(0, period*r.Interval, 0)
+	default:
+		return dtstart.AddDate(0, 0, period*r.Interval)
+	}
+}
+
+// candidates returns the sorted candidate start times within the nth period after dtstart
+func (r *Rule) candidates(dtstart time.Time, period int) []time.Time {
+	hour, minute, sec := dtstart.Clock()
+	loc := dtstart.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hour, minute, sec, 0, loc)
+	}
+
+	var out []time.Time
+	switch r.Freq {
+	case Daily:
+		out = append(out, r.periodStart(dtstart, period))
+	case Weekly:
+		weekStart := r.periodStart(dtstart, period)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []Day{{Weekday: dtstart.Weekday()}}
+		}
+		for _, d := range days {
+			out = append(out, weekStart.AddDate(0, 0, r.weekdayOffset(d.Weekday)))
+		}
+	case Monthly:
+		first := r.periodStart(dtstart, period)
+		y, m := first.Year(), first.Month()
+		last := first.AddDate(0, 1, -1).Day()
+
+		switch {
+		case len(r.ByDay) > 0:
+			for _, d := range r.ByDay {
+				days := monthWeekdays(first, last, d.Weekday)
+				if d.Nth == 0 {
+					for _, day := range days {
+						out = append(out, at(y, m, day))
+					}
+					continue
+				}
+
+				idx := d.Nth - 1
+				if d.Nth < 0 {
+					idx = len(days) + d.Nth
+				}
+				if idx >= 0 && idx < len(days) {
+					out = append(out, at(y, m, days[idx]))
+				}
+			}
+		case len(r.ByMonthDay) > 0:
+			for _, md := range r.ByMonthDay {
+				day := md
+				if md < 0 {
+					day = last + md + 1
+				}
+				if day >= 1 && day <= last {
+					out = append(out, at(y, m, day))
+				}
+			}
+		default:
+			if dtstart.Day() <= last {
+				out = append(out, at(y, m, dtstart.Day()))
+			}
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+// weekdayOffset returns how many days weekday is after the start of the week
+func (r *Rule) weekdayOffset(weekday time.Weekday) int {
+	return (int(weekday) - int(r.WeekStart) + 7) % 7
+}
+
+// monthWeekdays returns the days of the month starting at first that fall on weekday
+func monthWeekdays(first time.Time, last int, weekday time.Weekday) []int {
+	var days []int
+	day := 1 + (int(weekday)-int(first.Weekday())+7)%7
+	for ; day <= last; day += 7 {
+		days = append(days, day)
+	}
+	return days
+}
@@ -0,0 +1,237 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d, hour int) time.Time {
+	return time.Date(y, m, d, hour, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rule    string
+		wantErr bool
+	}{
+		{rule: "FREQ=WEEKLY;BYDAY=TU"},
+		{rule: "RRULE:FREQ=MONTHLY;BYDAY=-1FR"},
+		{rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU;WKST=SU"},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=-1,15"},
+		{rule: "FREQ=DAILY;UNTIL=20240105T000000Z"},
+		{rule: "", wantErr: true},
+		{rule: "BYDAY=TU", wantErr: true},
+		{rule: "FREQ=YEARLY", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=2;UNTIL=20240105", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=2TU", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYMONTHDAY=1", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYDAY=6TU", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{rule: "FREQ=WEEKLY;WKST=XX", wantErr: true},
+		{rule: "FREQ=DAILY;BYHOUR=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			if _, err := Parse(tt.rule); (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		before  time.Time
+		want    []time.Time
+	}{
+		{
+			name:    "second tuesday",
+			rule:    "FREQ=MONTHLY;BYDAY=2TU;COUNT=3",
+			dtstart: date(2024, time.January, 9, 0),
+			before:  date(2025, time.January, 1, 0),
+			want:    []time.Time{date(2024, time.January, 9, 0), date(2024, time.February, 13, 0), date(2024, time.March, 12, 0)},
+		},
+		{
+			name:    "last friday",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			dtstart: date(2024, time.January, 26, 0),
+			before:  date(2025, time.January, 1, 0),
+			want:    []time.Time{date(2024, time.January, 26, 0), date(2024, time.February, 23, 0), date(2024, time.March, 29, 0)},
+		},
+		{
+			name:    "fifth friday skips months without one",
+			rule:    "FREQ=MONTHLY;BYDAY=5FR;COUNT=3",
+			dtstart: date(2024, time.March, 29, 0),
+			before:  date(2025, time.January, 1, 0),
+			want:    []time.Time{date(2024, time.March, 29, 0), date(2024, time.May, 31, 0), date(2024, time.August, 30, 0)},
+		},
+		{
+			name:    "last day of the month",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=4",
+			dtstart: date(2024, time.January, 31, 0),
+			before:  date(2025, time.January, 1, 0),
+			want: []time.Time{date(2024, time.January, 31, 0), date(2024, time.February, 29, 0), date(2024, time.March, 31, 0),
+				date(2024, time.April, 30, 0)},
+		},
+		{
+			name:    "second to last day of the month",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-2;COUNT=2",
+			dtstart: date(2023, time.February, 27, 0),
+			before:  date(2025, time.January, 1, 0),
+			want:    []time.Time{date(2023, time.February, 27, 0), date(2023, time.March, 30, 0)},
+		},
+		{
+			name:    "months without the start day are skipped",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: date(2024, time.January, 31, 0),
+			before:  date(2025, time.January, 1, 0),
+			want:    []time.Time{date(2024, time.January, 31, 0), date(2024, time.March, 31, 0), date(2024, time.May, 31, 0)},
+		},
+		{
+			name:    "count includes dtstart",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: date(2024, time.January, 1, 20),
+			before:  date(2025, time.January, 1, 0),
+			want:    []time.Time{date(2024, time.January, 1, 20), date(2024, time.January, 2, 20), date(2024, time.January, 3, 20)},
+		},
+		{
+			name:    "until is inclusive",
+			rule:    "FREQ=DAILY;UNTIL=20240103T200000Z",
+			dtstart: date(2024, time.January, 1, 20),
+			before:  date(2025, time.January, 1, 0),
+			want:    []time.Time{date(2024, time.January, 1, 20), date(2024, time.January, 2, 20), date(2024, time.January, 3, 20)},
+		},
+		{
+			name:    "until date covers the whole day",
+			rule:    "FREQ=DAILY;INTERVAL=2;UNTIL=20240105",
+			dtstart: date(2024, time.January, 1, 20),
+			before:  date(2025, time.January, 1, 0),
+			want:    []time.Time{date(2024, time.January, 1, 20), date(2024, time.January, 3, 20), date(2024, time.January, 5, 20)},
+		},
+		{
+			name:    "before cuts off a count",
+			rule:    "FREQ=WEEKLY;COUNT=10",
+			dtstart: date(2024, time.January, 2, 0),
+			before:  date(2024, time.January, 16, 0),
+			want:    []time.Time{date(2024, time.January, 2, 0), date(2024, time.January, 9, 0), date(2024, time.January, 16, 0)},
+		},
+		{
+			name:    "weeks start on monday by default",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU",
+			dtstart: date(1997, time.August, 5, 9),
+			before:  date(1998, time.January, 1, 0),
+			want: []time.Time{date(1997, time.August, 5, 9), date(1997, time.August, 10, 9), date(1997, time.August, 19, 9),
+				date(1997, time.August, 24, 9)},
+		},
+		{
+			name:    "weeks start on sunday",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+			dtstart: date(1997, time.August, 5, 9),
+			before:  date(1998, time.January, 1, 0),
+			want: []time.Time{date(1997, time.August, 5, 9), date(1997, time.August, 17, 9), date(1997, time.August, 19, 9),
+				date(1997, time.August, 31, 9)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.rule, err)
+			}
+
+			got := rule.Occurrences(tt.dtstart, tt.before)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Occurrences() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOccurrencesLongRange(t *testing.T) {
+	tests := []struct {
+		rule string
+		want int
+		last time.Time
+	}{
+		// An open ended rule is expanded up to before however many periods that takes
+		{rule: "FREQ=DAILY", want: 3654, last: date(2034, time.January, 1, 0)},
+		{rule: "FREQ=DAILY;UNTIL=20301231T235959Z", want: 2557, last: date(2030, time.December, 31, 0)},
+		{rule: "FREQ=DAILY;COUNT=1500", want: 1500, last: date(2028, time.February, 8, 0)},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=31", want: 71, last: date(2033, time.December, 31, 0)},
+	}
+
+	dtstart := date(2024, time.January, 1, 0)
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := rule.Occurrences(dtstart, dtstart.AddDate(10, 0, 0))
+			if len(got) != tt.want {
+				t.Fatalf("Occurrences() returned %d occurrences, want %d", len(got), tt.want)
+			}
+			if !got[len(got)-1].Equal(tt.last) {
+				t.Errorf("last occurrence = %s, want %s", got[len(got)-1], tt.last)
+			}
+		})
+	}
+}
+
+func TestOccurrencesTimeZone(t *testing.T) {
+	denver, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	rule, err := Parse("FREQ=WEEKLY;COUNT=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// DST starts in Denver on 2024-03-10, a local 18:00 start is 01:00Z before and 00:00Z after
+	dtstart := time.Date(2024, time.March, 2, 18, 0, 0, 0, denver)
+	got := rule.Occurrences(dtstart, dtstart.AddDate(1, 0, 0))
+	want := []time.Time{
+		date(2024, time.March, 3, 1),
+		date(2024, time.March, 10, 1),
+		date(2024, time.March, 17, 0),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Occurrences() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("occurrence %d = %s, want %s", i, got[i].UTC(), want[i])
+		}
+		if hour := got[i].In(denver).Hour(); hour != 18 {
+			t.Errorf("occurrence %d starts at %d:00 local time, want 18:00", i, hour)
+		}
+	}
+
+	// The same rule anchored in UTC keeps the UTC time instead
+	got = rule.Occurrences(dtstart.UTC(), dtstart.AddDate(1, 0, 0))
+	if !got[2].Equal(date(2024, time.March, 17, 1)) {
+		t.Errorf("UTC occurrence 2 = %s, want %s", got[2], date(2024, time.March, 17, 1))
+	}
+
+	// UNTIL without a trailing Z is a local time
+	rule, err = Parse("FREQ=WEEKLY;BYDAY=SA;UNTIL=20240309T180000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rule.Occurrences(dtstart, dtstart.AddDate(1, 0, 0)); len(got) != 2 {
+		t.Errorf("Occurrences() with local UNTIL = %v, want 2 occurrences", got)
+	}
+}
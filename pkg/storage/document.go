@@ -12,6 +12,9 @@ import (
 
 var PublicBucket *StorageClient
 
+// PrivateBucket holds files that are only served through the API, like disciplinary attachments
+var PrivateBucket *StorageClient
+
 type StorageClient struct {
 	client *s3.Client
 	bucket string
@@ -33,6 +36,11 @@ func NewS3Client(cfg *cfg.S3Config) (*StorageClient, error) {
 	return client, nil
 }
 
+// WithBucket returns a client for another bucket on the same endpoint
+func (s *StorageClient) WithBucket(bucket string) *StorageClient {
+	return &StorageClient{client: s.client, bucket: bucket}
+}
+
 func (s *StorageClient) Upload(directory string, filename string, body io.Reader) error {
 	fullKey := path.Join(directory, filename)
 	_, err := s.client.PutObject(context.Background(), &s3.PutObjectInput{
@@ -56,3 +64,16 @@ func (s *StorageClient) Delete(directory, filename string) error {
 	})
 	return err
 }
+
+// Download opens an object, the caller has to close it
+func (s *StorageClient) Download(directory, filename string) (io.ReadCloser, error) {
+	fullKey := path.Join(directory, filename)
+	out, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(fullKey),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
@@ -0,0 +1,62 @@
+package stream
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+// subscriberBuffer is how many events a subscriber can fall behind before events are dropped for it
+const subscriberBuffer = 16
+
+// Hub is an in-process Broker. It only reaches subscribers connected to the same instance.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[uint]map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: map[uint]map[chan Event]struct{}{}}
+}
+
+func (h *Hub) Publish(_ context.Context, cid uint, e Event) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subscribers[cid] {
+		select {
+		case ch <- e:
+		default:
+			// A slow client must not block the request that published the event
+			log.Warnf("Dropping %s stream event for user %d, subscriber is not keeping up", e.Type, cid)
+		}
+	}
+
+	return nil
+}
+
+func (h *Hub) Subscribe(ctx context.Context, cid uint) (<-chan Event, error) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[cid] == nil {
+		h.subscribers[cid] = map[chan Event]struct{}{}
+	}
+	h.subscribers[cid][ch] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		h.mu.Lock()
+		delete(h.subscribers[cid], ch)
+		if len(h.subscribers[cid]) == 0 {
+			delete(h.subscribers, cid)
+		}
+		h.mu.Unlock()
+
+		close(ch)
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,50 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+)
+
+// Event types pushed to users
+const (
+	TypeNotification    = "notification"
+	TypeRosterRequest   = "roster_request"
+	TypeEventAssignment = "event_assignment"
+)
+
+// Event is a message pushed to a single user. Data is kept encoded so events can travel through an external broker
+// unchanged.
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Broker fans events out to the subscribers of a user. Hub is the in-process implementation; a broker backed by
+// Redis pub/sub or similar can replace it when the API runs on more than one instance.
+type Broker interface {
+	Publish(ctx context.Context, cid uint, e Event) error
+	// Subscribe returns the events published to cid until ctx is done, after which the channel is closed
+	Subscribe(ctx context.Context, cid uint) (<-chan Event, error)
+}
+
+// Default is the broker used by Publish and the user stream endpoint
+var Default Broker = NewHub()
+
+// Publish encodes data and publishes it to cid through the Default broker, logging any failure. Publishing is best
+// effort; users that are not connected simply miss the event.
+func Publish(cid uint, eventType string, data interface{}) {
+	if Default == nil || cid == 0 {
+		return
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.WithError(err).Errorf("Error encoding %s stream event for user %d", eventType, cid)
+		return
+	}
+
+	if err := Default.Publish(context.Background(), cid, Event{Type: eventType, Data: raw}); err != nil {
+		log.WithError(err).Errorf("Error publishing %s stream event for user %d", eventType, cid)
+	}
+}
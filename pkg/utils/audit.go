@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// AuditKey holds the AuditRecord of a mutating request
+type AuditKey struct{}
+
+// AuditRecord collects what the audit middleware needs from deeper in the handler chain. Contexts only flow down, so
+// the resources loaded by the route Ctx middlewares and the request as the handler saw it are recorded here instead.
+type AuditRecord struct {
+	Resources []*AuditResource
+	// Request is the last request rendered or to load a resource, carrying the context values set on the way to the
+	// handler
+	Request *http.Request
+}
+
+// AuditResource is a resource a request targeted, with its state when it was loaded
+type AuditResource struct {
+	Type   string
+	Value  any
+	Before json.RawMessage
+}
+
+func GetAuditRecord(r *http.Request) *AuditRecord {
+	rec, ok := r.Context().Value(AuditKey{}).(*AuditRecord)
+	if !ok {
+		return nil
+	}
+	return rec
+}
+
+// WithResource stores a resource loaded from the URL under key, snapshotting it for the audit log. The resource type
+// is the key's name without its Key suffix.
+func WithResource(r *http.Request, key any, value any) context.Context {
+	if rec := GetAuditRecord(r); rec != nil {
+		rec.Request = r
+		before, err := json.Marshal(value)
+		if err != nil {
+			before = nil
+		}
+		rec.Resources = append(rec.Resources, &AuditResource{
+			Type:   strings.TrimSuffix(reflect.TypeOf(key).Name(), "Key"),
+			Value:  value,
+			Before: before,
+		})
+	}
+	return context.WithValue(r.Context(), key, value)
+}
+
+func trackAuditRequest(r *http.Request) {
+	if rec := GetAuditRecord(r); rec != nil {
+		rec.Request = r
+	}
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// Name is how the principal is recorded in CreatedBy and UpdatedBy, the CID of users
+func (p Principal) Name() string {
+	if p.User != nil {
+		return fmt.Sprint(p.User.CID)
+	}
+	if p.Facility != nil {
+		return string(p.Facility.ID) + " API Key"
+	}
+	return "System"
+}
+
+// LogUserChange writes changes to the user's action log, described by entry or, when it is empty, by the changes
+// themselves. Nothing is written when there are no changes. Failures are logged, the change has already been made.
+func LogUserChange(r *http.Request, cid uint, entry string, changes models.Changes) {
+	if len(changes) == 0 {
+		return
+	}
+	if entry == "" {
+		entry = changes.String()
+	}
+
+	by := GetPrincipal(r).Name()
+	ale := &models.ActionLogEntry{
+		CID:       cid,
+		Entry:     entry,
+		Changes:   changes,
+		CreatedBy: by,
+		UpdatedBy: by,
+	}
+	if err := ale.Create(); err != nil {
+		log.WithError(err).Errorf("Error writing action log entry for %d: %s", cid, entry)
+	}
+}
+
+// LogFacilityChange writes changes to the facility log, like LogUserChange
+func LogFacilityChange(r *http.Request, facility constants.FacilityID, entry string, changes models.Changes) {
+	if len(changes) == 0 {
+		return
+	}
+	if entry == "" {
+		entry = changes.String()
+	}
+
+	by := GetPrincipal(r).Name()
+	fle := &models.FacilityLogEntry{
+		Facility:  facility,
+		Entry:     entry,
+		Changes:   changes,
+		CreatedBy: by,
+		UpdatedBy: by,
+	}
+	if err := fle.Create(); err != nil {
+		log.WithError(err).Errorf("Error writing facility log entry for %s: %s", facility, entry)
+	}
+}
@@ -1,10 +1,51 @@
 package utils
 
 import (
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	"net/http"
 )
 
+type AccessTokenKey struct{}
+
+func GetAccessTokenCtx(r *http.Request) *models.PersonalAccessToken {
+	pat, ok := r.Context().Value(AccessTokenKey{}).(*models.PersonalAccessToken)
+	if !ok {
+		return nil
+	}
+	return pat
+}
+
+type APIKeyKey struct{}
+
+func GetAPIKeyCtx(r *http.Request) *models.FacilityAPIKey {
+	key, ok := r.Context().Value(APIKeyKey{}).(*models.FacilityAPIKey)
+	if !ok {
+		return nil
+	}
+	return key
+}
+
+type OAuthClientKey struct{}
+
+func GetOAuthClientCtx(r *http.Request) *models.OAuthClient {
+	client, ok := r.Context().Value(OAuthClientKey{}).(*models.OAuthClient)
+	if !ok {
+		return nil
+	}
+	return client
+}
+
+type SessionKey struct{}
+
+func GetSessionCtx(r *http.Request) *models.Session {
+	session, ok := r.Context().Value(SessionKey{}).(*models.Session)
+	if !ok {
+		return nil
+	}
+	return session
+}
+
 type AleKey struct{}
 
 func GetActionLogCtx(r *http.Request) *models.ActionLogEntry {
@@ -25,6 +66,56 @@ func GetDisciplinaryLogCtx(r *http.Request) *models.DisciplinaryLogEntry {
 	return dle
 }
 
+type DisciplinaryAttachmentKey struct{}
+
+func GetDisciplinaryAttachmentCtx(r *http.Request) *models.DisciplinaryAttachment {
+	attachment, ok := r.Context().Value(DisciplinaryAttachmentKey{}).(*models.DisciplinaryAttachment)
+	if !ok {
+		return nil
+	}
+	return attachment
+}
+
+type TrainingSessionKey struct{}
+
+func GetTrainingSessionCtx(r *http.Request) *models.TrainingSession {
+	session, ok := r.Context().Value(TrainingSessionKey{}).(*models.TrainingSession)
+	if !ok {
+		return nil
+	}
+	return session
+}
+
+type OTSExamKey struct{}
+
+func GetOTSExamCtx(r *http.Request) *models.OTSExam {
+	exam, ok := r.Context().Value(OTSExamKey{}).(*models.OTSExam)
+	if !ok {
+		return nil
+	}
+	return exam
+}
+
+type PromotionKey struct{}
+
+func GetPromotionCtx(r *http.Request) *models.Promotion {
+	promotion, ok := r.Context().Value(PromotionKey{}).(*models.Promotion)
+	if !ok {
+		return nil
+	}
+	return promotion
+}
+
+type BroadcastKey struct{}
+
+func GetBroadcastCtx(r *http.Request) *models.Broadcast {
+	b, ok := r.Context().Value(BroadcastKey{}).(*models.Broadcast)
+	if !ok {
+		return nil
+	}
+	return b
+}
+
 type DocumentKey struct{}
 
 func GetDocumentCtx(r *http.Request) *models.Document {
@@ -35,6 +126,16 @@ func GetDocumentCtx(r *http.Request) *models.Document {
 	return doc
 }
 
+type EmailTemplateKey struct{}
+
+func GetEmailTemplateCtx(r *http.Request) *models.EmailTemplate {
+	et, ok := r.Context().Value(EmailTemplateKey{}).(*models.EmailTemplate)
+	if !ok {
+		return nil
+	}
+	return et
+}
+
 type EventKey struct{}
 
 func GetEventCtx(r *http.Request) *models.Event {
@@ -55,6 +156,16 @@ func GetEventPositionCtx(r *http.Request) *models.EventPosition {
 	return ep
 }
 
+type EventShiftKey struct{}
+
+func GetEventShiftCtx(r *http.Request) *models.EventShift {
+	es, ok := r.Context().Value(EventShiftKey{}).(*models.EventShift)
+	if !ok {
+		return nil
+	}
+	return es
+}
+
 type EventSignupKey struct{}
 
 func GetEventSignupCtx(r *http.Request) *models.EventSignup {
@@ -215,6 +326,27 @@ func GetUserRoleCtx(r *http.Request) *models.UserRole {
 	return ur
 }
 
+type AuditLogKey struct{}
+
+func GetAuditLogCtx(r *http.Request) *models.AuditLogEntry {
+	entry, ok := r.Context().Value(AuditLogKey{}).(*models.AuditLogEntry)
+	if !ok {
+		return nil
+	}
+	return entry
+}
+
+// RoleKey holds the role named in the URL
+type RoleKey struct{}
+
+func GetRoleCtx(r *http.Request) constants.RoleID {
+	role, ok := r.Context().Value(RoleKey{}).(constants.RoleID)
+	if !ok {
+		return ""
+	}
+	return role
+}
+
 type XUser struct{}
 
 func GetXUser(r *http.Request) *models.User {
@@ -225,6 +357,28 @@ func GetXUser(r *http.Request) *models.User {
 	return user
 }
 
+// XSession holds the session a request's VATUSA cookie belongs to
+type XSession struct{}
+
+func GetXSession(r *http.Request) *models.Session {
+	session, ok := r.Context().Value(XSession{}).(*models.Session)
+	if !ok {
+		return nil
+	}
+	return session
+}
+
+// XImpersonator holds the staff member behind a request while they are impersonating XUser
+type XImpersonator struct{}
+
+func GetXImpersonator(r *http.Request) *models.User {
+	user, ok := r.Context().Value(XImpersonator{}).(*models.User)
+	if !ok {
+		return nil
+	}
+	return user
+}
+
 type XFacility struct{}
 
 func GetXFacility(r *http.Request) *models.Facility {
@@ -235,6 +389,53 @@ func GetXFacility(r *http.Request) *models.Facility {
 	return fac
 }
 
+// XFacilityAPIKey holds the facility API key a request was authenticated with
+type XFacilityAPIKey struct{}
+
+func GetXFacilityAPIKey(r *http.Request) *models.FacilityAPIKey {
+	key, ok := r.Context().Value(XFacilityAPIKey{}).(*models.FacilityAPIKey)
+	if !ok {
+		return nil
+	}
+	return key
+}
+
+// XAccessToken holds the personal access token a request was authenticated with
+type XAccessToken struct{}
+
+func GetXAccessToken(r *http.Request) *models.PersonalAccessToken {
+	pat, ok := r.Context().Value(XAccessToken{}).(*models.PersonalAccessToken)
+	if !ok {
+		return nil
+	}
+	return pat
+}
+
+// XScopes holds the scopes of the personal access token a request was authenticated with. It is not set for
+// cookie sessions, which are not limited by scopes.
+type XScopes struct{}
+
+func GetXScopes(r *http.Request) ([]constants.Scope, bool) {
+	scopes, ok := r.Context().Value(XScopes{}).([]constants.Scope)
+	return scopes, ok
+}
+
+// XScopeChecked is set once a route's scope has been checked for a personal access token
+type XScopeChecked struct{}
+
+func GetXScopeChecked(r *http.Request) bool {
+	checked, ok := r.Context().Value(XScopeChecked{}).(bool)
+	return ok && checked
+}
+
+// XFacilityScopes holds the scopes of the facility API key a request was authenticated with
+type XFacilityScopes struct{}
+
+func GetXFacilityScopes(r *http.Request) ([]constants.Scope, bool) {
+	scopes, ok := r.Context().Value(XFacilityScopes{}).([]constants.Scope)
+	return scopes, ok
+}
+
 type XGuest struct{}
 
 func GetXGuest(r *http.Request) bool {
@@ -47,6 +47,15 @@ func ErrConflict(err error) render.Renderer {
 	}
 }
 
+func ErrRestricted(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: 403,
+		StatusText:     "Restricted",
+		ErrorText:      err.Error(),
+	}
+}
+
 func ErrRender(err error) render.Renderer {
 	return &ErrResponse{
 		Err:            err,
@@ -3,20 +3,24 @@ package utils
 import (
 	"github.com/go-chi/render"
 	"log"
+	"net"
 	"net/http"
 )
 
 func Response(r *http.Request, code int) {
+	trackAuditRequest(r)
 	render.Status(r, code)
 }
 
 func Render(w http.ResponseWriter, r *http.Request, renderer render.Renderer) {
+	trackAuditRequest(r)
 	if err := render.Render(w, r, renderer); err != nil {
 		log.Printf("Error rendering response: %v", err)
 	}
 }
 
 func JSON(w http.ResponseWriter, r *http.Request, code int, data interface{}) {
+	trackAuditRequest(r)
 	render.Status(r, code)
 	render.JSON(w, r, data)
 }
@@ -24,3 +28,12 @@ func JSON(w http.ResponseWriter, r *http.Request, code int, data interface{}) {
 func TempRedirect(w http.ResponseWriter, r *http.Request, location string) {
 	http.Redirect(w, r, location, http.StatusTemporaryRedirect)
 }
+
+// ClientIP returns the address a request came from. RealIP has already replaced RemoteAddr with the client's address
+// when the API is behind a proxy.
+func ClientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"net/http"
+	"slices"
+)
+
+// Principal is who a request acts as: a user, a facility API key, or both when a logged-in user also sends a key.
+// Permissions are evaluated per facility and either one holding a permission is enough.
+type Principal struct {
+	User     *models.User
+	Facility *models.Facility
+}
+
+func GetPrincipal(r *http.Request) Principal {
+	return Principal{
+		User:     GetXUser(r),
+		Facility: GetXFacility(r),
+	}
+}
+
+// Can checks if the principal holds perm at facility
+func (p Principal) Can(perm constants.Permission, facility constants.FacilityID) bool {
+	if p.User != nil && UserCan(p.User, perm, facility) {
+		return true
+	}
+
+	return p.Facility != nil && p.Facility.ID == facility && slices.Contains(constants.FacilityKeyPermissions, perm)
+}
+
+// CanForUser checks if the principal holds perm at any facility target is rostered at
+func (p Principal) CanForUser(perm constants.Permission, target *models.User) bool {
+	if p.User != nil && IsVATUSAStaff(p.User) {
+		return true
+	}
+
+	for _, roster := range target.Roster {
+		if p.Can(perm, roster.Facility) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanAnywhere checks if the principal holds perm at any facility
+func (p Principal) CanAnywhere(perm constants.Permission) bool {
+	if p.User != nil {
+		if IsVATUSAStaff(p.User) {
+			return true
+		}
+		for _, roster := range p.User.Roster {
+			if UserCan(p.User, perm, roster.Facility) {
+				return true
+			}
+		}
+	}
+
+	return p.Facility != nil && slices.Contains(constants.FacilityKeyPermissions, perm)
+}
+
+// Permissions lists what the principal can do at each facility. Division permissions apply at every facility.
+func (p Principal) Permissions() (division []constants.Permission, facilities map[constants.FacilityID][]constants.Permission) {
+	division = []constants.Permission{}
+	facilities = map[constants.FacilityID][]constants.Permission{}
+
+	if p.User != nil && IsVATUSAStaff(p.User) {
+		division = allPermissions()
+	}
+
+	add := func(facility constants.FacilityID) {
+		for _, perm := range allPermissions() {
+			if p.Can(perm, facility) && !slices.Contains(facilities[facility], perm) {
+				facilities[facility] = append(facilities[facility], perm)
+			}
+		}
+	}
+
+	if p.User != nil && len(division) == 0 {
+		for _, roster := range p.User.Roster {
+			add(roster.Facility)
+		}
+	}
+	if p.Facility != nil {
+		add(p.Facility.ID)
+	}
+
+	for facility, perms := range facilities {
+		if len(perms) == 0 {
+			delete(facilities, facility)
+		}
+	}
+
+	return division, facilities
+}
+
+// UserCan checks if user holds perm at facility through the roles on their roster there and, for roles restricted to
+// some ratings, their rating
+func UserCan(user *models.User, perm constants.Permission, facility constants.FacilityID) bool {
+	if IsVATUSAStaff(user) {
+		return true
+	}
+
+	for _, roster := range user.Roster {
+		if roster.Facility != facility {
+			continue
+		}
+		for _, role := range roster.Roles {
+			if role.RoleID.GrantsPermission(perm, user.ControllerRating) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func allPermissions() []constants.Permission {
+	perms := make([]constants.Permission, 0, len(constants.Permissions))
+	for perm := range constants.Permissions {
+		perms = append(perms, perm)
+	}
+	slices.Sort(perms)
+	return perms
+}
+
+// DisciplinaryVisibilities lists the disciplinary log tiers of target the principal can see. Users can always see
+// the entries visible to themselves.
+func (p Principal) DisciplinaryVisibilities(target *models.User) []types.DisciplinaryVisibility {
+	switch {
+	case p.CanForUser(constants.ViewVATUSADisciplinaryLogPermission, target):
+		return []types.DisciplinaryVisibility{types.DisciplinaryVATUSAOnly, types.DisciplinarySeniorStaff, types.DisciplinaryMember}
+	case p.CanForUser(constants.ViewDisciplinaryLogPermission, target):
+		return []types.DisciplinaryVisibility{types.DisciplinarySeniorStaff, types.DisciplinaryMember}
+	case p.User != nil && p.User.CID == target.CID:
+		return []types.DisciplinaryVisibility{types.DisciplinaryMember}
+	}
+	return []types.DisciplinaryVisibility{}
+}
@@ -3,8 +3,10 @@ package utils
 import (
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
+	"slices"
 )
 
+// IsVATUSAStaff checks if the user is on the ZHQ roster. Division staff hold every permission at every facility.
 func IsVATUSAStaff(user *models.User) bool {
 	for _, roster := range user.Roster {
 		if roster.Facility == "ZHQ" {
@@ -15,25 +17,47 @@ func IsVATUSAStaff(user *models.User) bool {
 	return false
 }
 
+// IsSeniorStaff checks if the user is the ATM, DATM or TA of any facility
 func IsSeniorStaff(user *models.User) bool {
-	for _, roster := range user.Roster {
-		for _, roles := range roster.Roles {
-			if roles.RoleID == constants.AirTrafficManagerRole || roles.RoleID == constants.DeputyAirTrafficManagerRole || roles.RoleID == constants.TrainingAdministratorRole {
-				return true
-			}
-		}
-	}
-
-	return false
+	return HasGroupRole(user, "", constants.FacilitySeniorStaff)
 }
 
+// IsFacilitySeniorStaff checks if the user is the facility's ATM, DATM or TA
 func IsFacilitySeniorStaff(user *models.User, facility constants.FacilityID) bool {
+	return HasGroupRole(user, facility, constants.FacilitySeniorStaff)
+}
+
+// IsFacilityStaff checks if the user is on the facility's staff: its senior staff, EC, FE or WM
+func IsFacilityStaff(user *models.User, facility constants.FacilityID) bool {
+	return HasGroupRole(user, facility, constants.FacilityStaff, constants.FacilitySeniorStaff)
+}
+
+// IsFacilityEventsStaff checks if the user is the facility's EC or AEC
+func IsFacilityEventsStaff(user *models.User, facility constants.FacilityID) bool {
+	return HasGroupRole(user, facility, constants.FacilityEvents)
+}
+
+// CanEditFacility checks if the user is the facility's ATM, DATM or WM
+func CanEditFacility(user *models.User, facility constants.FacilityID) bool {
+	return HasGroupRole(user, facility, constants.FacilityManagement) || hasRole(user, facility, constants.WebMasterRole)
+}
+
+// IsInstructor checks if the user is the facility's TA or one of its instructors and holds an instructor rating
+func IsInstructor(user *models.User, facility constants.FacilityID) bool {
+	if user.ControllerRating != constants.InstructorRating && user.ControllerRating != constants.SeniorInstructorRating {
+		return false
+	}
+	return hasRole(user, facility, constants.TrainingAdministratorRole, constants.InstructorRole)
+}
+
+func hasRole(user *models.User, facility constants.FacilityID, roles ...constants.RoleID) bool {
 	for _, roster := range user.Roster {
-		if roster.Facility == facility {
-			for _, roles := range roster.Roles {
-				if roles.RoleID == constants.AirTrafficManagerRole || roles.RoleID == constants.DeputyAirTrafficManagerRole || roles.RoleID == constants.TrainingAdministratorRole {
-					return true
-				}
+		if roster.Facility != facility {
+			continue
+		}
+		for _, role := range roster.Roles {
+			if slices.Contains(roles, role.RoleID) {
+				return true
 			}
 		}
 	}
@@ -41,25 +65,42 @@ func IsFacilitySeniorStaff(user *models.User, facility constants.FacilityID) boo
 	return false
 }
 
-func IsFacilityStaff(user *models.User, facility constants.FacilityID) bool {
+// HeldRoleGrant is a role held at Facility that allows assigning another role
+type HeldRoleGrant struct {
+	constants.RoleGrant
+	Facility constants.FacilityID `json:"facility" example:"ZDV"`
+}
+
+// AddRoleGrants lists the roles user holds at facilityId or ZHQ that allow assigning roleId there
+func AddRoleGrants(user *models.User, roleId constants.RoleID, facilityId constants.FacilityID) []HeldRoleGrant {
+	grants := []HeldRoleGrant{}
 	for _, roster := range user.Roster {
-		if roster.Facility == facility {
-			for _, roles := range roster.Roles {
-				if roles.RoleID == constants.FacilityEngineerRole || roles.RoleID == constants.WebMasterRole || roles.RoleID == constants.EventCoordinatorRole || IsFacilitySeniorStaff(user, facility) {
-					return true
-				}
+		if roster.Facility != facilityId && roster.Facility != "ZHQ" {
+			continue
+		}
+		for _, role := range roster.Roles {
+			for _, grant := range constants.AddRoleGrants([]constants.RoleID{role.RoleID}, roleId) {
+				grants = append(grants, HeldRoleGrant{RoleGrant: grant, Facility: roster.Facility})
 			}
 		}
 	}
 
-	return false
+	return grants
 }
 
-func IsFacilityEventsStaff(user *models.User, facility constants.FacilityID) bool {
+func CanAddRole(user *models.User, roleId constants.RoleID, facilityId constants.FacilityID) bool {
+	return len(AddRoleGrants(user, roleId, facilityId)) > 0
+}
+
+// HasGroupRole checks if the user holds a role in any of groups at facility, or at any facility if facility is empty
+func HasGroupRole(user *models.User, facility constants.FacilityID, groups ...constants.GroupID) bool {
 	for _, roster := range user.Roster {
-		if roster.Facility == facility {
-			for _, roles := range roster.Roles {
-				if roles.RoleID == constants.EventCoordinatorRole || roles.RoleID == constants.AssistantEventCoordinator {
+		if facility != "" && roster.Facility != facility {
+			continue
+		}
+		for _, role := range roster.Roles {
+			for _, group := range groups {
+				if role.RoleID.InGroup(group) {
 					return true
 				}
 			}
@@ -69,40 +110,47 @@ func IsFacilityEventsStaff(user *models.User, facility constants.FacilityID) boo
 	return false
 }
 
-func CanEditFacility(user *models.User, facility constants.FacilityID) bool {
-	for _, roster := range user.Roster {
-		if roster.Facility == facility {
-			for _, roles := range roster.Roles {
-				if roles.RoleID == constants.AirTrafficManagerRole || roles.RoleID == constants.DeputyAirTrafficManagerRole || roles.RoleID == constants.WebMasterRole {
-					return true
-				}
-			}
-		}
+// CanBroadcast checks if the user can send the broadcast. Division staff can broadcast to anyone, facility staff
+// only to their own facility.
+func CanBroadcast(user *models.User, b *models.Broadcast) bool {
+	if HasGroupRole(user, "", constants.DivisionManagement, constants.DivisionStaff) {
+		return true
 	}
 
-	return false
+	if b.Facility == "" {
+		return false
+	}
+
+	return HasGroupRole(user, b.Facility, constants.FacilityManagement, constants.FacilityStaff)
 }
 
-func CanAddRole(user *models.User, roleId constants.RoleID, facilityId constants.FacilityID) bool {
-	var userRoles []constants.RoleID
+// CanImpersonate checks if the user can view the API as another user. Only support services, the technical manager
+// and division development can.
+func CanImpersonate(user *models.User) bool {
 	for _, roster := range user.Roster {
-		if roster.Facility == facilityId || roster.Facility == "ZHQ" {
-			for _, role := range roster.Roles {
-				userRoles = append(userRoles, role.RoleID)
+		if roster.Facility != "ZHQ" {
+			continue
+		}
+		for _, role := range roster.Roles {
+			if role.RoleID == constants.SupportServicesRole || role.RoleID == constants.TechnicalManagerRole {
+				return true
 			}
 		}
 	}
 
-	return constants.CanAddRole(userRoles, roleId)
+	return HasGroupRole(user, "ZHQ", constants.DivisionDevelopment)
 }
 
-func IsInstructor(user *models.User, facility constants.FacilityID) bool {
+// CanApprovePromotions checks if the user can approve and reject promotions. Only division training staff, the
+// Deputy Director Training Services and the Training Services Manager, can.
+func CanApprovePromotions(user *models.User) bool {
 	for _, roster := range user.Roster {
-		if roster.Facility == facility {
-			for _, roles := range roster.Roles {
-				if roles.RoleID == constants.TrainingAdministratorRole || roles.RoleID == constants.InstructorRole {
-					return user.ControllerRating == constants.InstructorRating || user.ControllerRating == constants.SeniorInstructorRating
-				}
+		if roster.Facility != "ZHQ" {
+			continue
+		}
+		for _, role := range roster.Roles {
+			if role.RoleID == constants.TrainingServicesRole || role.RoleID == constants.TrainingServicesManagerRole {
+				return true
 			}
 		}
 	}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"testing"
+)
+
+func staffUser(rating constants.ATCRating, facility constants.FacilityID, roles ...constants.RoleID) *models.User {
+	roster := models.Roster{Facility: facility, Home: true}
+	for _, role := range roles {
+		roster.Roles = append(roster.Roles, models.UserRole{RoleID: role, FacilityID: facility})
+	}
+	return &models.User{CID: 1293257, ControllerRating: rating, Roster: []models.Roster{roster}}
+}
+
+// TestPermissionsMatchStaffHelpers checks that the permissions of every facility role agree with the staff helpers
+// the permissions replaced
+func TestPermissionsMatchStaffHelpers(t *testing.T) {
+	for role := range constants.Roles {
+		// Division roles are only held on the ZHQ roster, where they grant everything
+		if role.InGroup(constants.DivisionManagement) || role.InGroup(constants.DivisionStaff) {
+			continue
+		}
+
+		for _, rating := range []constants.ATCRating{constants.ControllerRating, constants.InstructorRating} {
+			user := staffUser(rating, "ZDV", role)
+
+			checks := []struct {
+				perm constants.Permission
+				want bool
+			}{
+				{perm: constants.ViewFacilityLogPermission, want: IsFacilityStaff(user, "ZDV")},
+				{perm: constants.EditNewsPermission, want: IsFacilityStaff(user, "ZDV")},
+				{perm: constants.EditRosterPermission, want: IsFacilitySeniorStaff(user, "ZDV")},
+				{perm: constants.ViewDisciplinaryLogPermission, want: IsFacilitySeniorStaff(user, "ZDV")},
+				{perm: constants.EditEventPermission, want: IsFacilitySeniorStaff(user, "ZDV") || IsFacilityEventsStaff(user, "ZDV")},
+				{perm: constants.EditFacilityPermission, want: CanEditFacility(user, "ZDV")},
+				{perm: constants.ViewRatingChangePermission, want: IsFacilityStaff(user, "ZDV") || IsInstructor(user, "ZDV")},
+			}
+
+			for _, check := range checks {
+				if got := UserCan(user, check.perm, "ZDV"); got != check.want {
+					t.Errorf("%s rated %s: UserCan(%s) = %v, want %v", role, rating.Short(), check.perm, got, check.want)
+				}
+				if UserCan(user, check.perm, "ZLC") {
+					t.Errorf("%s at ZDV holds %s at ZLC", role, check.perm)
+				}
+			}
+		}
+	}
+}
+
+func TestStaffHelpers(t *testing.T) {
+	tests := []struct {
+		name  string
+		user  *models.User
+		check func(*models.User) bool
+		want  bool
+	}{
+		{name: "ATM is senior staff", user: staffUser(constants.ControllerRating, "ZDV", constants.AirTrafficManagerRole), check: IsSeniorStaff, want: true},
+		{name: "TA is senior staff", user: staffUser(constants.ControllerRating, "ZDV", constants.TrainingAdministratorRole), check: IsSeniorStaff, want: true},
+		{name: "EC is not senior staff", user: staffUser(constants.ControllerRating, "ZDV", constants.EventCoordinatorRole), check: IsSeniorStaff},
+		{
+			name:  "TA is facility staff",
+			user:  staffUser(constants.ControllerRating, "ZDV", constants.TrainingAdministratorRole),
+			check: func(u *models.User) bool { return IsFacilityStaff(u, "ZDV") },
+			want:  true,
+		},
+		{
+			name:  "staff of another facility",
+			user:  staffUser(constants.ControllerRating, "ZLC", constants.WebMasterRole),
+			check: func(u *models.User) bool { return IsFacilityStaff(u, "ZDV") },
+		},
+		{
+			name:  "AEC is events staff",
+			user:  staffUser(constants.ControllerRating, "ZDV", constants.AssistantEventCoordinator),
+			check: func(u *models.User) bool { return IsFacilityEventsStaff(u, "ZDV") },
+			want:  true,
+		},
+		{
+			name:  "WM can edit the facility",
+			user:  staffUser(constants.ControllerRating, "ZDV", constants.WebMasterRole),
+			check: func(u *models.User) bool { return CanEditFacility(u, "ZDV") },
+			want:  true,
+		},
+		{
+			name:  "TA can't edit the facility",
+			user:  staffUser(constants.ControllerRating, "ZDV", constants.TrainingAdministratorRole),
+			check: func(u *models.User) bool { return CanEditFacility(u, "ZDV") },
+		},
+		{
+			name:  "I1 instructor",
+			user:  staffUser(constants.InstructorRating, "ZDV", constants.InstructorRole),
+			check: func(u *models.User) bool { return IsInstructor(u, "ZDV") },
+			want:  true,
+		},
+		{
+			name:  "I3 instructor",
+			user:  staffUser(constants.SeniorInstructorRating, "ZDV", constants.InstructorRole),
+			check: func(u *models.User) bool { return IsInstructor(u, "ZDV") },
+			want:  true,
+		},
+		{
+			name:  "instructor role without an instructor rating",
+			user:  staffUser(constants.SeniorControllerRating, "ZDV", constants.InstructorRole),
+			check: func(u *models.User) bool { return IsInstructor(u, "ZDV") },
+		},
+		{
+			name:  "instructor rating without an instructor role",
+			user:  staffUser(constants.InstructorRating, "ZDV", constants.MentorRole),
+			check: func(u *models.User) bool { return IsInstructor(u, "ZDV") },
+		},
+		{
+			name:  "instructor of another facility",
+			user:  staffUser(constants.InstructorRating, "ZLC", constants.InstructorRole),
+			check: func(u *models.User) bool { return IsInstructor(u, "ZDV") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check(tt.user); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
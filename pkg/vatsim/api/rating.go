@@ -0,0 +1,48 @@
+package vatsim_api
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"sync"
+)
+
+// RatingPusher applies a controller rating change on VATSIM once the division has approved it
+type RatingPusher interface {
+	PushRating(ctx context.Context, cid uint, rating constants.ATCRating) error
+}
+
+// DefaultRatingPusher is used to push approved promotions. Without one, ratings have to be updated on VATSIM by hand.
+var DefaultRatingPusher RatingPusher
+
+// PushedRating is a rating change received by StubRatingPusher
+type PushedRating struct {
+	CID    uint
+	Rating constants.ATCRating
+}
+
+// StubRatingPusher records the ratings it is asked to push instead of sending them to VATSIM, returning Err if set
+type StubRatingPusher struct {
+	Err error
+
+	mu     sync.Mutex
+	pushed []PushedRating
+}
+
+func (s *StubRatingPusher) PushRating(_ context.Context, cid uint, rating constants.ATCRating) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+	s.pushed = append(s.pushed, PushedRating{CID: cid, Rating: rating})
+	return nil
+}
+
+// Pushed returns the ratings pushed so far
+func (s *StubRatingPusher) Pushed() []PushedRating {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]PushedRating(nil), s.pushed...)
+}
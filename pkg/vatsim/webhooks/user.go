@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
 	log "github.com/sirupsen/logrus"
 	"strings"
 	"time"
@@ -165,32 +166,24 @@ func handleUserChange(cid uint, action Action) error {
 	}
 
 	if user.ControllerRating == constants.SuspendedRating {
-		// Create a disciplinary log entry
+		// Open a suspension case, which keeps the user from holding staff roles
+		now := time.Now()
 		disciplinaryLogEntry := &models.DisciplinaryLogEntry{
-			CID:        user.CID,
-			Entry:      "User controller rating changed to suspended(0).",
-			VATUSAOnly: false,
-			CreatedAt:  time.Now(),
-			CreatedBy:  "VATSIM Webhook",
-			UpdatedAt:  time.Now(),
-			UpdatedBy:  "",
+			CID:           user.CID,
+			Type:          types.DisciplinarySuspension,
+			Entry:         "User controller rating changed to suspended(0).",
+			Visibility:    types.DisciplinarySeniorStaff,
+			Restriction:   types.RestrictionNoStaffRole,
+			EffectiveFrom: &now,
+			CreatedBy:     "VATSIM Webhook",
 		}
 
-		if err := disciplinaryLogEntry.Create(); err != nil {
+		if err := disciplinaryLogEntry.CreateCase(); err != nil {
 			return err
 		}
 
-		userFlag := &models.UserFlag{
-			CID: user.CID,
-		}
-
-		if err := userFlag.Get(); err != nil {
-			return err
-		}
-
-		userFlag.NoStaffRole = true
-		userFlag.NoStaffLogEntryID = disciplinaryLogEntry.ID
-		if err := userFlag.Update(); err != nil {
+		// Log the user out everywhere
+		if err := models.RevokeSessionsByCID(user.CID); err != nil {
 			return err
 		}
 	}
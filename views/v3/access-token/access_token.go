@@ -0,0 +1,232 @@
+package access_token
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	gonanoid "github.com/matoous/go-nanoid"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultExpiry = 90 * 24 * time.Hour
+	maxExpiry     = 365 * 24 * time.Hour
+)
+
+type Request struct {
+	Name      string            `json:"name" example:"Roster sync script"`
+	Scopes    []constants.Scope `json:"scopes" example:"read:roster"`
+	ExpiresAt *time.Time        `json:"expires_at" example:"2021-01-01T00:00:00Z"`
+}
+
+func (req *Request) Validate() error {
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(req.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !scope.IsValidScope() {
+			return fmt.Errorf("invalid scope %q", scope)
+		}
+	}
+
+	if req.ExpiresAt != nil {
+		if req.ExpiresAt.Before(time.Now()) {
+			return errors.New("expires_at must be in the future")
+		}
+		if req.ExpiresAt.After(time.Now().Add(maxExpiry)) {
+			return errors.New("expires_at must be within a year")
+		}
+	}
+
+	return nil
+}
+
+func (req *Request) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+type Response struct {
+	*models.PersonalAccessToken
+}
+
+func (res *Response) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.PersonalAccessToken == nil {
+		return errors.New("access token not found")
+	}
+	return nil
+}
+
+// CreatedResponse includes the token itself, which is only ever shown when it is created
+type CreatedResponse struct {
+	*models.PersonalAccessToken
+	Token string `json:"token" example:"vatusa_pat_Ab12..."`
+}
+
+func (res *CreatedResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+type ScopeResponse struct {
+	Scope       constants.Scope `json:"scope" example:"read:roster"`
+	Description string          `json:"description" example:"View rosters and roster requests"`
+}
+
+func (res *ScopeResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// ListScopes godoc
+// @Summary List access token scopes
+// @Description List the scopes personal access tokens can be granted
+// @Tags access-token
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []ScopeResponse
+// @Failure 401 {object} utils.ErrResponse
+// @Router /user/tokens/scopes [get]
+func ListScopes(w http.ResponseWriter, r *http.Request) {
+	list := []render.Renderer{}
+	for _, scope := range scopeOrder() {
+		list = append(list, &ScopeResponse{Scope: scope, Description: constants.Scopes[scope]})
+	}
+
+	if err := render.RenderList(w, r, list); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// ListAccessTokens godoc
+// @Summary List your access tokens
+// @Description List your personal access tokens. The tokens themselves are never returned after creation.
+// @Tags access-token
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []Response
+// @Failure 401 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/tokens [get]
+func ListAccessTokens(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetXUser(r)
+
+	tokens, err := models.GetPersonalAccessTokensByCID(user.CID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting access tokens for user %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	list := []render.Renderer{}
+	for idx := range tokens {
+		list = append(list, &Response{PersonalAccessToken: &tokens[idx]})
+	}
+
+	if err := render.RenderList(w, r, list); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// CreateAccessToken godoc
+// @Summary Create an access token
+// @Description Create a personal access token, sent as "Authorization: Bearer <token>". The token is only shown in
+// @Description this response. Tokens expire after 90 days unless expires_at is set, at most a year ahead.
+// @Tags access-token
+// @Accept  json
+// @Produce  json
+// @Param token body Request true "Access Token"
+// @Success 201 {object} CreatedResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 401 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/tokens [post]
+func CreateAccessToken(w http.ResponseWriter, r *http.Request) {
+	data := &Request{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	secret, err := gonanoid.Generate("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789", 40)
+	if err != nil {
+		log.WithError(err).Error("Error generating access token")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+	token := models.PersonalAccessTokenPrefix + secret
+
+	expiresAt := time.Now().Add(defaultExpiry)
+	if data.ExpiresAt != nil {
+		expiresAt = *data.ExpiresAt
+	}
+
+	user := utils.GetXUser(r)
+	pat := &models.PersonalAccessToken{
+		CID:       user.CID,
+		Name:      data.Name,
+		Prefix:    token[:len(models.PersonalAccessTokenPrefix)+4],
+		TokenHash: models.HashToken(token),
+		Scopes:    data.Scopes,
+		ExpiresAt: &expiresAt,
+	}
+	if err := pat.Create(); err != nil {
+		log.WithError(err).Errorf("Error creating access token for user %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Response(r, http.StatusCreated)
+	utils.Render(w, r, &CreatedResponse{PersonalAccessToken: pat, Token: token})
+}
+
+// DeleteAccessToken godoc
+// @Summary Revoke an access token
+// @Description Revoke one of your personal access tokens
+// @Tags access-token
+// @Accept  json
+// @Produce  json
+// @Param TokenID path int true "Token ID"
+// @Success 204
+// @Failure 401 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/tokens/{TokenID} [delete]
+func DeleteAccessToken(w http.ResponseWriter, r *http.Request) {
+	pat := utils.GetAccessTokenCtx(r)
+	if err := pat.Delete(); err != nil {
+		log.WithError(err).Errorf("Error deleting access token %d", pat.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func scopeOrder() []constants.Scope {
+	return []constants.Scope{
+		constants.ReadRosterScope, constants.WriteRosterScope,
+		constants.ReadEventsScope, constants.WriteEventsScope,
+		constants.ReadFeedbackScope, constants.WriteFeedbackScope,
+		constants.ReadUsersScope, constants.WriteUsersScope,
+		constants.ReadNotificationsScope, constants.WriteNotificationsScope,
+		constants.ReadTrainingScope, constants.WriteTrainingScope,
+	}
+}
@@ -0,0 +1,40 @@
+package access_token
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+// Router serves the logged-in user's personal access tokens. Tokens cannot be used to manage tokens.
+func Router(r chi.Router) {
+	r.Use(middleware.NoAccessToken, middleware.NotGuest, middleware.NoImpersonation)
+
+	r.Get("/", ListAccessTokens)
+	r.Post("/", CreateAccessToken)
+	r.Get("/scopes", ListScopes)
+
+	r.With(Ctx).Delete("/{TokenID}", DeleteAccessToken)
+}
+
+func Ctx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "TokenID"), 10, 64)
+		if err != nil {
+			utils.Render(w, r, utils.ErrBadRequest)
+			return
+		}
+
+		pat := &models.PersonalAccessToken{ID: uint(id)}
+		if err := pat.Get(); err != nil || pat.CID != utils.GetXUser(r).CID {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		ctx := utils.WithResource(r, utils.AccessTokenKey{}, pat)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -1,7 +1,7 @@
 package action_log
 
 import (
-	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
@@ -11,15 +11,15 @@ import (
 )
 
 func Router(r chi.Router) {
-	r.With(middleware.NotGuest, middleware.CanReadActionLog).Get("/", GetActionLog)
-	r.With(middleware.NotGuest, middleware.CanEditActionLog).Post("/", CreateActionLogEntry)
+	r.With(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope), middleware.NotGuest, middleware.CanReadActionLog).Get("/", GetActionLog)
+	r.With(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope), middleware.NotGuest, middleware.CanEditActionLog).Post("/", CreateActionLogEntry)
 
 	r.Route("/{ActionLogID}", func(r chi.Router) {
 		r.Use(Ctx)
 
-		r.With(middleware.NotGuest, middleware.CanEditActionLog).Patch("/", PatchActionLogEntry)
-		r.With(middleware.NotGuest, middleware.CanEditActionLog).Put("/", UpdateActionLogEntry)
-		r.With(middleware.NotGuest, middleware.CanEditActionLog).Delete("/", DeleteActionLogEntry)
+		r.With(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope), middleware.NotGuest, middleware.CanEditActionLog).Patch("/", PatchActionLogEntry)
+		r.With(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope), middleware.NotGuest, middleware.CanEditActionLog).Put("/", UpdateActionLogEntry)
+		r.With(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope), middleware.NotGuest, middleware.CanEditActionLog).Delete("/", DeleteActionLogEntry)
 	})
 }
 
@@ -43,7 +43,7 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.AleKey{}, actionLog)
+		ctx := utils.WithResource(r, utils.AleKey{}, actionLog)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
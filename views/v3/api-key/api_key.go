@@ -0,0 +1,311 @@
+package api_key
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultGracePeriod is how long a rotated key keeps working unless another period is requested
+	DefaultGracePeriod = 24 * time.Hour
+	maxGracePeriod     = 7 * 24 * time.Hour
+)
+
+type Request struct {
+	Name       string            `json:"name" example:"Website"`
+	Scopes     []constants.Scope `json:"scopes" example:"read:roster"`
+	AllowedIPs []string          `json:"allowed_ips" example:"203.0.113.0/24"`
+}
+
+func (req *Request) Validate() error {
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(req.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !scope.IsValidScope() {
+			return fmt.Errorf("invalid scope %q", scope)
+		}
+	}
+
+	for _, ip := range req.AllowedIPs {
+		if _, _, err := net.ParseCIDR(ip); err != nil && net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid IP address or CIDR range %q", ip)
+		}
+	}
+
+	return nil
+}
+
+func (req *Request) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+type RotateRequest struct {
+	GraceHours *int `json:"grace_hours" example:"24"`
+}
+
+func (req *RotateRequest) Bind(r *http.Request) error {
+	if r.ContentLength == 0 {
+		return nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (req *RotateRequest) GracePeriod() (time.Duration, error) {
+	if req.GraceHours == nil {
+		return DefaultGracePeriod, nil
+	}
+
+	grace := time.Duration(*req.GraceHours) * time.Hour
+	if grace < 0 || grace > maxGracePeriod {
+		return 0, errors.New("grace_hours must be between 0 and 168")
+	}
+	return grace, nil
+}
+
+type Response struct {
+	*models.FacilityAPIKey
+}
+
+func (res *Response) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.FacilityAPIKey == nil {
+		return errors.New("api key not found")
+	}
+	return nil
+}
+
+// CreatedResponse includes the key itself, which is only ever shown when it is created
+type CreatedResponse struct {
+	*models.FacilityAPIKey
+	Key string `json:"key" example:"1a2b3c4d..."`
+}
+
+func (res *CreatedResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List the facility's API keys, including rotated keys still in their grace period
+// @Tags api-key
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Success 200 {object} []Response
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/api-keys [get]
+func ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	fac := utils.GetFacilityCtx(r)
+
+	keys, err := models.GetFacilityAPIKeys(fac.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting API keys for %s", fac.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	list := []render.Renderer{}
+	for idx := range keys {
+		list = append(list, &Response{FacilityAPIKey: &keys[idx]})
+	}
+
+	if err := render.RenderList(w, r, list); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create an API key for the facility, sent in the x-api-key header. The key is only shown in this
+// @Description response. Keys with allowed_ips can only be used from those addresses or CIDR ranges.
+// @Tags api-key
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param api_key body Request true "API Key"
+// @Success 201 {object} CreatedResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/api-keys [post]
+func CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	data := &Request{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	fac := utils.GetFacilityCtx(r)
+	key, plaintext, err := models.NewFacilityAPIKey(fac.ID, data.Name, data.Scopes, data.AllowedIPs, utils.GetXUser(r).CID)
+	if err != nil {
+		log.WithError(err).Errorf("Error generating API key for %s", fac.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	if err := key.Create(); err != nil {
+		log.WithError(err).Errorf("Error creating API key for %s", fac.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Response(r, http.StatusCreated)
+	utils.Render(w, r, &CreatedResponse{FacilityAPIKey: key, Key: plaintext})
+}
+
+// UpdateAPIKey godoc
+// @Summary Update an API key
+// @Description Update the name, scopes and IP allowlist of an API key
+// @Tags api-key
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param APIKeyID path int true "API Key ID"
+// @Param api_key body Request true "API Key"
+// @Success 200 {object} Response
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/api-keys/{APIKeyID} [put]
+func UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	data := &Request{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	key := utils.GetAPIKeyCtx(r)
+	key.Name = data.Name
+	key.Scopes = data.Scopes
+	key.AllowedIPs = data.AllowedIPs
+	if err := key.Update(); err != nil {
+		log.WithError(err).Errorf("Error updating API key %d", key.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, &Response{FacilityAPIKey: key})
+}
+
+// RotateAPIKey godoc
+// @Summary Rotate an API key
+// @Description Create a replacement for an API key with the same name, scopes and IP allowlist. The old key keeps
+// @Description working for grace_hours, 24 by default and at most 168, so integrations can switch over.
+// @Tags api-key
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param APIKeyID path int true "API Key ID"
+// @Param rotate body RotateRequest false "Rotation"
+// @Success 201 {object} CreatedResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/api-keys/{APIKeyID}/rotate [post]
+func RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	data := &RotateRequest{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	grace, err := data.GracePeriod()
+	if err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	old := utils.GetAPIKeyCtx(r)
+	if old.IsExpired() {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("expired keys cannot be rotated")))
+		return
+	}
+
+	key, plaintext, err := Rotate(old, grace, utils.GetXUser(r).CID)
+	if err != nil {
+		log.WithError(err).Errorf("Error rotating API key %d", old.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Response(r, http.StatusCreated)
+	utils.Render(w, r, &CreatedResponse{FacilityAPIKey: key, Key: plaintext})
+}
+
+// DeleteAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoke an API key immediately
+// @Tags api-key
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param APIKeyID path int true "API Key ID"
+// @Success 204
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/api-keys/{APIKeyID} [delete]
+func DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	key := utils.GetAPIKeyCtx(r)
+	if err := key.Delete(); err != nil {
+		log.WithError(err).Errorf("Error deleting API key %d", key.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Rotate creates a replacement for old and lets old expire after grace
+func Rotate(old *models.FacilityAPIKey, grace time.Duration, createdBy uint) (*models.FacilityAPIKey, string, error) {
+	key, plaintext, err := models.NewFacilityAPIKey(old.Facility, old.Name, old.Scopes, old.AllowedIPs, createdBy)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := key.Create(); err != nil {
+		return nil, "", err
+	}
+
+	expiresAt := time.Now().Add(grace)
+	if old.ExpiresAt == nil || old.ExpiresAt.After(expiresAt) {
+		old.ExpiresAt = &expiresAt
+		if err := old.Update(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return key, plaintext, nil
+}
@@ -0,0 +1,45 @@
+package api_key
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+// Router serves a facility's API keys. Keys can only be managed by logged-in facility staff, not with a key or token.
+func Router(r chi.Router) {
+	r.Use(middleware.NoAccessToken, middleware.NotGuest, middleware.RequireUser, middleware.CanEditFacility)
+
+	r.Get("/", ListAPIKeys)
+	r.Post("/", CreateAPIKey)
+
+	r.Route("/{APIKeyID}", func(r chi.Router) {
+		r.Use(Ctx)
+
+		r.Put("/", UpdateAPIKey)
+		r.Delete("/", DeleteAPIKey)
+		r.Post("/rotate", RotateAPIKey)
+	})
+}
+
+func Ctx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "APIKeyID"), 10, 64)
+		if err != nil {
+			utils.Render(w, r, utils.ErrBadRequest)
+			return
+		}
+
+		key := &models.FacilityAPIKey{ID: uint(id)}
+		if err := key.Get(); err != nil || key.Facility != utils.GetFacilityCtx(r).ID {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		ctx := utils.WithResource(r, utils.APIKeyKey{}, key)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,139 @@
+package audit_log
+
+import (
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultPageSize = 25
+	maxPageSize     = 100
+)
+
+type Response struct {
+	*models.AuditLogEntry
+}
+
+func NewAuditLogEntryResponse(entry *models.AuditLogEntry) *Response {
+	return &Response{AuditLogEntry: entry}
+}
+
+func (res *Response) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func NewAuditLogEntryListResponse(entries []models.AuditLogEntry) []render.Renderer {
+	list := []render.Renderer{}
+	for idx := range entries {
+		list = append(list, NewAuditLogEntryResponse(&entries[idx]))
+	}
+	return list
+}
+
+// ListAuditLog godoc
+// @Summary List the audit log
+// @Description List audited POST, PUT, PATCH and DELETE requests, newest first. Filter by actor with actor_cid or
+// @Description api_key_id, and by resource with resource_type (e.g. Facility, User, Roster) and resource_id. To get
+// @Description the next page pass the created_at of the last entry as before.
+// @Tags audit-log
+// @Accept  json
+// @Produce  json
+// @Param actor_cid query int false "User who made, or impersonated the user who made, the request"
+// @Param api_key_id query int false "Facility API key the request was made with"
+// @Param facility query string false "Facility of the API key the request was made with"
+// @Param resource_type query string false "Type of a targeted resource"
+// @Param resource_id query string false "ID of a targeted resource"
+// @Param before query string false "Only entries created before this RFC 3339 timestamp"
+// @Param limit query int false "Page size, default 25, max 100"
+// @Success 200 {object} []Response
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /audit-log [get]
+func ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, limit, err := parseFilter(r)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	entries, err := models.GetAuditLogEntries(filter, limit)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	if err := render.RenderList(w, r, NewAuditLogEntryListResponse(entries)); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// GetAuditLogEntry godoc
+// @Summary Get an audit log entry
+// @Description Get an audit log entry with the before and after state of each resource it targeted
+// @Tags audit-log
+// @Accept  json
+// @Produce  json
+// @Param AuditLogEntryID path int true "Audit Log Entry ID"
+// @Success 200 {object} Response
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Router /audit-log/{AuditLogEntryID} [get]
+func GetAuditLogEntry(w http.ResponseWriter, r *http.Request) {
+	utils.Render(w, r, NewAuditLogEntryResponse(utils.GetAuditLogCtx(r)))
+}
+
+func parseFilter(r *http.Request) (filter models.AuditLogFilter, limit int, err error) {
+	limit = defaultPageSize
+	q := r.URL.Query()
+
+	if v := q.Get("actor_cid"); v != "" {
+		cid, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return filter, limit, errors.New("actor_cid must be a CID")
+		}
+		filter.ActorCID = uint(cid)
+	}
+
+	if v := q.Get("api_key_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return filter, limit, errors.New("api_key_id must be an ID")
+		}
+		filter.APIKeyID = uint(id)
+	}
+
+	if v := q.Get("facility"); v != "" {
+		filter.Facility = constants.FacilityID(v)
+		if !filter.Facility.IsValidFacility() {
+			return filter, limit, errors.New("facility must be a valid facility")
+		}
+	}
+
+	filter.ResourceType = q.Get("resource_type")
+	filter.ResourceID = q.Get("resource_id")
+
+	if v := q.Get("before"); v != "" {
+		if filter.Before, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return filter, limit, errors.New("before must be an RFC 3339 timestamp")
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			return filter, limit, errors.New("limit must be a positive integer")
+		}
+		if limit > maxPageSize {
+			limit = maxPageSize
+		}
+	}
+
+	return filter, limit, nil
+}
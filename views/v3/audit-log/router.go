@@ -0,0 +1,37 @@
+package audit_log
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+func Router(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope), middleware.NotGuest, middleware.CanViewAuditLog)
+
+	r.Get("/", ListAuditLog)
+	r.With(Ctx).Get("/{AuditLogEntryID}", GetAuditLogEntry)
+}
+
+func Ctx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "AuditLogEntryID"), 10, 64)
+		if err != nil {
+			utils.Render(w, r, utils.ErrBadRequest)
+			return
+		}
+
+		entry := &models.AuditLogEntry{ID: uint(id)}
+		if err := entry.Get(); err != nil {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		ctx := utils.WithResource(r, utils.AuditLogKey{}, entry)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,286 @@
+package broadcast
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+type Request struct {
+	Target    types.BroadcastTarget `json:"target" example:"facility" validate:"required"`
+	Facility  constants.FacilityID  `json:"facility" example:"ZDV"`
+	Group     constants.GroupID     `json:"group" example:"fac_staff"`
+	MinRating constants.ATCRating   `json:"min_rating" example:"1"`
+	MaxRating constants.ATCRating   `json:"max_rating" example:"12"`
+	Category  string                `json:"category" example:"Administration" validate:"required"`
+	Title     string                `json:"title" example:"Scheduled Maintenance" validate:"required"`
+	Body      string                `json:"body" example:"The website will be down for maintenance on Sunday." validate:"required"`
+	PublishAt *time.Time            `json:"publish_at" example:"2021-01-01T00:00:00Z"`
+	ExpireAt  time.Time             `json:"expire_at" example:"2021-01-01T00:00:00Z" validate:"required"`
+}
+
+func (req *Request) Validate() error {
+	if err := validator.New().Struct(req); err != nil {
+		return err
+	}
+
+	if req.Facility != "" && !req.Facility.IsValidFacility() {
+		return errors.New("invalid facility")
+	}
+
+	switch req.Target {
+	case types.BroadcastDivision:
+		if req.Facility != "" {
+			return errors.New("division broadcasts cannot have a facility")
+		}
+	case types.BroadcastFacility:
+		if req.Facility == "" {
+			return errors.New("facility is required for facility broadcasts")
+		}
+	case types.BroadcastGroup:
+		if len(req.Group.RolesInGroup()) == 0 {
+			return errors.New("invalid group")
+		}
+	case types.BroadcastRating:
+		if req.MinRating > req.MaxRating {
+			return errors.New("min_rating must not be above max_rating")
+		}
+	default:
+		return errors.New("invalid target")
+	}
+
+	publishAt := time.Now()
+	if req.PublishAt != nil {
+		publishAt = *req.PublishAt
+	}
+	if !req.ExpireAt.After(publishAt) || req.ExpireAt.Before(time.Now()) {
+		return errors.New("expire_at must be in the future and after publish_at")
+	}
+
+	return nil
+}
+
+func (req *Request) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+// apply copies the request onto b
+func (req *Request) apply(b *models.Broadcast) {
+	b.Target = req.Target
+	b.Facility = req.Facility
+	b.Group = ""
+	b.MinRating = 0
+	b.MaxRating = 0
+	switch req.Target {
+	case types.BroadcastGroup:
+		b.Group = req.Group
+	case types.BroadcastRating:
+		b.MinRating = req.MinRating
+		b.MaxRating = req.MaxRating
+	}
+
+	b.Category = req.Category
+	b.Title = req.Title
+	b.Body = req.Body
+	b.ExpireAt = req.ExpireAt
+	if req.PublishAt != nil {
+		b.PublishAt = *req.PublishAt
+	} else if b.PublishAt.IsZero() {
+		b.PublishAt = time.Now()
+	}
+}
+
+type Response struct {
+	*models.Broadcast
+}
+
+func NewBroadcastResponse(b *models.Broadcast) *Response {
+	return &Response{Broadcast: b}
+}
+
+func (res *Response) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.Broadcast == nil {
+		return errors.New("broadcast not found")
+	}
+	return nil
+}
+
+func NewBroadcastListResponse(broadcasts []models.Broadcast) []render.Renderer {
+	list := []render.Renderer{}
+	for idx := range broadcasts {
+		list = append(list, NewBroadcastResponse(&broadcasts[idx]))
+	}
+	return list
+}
+
+// CreateBroadcast godoc
+// @Summary Create a broadcast
+// @Description Create a notification for the whole division, a facility roster, a role group or a rating band.
+// @Description Group and rating broadcasts can be limited to a facility. Division staff can broadcast to anyone,
+// @Description facility staff only to their facility.
+// @Tags broadcast
+// @Accept  json
+// @Produce  json
+// @Param broadcast body Request true "Broadcast"
+// @Success 201 {object} Response
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /broadcasts [post]
+func CreateBroadcast(w http.ResponseWriter, r *http.Request) {
+	data := &Request{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	user := middleware.GetCredentials(r).User
+	b := &models.Broadcast{CreatedBy: user.CID}
+	data.apply(b)
+
+	if !utils.CanBroadcast(user, b) {
+		utils.Render(w, r, utils.ErrForbidden)
+		return
+	}
+
+	if err := b.Create(); err != nil {
+		log.WithError(err).Error("Error creating broadcast")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Response(r, http.StatusCreated)
+	utils.Render(w, r, NewBroadcastResponse(b))
+}
+
+// ListBroadcasts godoc
+// @Summary List broadcasts
+// @Description List the broadcasts you can manage, including scheduled and expired ones
+// @Tags broadcast
+// @Accept  json
+// @Produce  json
+// @Param facility query string false "Only broadcasts scoped to this facility"
+// @Success 200 {object} []Response
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /broadcasts [get]
+func ListBroadcasts(w http.ResponseWriter, r *http.Request) {
+	broadcasts, err := models.GetBroadcasts(constants.FacilityID(r.URL.Query().Get("facility")))
+	if err != nil {
+		log.WithError(err).Error("Error getting broadcasts")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	user := middleware.GetCredentials(r).User
+	manageable := []models.Broadcast{}
+	for _, b := range broadcasts {
+		if utils.CanBroadcast(user, &b) {
+			manageable = append(manageable, b)
+		}
+	}
+
+	if err := render.RenderList(w, r, NewBroadcastListResponse(manageable)); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// GetBroadcast godoc
+// @Summary Get a broadcast
+// @Description Get a broadcast
+// @Tags broadcast
+// @Accept  json
+// @Produce  json
+// @Param BroadcastID path int true "Broadcast ID"
+// @Success 200 {object} Response
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Router /broadcasts/{BroadcastID} [get]
+func GetBroadcast(w http.ResponseWriter, r *http.Request) {
+	utils.Render(w, r, NewBroadcastResponse(utils.GetBroadcastCtx(r)))
+}
+
+// UpdateBroadcast godoc
+// @Summary Update a broadcast
+// @Description Update a broadcast. Users that already read it keep their read state.
+// @Tags broadcast
+// @Accept  json
+// @Produce  json
+// @Param BroadcastID path int true "Broadcast ID"
+// @Param broadcast body Request true "Broadcast"
+// @Success 200 {object} Response
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /broadcasts/{BroadcastID} [put]
+func UpdateBroadcast(w http.ResponseWriter, r *http.Request) {
+	data := &Request{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	b := utils.GetBroadcastCtx(r)
+	data.apply(b)
+
+	// The new target has to be one the user can broadcast to as well
+	if !utils.CanBroadcast(middleware.GetCredentials(r).User, b) {
+		utils.Render(w, r, utils.ErrForbidden)
+		return
+	}
+
+	if err := b.Update(); err != nil {
+		log.WithError(err).Errorf("Error updating broadcast %d", b.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, NewBroadcastResponse(b))
+}
+
+// DeleteBroadcast godoc
+// @Summary Delete a broadcast
+// @Description Delete a broadcast along with its read receipts
+// @Tags broadcast
+// @Accept  json
+// @Produce  json
+// @Param BroadcastID path int true "Broadcast ID"
+// @Success 204
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /broadcasts/{BroadcastID} [delete]
+func DeleteBroadcast(w http.ResponseWriter, r *http.Request) {
+	b := utils.GetBroadcastCtx(r)
+	if err := b.Delete(); err != nil {
+		log.WithError(err).Errorf("Error deleting broadcast %d", b.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
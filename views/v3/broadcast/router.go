@@ -0,0 +1,54 @@
+package broadcast
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+func Router(r chi.Router) {
+	r.Use(middleware.NotGuest, middleware.CanSendBroadcasts)
+
+	r.Get("/", ListBroadcasts)
+	r.Post("/", CreateBroadcast)
+
+	r.Route("/{BroadcastID}", func(r chi.Router) {
+		r.Use(Ctx, middleware.CanEditBroadcast)
+
+		r.Get("/", GetBroadcast)
+		r.Put("/", UpdateBroadcast)
+		r.Delete("/", DeleteBroadcast)
+	})
+}
+
+// UserRouter serves a user's own broadcasts, it is mounted under the user's routes
+func UserRouter(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadNotificationsScope, constants.WriteNotificationsScope), middleware.NotGuest, middleware.CanViewNotifications)
+
+	r.Get("/", ListUserBroadcasts)
+	r.Post("/read", ReadUserBroadcasts)
+	r.Post("/dismiss", DismissUserBroadcasts)
+}
+
+func Ctx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "BroadcastID"), 10, 64)
+		if err != nil {
+			utils.Render(w, r, utils.ErrBadRequest)
+			return
+		}
+
+		b := &models.Broadcast{ID: uint(id)}
+		if err := b.Get(); err != nil {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		ctx := utils.WithResource(r, utils.BroadcastKey{}, b)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
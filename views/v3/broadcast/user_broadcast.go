@@ -0,0 +1,158 @@
+package broadcast
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type ReceiptRequest struct {
+	IDs []uint `json:"ids" example:"1,2,3"`
+	All bool   `json:"all" example:"false"`
+}
+
+func (req *ReceiptRequest) Validate() error {
+	if len(req.IDs) == 0 && !req.All {
+		return errors.New("ids or all is required")
+	}
+	return nil
+}
+
+func (req *ReceiptRequest) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+type UserResponse struct {
+	*models.UserBroadcast
+}
+
+func (res *UserResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.UserBroadcast == nil {
+		return errors.New("broadcast not found")
+	}
+	return nil
+}
+
+// ListUserBroadcasts godoc
+// @Summary List a user's broadcasts
+// @Description List the live broadcasts the user is a recipient of and has not dismissed, with their read state
+// @Tags broadcast
+// @Accept  json
+// @Produce  json
+// @Param CID path int true "CID"
+// @Success 200 {object} []UserResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{CID}/broadcasts [get]
+func ListUserBroadcasts(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserCtx(r)
+
+	broadcasts, err := models.GetUserBroadcasts(user)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting broadcasts for user %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	list := []render.Renderer{}
+	for idx := range broadcasts {
+		list = append(list, &UserResponse{UserBroadcast: &broadcasts[idx]})
+	}
+
+	if err := render.RenderList(w, r, list); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// ReadUserBroadcasts godoc
+// @Summary Mark broadcasts read
+// @Description Mark several of the user's broadcasts read, or all of them with all=true
+// @Tags broadcast
+// @Accept  json
+// @Produce  json
+// @Param CID path int true "CID"
+// @Param receipt body ReceiptRequest true "Broadcasts to mark read"
+// @Success 204
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{CID}/broadcasts/read [post]
+func ReadUserBroadcasts(w http.ResponseWriter, r *http.Request) {
+	updateReceipts(w, r, models.MarkBroadcastsRead)
+}
+
+// DismissUserBroadcasts godoc
+// @Summary Dismiss broadcasts
+// @Description Dismiss several of the user's broadcasts, or all of them with all=true
+// @Tags broadcast
+// @Accept  json
+// @Produce  json
+// @Param CID path int true "CID"
+// @Param receipt body ReceiptRequest true "Broadcasts to dismiss"
+// @Success 204
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{CID}/broadcasts/dismiss [post]
+func DismissUserBroadcasts(w http.ResponseWriter, r *http.Request) {
+	updateReceipts(w, r, models.DismissBroadcasts)
+}
+
+func updateReceipts(w http.ResponseWriter, r *http.Request, update func(cid uint, ids []uint) error) {
+	data := &ReceiptRequest{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	user := utils.GetUserCtx(r)
+	ids, err := recipientBroadcastIDs(user, data)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting broadcasts for user %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	if err := update(user.CID, ids); err != nil {
+		log.WithError(err).Errorf("Error updating broadcast receipts for user %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// recipientBroadcastIDs limits the requested broadcasts to the live ones the user is a recipient of, so receipts
+// are never created for other broadcasts
+func recipientBroadcastIDs(user *models.User, data *ReceiptRequest) ([]uint, error) {
+	broadcasts, err := models.GetUserBroadcasts(user)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := map[uint]bool{}
+	for _, id := range data.IDs {
+		requested[id] = true
+	}
+
+	var ids []uint
+	for _, b := range broadcasts {
+		if data.All || requested[b.ID] {
+			ids = append(ids, b.ID)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,159 @@
+package disciplinary_log
+
+import (
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/storage"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	gonanoid "github.com/matoous/go-nanoid"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxAttachmentSize is the largest file that can be attached to an entry
+const maxAttachmentSize = 10 << 20
+
+type AttachmentResponse struct {
+	*models.DisciplinaryAttachment
+}
+
+func (res *AttachmentResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// CreateAttachment godoc
+// @Summary Attach a file to a disciplinary log entry
+// @Description Upload a file, at most 10 MB, as the multipart form field file. Attachments are stored privately and
+// @Description can only be downloaded by those who can see the entry.
+// @Tags disciplinary-log
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param cid path int true "User CID"
+// @Param id path int true "Disciplinary Log Entry ID"
+// @Param file formData file true "Attachment"
+// @Success 201 {object} AttachmentResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{cid}/disciplinary-log/{id}/attachments [post]
+func CreateAttachment(w http.ResponseWriter, r *http.Request) {
+	dle := utils.GetDisciplinaryLogCtx(r)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+(1<<20))
+	if err := r.ParseMultipartForm(maxAttachmentSize); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(fmt.Errorf("attachments must be uploaded as the multipart field file, at most 10 MB: %w", err)))
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxAttachmentSize {
+		utils.Render(w, r, utils.ErrInvalidRequest(fmt.Errorf("attachments can be at most 10 MB")))
+		return
+	}
+
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	id, err := gonanoid.Nanoid()
+	if err != nil {
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+	filename := filepath.Base(header.Filename)
+	key := path.Join(fmt.Sprint(dle.CID), fmt.Sprint(dle.ID), id+strings.ToLower(filepath.Ext(filename)))
+
+	if err := storage.PrivateBucket.Upload(models.DisciplinaryAttachmentDirectory, key, file); err != nil {
+		log.WithError(err).Errorf("Error uploading attachment for disciplinary log entry %d", dle.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	attachment := &models.DisciplinaryAttachment{
+		EntryID:     dle.ID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        header.Size,
+		StorageKey:  key,
+		CreatedBy:   utils.GetPrincipal(r).Name(),
+	}
+	if err := attachment.Create(); err != nil {
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	utils.Render(w, r, &AttachmentResponse{DisciplinaryAttachment: attachment})
+}
+
+// DownloadAttachment godoc
+// @Summary Download a disciplinary log attachment
+// @Description Download a file attached to a disciplinary log entry
+// @Tags disciplinary-log
+// @Produce  octet-stream
+// @Param cid path int true "User CID"
+// @Param id path int true "Disciplinary Log Entry ID"
+// @Param AttachmentID path int true "Attachment ID"
+// @Success 200 {file} file
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{cid}/disciplinary-log/{id}/attachments/{AttachmentID} [get]
+func DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment := utils.GetDisciplinaryAttachmentCtx(r)
+
+	body, err := storage.PrivateBucket.Download(models.DisciplinaryAttachmentDirectory, attachment.StorageKey)
+	if err != nil {
+		log.WithError(err).Errorf("Error downloading disciplinary attachment %d", attachment.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, body); err != nil {
+		log.WithError(err).Errorf("Error sending disciplinary attachment %d", attachment.ID)
+	}
+}
+
+// DeleteAttachment godoc
+// @Summary Delete a disciplinary log attachment
+// @Description Delete a file attached to a disciplinary log entry
+// @Tags disciplinary-log
+// @Param cid path int true "User CID"
+// @Param id path int true "Disciplinary Log Entry ID"
+// @Param AttachmentID path int true "Attachment ID"
+// @Success 204
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{cid}/disciplinary-log/{id}/attachments/{AttachmentID} [delete]
+func DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment := utils.GetDisciplinaryAttachmentCtx(r)
+
+	if err := storage.PrivateBucket.Delete(models.DisciplinaryAttachmentDirectory, attachment.StorageKey); err != nil {
+		log.WithError(err).Errorf("Error deleting disciplinary attachment %d from storage", attachment.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	if err := attachment.Delete(); err != nil {
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Response(r, http.StatusNoContent)
+}
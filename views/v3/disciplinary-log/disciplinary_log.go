@@ -2,21 +2,54 @@ package disciplinary_log
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/storage"
 	"github.com/VATUSA/primary-api/pkg/utils"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
 	"net/http"
+	"slices"
+	"time"
 )
 
 type Request struct {
-	Entry      string `json:"entry" example:"Misconduct in discord" validate:"required"`
-	VATUSAOnly bool   `json:"vatusa_only" example:"true"`
+	Entry string                 `json:"entry" example:"Misconduct in discord" validate:"required"`
+	Type  types.DisciplinaryType `json:"type" example:"suspension"`
+	// Visibility defaults to senior_staff, or vatusa when the deprecated vatusa_only is set
+	Visibility types.DisciplinaryVisibility `json:"visibility" example:"senior_staff"`
+	VATUSAOnly bool                         `json:"vatusa_only" example:"false"`
+	// Restriction is imposed on the user's flags when a suspension or removal is created, and cannot be changed later
+	Restriction    types.UserRestriction `json:"restriction" example:"no_staff_role"`
+	EffectiveFrom  *time.Time            `json:"effective_from" example:"2021-01-01T00:00:00Z"`
+	EffectiveUntil *time.Time            `json:"effective_until" example:"2021-02-01T00:00:00Z"`
 }
 
 func (req *Request) Validate() error {
-	return validator.New().Struct(req)
+	if err := validator.New().Struct(req); err != nil {
+		return err
+	}
+
+	if req.Type != "" && !req.Type.IsValid() {
+		return errors.New("type must be one of note, warning, suspension or removal")
+	}
+	if req.Visibility != "" && !req.Visibility.IsValid() {
+		return errors.New("visibility must be one of vatusa, senior_staff or member")
+	}
+	if req.Restriction != "" {
+		if !req.Restriction.IsValid() {
+			return errors.New("invalid restriction")
+		}
+		if req.Type != types.DisciplinarySuspension && req.Type != types.DisciplinaryRemoval {
+			return errors.New("only suspensions and removals can impose a restriction")
+		}
+	}
+	if req.EffectiveFrom != nil && req.EffectiveUntil != nil && !req.EffectiveUntil.After(*req.EffectiveFrom) {
+		return errors.New("effective_until must be after effective_from")
+	}
+	return nil
 }
 
 func (req *Request) Bind(r *http.Request) error {
@@ -35,8 +68,8 @@ func NewDisciplinaryLogEntryResponse(dle *models.DisciplinaryLogEntry) *Response
 }
 
 func (res *Response) Render(w http.ResponseWriter, r *http.Request) error {
-	if res.DisciplinaryLogEntry == nil {
-		return nil
+	if res.DisciplinaryLogEntry != nil && res.Attachments == nil {
+		res.Attachments = []models.DisciplinaryAttachment{}
 	}
 	return nil
 }
@@ -49,9 +82,16 @@ func NewDisciplinaryLogEntryListResponse(dle []models.DisciplinaryLogEntry) []re
 	return list
 }
 
+// canSetVisibility checks the principal could see an entry with visibility v themselves
+func canSetVisibility(r *http.Request, v types.DisciplinaryVisibility) bool {
+	return slices.Contains(utils.GetPrincipal(r).DisciplinaryVisibilities(utils.GetUserCtx(r)), v)
+}
+
 // CreateDisciplinaryLogEntry godoc
 // @Summary Create a new disciplinary log entry
-// @Description Create a new disciplinary log entry
+// @Description Create a note or a disciplinary case. Suspensions and removals can impose a user flag restriction,
+// @Description which is lifted automatically at effective_until. Restrictions take effect immediately, so
+// @Description effective_from cannot be in the future for them.
 // @Tags disciplinary-log
 // @Accept  json
 // @Produce  json
@@ -59,6 +99,7 @@ func NewDisciplinaryLogEntryListResponse(dle []models.DisciplinaryLogEntry) []re
 // @Param action_log body Request true "Disciplinary Log Entry"
 // @Success 201 {object} Response
 // @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
 // @Failure 500 {object} utils.ErrResponse
 // @Router /user/{cid}/disciplinary-log [post]
 func CreateDisciplinaryLogEntry(w http.ResponseWriter, r *http.Request) {
@@ -80,21 +121,47 @@ func CreateDisciplinaryLogEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	createdBy := ""
-	if self := utils.GetXUser(r); self != nil {
-		createdBy = fmt.Sprintf("%d", self.CID)
-	} else {
-		createdBy = string(utils.GetXFacility(r).ID)
+	if data.Type == "" {
+		data.Type = types.DisciplinaryNote
+	}
+	if data.Visibility == "" {
+		data.Visibility = types.DisciplinarySeniorStaff
+		if data.VATUSAOnly {
+			data.Visibility = types.DisciplinaryVATUSAOnly
+		}
+	}
+	if !canSetVisibility(r, data.Visibility) {
+		utils.Render(w, r, utils.ErrForbidden)
+		return
+	}
+
+	now := time.Now()
+	if data.EffectiveFrom == nil && data.Type != types.DisciplinaryNote {
+		data.EffectiveFrom = &now
+	}
+	if data.Restriction != "" && data.EffectiveFrom.After(now) {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("restrictions take effect immediately, effective_from cannot be in the future")))
+		return
+	}
+	if data.Restriction != "" && data.EffectiveUntil != nil && !data.EffectiveUntil.After(now) {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("effective_until must be in the future")))
+		return
 	}
 
+	createdBy := utils.GetPrincipal(r).Name()
 	dle := &models.DisciplinaryLogEntry{
-		CID:        user.CID,
-		Entry:      data.Entry,
-		VATUSAOnly: data.VATUSAOnly,
-		CreatedBy:  createdBy,
+		CID:            user.CID,
+		Type:           data.Type,
+		Entry:          data.Entry,
+		Visibility:     data.Visibility,
+		Restriction:    data.Restriction,
+		EffectiveFrom:  data.EffectiveFrom,
+		EffectiveUntil: data.EffectiveUntil,
+		CreatedBy:      createdBy,
+		UpdatedBy:      createdBy,
 	}
 
-	if err := dle.Create(); err != nil {
+	if err := dle.CreateCase(); err != nil {
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
 	}
@@ -105,21 +172,37 @@ func CreateDisciplinaryLogEntry(w http.ResponseWriter, r *http.Request) {
 
 // GetDisciplinaryLog godoc
 // @Summary Get all disciplinary log entries
-// @Description List all disciplinary log entries
+// @Description List the disciplinary log entries the requester can see, newest first. Division staff see every
+// @Description entry, facility senior staff everything but VATUSA-only entries and members the entries made visible
+// @Description to them.
 // @Tags disciplinary-log
 // @Accept  json
 // @Produce  json
 // @Param cid path int true "User CID"
-// @Param vatusa_only query bool false "VATUSA Only"
+// @Param visibility query string false "Only entries with this visibility" Enums(vatusa, senior_staff, member)
+// @Param vatusa_only query bool false "Deprecated: use visibility=vatusa"
 // @Success 200 {object} []Response
+// @Failure 403 {object} utils.ErrResponse
 // @Failure 422 {object} utils.ErrResponse
 // @Failure 500 {object} utils.ErrResponse
 // @Router /user/{cid}/disciplinary-log [get]
 func GetDisciplinaryLog(w http.ResponseWriter, r *http.Request) {
-	vatusaOnly := r.URL.Query().Get("vatusa_only")
-
 	user := utils.GetUserCtx(r)
-	dle, err := models.GetAllDisciplinaryLogEntriesByCID(user.CID, vatusaOnly == "true")
+	visibilities := utils.GetPrincipal(r).DisciplinaryVisibilities(user)
+
+	filter := types.DisciplinaryVisibility(r.URL.Query().Get("visibility"))
+	if r.URL.Query().Get("vatusa_only") == "true" {
+		filter = types.DisciplinaryVATUSAOnly
+	}
+	if filter != "" {
+		if !slices.Contains(visibilities, filter) {
+			utils.Render(w, r, utils.ErrForbidden)
+			return
+		}
+		visibilities = []types.DisciplinaryVisibility{filter}
+	}
+
+	dle, err := models.GetDisciplinaryLogEntriesByCID(user.CID, visibilities)
 	if err != nil {
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
@@ -131,16 +214,35 @@ func GetDisciplinaryLog(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetDisciplinaryLogEntry godoc
+// @Summary Get a disciplinary log entry
+// @Description Get a disciplinary log entry with its attachments
+// @Tags disciplinary-log
+// @Accept  json
+// @Produce  json
+// @Param cid path int true "User CID"
+// @Param id path int true "Disciplinary Log Entry ID"
+// @Success 200 {object} Response
+// @Failure 404 {object} utils.ErrResponse
+// @Router /user/{cid}/disciplinary-log/{id} [get]
+func GetDisciplinaryLogEntry(w http.ResponseWriter, r *http.Request) {
+	utils.Render(w, r, NewDisciplinaryLogEntryResponse(utils.GetDisciplinaryLogCtx(r)))
+}
+
 // UpdateDisciplinaryLogEntry godoc
 // @Summary Update a disciplinary log entry
-// @Description Update a disciplinary log entry
+// @Description Update a disciplinary log entry. The restriction cannot be changed, lift it and open a new case
+// @Description instead. Moving effective_until into the past lifts the restriction on the next run of the lifting
+// @Description job.
 // @Tags disciplinary-log
 // @Accept  json
 // @Produce  json
+// @Param cid path int true "User CID"
 // @Param id path int true "Disciplinary Log Entry ID"
 // @Param disciplinary_log body Request true "Disciplinary Log Entry"
 // @Success 200 {object} Response
 // @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
 // @Failure 404 {object} utils.ErrResponse
 // @Failure 500 {object} utils.ErrResponse
 // @Router /user/{cid}/disciplinary-log/{id} [put]
@@ -158,14 +260,31 @@ func UpdateDisciplinaryLogEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dle.Entry = data.Entry
-	dle.VATUSAOnly = data.VATUSAOnly
+	if data.Restriction != dle.Restriction {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("the restriction of an entry cannot be changed")))
+		return
+	}
 
-	if self := utils.GetXUser(r); self != nil {
-		dle.UpdatedBy = fmt.Sprintf("%d", self.CID)
-	} else {
-		dle.UpdatedBy = string(utils.GetXFacility(r).ID)
+	if data.Type == "" {
+		data.Type = dle.Type
 	}
+	if data.Visibility == "" {
+		data.Visibility = types.DisciplinarySeniorStaff
+		if data.VATUSAOnly {
+			data.Visibility = types.DisciplinaryVATUSAOnly
+		}
+	}
+	if !canSetVisibility(r, data.Visibility) {
+		utils.Render(w, r, utils.ErrForbidden)
+		return
+	}
+
+	dle.Entry = data.Entry
+	dle.Type = data.Type
+	dle.Visibility = data.Visibility
+	dle.EffectiveFrom = data.EffectiveFrom
+	dle.EffectiveUntil = data.EffectiveUntil
+	dle.UpdatedBy = utils.GetPrincipal(r).Name()
 
 	if err := dle.Update(); err != nil {
 		utils.Render(w, r, utils.ErrInternalServer)
@@ -177,7 +296,7 @@ func UpdateDisciplinaryLogEntry(w http.ResponseWriter, r *http.Request) {
 
 // PatchDisciplinaryLogEntry godoc
 // @Summary Patch an disciplinary log entry
-// @Description Patch an disciplinary log entry
+// @Description Patch an disciplinary log entry. The restriction cannot be changed.
 // @Tags disciplinary-log
 // @Accept  json
 // @Produce  json
@@ -186,6 +305,7 @@ func UpdateDisciplinaryLogEntry(w http.ResponseWriter, r *http.Request) {
 // @Param disciplinary_log body Request true "Disciplinary Log Entry"
 // @Success 200 {object} Response
 // @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
 // @Failure 404 {object} utils.ErrResponse
 // @Failure 500 {object} utils.ErrResponse
 // @Router /user/{cid}/disciplinary-log/{id} [patch]
@@ -198,18 +318,49 @@ func PatchDisciplinaryLogEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if data.Restriction != "" && data.Restriction != dle.Restriction {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("the restriction of an entry cannot be changed")))
+		return
+	}
+
+	if data.VATUSAOnly && data.Visibility == "" {
+		data.Visibility = types.DisciplinaryVATUSAOnly
+	}
+
 	if data.Entry != "" {
 		dle.Entry = data.Entry
 	}
-	if data.VATUSAOnly {
-		dle.VATUSAOnly = data.VATUSAOnly
+	if data.Type != "" {
+		dle.Type = data.Type
+	}
+	if data.Visibility != "" {
+		dle.Visibility = data.Visibility
+	}
+	if data.EffectiveFrom != nil {
+		dle.EffectiveFrom = data.EffectiveFrom
+	}
+	if data.EffectiveUntil != nil {
+		dle.EffectiveUntil = data.EffectiveUntil
 	}
 
-	if self := utils.GetXUser(r); self != nil {
-		dle.UpdatedBy = fmt.Sprintf("%d", self.CID)
-	} else {
-		dle.UpdatedBy = string(utils.GetXFacility(r).ID)
+	patched := &Request{
+		Entry:          dle.Entry,
+		Type:           dle.Type,
+		Visibility:     dle.Visibility,
+		Restriction:    dle.Restriction,
+		EffectiveFrom:  dle.EffectiveFrom,
+		EffectiveUntil: dle.EffectiveUntil,
+	}
+	if err := patched.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
 	}
+	if !canSetVisibility(r, dle.Visibility) {
+		utils.Render(w, r, utils.ErrForbidden)
+		return
+	}
+
+	dle.UpdatedBy = utils.GetPrincipal(r).Name()
 
 	if err := dle.Update(); err != nil {
 		utils.Render(w, r, utils.ErrInternalServer)
@@ -221,7 +372,8 @@ func PatchDisciplinaryLogEntry(w http.ResponseWriter, r *http.Request) {
 
 // DeleteDisciplinaryLogEntry godoc
 // @Summary Delete a disciplinary log entry
-// @Description Delete a disciplinary log entry
+// @Description Delete a disciplinary log entry and its attachments. Entries with a restriction still in place have
+// @Description to be lifted first.
 // @Tags disciplinary-log
 // @Accept  json
 // @Produce  json
@@ -229,11 +381,28 @@ func PatchDisciplinaryLogEntry(w http.ResponseWriter, r *http.Request) {
 // @Param id path int true "Disciplinary Log Entry ID"
 // @Success 204
 // @Failure 400 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
 // @Failure 500 {object} utils.ErrResponse
 // @Router /user/{cid}/disciplinary-log/{id} [delete]
 func DeleteDisciplinaryLogEntry(w http.ResponseWriter, r *http.Request) {
 	dle := utils.GetDisciplinaryLogCtx(r)
 
+	if dle.HasActiveRestriction() {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("lift the entry's restriction before deleting it")))
+		return
+	}
+
+	for idx := range dle.Attachments {
+		attachment := &dle.Attachments[idx]
+		if err := storage.PrivateBucket.Delete(models.DisciplinaryAttachmentDirectory, attachment.StorageKey); err != nil {
+			log.WithError(err).Errorf("Error deleting disciplinary attachment %d from storage", attachment.ID)
+		}
+		if err := attachment.Delete(); err != nil {
+			utils.Render(w, r, utils.ErrInternalServer)
+			return
+		}
+	}
+
 	if err := dle.Delete(); err != nil {
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
@@ -241,3 +410,34 @@ func DeleteDisciplinaryLogEntry(w http.ResponseWriter, r *http.Request) {
 
 	render.Status(r, http.StatusNoContent)
 }
+
+// LiftRestriction godoc
+// @Summary Lift a case's restriction
+// @Description Lift the restriction a case imposed before its effective_until. A note recording it is written to the
+// @Description disciplinary log and returned.
+// @Tags disciplinary-log
+// @Accept  json
+// @Produce  json
+// @Param cid path int true "User CID"
+// @Param id path int true "Disciplinary Log Entry ID"
+// @Success 201 {object} Response
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{cid}/disciplinary-log/{id}/lift [post]
+func LiftRestriction(w http.ResponseWriter, r *http.Request) {
+	dle := utils.GetDisciplinaryLogCtx(r)
+
+	note, err := dle.LiftRestriction(utils.GetPrincipal(r).Name())
+	if errors.Is(err, models.ErrRestrictionLifted) {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+	if err != nil {
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	utils.Render(w, r, NewDisciplinaryLogEntryResponse(note))
+}
@@ -1,7 +1,7 @@
 package disciplinary_log
 
 import (
-	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
@@ -11,15 +11,28 @@ import (
 )
 
 func Router(r chi.Router) {
-	r.With(middleware.NotGuest, middleware.CanReadDisciplinaryLog).Get("/", GetDisciplinaryLog)
-	r.With(middleware.NotGuest, middleware.CanEditDisciplinaryLog).Post("/", CreateDisciplinaryLogEntry)
+	r.With(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope), middleware.NotGuest, middleware.CanReadDisciplinaryLog).Get("/", GetDisciplinaryLog)
+	r.With(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope), middleware.NotGuest, middleware.CanEditDisciplinaryLog).Post("/", CreateDisciplinaryLogEntry)
 
 	r.Route("/{DisciplinaryLogID}", func(r chi.Router) {
-		r.Use(Ctx)
+		r.Use(Ctx, middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope), middleware.NotGuest, middleware.CanReadDisciplinaryLogEntry)
 
-		r.With(middleware.NotGuest, middleware.CanEditDisciplinaryLog).Patch("/", PatchDisciplinaryLogEntry)
-		r.With(middleware.NotGuest, middleware.CanEditDisciplinaryLog).Put("/", UpdateDisciplinaryLogEntry)
-		r.With(middleware.NotGuest, middleware.CanEditDisciplinaryLog).Delete("/", DeleteDisciplinaryLogEntry)
+		r.Get("/", GetDisciplinaryLogEntry)
+		r.With(middleware.CanEditDisciplinaryLog).Patch("/", PatchDisciplinaryLogEntry)
+		r.With(middleware.CanEditDisciplinaryLog).Put("/", UpdateDisciplinaryLogEntry)
+		r.With(middleware.CanEditDisciplinaryLog).Delete("/", DeleteDisciplinaryLogEntry)
+		r.With(middleware.CanEditDisciplinaryLog).Post("/lift", LiftRestriction)
+
+		r.Route("/attachments", func(r chi.Router) {
+			r.With(middleware.CanEditDisciplinaryLog).Post("/", CreateAttachment)
+
+			r.Route("/{AttachmentID}", func(r chi.Router) {
+				r.Use(AttachmentCtx)
+
+				r.Get("/", DownloadAttachment)
+				r.With(middleware.CanEditDisciplinaryLog).Delete("/", DeleteAttachment)
+			})
+		})
 	})
 }
 
@@ -38,12 +51,31 @@ func Ctx(next http.Handler) http.Handler {
 		}
 
 		disciplinaryLog := &models.DisciplinaryLogEntry{ID: uint(DisciplinaryLogID)}
-		if err = disciplinaryLog.Get(); err != nil {
-			http.Error(w, "Invalid request", http.StatusBadRequest)
+		if err = disciplinaryLog.Get(); err != nil || disciplinaryLog.CID != utils.GetUserCtx(r).CID {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		ctx := utils.WithResource(r, utils.DleKey{}, disciplinaryLog)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func AttachmentCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "AttachmentID"), 10, 64)
+		if err != nil {
+			utils.Render(w, r, utils.ErrBadRequest)
+			return
+		}
+
+		attachment := &models.DisciplinaryAttachment{ID: uint(id)}
+		if err := attachment.Get(); err != nil || attachment.EntryID != utils.GetDisciplinaryLogCtx(r).ID {
+			utils.Render(w, r, utils.ErrNotFound)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.DleKey{}, disciplinaryLog)
+		ctx := utils.WithResource(r, utils.DisciplinaryAttachmentKey{}, attachment)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,266 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/config"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/discord"
+	"gorm.io/gorm"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	embedColor          = 0x2B4E8C
+	maxEmbedDescription = 4096
+	maxEventsListed     = 10
+)
+
+type command struct {
+	Definition discord.ApplicationCommand
+	Run        func(ctx context.Context, i *discord.Interaction) (*discord.MessageData, error)
+}
+
+var commandOrder = []string{"whois", "roster", "events", "feedback"}
+
+var commands = map[string]command{
+	"whois": {
+		Definition: discord.ApplicationCommand{
+			Name:        "whois",
+			Description: "Look up a VATUSA controller",
+			Options: []discord.ApplicationCommandOption{
+				{Type: discord.OptionInteger, Name: "cid", Description: "VATSIM CID"},
+				{Type: discord.OptionUser, Name: "user", Description: "Discord user"},
+			},
+		},
+		Run: whois,
+	},
+	"roster": {
+		Definition: discord.ApplicationCommand{
+			Name:        "roster",
+			Description: "List a facility's roster",
+			Options: []discord.ApplicationCommandOption{
+				{Type: discord.OptionString, Name: "facility", Description: "Facility ID, e.g. ZDV", Required: true},
+			},
+		},
+		Run: roster,
+	},
+	"events": {
+		Definition: discord.ApplicationCommand{
+			Name:        "events",
+			Description: "List upcoming events",
+			Options: []discord.ApplicationCommandOption{
+				{Type: discord.OptionString, Name: "facility", Description: "Facility ID, e.g. ZDV"},
+			},
+		},
+		Run: events,
+	},
+	"feedback": {
+		Definition: discord.ApplicationCommand{
+			Name:        "feedback",
+			Description: "Get a link to leave controller feedback",
+			Options: []discord.ApplicationCommandOption{
+				{Type: discord.OptionString, Name: "facility", Description: "Facility ID, e.g. ZDV"},
+				{Type: discord.OptionInteger, Name: "cid", Description: "Controller CID"},
+			},
+		},
+		Run: feedback,
+	},
+}
+
+func whois(_ context.Context, i *discord.Interaction) (*discord.MessageData, error) {
+	user := &models.User{CID: i.Data.Uint("cid")}
+	if user.CID == 0 {
+		discordID := i.Data.String("user")
+		if discordID == "" && i.Invoker() != nil {
+			discordID = i.Invoker().ID
+		}
+		if discordID == "" {
+			return ephemeral("Provide a CID or a Discord user."), nil
+		}
+
+		// Looking users up by Discord ID does not load their roster, so load them again by CID below
+		linked := &models.User{DiscordID: discordID}
+		if err := linked.Get(); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ephemeral("That Discord user has not linked a VATUSA account."), nil
+			}
+			return nil, err
+		}
+		user.CID = linked.CID
+	}
+
+	if err := user.Get(); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ephemeral(fmt.Sprintf("No VATUSA user found with CID %d.", user.CID)), nil
+		}
+		return nil, err
+	}
+
+	var home, visiting, roles []string
+	for _, r := range user.Roster {
+		if r.Home {
+			home = append(home, string(r.Facility))
+		}
+		if r.Visiting {
+			visiting = append(visiting, string(r.Facility))
+		}
+		for _, role := range r.Roles {
+			roles = append(roles, fmt.Sprintf("%s %s", r.Facility, role.RoleID.DisplayName()))
+		}
+	}
+
+	return &discord.MessageData{Embeds: []discord.Embed{{
+		Title: fmt.Sprintf("%s (%d)", displayName(user), user.CID),
+		Color: embedColor,
+		Fields: []discord.EmbedField{
+			{Name: "Rating", Value: user.ControllerRating.Long(), Inline: true},
+			{Name: "Home Facility", Value: orNone(home), Inline: true},
+			{Name: "Visiting", Value: orNone(visiting), Inline: true},
+			{Name: "Roles", Value: orNone(roles)},
+		},
+	}}}, nil
+}
+
+func roster(_ context.Context, i *discord.Interaction) (*discord.MessageData, error) {
+	facility := constants.FacilityID(strings.ToUpper(i.Data.String("facility")))
+	if !facility.IsValidFacility() {
+		return ephemeral(fmt.Sprintf("Unknown facility %s.", facility)), nil
+	}
+
+	rosters, err := models.GetRostersByFacility(facility)
+	if err != nil {
+		return nil, err
+	}
+
+	cids := make([]uint, 0, len(rosters))
+	for _, r := range rosters {
+		cids = append(cids, r.CID)
+	}
+	users, err := models.GetUsersByCIDs(cids)
+	if err != nil {
+		return nil, err
+	}
+	byCID := map[uint]*models.User{}
+	for idx := range users {
+		byCID[users[idx].CID] = &users[idx]
+	}
+
+	var home, visiting []string
+	for _, r := range rosters {
+		user, ok := byCID[r.CID]
+		if !ok {
+			continue
+		}
+		line := fmt.Sprintf("%s (%d) - %s", displayName(user), user.CID, user.ControllerRating.Short())
+		if r.Home {
+			home = append(home, line)
+		} else if r.Visiting {
+			visiting = append(visiting, line)
+		}
+	}
+	sort.Strings(home)
+	sort.Strings(visiting)
+
+	description := fmt.Sprintf("**Home (%d)**\n%s\n\n**Visiting (%d)**\n%s", len(home), orNone(home), len(visiting), orNone(visiting))
+	return &discord.MessageData{Embeds: []discord.Embed{{
+		Title:       fmt.Sprintf("%s Roster", facility.DisplayName()),
+		URL:         websiteURL(fmt.Sprintf("/facility/%s/roster", facility), nil),
+		Description: truncate(description, maxEmbedDescription),
+		Color:       embedColor,
+	}}}, nil
+}
+
+func events(_ context.Context, i *discord.Interaction) (*discord.MessageData, error) {
+	facility := constants.FacilityID(strings.ToUpper(i.Data.String("facility")))
+	if facility != "" && !facility.IsValidFacility() {
+		return ephemeral(fmt.Sprintf("Unknown facility %s.", facility)), nil
+	}
+
+	upcoming, err := models.GetEventsFiltered(1, 100, facility, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(upcoming, func(a, b int) bool { return upcoming[a].StartDate.Before(upcoming[b].StartDate) })
+	if len(upcoming) > maxEventsListed {
+		upcoming = upcoming[:maxEventsListed]
+	}
+
+	title := "Upcoming VATUSA Events"
+	if facility != "" {
+		title = fmt.Sprintf("Upcoming %s Events", facility.DisplayName())
+	}
+
+	embed := discord.Embed{Title: title, Color: embedColor}
+	if len(upcoming) == 0 {
+		embed.Description = "No upcoming events."
+	}
+	for _, ev := range upcoming {
+		// Discord renders <t:unix:f> in each reader's own time zone
+		embed.Fields = append(embed.Fields, discord.EmbedField{
+			Name:  ev.Title,
+			Value: fmt.Sprintf("<t:%d:f> - <t:%d:t>\n%s", ev.StartDate.Unix(), ev.EndDate.Unix(), strings.Join(ev.Fields, ", ")),
+		})
+	}
+
+	return &discord.MessageData{Embeds: []discord.Embed{embed}}, nil
+}
+
+func feedback(_ context.Context, i *discord.Interaction) (*discord.MessageData, error) {
+	query := url.Values{}
+	facility := constants.FacilityID(strings.ToUpper(i.Data.String("facility")))
+	if facility != "" {
+		if !facility.IsValidFacility() {
+			return ephemeral(fmt.Sprintf("Unknown facility %s.", facility)), nil
+		}
+		query.Set("facility", string(facility))
+	}
+	if cid := i.Data.Uint("cid"); cid != 0 {
+		query.Set("controller", fmt.Sprintf("%d", cid))
+	}
+
+	return &discord.MessageData{
+		Content: fmt.Sprintf("Leave feedback for VATUSA controllers here: %s", websiteURL("/feedback", query)),
+	}, nil
+}
+
+func displayName(user *models.User) string {
+	first := user.FirstName
+	if user.PrefNameEnabled && user.PreferredName != "" {
+		first = user.PreferredName
+	}
+	return strings.TrimSpace(first + " " + user.LastName)
+}
+
+func websiteURL(path string, query url.Values) string {
+	u := strings.TrimRight(config.Cfg.Discord.WebsiteURL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+func orNone(values []string) string {
+	if len(values) == 0 {
+		return "None"
+	}
+	return strings.Join(values, "\n")
+}
+
+// truncate shortens s to at most limit bytes without splitting a line
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	const more = "\n..."
+	cut := strings.LastIndex(s[:limit-len(more)], "\n")
+	if cut < 0 {
+		cut = limit - len(more)
+	}
+	return s[:cut] + more
+}
@@ -0,0 +1,99 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/VATUSA/primary-api/pkg/config"
+	"github.com/VATUSA/primary-api/pkg/discord"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+)
+
+// maxInteractionSize bounds the request body read before its signature is checked
+const maxInteractionSize = 1 << 20
+
+// HandleInteraction godoc
+// @Summary Discord interactions
+// @Description Interactions endpoint of the VATUSA Discord application. Requests must be signed by Discord, slash
+// @Description commands are answered from VATUSA data.
+// @Tags discord
+// @Accept  json
+// @Produce  json
+// @Param X-Signature-Ed25519 header string true "Signature"
+// @Param X-Signature-Timestamp header string true "Timestamp"
+// @Success 200 {object} discord.InteractionResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 401 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /discord/interactions [post]
+func HandleInteraction(w http.ResponseWriter, r *http.Request) {
+	publicKey, err := discord.ParsePublicKey(config.Cfg.Discord.PublicKey)
+	if err != nil {
+		log.WithError(err).Error("Discord interactions are not configured")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxInteractionSize))
+	if err != nil {
+		utils.Render(w, r, utils.ErrBadRequest)
+		return
+	}
+
+	if !discord.VerifySignature(publicKey, r.Header.Get("X-Signature-Ed25519"), r.Header.Get("X-Signature-Timestamp"), body) {
+		utils.Render(w, r, utils.ErrUnauthorized)
+		return
+	}
+
+	interaction := &discord.Interaction{}
+	if err := json.Unmarshal(body, interaction); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	switch interaction.Type {
+	case discord.InteractionPing:
+		render.JSON(w, r, &discord.InteractionResponse{Type: discord.ResponsePong})
+	case discord.InteractionApplicationCommand:
+		if interaction.Data == nil {
+			utils.Render(w, r, utils.ErrBadRequest)
+			return
+		}
+		render.JSON(w, r, &discord.InteractionResponse{
+			Type: discord.ResponseChannelMessage,
+			Data: runCommand(r.Context(), interaction),
+		})
+	default:
+		utils.Render(w, r, utils.ErrBadRequest)
+	}
+}
+
+func runCommand(ctx context.Context, interaction *discord.Interaction) *discord.MessageData {
+	command, ok := commands[interaction.Data.Name]
+	if !ok {
+		return ephemeral("Unknown command.")
+	}
+
+	msg, err := command.Run(ctx, interaction)
+	if err != nil {
+		log.WithError(err).Errorf("Error running Discord command /%s", interaction.Data.Name)
+		return ephemeral("Something went wrong, please try again later.")
+	}
+	return msg
+}
+
+func ephemeral(content string) *discord.MessageData {
+	return &discord.MessageData{Content: content, Flags: discord.FlagEphemeral}
+}
+
+// RegisterCommands registers the slash commands with Discord so they show up in every guild the application is in
+func RegisterCommands(ctx context.Context, client discord.Client, applicationID string) error {
+	definitions := make([]discord.ApplicationCommand, 0, len(commands))
+	for _, name := range commandOrder {
+		definitions = append(definitions, commands[name].Definition)
+	}
+	return client.RegisterCommands(ctx, applicationID, definitions)
+}
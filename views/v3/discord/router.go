@@ -0,0 +1,9 @@
+package discord
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func Router(r chi.Router) {
+	r.Post("/interactions", HandleInteraction)
+}
@@ -0,0 +1,247 @@
+package email_template
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/notify"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type Request struct {
+	Subject string `json:"subject" example:"{{.Data.event}} assignment"`
+	Text    string `json:"text" example:"You are working {{.Data.position}}."`
+	HTML    string `json:"html" example:"<p>You are working {{.Data.position}}.</p>"`
+}
+
+func (req *Request) Validate() error {
+	if req.Subject == "" && req.Text == "" && req.HTML == "" {
+		return errors.New("at least one of subject, text or html is required")
+	}
+	return nil
+}
+
+func (req *Request) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+type Response struct {
+	notify.EmailTemplate
+	Default  *models.EmailTemplate `json:"default,omitempty"`
+	Override *models.EmailTemplate `json:"override"`
+}
+
+func NewEmailTemplateResponse(t notify.EmailTemplate, override *models.EmailTemplate) *Response {
+	res := &Response{EmailTemplate: t}
+	if override != nil && override.ID != 0 {
+		res.Override = override
+	}
+	return res
+}
+
+func (res *Response) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+type PreviewResponse struct {
+	*notify.Email
+}
+
+func (res *PreviewResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.Email == nil {
+		return errors.New("missing required email")
+	}
+	return nil
+}
+
+// ListEmailTemplates godoc
+// @Summary List email templates
+// @Description List the system email templates along with the facility's overrides
+// @Tags email-template
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Success 200 {object} []Response
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/email-templates [get]
+func ListEmailTemplates(w http.ResponseWriter, r *http.Request) {
+	fac := utils.GetFacilityCtx(r)
+
+	overrides, err := models.GetEmailTemplatesByFacility(fac.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting email templates for %s", fac.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	byName := map[string]*models.EmailTemplate{}
+	for idx := range overrides {
+		byName[overrides[idx].Name] = &overrides[idx]
+	}
+
+	list := []render.Renderer{}
+	for _, t := range notify.EmailTemplates() {
+		list = append(list, NewEmailTemplateResponse(t, byName[t.Name]))
+	}
+
+	if err := render.RenderList(w, r, list); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// GetEmailTemplate godoc
+// @Summary Get an email template
+// @Description Get the default source of a system email template along with the facility's override
+// @Tags email-template
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param EmailTemplateName path string true "Email Template Name"
+// @Success 200 {object} Response
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/email-templates/{EmailTemplateName} [get]
+func GetEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	et := utils.GetEmailTemplateCtx(r)
+	t, _ := notify.GetEmailTemplate(et.Name)
+
+	def, err := notify.DefaultEmailTemplate(et.Name)
+	if err != nil {
+		log.WithError(err).Errorf("Error loading default email template %s", et.Name)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	res := NewEmailTemplateResponse(t, et)
+	res.Default = def
+	utils.Render(w, r, res)
+}
+
+// UpdateEmailTemplate godoc
+// @Summary Override an email template
+// @Description Override a system email template for the facility. Empty parts fall back to the default template.
+// @Tags email-template
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param EmailTemplateName path string true "Email Template Name"
+// @Param email_template body Request true "Email Template"
+// @Success 200 {object} Response
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/email-templates/{EmailTemplateName} [put]
+func UpdateEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	data := &Request{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	et := utils.GetEmailTemplateCtx(r)
+	et.Subject = data.Subject
+	et.Text = data.Text
+	et.HTML = data.HTML
+
+	// Render the sample so a broken template is rejected instead of failing when it is sent
+	t, _ := notify.GetEmailTemplate(et.Name)
+	if _, err := notify.RenderEmailOverride(et.Name, utils.GetFacilityCtx(r), et, "Sample", t.Sample); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := et.Update(); err != nil {
+		log.WithError(err).Errorf("Error saving email template %s for %s", et.Name, et.Facility)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, NewEmailTemplateResponse(t, et))
+}
+
+// DeleteEmailTemplate godoc
+// @Summary Remove an email template override
+// @Description Remove the facility's override of a system email template, reverting it to the default
+// @Tags email-template
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param EmailTemplateName path string true "Email Template Name"
+// @Success 204
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/email-templates/{EmailTemplateName} [delete]
+func DeleteEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	et := utils.GetEmailTemplateCtx(r)
+	if et.ID == 0 {
+		utils.Render(w, r, utils.ErrNotFound)
+		return
+	}
+
+	if err := et.Delete(); err != nil {
+		log.WithError(err).Errorf("Error deleting email template %s for %s", et.Name, et.Facility)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PreviewEmailTemplate godoc
+// @Summary Preview an email template
+// @Description Render an email template with sample data and the facility's branding and override. Use format=html
+// @Description or format=text to get the rendered body instead of JSON.
+// @Tags email-template
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param EmailTemplateName path string true "Email Template Name"
+// @Param format query string false "Format" Enums(json, html, text)
+// @Success 200 {object} PreviewResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/email-templates/{EmailTemplateName}/preview [get]
+func PreviewEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	et := utils.GetEmailTemplateCtx(r)
+	t, _ := notify.GetEmailTemplate(et.Name)
+
+	user := utils.GetXUser(r)
+	name := "Sample"
+	if user != nil {
+		name = user.FirstName
+	}
+
+	email, err := notify.RenderEmailOverride(et.Name, utils.GetFacilityCtx(r), et, name, t.Sample)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(email.HTML))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(email.Text))
+	default:
+		utils.Render(w, r, &PreviewResponse{Email: email})
+	}
+}
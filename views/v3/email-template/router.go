@@ -0,0 +1,45 @@
+package email_template
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
+	"github.com/VATUSA/primary-api/pkg/notify"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+func Router(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFacility)
+
+	r.Get("/", ListEmailTemplates)
+
+	r.Route("/{EmailTemplateName}", func(r chi.Router) {
+		r.Use(Ctx)
+
+		r.Get("/", GetEmailTemplate)
+		r.Put("/", UpdateEmailTemplate)
+		r.Delete("/", DeleteEmailTemplate)
+		r.Get("/preview", PreviewEmailTemplate)
+	})
+}
+
+// Ctx loads the facility's override of the template, or an unsaved one if the facility has not overridden it
+func Ctx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "EmailTemplateName")
+		if _, ok := notify.GetEmailTemplate(name); !ok {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		et := &models.EmailTemplate{Facility: utils.GetFacilityCtx(r).ID, Name: name}
+		if err := et.Get(); err != nil {
+			et = &models.EmailTemplate{Facility: et.Facility, Name: name}
+		}
+
+		ctx := utils.WithResource(r, utils.EmailTemplateKey{}, et)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
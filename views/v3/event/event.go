@@ -2,6 +2,7 @@ package event
 
 import (
 	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
 	"github.com/VATUSA/primary-api/pkg/utils"
 	"github.com/go-chi/render"
 	log "github.com/sirupsen/logrus"
@@ -50,6 +51,10 @@ func CreateEvent(w http.ResponseWriter, r *http.Request) {
 		EndDate:     req.EndDate,
 		Fields:      req.Fields,
 		Facilities:  req.Facilities,
+
+		StaffingStatus: types.StaffingDraft,
+		RecurrenceRule: req.RecurrenceRule,
+		TimeZone:       req.TimeZone,
 	}
 
 	if err := ev.Create(); err != nil {
@@ -58,6 +63,12 @@ func CreateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if ev.IsSeries() {
+		if err := ev.GenerateOccurrences(time.Now().Add(models.EventSeriesHorizon)); err != nil {
+			log.WithError(err).Errorf("Error generating occurrences for event series %d", ev.ID)
+		}
+	}
+
 	utils.Response(r, http.StatusCreated)
 	utils.Render(w, r, NewEventResponse(ev))
 }
@@ -182,12 +193,13 @@ func GetEvent(w http.ResponseWriter, r *http.Request) {
 
 // UpdateEvent godoc
 // @Summary Update Event
-// @Description Update Event by ID
+// @Description Update Event by ID. Updating a series definition, or an occurrence with scope=series, updates the whole series.
 // @Tags event
 // @Accept  json
 // @Produce  json
 // @Param FacilityID path string true "Facility ID"
 // @Param EventID path string true "Event ID"
+// @Param scope query string false "Set to series to apply the change to the whole series"
 // @Param event body EventRequest true "Event"
 // @Success 200 {object} EventResponse
 // @Failure 400 {object} utils.ErrResponse
@@ -208,15 +220,18 @@ func UpdateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ev.Title = req.Title
-	ev.Description = req.Description
-	ev.BannerURL = req.BannerURL
-	ev.StartDate = req.StartDate
-	ev.EndDate = req.EndDate
-	ev.Fields = req.Fields
-	ev.Facilities = req.Facilities
-
-	if err := ev.Update(); err != nil {
+	ev, err := saveEvent(r, ev, func(e *models.Event) {
+		e.Title = req.Title
+		e.Description = req.Description
+		e.BannerURL = req.BannerURL
+		e.StartDate = req.StartDate
+		e.EndDate = req.EndDate
+		e.Fields = req.Fields
+		e.Facilities = req.Facilities
+		e.RecurrenceRule = req.RecurrenceRule
+		e.TimeZone = req.TimeZone
+	})
+	if err != nil {
 		log.WithError(err).Error("Error updating event")
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
@@ -227,12 +242,13 @@ func UpdateEvent(w http.ResponseWriter, r *http.Request) {
 
 // PatchEvent godoc
 // @Summary Patch Event
-// @Description Patch Event by ID
+// @Description Patch Event by ID. Patching a series definition, or an occurrence with scope=series, updates the whole series.
 // @Tags event
 // @Accept  json
 // @Produce  json
 // @Param FacilityID path string true "Facility ID"
 // @Param EventID path string true "Event ID"
+// @Param scope query string false "Set to series to apply the change to the whole series"
 // @Param event body EventRequest true "Event"
 // @Success 200 {object} EventResponse
 // @Failure 400 {object} utils.ErrResponse
@@ -253,29 +269,36 @@ func PatchEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Title != "" {
-		ev.Title = req.Title
-	}
-	if req.Description != "" {
-		ev.Description = req.Description
-	}
-	if req.BannerURL != "" {
-		ev.BannerURL = req.BannerURL
-	}
-	if !req.StartDate.IsZero() {
-		ev.StartDate = req.StartDate
-	}
-	if !req.EndDate.IsZero() {
-		ev.EndDate = req.EndDate
-	}
-	if len(req.Fields) > 0 {
-		ev.Fields = req.Fields
-	}
-	if len(req.Facilities) > 0 {
-		ev.Facilities = req.Facilities
-	}
-
-	if err := ev.Update(); err != nil {
+	ev, err := saveEvent(r, ev, func(e *models.Event) {
+		if req.Title != "" {
+			e.Title = req.Title
+		}
+		if req.Description != "" {
+			e.Description = req.Description
+		}
+		if req.BannerURL != "" {
+			e.BannerURL = req.BannerURL
+		}
+		if !req.StartDate.IsZero() {
+			e.StartDate = req.StartDate
+		}
+		if !req.EndDate.IsZero() {
+			e.EndDate = req.EndDate
+		}
+		if len(req.Fields) > 0 {
+			e.Fields = req.Fields
+		}
+		if len(req.Facilities) > 0 {
+			e.Facilities = req.Facilities
+		}
+		if req.RecurrenceRule != "" {
+			e.RecurrenceRule = req.RecurrenceRule
+		}
+		if req.TimeZone != "" {
+			e.TimeZone = req.TimeZone
+		}
+	})
+	if err != nil {
 		log.WithError(err).Error("Error updating event")
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
@@ -286,12 +309,13 @@ func PatchEvent(w http.ResponseWriter, r *http.Request) {
 
 // DeleteEvent godoc
 // @Summary Delete Event
-// @Description Delete Event by ID
+// @Description Delete Event by ID. Deleting a series definition, or an occurrence with scope=series, deletes the whole series.
 // @Tags event
 // @Accept  json
 // @Produce  json
 // @Param FacilityID path string true "Facility ID"
 // @Param EventID path string true "Event ID"
+// @Param scope query string false "Set to series to delete the whole series"
 // @Success 204
 // @Failure 400 {object} utils.ErrResponse
 // @Failure 404 {object} utils.ErrResponse
@@ -300,7 +324,7 @@ func PatchEvent(w http.ResponseWriter, r *http.Request) {
 func DeleteEvent(w http.ResponseWriter, r *http.Request) {
 	ev := utils.GetEventCtx(r)
 
-	if err := ev.Delete(); err != nil {
+	if err := deleteEvent(r, ev); err != nil {
 		log.WithError(err).Error("Error deleting event")
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
@@ -0,0 +1,138 @@
+package event
+
+import (
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/config"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/ical"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// calendarHistory is how far back calendar feeds include events, so recently finished events do not disappear
+// from subscribed calendars straight away
+const calendarHistory = 30 * 24 * time.Hour
+
+// GetAllEventsCalendar godoc
+// @Summary Get All Events Calendar
+// @Description Get an iCalendar feed of all upcoming events
+// @Tags event
+// @Produce  text/calendar
+// @Success 200 {string} string
+// @Failure 500 {object} utils.ErrResponse
+// @Router /events.ics [get]
+func GetAllEventsCalendar(w http.ResponseWriter, r *http.Request) {
+	serveEventsCalendar(w, r, "", "VATUSA Events")
+}
+
+// GetEventsCalendar godoc
+// @Summary Get Events Calendar
+// @Description Get an iCalendar feed of a Facility's upcoming events
+// @Tags event
+// @Produce  text/calendar
+// @Param FacilityID path string true "Facility ID"
+// @Success 200 {string} string
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/events.ics [get]
+func GetEventsCalendar(w http.ResponseWriter, r *http.Request) {
+	fac := utils.GetFacilityCtx(r)
+	serveEventsCalendar(w, r, fac.ID, fmt.Sprintf("%s Events", fac.Name))
+}
+
+func serveEventsCalendar(w http.ResponseWriter, r *http.Request, facility constants.FacilityID, name string) {
+	events, err := models.GetEventsFiltered(1, 1000, facility, time.Now().Add(-calendarHistory))
+	if err != nil {
+		log.WithError(err).Error("Error getting events")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	cal := &ical.Calendar{Name: name}
+	for _, ev := range events {
+		cal.Events = append(cal.Events, ical.Event{
+			UID:         calendarUID("event", ev.ID),
+			Summary:     ev.Title,
+			Description: ev.Description,
+			Location:    strings.Join(ev.Fields, ", "),
+			Start:       ev.StartDate,
+			End:         ev.EndDate,
+			Modified:    ev.UpdatedAt,
+			Sequence:    ev.UpdatedAt.Unix(),
+			Status:      ical.StatusConfirmed,
+		})
+	}
+
+	ical.Serve(w, cal)
+}
+
+// GetUserCalendar godoc
+// @Summary Get User Assignments Calendar
+// @Description Get an iCalendar feed of the event positions a user is assigned to or signed up for. The feed is
+// @Description authenticated by the token in the URL, see /user/calendar.
+// @Tags event
+// @Produce  text/calendar
+// @Param Token path string true "Calendar Token"
+// @Success 200 {string} string
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/calendar/{Token}.ics [get]
+func GetUserCalendar(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "Token")
+	if token == "" {
+		utils.Render(w, r, utils.ErrNotFound)
+		return
+	}
+
+	user, err := models.GetUserByCalendarToken(token)
+	if err != nil {
+		utils.Render(w, r, utils.ErrNotFound)
+		return
+	}
+
+	assignments, err := models.GetEventAssignments(user.CID, time.Now().Add(-calendarHistory))
+	if err != nil {
+		log.WithError(err).Errorf("Error getting event assignments for user %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	cal := &ical.Calendar{Name: "VATUSA Event Assignments"}
+	for _, a := range assignments {
+		summary := fmt.Sprintf("%s - %s", a.Position, a.EventTitle)
+		status := ical.StatusConfirmed
+		if !a.Assigned {
+			summary = fmt.Sprintf("%s (signed up)", summary)
+			status = ical.StatusTentative
+		}
+
+		cal.Events = append(cal.Events, ical.Event{
+			// The UID is the shift so a signup turns into the assignment in place once published
+			UID:         calendarUID("shift", a.ShiftID),
+			Summary:     summary,
+			Description: fmt.Sprintf("%s, %s for %s", a.Position, a.Facility, a.EventTitle),
+			Location:    string(a.Facility),
+			Start:       a.StartTime,
+			End:         a.EndTime,
+			Modified:    a.UpdatedAt,
+			Sequence:    a.UpdatedAt.Unix(),
+			Status:      status,
+		})
+	}
+
+	ical.Serve(w, cal)
+}
+
+func calendarUID(kind string, id uint) string {
+	host := "vatusa.net"
+	if u, err := url.Parse(config.Cfg.API.BaseURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return fmt.Sprintf("%s-%d@%s", kind, id, host)
+}
@@ -1,7 +1,9 @@
 package event
 
 import (
+	"errors"
 	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
 	"github.com/VATUSA/primary-api/pkg/utils"
 	"github.com/go-chi/render"
 	log "github.com/sirupsen/logrus"
@@ -29,28 +31,33 @@ func CreateEventPosition(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := req.Validate(); err != nil {
-		utils.Render(w, r, utils.ErrBadRequest)
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
 		return
 	}
 
-	fac := utils.GetFacilityCtx(r)
-	position := &models.EventPosition{
-		EventID:           utils.GetEventCtx(r).ID,
-		Facility:          fac.ID,
-		Position:          req.Position,
-		Shifts:            false,
-		Assignee:          0,
-		SecondaryAssignee: 0,
+	ev := utils.GetEventCtx(r)
+	if ev.StaffingStatus == types.StaffingFinalized {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("event assignments have been finalized")))
+		return
 	}
 
-	if req.Shifts != nil {
-		position.Shifts = *req.Shifts
+	shiftCount := 1
+	if req.ShiftCount != nil {
+		shiftCount = int(*req.ShiftCount)
 	}
-	if req.Assignee != nil {
-		position.Assignee = *req.Assignee
+
+	shifts, err := models.SplitEventShifts(ev.ID, 0, ev.StartDate, ev.EndDate, shiftCount)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
 	}
-	if req.SecondaryAssignee != nil {
-		position.SecondaryAssignee = *req.SecondaryAssignee
+
+	fac := utils.GetFacilityCtx(r)
+	position := &models.EventPosition{
+		EventID:  ev.ID,
+		Facility: fac.ID,
+		Position: req.Position,
+		Shifts:   shifts,
 	}
 
 	if err := position.Create(); err != nil {
@@ -139,15 +146,6 @@ func PatchEventPosition(w http.ResponseWriter, r *http.Request) {
 	if req.Position != "" {
 		position.Position = req.Position
 	}
-	if req.Assignee != nil {
-		position.Assignee = *req.Assignee
-	}
-	if req.Shifts != nil {
-		position.Shifts = *req.Shifts
-	}
-	if req.SecondaryAssignee != nil {
-		position.SecondaryAssignee = *req.SecondaryAssignee
-	}
 
 	if err := position.Update(); err != nil {
 		log.WithError(err).Error("Error updating event position")
@@ -172,12 +170,23 @@ func PatchEventPosition(w http.ResponseWriter, r *http.Request) {
 // @Failure 500 {object} utils.ErrResponse
 // @Router /facility/{FacilityID}/events/{EventID}/positions/{EventPositionID} [delete]
 func DeleteEventPosition(w http.ResponseWriter, r *http.Request) {
+	ev := utils.GetEventCtx(r)
 	position := utils.GetEventPositionCtx(r)
+
+	if ev.StaffingStatus == types.StaffingFinalized {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("event assignments have been finalized")))
+		return
+	}
+
 	if err := position.Delete(); err != nil {
 		log.WithError(err).Error("Error deleting event position")
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
 	}
 
+	for idx := range position.Shifts {
+		notifyAssignmentChange(ev, position.Position, &position.Shifts[idx], position.Shifts[idx].Assignee, 0)
+	}
+
 	utils.Response(r, http.StatusNoContent)
 }
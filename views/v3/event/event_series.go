@@ -0,0 +1,110 @@
+package event
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// seriesScope is the value of the scope query parameter that applies a change to the whole series
+const seriesScope = "series"
+
+// saveEvent persists the changes made by apply. Changes to a series definition, or to an occurrence with
+// ?scope=series, are carried over to the whole series and the series definition is returned. Any other change to
+// an occurrence only affects that occurrence and detaches it from later series updates.
+func saveEvent(r *http.Request, ev *models.Event, apply func(e *models.Event)) (*models.Event, error) {
+	if ev.SeriesID != nil && r.URL.Query().Get("scope") == seriesScope {
+		series := &models.Event{ID: *ev.SeriesID}
+		if err := series.Get(); err != nil {
+			return nil, err
+		}
+
+		// Times are edited relative to the occurrence, so move the series definition by the same amount
+		edited := *ev
+		apply(&edited)
+		shift := edited.StartDate.Sub(ev.StartDate)
+		length := edited.EndDate.Sub(edited.StartDate)
+
+		seriesStart := series.StartDate
+		apply(series)
+		series.StartDate = seriesStart.Add(shift)
+		series.EndDate = series.StartDate.Add(length)
+
+		if err := series.Update(); err != nil {
+			return nil, err
+		}
+		return series, series.UpdateSeries()
+	}
+
+	wasSeries := ev.IsSeries()
+	apply(ev)
+
+	if ev.SeriesID != nil {
+		ev.RecurrenceRule = ""
+		ev.Detached = true
+	}
+
+	if err := ev.Update(); err != nil {
+		return nil, err
+	}
+
+	if wasSeries {
+		return ev, ev.UpdateSeries()
+	}
+	if ev.IsSeries() {
+		return ev, ev.GenerateOccurrences(time.Now().Add(models.EventSeriesHorizon))
+	}
+
+	return ev, nil
+}
+
+// deleteEvent deletes ev. Deleting a series definition, or an occurrence with ?scope=series, deletes the whole
+// series; deleting a single occurrence keeps it from being generated again.
+func deleteEvent(r *http.Request, ev *models.Event) error {
+	if ev.IsSeries() {
+		return ev.DeleteSeries()
+	}
+
+	if ev.SeriesID != nil {
+		if r.URL.Query().Get("scope") == seriesScope {
+			series := &models.Event{ID: *ev.SeriesID}
+			if err := series.Get(); err != nil {
+				return err
+			}
+			return series.DeleteSeries()
+		}
+		return ev.DeleteOccurrence()
+	}
+
+	return ev.Delete()
+}
+
+// syncSeries carries successful changes to the positions, shifts or routing of a series definition over to the
+// series' occurrences.
+func syncSeries(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || !utils.GetEventCtx(r).IsSeries() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+		if ww.Status() >= http.StatusBadRequest {
+			return
+		}
+
+		series := &models.Event{ID: utils.GetEventCtx(r).ID}
+		if err := series.Get(); err != nil {
+			log.WithError(err).Errorf("Error loading event series %d", series.ID)
+			return
+		}
+
+		if err := series.UpdateSeries(); err != nil {
+			log.WithError(err).Errorf("Error updating occurrences of event series %d", series.ID)
+		}
+	})
+}
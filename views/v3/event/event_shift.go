@@ -0,0 +1,234 @@
+package event
+
+import (
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// GetEventShifts godoc
+// @Summary Get Event Position Shifts
+// @Description Get the shifts for an Event Position
+// @Tags event
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param EventID path string true "Event ID"
+// @Param EventPositionID path string true "Event Position ID"
+// @Success 200 {object} []EventShiftResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/events/{EventID}/positions/{EventPositionID}/shifts [get]
+func GetEventShifts(w http.ResponseWriter, r *http.Request) {
+	position := utils.GetEventPositionCtx(r)
+
+	shifts, err := models.GetEventShiftsFiltered(position.ID)
+	if err != nil {
+		log.WithError(err).Error("Error getting event shifts")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	if err := render.RenderList(w, r, NewEventShiftListResponse(shifts, position.Facility)); err != nil {
+		log.WithError(err).Error("Error rendering event shifts")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+}
+
+// CreateEventShift godoc
+// @Summary Create an Event Position Shift
+// @Description Create a shift with an explicit start and end time for an Event Position
+// @Tags event
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param EventID path string true "Event ID"
+// @Param EventPositionID path string true "Event Position ID"
+// @Param event body EventShiftRequest true "Event Shift"
+// @Success 201 {object} EventShiftResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/events/{EventID}/positions/{EventPositionID}/shifts [post]
+func CreateEventShift(w http.ResponseWriter, r *http.Request) {
+	req := &EventShiftRequest{}
+	if err := req.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrBadRequest)
+		return
+	}
+
+	if req.StartTime.IsZero() || req.EndTime.IsZero() {
+		utils.Render(w, r, utils.ErrBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	ev := utils.GetEventCtx(r)
+	if ev.StaffingStatus == types.StaffingFinalized {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("event assignments have been finalized")))
+		return
+	}
+
+	if err := models.ValidateEventShift(ev, req.StartTime, req.EndTime); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	position := utils.GetEventPositionCtx(r)
+	count, err := models.CountEventShifts(position.ID)
+	if err != nil {
+		log.WithError(err).Error("Error counting event shifts")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+	if count >= models.MaxEventShifts {
+		utils.Render(w, r, utils.ErrInvalidRequest(models.ErrEventShiftCount))
+		return
+	}
+
+	shift := &models.EventShift{
+		EventID:    ev.ID,
+		PositionID: position.ID,
+		StartTime:  req.StartTime,
+		EndTime:    req.EndTime,
+	}
+
+	if req.Assignee != nil {
+		shift.Assignee = *req.Assignee
+	}
+
+	if err := shift.Create(); err != nil {
+		log.WithError(err).Error("Error creating event shift")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	notifyAssignmentChange(ev, position.Position, shift, 0, shift.Assignee)
+
+	utils.Response(r, http.StatusCreated)
+	utils.Render(w, r, NewEventShiftResponse(shift, position.Facility))
+}
+
+// GetEventShift godoc
+// @Summary Get an Event Position Shift
+// @Description Get an Event Position Shift
+// @Tags event
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param EventID path string true "Event ID"
+// @Param EventPositionID path string true "Event Position ID"
+// @Param EventShiftID path string true "Event Shift ID"
+// @Success 200 {object} EventShiftResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/events/{EventID}/positions/{EventPositionID}/shifts/{EventShiftID} [get]
+func GetEventShift(w http.ResponseWriter, r *http.Request) {
+	utils.Render(w, r, NewEventShiftResponse(utils.GetEventShiftCtx(r), utils.GetEventPositionCtx(r).Facility))
+}
+
+// PatchEventShift godoc
+// @Summary Patch an Event Position Shift
+// @Description Patch the window or assignee of an Event Position Shift
+// @Tags event
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param EventID path string true "Event ID"
+// @Param EventPositionID path string true "Event Position ID"
+// @Param EventShiftID path string true "Event Shift ID"
+// @Param event body EventShiftRequest true "Event Shift"
+// @Success 200 {object} EventShiftResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/events/{EventID}/positions/{EventPositionID}/shifts/{EventShiftID} [patch]
+func PatchEventShift(w http.ResponseWriter, r *http.Request) {
+	ev := utils.GetEventCtx(r)
+	position := utils.GetEventPositionCtx(r)
+	shift := utils.GetEventShiftCtx(r)
+
+	req := &EventShiftRequest{}
+	if err := req.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrBadRequest)
+		return
+	}
+
+	if ev.StaffingStatus == types.StaffingFinalized {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("event assignments have been finalized")))
+		return
+	}
+
+	oldAssignee := shift.Assignee
+
+	if !req.StartTime.IsZero() {
+		shift.StartTime = req.StartTime
+	}
+	if !req.EndTime.IsZero() {
+		shift.EndTime = req.EndTime
+	}
+	if req.Assignee != nil {
+		shift.Assignee = *req.Assignee
+	}
+
+	if !shift.EndTime.After(shift.StartTime) {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("end_time must be after start_time")))
+		return
+	}
+	if err := models.ValidateEventShift(ev, shift.StartTime, shift.EndTime); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := shift.Update(); err != nil {
+		log.WithError(err).Error("Error updating event shift")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	notifyAssignmentChange(ev, position.Position, shift, oldAssignee, shift.Assignee)
+
+	utils.Render(w, r, NewEventShiftResponse(shift, position.Facility))
+}
+
+// DeleteEventShift godoc
+// @Summary Delete an Event Position Shift
+// @Description Delete an Event Position Shift and its signups
+// @Tags event
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param EventID path string true "Event ID"
+// @Param EventPositionID path string true "Event Position ID"
+// @Param EventShiftID path string true "Event Shift ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/events/{EventID}/positions/{EventPositionID}/shifts/{EventShiftID} [delete]
+func DeleteEventShift(w http.ResponseWriter, r *http.Request) {
+	ev := utils.GetEventCtx(r)
+	position := utils.GetEventPositionCtx(r)
+	shift := utils.GetEventShiftCtx(r)
+
+	if ev.StaffingStatus == types.StaffingFinalized {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("event assignments have been finalized")))
+		return
+	}
+
+	if err := shift.Delete(); err != nil {
+		log.WithError(err).Error("Error deleting event shift")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	notifyAssignmentChange(ev, position.Position, shift, shift.Assignee, 0)
+
+	utils.Response(r, http.StatusNoContent)
+}
@@ -0,0 +1,65 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/dbtest"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateEventShift(t *testing.T) {
+	dbtest.Open(t, &models.Event{}, &models.EventPosition{}, &models.EventShift{}, &models.EventSignup{})
+
+	start := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	ev := &models.Event{Title: "KDEN FNO", Facilities: []constants.FacilityID{"ZDV"}, StartDate: start, EndDate: start.Add(12 * time.Hour)}
+	if err := ev.Create(); err != nil {
+		t.Fatal(err)
+	}
+	position := &models.EventPosition{EventID: ev.ID, Facility: "ZDV", Position: "DEN_APP"}
+	if err := position.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(from, to time.Time) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"start_time": %q, "end_time": %q}`, from.Format(time.RFC3339), to.Format(time.RFC3339))
+		r := httptest.NewRequest(http.MethodPost, "/v3/facility/ZDV/events/1/positions/1/shifts", strings.NewReader(body))
+		ctx := context.WithValue(r.Context(), utils.EventKey{}, ev)
+		ctx = context.WithValue(ctx, utils.EventPositionKey{}, position)
+		w := httptest.NewRecorder()
+		CreateEventShift(w, r.WithContext(ctx))
+		return w
+	}
+
+	// Shifts outside the event or shorter than the minimum length are refused
+	for name, window := range map[string][2]time.Time{
+		"before the event": {start.Add(-time.Hour), start.Add(time.Hour)},
+		"after the event":  {start.Add(11 * time.Hour), start.Add(13 * time.Hour)},
+		"too short":        {start, start.Add(models.MinEventShiftLength - time.Minute)},
+		"end before start": {start.Add(time.Hour), start},
+	} {
+		if w := create(window[0], window[1]); w.Code != http.StatusBadRequest {
+			t.Errorf("CreateEventShift() %s returned %d, want 400", name, w.Code)
+		}
+	}
+
+	// A position holds at most MaxEventShifts shifts
+	for i := 0; i < models.MaxEventShifts; i++ {
+		from := start.Add(time.Duration(i) * time.Hour)
+		if w := create(from, from.Add(time.Hour)); w.Code != http.StatusCreated {
+			t.Fatalf("CreateEventShift() shift %d returned %d: %s", i+1, w.Code, w.Body.String())
+		}
+	}
+	if w := create(start, start.Add(time.Hour)); w.Code != http.StatusBadRequest {
+		t.Errorf("CreateEventShift() past the cap returned %d, want 400", w.Code)
+	}
+	if count, err := models.CountEventShifts(position.ID); err != nil || count != models.MaxEventShifts {
+		t.Errorf("CountEventShifts() = %d, %v, want %d", count, err, models.MaxEventShifts)
+	}
+}
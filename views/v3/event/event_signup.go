@@ -1,7 +1,9 @@
 package event
 
 import (
+	"errors"
 	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
 	"github.com/VATUSA/primary-api/pkg/utils"
 	"github.com/go-chi/render"
 	log "github.com/sirupsen/logrus"
@@ -36,11 +38,22 @@ func CreateEventSignup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	event := utils.GetEventCtx(r)
+	if event.StaffingStatus == types.StaffingFinalized {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("event assignments have been finalized")))
+		return
+	}
+
+	shift := &models.EventShift{ID: req.ShiftID}
+	if err := shift.Get(); err != nil || shift.EventID != event.ID {
+		utils.Render(w, r, utils.ErrBadRequest)
+		return
+	}
+
 	signup := &models.EventSignup{
 		EventID:    event.ID,
-		PositionID: req.PositionID,
+		PositionID: shift.PositionID,
+		ShiftID:    shift.ID,
 		CID:        req.CID,
-		Shift:      req.Shift,
 	}
 
 	if err := signup.Create(); err != nil {
@@ -0,0 +1,60 @@
+package event
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/dbtest"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateEventSignup(t *testing.T) {
+	tests := []struct {
+		status types.StaffingStatus
+		want   int
+	}{
+		{status: types.StaffingDraft, want: http.StatusCreated},
+		{status: types.StaffingPublished, want: http.StatusCreated},
+		{status: types.StaffingFinalized, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			dbtest.Open(t, &models.Event{}, &models.EventPosition{}, &models.EventShift{}, &models.EventSignup{})
+
+			start := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+			ev := &models.Event{Title: "KDEN FNO", Facilities: []constants.FacilityID{"ZDV"}, StartDate: start,
+				EndDate: start.Add(4 * time.Hour), StaffingStatus: tt.status}
+			if err := ev.Create(); err != nil {
+				t.Fatal(err)
+			}
+			shift := &models.EventShift{EventID: ev.ID, PositionID: 1, StartTime: ev.StartDate, EndTime: ev.EndDate}
+			if err := shift.Create(); err != nil {
+				t.Fatal(err)
+			}
+
+			body := `{"shift_id": ` + strconv.FormatUint(uint64(shift.ID), 10) + `, "cid": 1293257}`
+			r := httptest.NewRequest(http.MethodPost, "/v3/facility/ZDV/events/1/signups", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			CreateEventSignup(w, r.WithContext(context.WithValue(r.Context(), utils.EventKey{}, ev)))
+			if w.Code != tt.want {
+				t.Fatalf("CreateEventSignup() on a %s event returned %d, want %d", tt.status, w.Code, tt.want)
+			}
+
+			signups, err := models.GetEventSignupFiltered(ev.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created := len(signups) == 1; created != (tt.want == http.StatusCreated) {
+				t.Errorf("%d signups after a %d response", len(signups), w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/notify"
+	"github.com/VATUSA/primary-api/pkg/stream"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// GetEventStaffing godoc
+// @Summary Get Event Staffing Summary
+// @Description Get the staffing summary for an Event, including coverage gaps and unassigned signups
+// @Tags event
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param EventID path string true "Event ID"
+// @Success 200 {object} EventStaffingResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/events/{EventID}/staffing [get]
+func GetEventStaffing(w http.ResponseWriter, r *http.Request) {
+	ev := utils.GetEventCtx(r)
+	utils.Render(w, r, NewEventStaffingResponse(ev))
+}
+
+// PublishEventStaffing godoc
+// @Summary Publish Event Assignments
+// @Description Publish the assignments for an Event and notify assignees and unselected signups
+// @Tags event
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param EventID path string true "Event ID"
+// @Success 200 {object} EventStaffingResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/events/{EventID}/staffing/publish [post]
+func PublishEventStaffing(w http.ResponseWriter, r *http.Request) {
+	ev := utils.GetEventCtx(r)
+
+	if ev.StaffingStatus != types.StaffingDraft && ev.StaffingStatus != "" {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("event assignments have already been published")))
+		return
+	}
+
+	now := time.Now()
+	ev.StaffingStatus = types.StaffingPublished
+	ev.PublishedAt = &now
+
+	if err := ev.Update(); err != nil {
+		log.WithError(err).Error("Error publishing event assignments")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	notifyPublishedAssignments(ev)
+
+	utils.Render(w, r, NewEventStaffingResponse(ev))
+}
+
+// FinalizeEventStaffing godoc
+// @Summary Finalize Event Assignments
+// @Description Finalize the assignments for an Event, after which they can no longer be changed
+// @Tags event
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param EventID path string true "Event ID"
+// @Success 200 {object} EventStaffingResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/events/{EventID}/staffing/finalize [post]
+func FinalizeEventStaffing(w http.ResponseWriter, r *http.Request) {
+	ev := utils.GetEventCtx(r)
+
+	if ev.StaffingStatus != types.StaffingPublished {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("event assignments must be published before they are finalized")))
+		return
+	}
+
+	now := time.Now()
+	ev.StaffingStatus = types.StaffingFinalized
+	ev.FinalizedAt = &now
+
+	if err := ev.Update(); err != nil {
+		log.WithError(err).Error("Error finalizing event assignments")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, NewEventStaffingResponse(ev))
+}
+
+// notifyPublishedAssignments notifies every assignee of their shift, and every signup that did not receive a shift
+func notifyPublishedAssignments(ev *models.Event) {
+	assigned := map[uint]bool{}
+	for _, position := range ev.Positions {
+		for idx := range position.Shifts {
+			shift := &position.Shifts[idx]
+			if shift.Assignee != 0 {
+				assigned[shift.Assignee] = true
+				notifyAssigned(ev, position.Position, shift, shift.Assignee)
+			}
+		}
+	}
+
+	notified := map[uint]bool{}
+	for _, position := range ev.Positions {
+		for _, shift := range position.Shifts {
+			for _, signup := range shift.Signups {
+				if assigned[signup.CID] || notified[signup.CID] {
+					continue
+				}
+				notified[signup.CID] = true
+				createEventNotification(ev, signup.CID, "Event Assignment", fmt.Sprintf("You were not selected for a position for %s. Thank you for signing up!", ev.Title))
+			}
+		}
+	}
+}
+
+// notifyAssignmentChange notifies the old and new assignee when a shift assignment changes after the event was published
+func notifyAssignmentChange(ev *models.Event, position string, shift *models.EventShift, oldCID, newCID uint) {
+	if ev.StaffingStatus != types.StaffingPublished || oldCID == newCID {
+		return
+	}
+
+	if oldCID != 0 {
+		publishAssignment(ev, position, shift, oldCID, false)
+		createEventNotification(ev, oldCID, "Event Assignment Changed", fmt.Sprintf("You are no longer assigned to %s (%s) for %s.", position, shiftWindow(shift), ev.Title))
+	}
+	if newCID != 0 {
+		notifyAssigned(ev, position, shift, newCID)
+	}
+}
+
+func notifyAssigned(ev *models.Event, position string, shift *models.EventShift, cid uint) {
+	publishAssignment(ev, position, shift, cid, true)
+
+	notification := newEventNotification(ev, cid, "Event Assignment", fmt.Sprintf("You have been assigned to %s (%s) for %s.", position, shiftWindow(shift), ev.Title))
+	notification.Template = notify.TemplateEventAssignment
+	notification.TemplateData = map[string]string{
+		"event":    ev.Title,
+		"position": position,
+		"shift":    shiftWindow(shift),
+	}
+	if len(ev.Facilities) > 0 {
+		notification.Facility = ev.Facilities[0]
+	}
+
+	notify.Send(notification)
+}
+
+// AssignmentEvent is pushed to a user's stream when they are assigned to or removed from an event shift
+type AssignmentEvent struct {
+	EventID   uint      `json:"event_id"`
+	Event     string    `json:"event"`
+	Position  string    `json:"position"`
+	ShiftID   uint      `json:"shift_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Assigned  bool      `json:"assigned"`
+}
+
+func publishAssignment(ev *models.Event, position string, shift *models.EventShift, cid uint, assigned bool) {
+	stream.Publish(cid, stream.TypeEventAssignment, &AssignmentEvent{
+		EventID:   ev.ID,
+		Event:     ev.Title,
+		Position:  position,
+		ShiftID:   shift.ID,
+		StartTime: shift.StartTime,
+		EndTime:   shift.EndTime,
+		Assigned:  assigned,
+	})
+}
+
+func shiftWindow(shift *models.EventShift) string {
+	return fmt.Sprintf("%s-%sz", shift.StartTime.UTC().Format("1504"), shift.EndTime.UTC().Format("1504"))
+}
+
+func createEventNotification(ev *models.Event, cid uint, title, body string) {
+	notify.Send(newEventNotification(ev, cid, title, body))
+}
+
+func newEventNotification(ev *models.Event, cid uint, title, body string) *models.Notification {
+	return &models.Notification{
+		CID:      cid,
+		Category: notify.CategoryEvents,
+		Title:    title,
+		Body:     body,
+		ExpireAt: ev.EndDate,
+	}
+}
@@ -1,11 +1,14 @@
 package event
 
 import (
+	"errors"
 	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
 	"github.com/VATUSA/primary-api/pkg/utils"
 	"github.com/go-chi/render"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"slices"
 )
 
 // GetEventTemplates godoc
@@ -35,6 +38,122 @@ func GetEventTemplates(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// CreateEventTemplate godoc
+// @Summary Create an Event Template
+// @Description Create an Event Template
+// @Tags event
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param event body EventTemplateRequest true "Event Template"
+// @Success 201 {object} EventTemplateResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/event-templates [post]
+func CreateEventTemplate(w http.ResponseWriter, r *http.Request) {
+	req := &EventTemplateRequest{}
+	if err := render.Bind(r, req); err != nil {
+		utils.Render(w, r, utils.ErrBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	fac := utils.GetFacilityCtx(r)
+	if !slices.Contains(req.Facilities, fac.ID) {
+		log.Errorf("Facility %s not in facilities list", fac.ID)
+		utils.Render(w, r, utils.ErrBadRequest)
+		return
+	}
+
+	et := &models.EventTemplate{
+		Title:      req.Title,
+		Positions:  req.Positions,
+		Facilities: req.Facilities,
+		Fields:     req.Fields,
+		ShiftCount: req.ShiftCount,
+	}
+	if et.ShiftCount == 0 {
+		et.ShiftCount = 1
+	}
+
+	if err := et.Create(); err != nil {
+		log.WithError(err).Error("Error creating event template")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Response(r, http.StatusCreated)
+	utils.Render(w, r, NewEventTemplateResponse(et))
+}
+
+// CreateEventFromTemplate godoc
+// @Summary Create an Event from an Event Template
+// @Description Create an Event and all of its positions from an Event Template
+// @Tags event
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param EventTemplateID path string true "Event Template ID"
+// @Param event body EventFromTemplateRequest true "Event overrides"
+// @Success 201 {object} EventResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/events/from-template/{EventTemplateID} [post]
+func CreateEventFromTemplate(w http.ResponseWriter, r *http.Request) {
+	et := utils.GetEventTemplateCtx(r)
+
+	req := &EventFromTemplateRequest{}
+	if err := render.Bind(r, req); err != nil {
+		utils.Render(w, r, utils.ErrBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	fac := utils.GetFacilityCtx(r)
+	if !slices.Contains(et.Facilities, fac.ID) {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("event template does not belong to this facility")))
+		return
+	}
+
+	ev := &models.Event{
+		Title:       et.Title,
+		Description: req.Description,
+		BannerURL:   req.BannerURL,
+		StartDate:   req.StartDate,
+		EndDate:     req.EndDate,
+		Fields:      et.Fields,
+		Facilities:  et.Facilities,
+
+		StaffingStatus: types.StaffingDraft,
+	}
+	if req.Title != "" {
+		ev.Title = req.Title
+	}
+
+	if err := et.CreateEvent(ev, fac.ID); err != nil {
+		if errors.Is(err, models.ErrEventShiftCount) || errors.Is(err, models.ErrEventShiftTooShort) {
+			utils.Render(w, r, utils.ErrInvalidRequest(err))
+			return
+		}
+
+		log.WithError(err).Errorf("Error creating event from template %d", et.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Response(r, http.StatusCreated)
+	utils.Render(w, r, NewEventResponse(ev))
+}
+
 // UpdateEventTemplate godoc
 // @Summary Update an Event Template
 // @Description Update an Event Template
@@ -58,7 +177,7 @@ func UpdateEventTemplate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := req.Validate(); err != nil {
-		utils.Render(w, r, utils.ErrBadRequest)
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
 		return
 	}
 
@@ -66,7 +185,10 @@ func UpdateEventTemplate(w http.ResponseWriter, r *http.Request) {
 	et.Positions = req.Positions
 	et.Facilities = req.Facilities
 	et.Fields = req.Fields
-	et.Shifts = req.Shifts
+	et.ShiftCount = req.ShiftCount
+	if et.ShiftCount == 0 {
+		et.ShiftCount = 1
+	}
 
 	if err := et.Update(); err != nil {
 		utils.Render(w, r, utils.ErrInternalServer)
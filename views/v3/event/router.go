@@ -1,19 +1,23 @@
 package event
 
 import (
-	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
 	"github.com/go-chi/chi/v5"
 	"net/http"
+	"slices"
 	"strconv"
 )
 
 func EventRouter(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadEventsScope, constants.WriteEventsScope))
+
 	r.Get("/", GetEvents)
 	r.Get("/previous", GetEventsPrevious)
 	r.With(middleware.NotGuest, middleware.CanEditEvent).Post("/", CreateEvent)
+	r.With(middleware.NotGuest, middleware.CanEditEvent, templateCtx).Post("/from-template/{EventTemplateID}", CreateEventFromTemplate)
 
 	r.Route("/{EventID}", func(r chi.Router) {
 		r.Use(eventCtx)
@@ -23,7 +27,17 @@ func EventRouter(r chi.Router) {
 		r.With(middleware.NotGuest, middleware.CanEditEvent).Patch("/", PatchEvent)
 		r.With(middleware.NotGuest, middleware.CanEditEvent).Delete("/", DeleteEvent)
 
+		r.Route("/staffing", func(r chi.Router) {
+			r.Use(middleware.NotGuest, middleware.CanEditEvent)
+
+			r.Get("/", GetEventStaffing)
+			r.Post("/publish", PublishEventStaffing)
+			r.Post("/finalize", FinalizeEventStaffing)
+		})
+
 		r.Route("/positions", func(r chi.Router) {
+			r.Use(syncSeries)
+
 			r.Get("/", GetEventPositions)
 			r.With(middleware.NotGuest, middleware.CanEditEvent).Post("/", CreateEventPosition)
 
@@ -33,6 +47,19 @@ func EventRouter(r chi.Router) {
 				r.Get("/", GetEventPosition)
 				r.With(middleware.NotGuest, middleware.CanEditEvent).Patch("/", PatchEventPosition)
 				r.With(middleware.NotGuest, middleware.CanEditEvent).Delete("/", DeleteEventPosition)
+
+				r.Route("/shifts", func(r chi.Router) {
+					r.Get("/", GetEventShifts)
+					r.With(middleware.NotGuest, middleware.CanEditEvent).Post("/", CreateEventShift)
+
+					r.Route("/{EventShiftID}", func(r chi.Router) {
+						r.Use(shiftCtx)
+
+						r.Get("/", GetEventShift)
+						r.With(middleware.NotGuest, middleware.CanEditEvent).Patch("/", PatchEventShift)
+						r.With(middleware.NotGuest, middleware.CanEditEvent).Delete("/", DeleteEventShift)
+					})
+				})
 			})
 		})
 
@@ -49,6 +76,8 @@ func EventRouter(r chi.Router) {
 		})
 
 		r.Route("/routing", func(r chi.Router) {
+			r.Use(syncSeries)
+
 			r.Get("/", GetEventRouting)
 			r.With(middleware.NotGuest, middleware.CanEditEvent).Post("/", CreateEventRouting)
 
@@ -63,7 +92,10 @@ func EventRouter(r chi.Router) {
 }
 
 func TemplateRouter(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadEventsScope, constants.WriteEventsScope))
+
 	r.With(middleware.NotGuest).Get("/", GetEventTemplates)
+	r.With(middleware.NotGuest, middleware.CanEditEvent).Post("/", CreateEventTemplate)
 
 	r.Route("/{EventTemplateID}", func(r chi.Router) {
 		r.Use(templateCtx)
@@ -87,13 +119,14 @@ func eventCtx(next http.Handler) http.Handler {
 			return
 		}
 
+		// An event is only reachable through the facilities taking part in it
 		ev := &models.Event{ID: uint(uintEventID)}
-		if err := ev.Get(); err != nil {
+		if err := ev.Get(); err != nil || !slices.Contains(ev.Facilities, utils.GetFacilityCtx(r).ID) {
 			utils.Render(w, r, utils.ErrNotFound)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.EventKey{}, ev)
+		ctx := utils.WithResource(r, utils.EventKey{}, ev)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -113,12 +146,37 @@ func positionCtx(next http.Handler) http.Handler {
 		}
 
 		ep := &models.EventPosition{ID: uint(uintPositionID)}
-		if err := ep.Get(); err != nil {
+		if err := ep.Get(); err != nil || ep.EventID != utils.GetEventCtx(r).ID {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		ctx := utils.WithResource(r, utils.EventPositionKey{}, ep)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func shiftCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shiftID := chi.URLParam(r, "EventShiftID")
+		if shiftID == "" {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		uintShiftID, err := strconv.ParseUint(shiftID, 10, 64)
+		if err != nil {
+			utils.Render(w, r, utils.ErrBadRequest)
+			return
+		}
+
+		es := &models.EventShift{ID: uint(uintShiftID)}
+		if err := es.Get(); err != nil || es.PositionID != utils.GetEventPositionCtx(r).ID {
 			utils.Render(w, r, utils.ErrNotFound)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.EventPositionKey{}, ep)
+		ctx := utils.WithResource(r, utils.EventShiftKey{}, es)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -138,12 +196,12 @@ func signupCtx(next http.Handler) http.Handler {
 		}
 
 		es := &models.EventSignup{ID: uint(uintSignupID)}
-		if err := es.Get(); err != nil {
+		if err := es.Get(); err != nil || es.EventID != utils.GetEventCtx(r).ID {
 			utils.Render(w, r, utils.ErrNotFound)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.EventSignupKey{}, es)
+		ctx := utils.WithResource(r, utils.EventSignupKey{}, es)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -163,12 +221,12 @@ func routingCtx(next http.Handler) http.Handler {
 		}
 
 		er := &models.EventRouting{ID: uint(uintRoutingID)}
-		if err := er.Get(); err != nil {
+		if err := er.Get(); err != nil || er.EventID != utils.GetEventCtx(r).ID {
 			utils.Render(w, r, utils.ErrNotFound)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.EventRoutingKey{}, er)
+		ctx := utils.WithResource(r, utils.EventRoutingKey{}, er)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -193,7 +251,7 @@ func templateCtx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.EventTemplateKey{}, et)
+		ctx := utils.WithResource(r, utils.EventTemplateKey{}, et)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
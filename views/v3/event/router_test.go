@@ -0,0 +1,77 @@
+package event
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/dbtest"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestChildCtxBelongToEvent(t *testing.T) {
+	dbtest.Open(t, &models.Event{}, &models.EventPosition{}, &models.EventShift{}, &models.EventSignup{},
+		&models.EventRouting{})
+
+	start := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	var events []*models.Event
+	for _, title := range []string{"KDEN FNO", "ZLC Live"} {
+		ev := &models.Event{Title: title, Facilities: []constants.FacilityID{"ZDV"}, StartDate: start,
+			EndDate: start.Add(4 * time.Hour)}
+		if err := ev.Create(); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, ev)
+	}
+
+	position := &models.EventPosition{EventID: events[0].ID, Facility: "ZDV", Position: "DEN_APP"}
+	if err := position.Create(); err != nil {
+		t.Fatal(err)
+	}
+	signup := &models.EventSignup{EventID: events[0].ID, PositionID: position.ID, CID: 1293257}
+	if err := signup.Create(); err != nil {
+		t.Fatal(err)
+	}
+	routing := &models.EventRouting{EventID: events[0].ID, Origin: "KDEN", Destination: "KSLC"}
+	if err := routing.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		mw    func(http.Handler) http.Handler
+		param string
+		id    uint
+	}{
+		{name: "position", mw: positionCtx, param: "EventPositionID", id: position.ID},
+		{name: "signup", mw: signupCtx, param: "EventSignupID", id: signup.ID},
+		{name: "routing", mw: routingCtx, param: "EventRoutingID", id: routing.ID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, ev := range events {
+				passed := false
+				handler := tt.mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { passed = true }))
+
+				rctx := chi.NewRouteContext()
+				rctx.URLParams.Add(tt.param, strconv.FormatUint(uint64(tt.id), 10))
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+				ctx = context.WithValue(ctx, utils.EventKey{}, ev)
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r.WithContext(ctx))
+
+				// Only the first event owns the resource, the other must not reach it
+				if owner := i == 0; passed != owner || !owner && w.Code != http.StatusNotFound {
+					t.Errorf("%s %d through event %d passed = %v with %d", tt.name, tt.id, ev.ID, passed, w.Code)
+				}
+			}
+		})
+	}
+}
@@ -2,12 +2,16 @@ package event
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/recurrence"
 	"github.com/go-chi/render"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"slices"
 	"time"
 )
 
@@ -16,10 +20,20 @@ type EventTemplateRequest struct {
 	Positions  []string               `json:"positions" example:"ZDV_APP" validate:"required"`
 	Facilities []constants.FacilityID `json:"facilities" example:"ZDV" validate:"required"`
 	Fields     []string               `json:"fields" example:"KDEN" validate:"required"`
-	Shifts     bool                   `json:"shifts" example:"true"`
+	ShiftCount uint                   `json:"shift_count" example:"2"`
 }
 
 func (req *EventTemplateRequest) Validate() error {
+	if req.Title == "" || len(req.Positions) == 0 || len(req.Facilities) == 0 {
+		return errors.New("title, positions and facilities are required")
+	}
+	if slices.Contains(req.Positions, "") {
+		return errors.New("positions must not be empty")
+	}
+	// A shift count of 0 falls back to a single shift
+	if req.ShiftCount > models.MaxEventShifts {
+		return models.ErrEventShiftCount
+	}
 	return nil
 }
 
@@ -30,6 +44,31 @@ func (req *EventTemplateRequest) Bind(r *http.Request) error {
 	return nil
 }
 
+type EventFromTemplateRequest struct {
+	Title       string    `json:"title" example:"ZDV FNO"`
+	Description string    `json:"description" example:"Join us for a fun night of flying in and out of Denver!" validate:"required"`
+	BannerURL   string    `json:"banner_url" example:"https://zdvartcc.org/banner.jpg"`
+	StartDate   time.Time `json:"start_date" example:"2021-01-01T00:00:00Z" validate:"required"`
+	EndDate     time.Time `json:"end_date" example:"2021-01-01T00:00:00Z" validate:"required"`
+}
+
+func (req *EventFromTemplateRequest) Validate() error {
+	if req.Description == "" || req.StartDate.IsZero() || req.EndDate.IsZero() {
+		return errors.New("description, start_date and end_date are required")
+	}
+	if !req.EndDate.After(req.StartDate) {
+		return errors.New("end_date must be after start_date")
+	}
+	return nil
+}
+
+func (req *EventFromTemplateRequest) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
 type EventTemplateResponse struct {
 	*models.EventTemplate
 }
@@ -61,9 +100,24 @@ type EventRequest struct {
 	EndDate     time.Time              `json:"end_date" example:"2021-01-01T00:00:00Z" validate:"required"`
 	Fields      []string               `json:"fields" example:"KDEN" validate:"required"`
 	Facilities  []constants.FacilityID `json:"facilities" example:"ZDV" validate:"required"`
+
+	RecurrenceRule string `json:"recurrence_rule" example:"FREQ=WEEKLY;BYDAY=TU"`
+	// TimeZone is the IANA time zone the recurrence rule is expanded in, so a weekly event keeps its local start
+	// time across DST changes. Without it the rule is expanded in UTC.
+	TimeZone string `json:"time_zone" example:"America/Denver"`
 }
 
 func (req *EventRequest) Validate() error {
+	if req.RecurrenceRule != "" {
+		if _, err := recurrence.Parse(req.RecurrenceRule); err != nil {
+			return err
+		}
+	}
+	if req.TimeZone != "" {
+		if _, err := time.LoadLocation(req.TimeZone); err != nil {
+			return fmt.Errorf("invalid time_zone: %s", req.TimeZone)
+		}
+	}
 	return nil
 }
 
@@ -98,13 +152,14 @@ func NewEventListResponse(es []models.Event) []render.Renderer {
 }
 
 type EventPositionRequest struct {
-	Position          string `json:"position" example:"ZDV_APP" validate:"required"`
-	Assignee          *uint  `json:"assignee" example:"1293257"`
-	Shifts            *bool  `json:"shifts" example:"true"`
-	SecondaryAssignee *uint  `json:"secondary_assignee" example:"1293257"`
+	Position   string `json:"position" example:"ZDV_APP" validate:"required"`
+	ShiftCount *uint  `json:"shift_count" example:"2"`
 }
 
 func (req *EventPositionRequest) Validate() error {
+	if req.ShiftCount != nil && (*req.ShiftCount < 1 || *req.ShiftCount > models.MaxEventShifts) {
+		return models.ErrEventShiftCount
+	}
 	return nil
 }
 
@@ -117,75 +172,109 @@ func (req *EventPositionRequest) Bind(r *http.Request) error {
 
 type EventPositionResponse struct {
 	*models.EventPosition
-	AssigneeName          string `json:"assignee_name" example:"John Doe"`
-	SecondaryAssigneeName string `json:"secondary_assignee_name" example:"Jane Doe"`
 }
 
 func NewEventPositionResponse(ep *models.EventPosition) *EventPositionResponse {
-	resp := &EventPositionResponse{EventPosition: ep}
-	if ep.Assignee != 0 {
-		user := models.User{CID: ep.Assignee}
-		if err := user.Get(); err != nil {
-			log.WithError(err).Errorf("Error getting user %d", ep.Assignee)
-			resp.AssigneeName = "Unknown"
-		}
+	for idx := range ep.Shifts {
+		setShiftNames(&ep.Shifts[idx], ep.Facility)
+	}
 
-		ois, err := models.GetUserOIs(user.CID, ep.Facility)
-		if err != nil {
-			log.WithError(err).Errorf("Error getting OIs for user %d", user.CID)
-		}
-		resp.AssigneeName = fmt.Sprintf("%s - %s", user.FirstName, ois)
+	return &EventPositionResponse{EventPosition: ep}
+}
+
+func (res *EventPositionResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.EventPosition == nil {
+		return nil
 	}
-	if ep.SecondaryAssignee != 0 {
-		user := models.User{CID: ep.SecondaryAssignee}
-		if err := user.Get(); err != nil {
-			log.WithError(err).Errorf("Error getting user %d", ep.SecondaryAssignee)
-			resp.SecondaryAssigneeName = "Unknown"
-		}
+	return nil
+}
 
-		ois, err := models.GetUserOIs(user.CID, ep.Facility)
-		if err != nil {
-			log.WithError(err).Errorf("Error getting OIs for user %d", user.CID)
-		}
-		resp.SecondaryAssigneeName = fmt.Sprintf("%s - %s", user.FirstName, ois)
+func NewEventPositionListResponse(eps []models.EventPosition) []render.Renderer {
+	list := []render.Renderer{}
+	for idx := range eps {
+		list = append(list, NewEventPositionResponse(&eps[idx]))
 	}
+	return list
+}
 
-	for idx, signup := range ep.Signups {
-		user := models.User{CID: signup.CID}
-		if err := user.Get(); err != nil {
-			log.WithError(err).Errorf("Error getting user %d", signup.CID)
-			continue
-		}
+type EventShiftRequest struct {
+	StartTime time.Time `json:"start_time" example:"2021-01-01T23:00:00Z" validate:"required"`
+	EndTime   time.Time `json:"end_time" example:"2021-01-02T01:00:00Z" validate:"required"`
+	Assignee  *uint     `json:"assignee" example:"1293257"`
+}
 
-		ois, err := models.GetUserOIs(user.CID, ep.Facility)
-		if err != nil {
-			log.WithError(err).Errorf("Error getting OIs for user %d", user.CID)
-		}
-		ep.Signups[idx].Name = fmt.Sprintf("%s - %s", user.FirstName, ois)
+func (req *EventShiftRequest) Validate() error {
+	if req.StartTime.IsZero() || req.EndTime.IsZero() {
+		return nil
+	}
+	if !req.EndTime.After(req.StartTime) {
+		return errors.New("end_time must be after start_time")
+	}
+	if req.EndTime.Sub(req.StartTime) < models.MinEventShiftLength {
+		return models.ErrEventShiftTooShort
 	}
+	return nil
+}
 
-	return resp
+func (req *EventShiftRequest) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
 }
 
-func (res *EventPositionResponse) Render(w http.ResponseWriter, r *http.Request) error {
-	if res.EventPosition == nil {
+type EventShiftResponse struct {
+	*models.EventShift
+}
+
+func NewEventShiftResponse(es *models.EventShift, facility constants.FacilityID) *EventShiftResponse {
+	setShiftNames(es, facility)
+	return &EventShiftResponse{EventShift: es}
+}
+
+func (res *EventShiftResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.EventShift == nil {
 		return nil
 	}
 	return nil
 }
 
-func NewEventPositionListResponse(eps []models.EventPosition) []render.Renderer {
+func NewEventShiftListResponse(ess []models.EventShift, facility constants.FacilityID) []render.Renderer {
 	list := []render.Renderer{}
-	for idx := range eps {
-		list = append(list, NewEventPositionResponse(&eps[idx]))
+	for idx := range ess {
+		list = append(list, NewEventShiftResponse(&ess[idx], facility))
 	}
 	return list
 }
 
+// setShiftNames fills in the display names for the assignee and signups of a shift
+func setShiftNames(es *models.EventShift, facility constants.FacilityID) {
+	if es.Assignee != 0 {
+		es.AssigneeName = displayName(es.Assignee, facility)
+	}
+
+	for idx, signup := range es.Signups {
+		es.Signups[idx].Name = displayName(signup.CID, facility)
+	}
+}
+
+func displayName(cid uint, facility constants.FacilityID) string {
+	user := models.User{CID: cid}
+	if err := user.Get(); err != nil {
+		log.WithError(err).Errorf("Error getting user %d", cid)
+		return "Unknown"
+	}
+
+	ois, err := models.GetUserOIs(user.CID, facility)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting OIs for user %d", user.CID)
+	}
+	return fmt.Sprintf("%s - %s", user.FirstName, ois)
+}
+
 type EventSignupRequest struct {
-	PositionID uint `json:"position_id" example:"1" validate:"required"`
-	CID        uint `json:"cid" example:"1293257" validate:"required"`
-	Shift      uint `json:"shift" example:"1" validate:"required"` // 1 = Primary, 2 = Secondary
+	ShiftID uint `json:"shift_id" example:"1" validate:"required"`
+	CID     uint `json:"cid" example:"1293257" validate:"required"`
 }
 
 func (req *EventSignupRequest) Validate() error {
@@ -262,3 +351,114 @@ func NewEventRoutingListResponse(ers []models.EventRouting) []render.Renderer {
 	}
 	return list
 }
+
+type EventStaffingShift struct {
+	ID        uint      `json:"id" example:"1"`
+	StartTime time.Time `json:"start_time" example:"2021-01-01T23:00:00Z"`
+	EndTime   time.Time `json:"end_time" example:"2021-01-02T01:00:00Z"`
+	Assignee  uint      `json:"assignee" example:"1293257"`
+	Signups   int       `json:"signups" example:"3"`
+}
+
+type EventStaffingPosition struct {
+	ID       uint                 `json:"id" example:"1"`
+	Facility constants.FacilityID `json:"facility" example:"ZDV"`
+	Position string               `json:"position" example:"ZDV_APP"`
+	Shifts   []EventStaffingShift `json:"shifts"`
+}
+
+type EventStaffingGap struct {
+	PositionID uint                 `json:"position_id" example:"1"`
+	ShiftID    uint                 `json:"shift_id" example:"1"`
+	Facility   constants.FacilityID `json:"facility" example:"ZDV"`
+	Position   string               `json:"position" example:"ZDV_APP"`
+	StartTime  time.Time            `json:"start_time" example:"2021-01-01T23:00:00Z"`
+	EndTime    time.Time            `json:"end_time" example:"2021-01-02T01:00:00Z"`
+}
+
+type EventStaffingResponse struct {
+	EventID           uint                    `json:"event_id" example:"1"`
+	Status            types.StaffingStatus    `json:"status" example:"draft"`
+	TotalPositions    int                     `json:"total_positions" example:"10"`
+	FilledPositions   int                     `json:"filled_positions" example:"8"`
+	TotalShifts       int                     `json:"total_shifts" example:"20"`
+	FilledShifts      int                     `json:"filled_shifts" example:"17"`
+	TotalSignups      int                     `json:"total_signups" example:"12"`
+	UnassignedSignups []uint                  `json:"unassigned_signups" example:"1293257"`
+	Gaps              []EventStaffingGap      `json:"gaps"`
+	Positions         []EventStaffingPosition `json:"positions"`
+}
+
+func NewEventStaffingResponse(ev *models.Event) *EventStaffingResponse {
+	resp := &EventStaffingResponse{
+		EventID:           ev.ID,
+		Status:            ev.StaffingStatus,
+		TotalPositions:    len(ev.Positions),
+		UnassignedSignups: []uint{},
+		Gaps:              []EventStaffingGap{},
+		Positions:         []EventStaffingPosition{},
+	}
+
+	assigned := map[uint]bool{}
+	for _, ep := range ev.Positions {
+		position := EventStaffingPosition{
+			ID:       ep.ID,
+			Facility: ep.Facility,
+			Position: ep.Position,
+			Shifts:   []EventStaffingShift{},
+		}
+
+		filled := len(ep.Shifts) > 0
+		for _, shift := range ep.Shifts {
+			resp.TotalShifts++
+			if shift.Assignee != 0 {
+				assigned[shift.Assignee] = true
+				resp.FilledShifts++
+			} else {
+				filled = false
+				resp.Gaps = append(resp.Gaps, EventStaffingGap{
+					PositionID: ep.ID,
+					ShiftID:    shift.ID,
+					Facility:   ep.Facility,
+					Position:   ep.Position,
+					StartTime:  shift.StartTime,
+					EndTime:    shift.EndTime,
+				})
+			}
+
+			position.Shifts = append(position.Shifts, EventStaffingShift{
+				ID:        shift.ID,
+				StartTime: shift.StartTime,
+				EndTime:   shift.EndTime,
+				Assignee:  shift.Assignee,
+				Signups:   len(shift.Signups),
+			})
+		}
+
+		if filled {
+			resp.FilledPositions++
+		}
+
+		resp.Positions = append(resp.Positions, position)
+	}
+
+	seen := map[uint]bool{}
+	for _, ep := range ev.Positions {
+		for _, shift := range ep.Shifts {
+			for _, signup := range shift.Signups {
+				resp.TotalSignups++
+				if assigned[signup.CID] || seen[signup.CID] {
+					continue
+				}
+				seen[signup.CID] = true
+				resp.UnassignedSignups = append(resp.UnassignedSignups, signup.CID)
+			}
+		}
+	}
+
+	return resp
+}
+
+func (res *EventStaffingResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
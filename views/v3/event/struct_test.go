@@ -0,0 +1,70 @@
+package event
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"testing"
+)
+
+func TestEventTemplateRequestValidate(t *testing.T) {
+	valid := func() EventTemplateRequest {
+		return EventTemplateRequest{
+			Title:      "KDEN FNO",
+			Positions:  []string{"DEN_APP", "DEN_TWR"},
+			Facilities: []constants.FacilityID{"ZDV"},
+			Fields:     []string{"KDEN"},
+			ShiftCount: 2,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(req *EventTemplateRequest)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(req *EventTemplateRequest) {}},
+		{name: "default shift count", modify: func(req *EventTemplateRequest) { req.ShiftCount = 0 }},
+		{name: "maximum shift count", modify: func(req *EventTemplateRequest) { req.ShiftCount = models.MaxEventShifts }},
+		{name: "too many shifts", modify: func(req *EventTemplateRequest) { req.ShiftCount = models.MaxEventShifts + 1 }, wantErr: true},
+		{name: "missing title", modify: func(req *EventTemplateRequest) { req.Title = "" }, wantErr: true},
+		{name: "missing positions", modify: func(req *EventTemplateRequest) { req.Positions = nil }, wantErr: true},
+		{name: "blank position", modify: func(req *EventTemplateRequest) { req.Positions = []string{"DEN_APP", ""} }, wantErr: true},
+		{name: "missing facilities", modify: func(req *EventTemplateRequest) { req.Facilities = nil }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.modify(&req)
+			if err := req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEventPositionRequestValidate(t *testing.T) {
+	count := func(n uint) *uint { return &n }
+
+	tests := []struct {
+		name       string
+		shiftCount *uint
+		wantErr    bool
+	}{
+		{name: "default shift count", shiftCount: nil},
+		{name: "single shift", shiftCount: count(1)},
+		{name: "maximum shift count", shiftCount: count(models.MaxEventShifts)},
+		{name: "zero shifts", shiftCount: count(0), wantErr: true},
+		{name: "too many shifts", shiftCount: count(models.MaxEventShifts + 1), wantErr: true},
+		{name: "huge shift count", shiftCount: count(1 << 31), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := EventPositionRequest{Position: "DEN_APP", ShiftCount: tt.shiftCount}
+			if err := req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
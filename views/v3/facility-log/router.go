@@ -1,7 +1,7 @@
 package facility_log
 
 import (
-	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
@@ -11,15 +11,15 @@ import (
 )
 
 func Router(r chi.Router) {
-	r.With(middleware.NotGuest, middleware.CanViewFacilityLog).Get("/", ListFacilityLog)
+	r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanViewFacilityLog).Get("/", ListFacilityLog)
 
 	r.Route("/{FacilityLogID}", func(r chi.Router) {
 		r.Use(Ctx)
 
-		r.With(middleware.NotGuest, middleware.CanEditFacility).Post("/", CreateFacilityLogEntry)
-		r.With(middleware.NotGuest, middleware.CanEditFacilityLog).Put("/", UpdateFacilityLog)
-		r.With(middleware.NotGuest, middleware.CanEditFacilityLog).Patch("/", PatchFacilityLog)
-		r.With(middleware.NotGuest, middleware.CanEditFacilityLog).Delete("/", DeleteFacilityLog)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFacility).Post("/", CreateFacilityLogEntry)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFacilityLog).Put("/", UpdateFacilityLog)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFacilityLog).Patch("/", PatchFacilityLog)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFacilityLog).Delete("/", DeleteFacilityLog)
 	})
 }
 
@@ -44,7 +44,7 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.FacilityLogKey{}, fac)
+		ctx := utils.WithResource(r, utils.FacilityLogKey{}, fac)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,166 @@
+package facility
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type DiscordGuildRequest struct {
+	GuildID string                      `json:"guild_id" example:"1234567890"`
+	Roles   []models.DiscordRoleMapping `json:"roles"`
+}
+
+func (req *DiscordGuildRequest) Validate() error {
+	if req.GuildID == "" {
+		return errors.New("guild_id is required")
+	}
+
+	for _, m := range req.Roles {
+		if m.DiscordRoleID == "" {
+			return errors.New("discord_role_id is required")
+		}
+
+		switch m.Source {
+		case types.DiscordSourceRating:
+			if !validRatingShort(m.Value) {
+				return fmt.Errorf("invalid rating %q", m.Value)
+			}
+		case types.DiscordSourceStaff:
+			if !constants.RoleID(m.Value).IsValidRole() && len(constants.GroupID(m.Value).RolesInGroup()) == 0 {
+				return fmt.Errorf("invalid role or group %q", m.Value)
+			}
+		case types.DiscordSourceHome, types.DiscordSourceVisiting:
+		default:
+			return fmt.Errorf("invalid source %q", m.Source)
+		}
+	}
+
+	return nil
+}
+
+func (req *DiscordGuildRequest) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+func validRatingShort(short string) bool {
+	for r := constants.InactiveRating; r <= constants.AdministratorRating; r++ {
+		if r.IsValidRating() && r.Short() == short {
+			return true
+		}
+	}
+	return false
+}
+
+type DiscordGuildResponse struct {
+	*models.DiscordGuild
+}
+
+func (res *DiscordGuildResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.DiscordGuild == nil {
+		return errors.New("discord guild not found")
+	}
+	return nil
+}
+
+// GetDiscordGuild godoc
+// @Summary Get a facility's Discord guild
+// @Description Get the facility's Discord guild and the roles mapped to ratings, rosters and staff roles
+// @Tags facility
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Success 200 {object} DiscordGuildResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/discord [get]
+func GetDiscordGuild(w http.ResponseWriter, r *http.Request) {
+	guild := &models.DiscordGuild{Facility: utils.GetFacilityCtx(r).ID}
+	if err := guild.Get(); err != nil {
+		utils.Render(w, r, utils.ErrNotFound)
+		return
+	}
+
+	utils.Render(w, r, &DiscordGuildResponse{DiscordGuild: guild})
+}
+
+// UpdateDiscordGuild godoc
+// @Summary Configure a facility's Discord guild
+// @Description Set the facility's Discord guild and replace its role mappings. Rating mappings take a rating such as
+// @Description S3, staff mappings a role such as ATM or a role group such as fac_staff. ZHQ is the division's guild,
+// @Description its mappings apply across every facility.
+// @Tags facility
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param discord body DiscordGuildRequest true "Discord Guild"
+// @Success 200 {object} DiscordGuildResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/discord [put]
+func UpdateDiscordGuild(w http.ResponseWriter, r *http.Request) {
+	data := &DiscordGuildRequest{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	fac := utils.GetFacilityCtx(r)
+	guild := &models.DiscordGuild{Facility: fac.ID}
+	if err := guild.Get(); err != nil {
+		guild = &models.DiscordGuild{Facility: fac.ID}
+	}
+
+	guild.GuildID = data.GuildID
+	guild.Roles = data.Roles
+	if err := guild.Update(); err != nil {
+		log.WithError(err).Errorf("Error saving Discord guild for %s", fac.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, &DiscordGuildResponse{DiscordGuild: guild})
+}
+
+// DeleteDiscordGuild godoc
+// @Summary Remove a facility's Discord guild
+// @Description Stop syncing roles to the facility's Discord guild. Roles already granted are left in place.
+// @Tags facility
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Success 204
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/discord [delete]
+func DeleteDiscordGuild(w http.ResponseWriter, r *http.Request) {
+	guild := &models.DiscordGuild{Facility: utils.GetFacilityCtx(r).ID}
+	if err := guild.Get(); err != nil {
+		utils.Render(w, r, utils.ErrNotFound)
+		return
+	}
+
+	if err := guild.Delete(); err != nil {
+		log.WithError(err).Errorf("Error deleting Discord guild for %s", guild.Facility)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
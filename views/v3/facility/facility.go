@@ -5,8 +5,11 @@ import (
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	"github.com/VATUSA/primary-api/pkg/utils"
+	api_key "github.com/VATUSA/primary-api/views/v3/api-key"
 	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
 	"net/http"
+	"time"
 )
 
 type Request struct {
@@ -14,6 +17,12 @@ type Request struct {
 	About      string `json:"about" example:"Seattle ARTCC contains ZSE... etc. etc. etc." validate:"required"`
 	URL        string `json:"url" example:"https://zseartcc.org" validate:"required"`
 	WebhookURL string `json:"webhook_url" example:"" validate:"required"`
+	// PromotionMinDaysAtRating raises the division's minimum time at rating before a promotion, left unchanged by a
+	// patch when omitted
+	PromotionMinDaysAtRating *uint `json:"promotion_min_days_at_rating" example:"45"`
+	// PromotionMinTrainingSessions is how many training sessions at the facility a promotion needs, left unchanged by
+	// a patch when omitted
+	PromotionMinTrainingSessions *uint `json:"promotion_min_training_sessions" example:"5"`
 }
 
 func (req *Request) Validate() error {
@@ -33,6 +42,9 @@ type Response struct {
 	About      string               `json:"about" example:"Denver ARTCC contains ZDV... etc. etc. etc."`
 	URL        string               `json:"url" example:"https://zdvartcc.org"`
 	WebhookURL string               `json:"webhook_url" example:""`
+
+	PromotionMinDaysAtRating     uint `json:"promotion_min_days_at_rating" example:"45"`
+	PromotionMinTrainingSessions uint `json:"promotion_min_training_sessions" example:"5"`
 }
 
 func NewFacilityResponse(facility *models.Facility) *Response {
@@ -42,6 +54,9 @@ func NewFacilityResponse(facility *models.Facility) *Response {
 		Name:       facility.Name,
 		URL:        facility.URL,
 		WebhookURL: facility.WebhookURL,
+
+		PromotionMinDaysAtRating:     facility.PromotionMinDaysAtRating,
+		PromotionMinTrainingSessions: facility.PromotionMinTrainingSessions,
 	}
 
 	return resp
@@ -122,7 +137,7 @@ func UpdateFacility(w http.ResponseWriter, r *http.Request) {
 	fac := utils.GetFacilityCtx(r)
 
 	req := &Request{}
-	if err := render.Bind(r, req); err != nil {
+	if err := req.Bind(r); err != nil {
 		utils.Render(w, r, utils.ErrBadRequest)
 		return
 	}
@@ -132,16 +147,27 @@ func UpdateFacility(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := *fac
 	fac.Name = req.Name
 	fac.About = req.About
 	fac.URL = req.URL
 	fac.WebhookURL = req.WebhookURL
+	fac.PromotionMinDaysAtRating = 0
+	if req.PromotionMinDaysAtRating != nil {
+		fac.PromotionMinDaysAtRating = *req.PromotionMinDaysAtRating
+	}
+	fac.PromotionMinTrainingSessions = 0
+	if req.PromotionMinTrainingSessions != nil {
+		fac.PromotionMinTrainingSessions = *req.PromotionMinTrainingSessions
+	}
 
 	if err := fac.Update(); err != nil {
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
 	}
 
+	utils.LogFacilityChange(r, fac.ID, "", models.Diff(&before, fac))
+
 	utils.Render(w, r, NewFacilityResponse(fac))
 }
 
@@ -162,35 +188,45 @@ func PatchFacility(w http.ResponseWriter, r *http.Request) {
 	fac := utils.GetFacilityCtx(r)
 
 	req := &Request{}
-	if err := render.Bind(r, req); err != nil {
+	if err := req.Bind(r); err != nil {
 		utils.Render(w, r, utils.ErrBadRequest)
 		return
 	}
 
-	if fac.Name != "" {
+	before := *fac
+	if req.Name != "" {
 		fac.Name = req.Name
 	}
-	if fac.About != "" {
+	if req.About != "" {
 		fac.About = req.About
 	}
-	if fac.URL != "" {
+	if req.URL != "" {
 		fac.URL = req.URL
 	}
-	if fac.WebhookURL != "" {
+	if req.WebhookURL != "" {
 		fac.WebhookURL = req.WebhookURL
 	}
+	if req.PromotionMinDaysAtRating != nil {
+		fac.PromotionMinDaysAtRating = *req.PromotionMinDaysAtRating
+	}
+	if req.PromotionMinTrainingSessions != nil {
+		fac.PromotionMinTrainingSessions = *req.PromotionMinTrainingSessions
+	}
 
 	if err := fac.Update(); err != nil {
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
 	}
 
+	utils.LogFacilityChange(r, fac.ID, "", models.Diff(&before, fac))
+
 	utils.Render(w, r, NewFacilityResponse(fac))
 }
 
 // ResetApiKey godoc
 // @Summary Regenerate an API key
-// @Description Regenerate an API key
+// @Description Create a new API key with every scope. Existing keys keep working for 24 hours so integrations can
+// @Description switch over. Deprecated: use /facility/{FacilityID}/api-keys to manage named and scoped keys.
 // @Tags facility
 // @Accept  json
 // @Produce  json
@@ -203,17 +239,34 @@ func PatchFacility(w http.ResponseWriter, r *http.Request) {
 func ResetApiKey(w http.ResponseWriter, r *http.Request) {
 	fac := utils.GetFacilityCtx(r)
 
-	key, err := models.GenerateApiKey()
+	existing, err := models.GetFacilityAPIKeys(fac.ID)
 	if err != nil {
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
 	}
 
-	fac.APIKey = key
-	if err := fac.Update(); err != nil {
+	key, plaintext, err := models.NewFacilityAPIKey(fac.ID, "Default", models.AllScopes(), nil, utils.GetXUser(r).CID)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	if err := key.Create(); err != nil {
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
 	}
 
-	utils.JSON(w, r, http.StatusOK, map[string]string{"api_key": key})
+	expiresAt := time.Now().Add(api_key.DefaultGracePeriod)
+	for idx := range existing {
+		old := &existing[idx]
+		if old.IsExpired() || (old.ExpiresAt != nil && old.ExpiresAt.Before(expiresAt)) {
+			continue
+		}
+		old.ExpiresAt = &expiresAt
+		if err := old.Update(); err != nil {
+			log.WithError(err).Errorf("Error expiring API key %d", old.ID)
+		}
+	}
+
+	utils.JSON(w, r, http.StatusOK, map[string]string{"api_key": plaintext})
 }
@@ -0,0 +1,119 @@
+package facility
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/dbtest"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPatchFacility(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want models.Facility
+	}{
+		{name: "webhook only", body: `{"webhook_url": "https://discord.com/api/webhooks/2"}`,
+			want: models.Facility{WebhookURL: "https://discord.com/api/webhooks/2", PromotionMinDaysAtRating: 45,
+				PromotionMinTrainingSessions: 5}},
+		{name: "days at rating only", body: `{"promotion_min_days_at_rating": 60}`,
+			want: models.Facility{WebhookURL: "https://discord.com/api/webhooks/1", PromotionMinDaysAtRating: 60,
+				PromotionMinTrainingSessions: 5}},
+		{name: "training sessions only", body: `{"promotion_min_training_sessions": 3}`,
+			want: models.Facility{WebhookURL: "https://discord.com/api/webhooks/1", PromotionMinDaysAtRating: 45,
+				PromotionMinTrainingSessions: 3}},
+		{name: "explicit zero", body: `{"promotion_min_days_at_rating": 0, "promotion_min_training_sessions": 0}`,
+			want: models.Facility{WebhookURL: "https://discord.com/api/webhooks/1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbtest.Open(t, &models.Facility{}, &models.FacilityLogEntry{})
+
+			fac := &models.Facility{ID: "ZDV", Name: "Denver ARTCC", WebhookURL: "https://discord.com/api/webhooks/1",
+				PromotionMinDaysAtRating: 45, PromotionMinTrainingSessions: 5}
+			if err := fac.Create(); err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodPatch, "/v3/facility/ZDV", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			r = r.WithContext(context.WithValue(r.Context(), utils.FacilityKey{}, fac))
+			r = r.WithContext(context.WithValue(r.Context(), utils.XUser{}, &models.User{CID: 1293257}))
+			w := httptest.NewRecorder()
+			PatchFacility(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("PatchFacility() returned %d: %s", w.Code, w.Body.String())
+			}
+
+			stored := &models.Facility{ID: "ZDV"}
+			if err := stored.Get(); err != nil {
+				t.Fatal(err)
+			}
+			if stored.Name != "Denver ARTCC" || stored.WebhookURL != tt.want.WebhookURL ||
+				stored.PromotionMinDaysAtRating != tt.want.PromotionMinDaysAtRating ||
+				stored.PromotionMinTrainingSessions != tt.want.PromotionMinTrainingSessions {
+				t.Errorf("facility = %q %q %d days %d sessions, want %q %q %d days %d sessions", stored.Name,
+					stored.WebhookURL, stored.PromotionMinDaysAtRating, stored.PromotionMinTrainingSessions, "Denver ARTCC",
+					tt.want.WebhookURL, tt.want.PromotionMinDaysAtRating, tt.want.PromotionMinTrainingSessions)
+			}
+		})
+	}
+}
+
+func TestFacilityKeyScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []constants.Scope
+		want   int
+	}{
+		{name: "other scope", scopes: []constants.Scope{constants.ReadRosterScope}, want: http.StatusForbidden},
+		{name: "read scope", scopes: []constants.Scope{constants.ReadFacilityScope}, want: http.StatusForbidden},
+		{name: "no scopes", scopes: []constants.Scope{}, want: http.StatusForbidden},
+		{name: "write scope", scopes: []constants.Scope{constants.WriteFacilityScope}, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbtest.Open(t, &models.Facility{}, &models.FacilityLogEntry{})
+
+			fac := &models.Facility{ID: "ZDV", Name: "Denver ARTCC"}
+			if err := fac.Create(); err != nil {
+				t.Fatal(err)
+			}
+
+			router := chi.NewRouter()
+			router.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ctx := context.WithValue(r.Context(), utils.XFacility{}, fac)
+					ctx = context.WithValue(ctx, utils.XGuest{}, false)
+					ctx = context.WithValue(ctx, utils.XFacilityScopes{}, tt.scopes)
+					next.ServeHTTP(w, r.WithContext(ctx))
+				})
+			})
+			router.Route("/facility", Router)
+
+			r := httptest.NewRequest(http.MethodPatch, "/facility/ZDV", strings.NewReader(`{"name": "Denver Center"}`))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("PATCH /facility/ZDV with %v returned %d, want %d", tt.scopes, w.Code, tt.want)
+			}
+
+			stored := &models.Facility{ID: "ZDV"}
+			if err := stored.Get(); err != nil {
+				t.Fatal(err)
+			}
+			if changed := stored.Name != "Denver ARTCC"; changed != (tt.want == http.StatusOK) {
+				t.Errorf("facility name = %q after a %d response", stored.Name, w.Code)
+			}
+		})
+	}
+}
@@ -1,18 +1,22 @@
 package facility
 
 import (
-	"context"
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
+	api_key "github.com/VATUSA/primary-api/views/v3/api-key"
+	email_template "github.com/VATUSA/primary-api/views/v3/email-template"
 	"github.com/VATUSA/primary-api/views/v3/event"
 	facility_log "github.com/VATUSA/primary-api/views/v3/facility-log"
 	"github.com/VATUSA/primary-api/views/v3/faq"
 	"github.com/VATUSA/primary-api/views/v3/feedback"
 	"github.com/VATUSA/primary-api/views/v3/news"
+	oauth_client "github.com/VATUSA/primary-api/views/v3/oauth-client"
+	"github.com/VATUSA/primary-api/views/v3/promotion"
 	"github.com/VATUSA/primary-api/views/v3/roster"
 	roster_request "github.com/VATUSA/primary-api/views/v3/roster-request"
+	"github.com/VATUSA/primary-api/views/v3/training"
 	"github.com/go-chi/chi/v5"
 	"net/http"
 )
@@ -25,14 +29,27 @@ func Router(r chi.Router) {
 
 		r.Get("/", GetFacility)
 
-		r.With(middleware.NotGuest, middleware.CanEditFacility).Put("/", UpdateFacility)
-		r.With(middleware.NotGuest, middleware.CanEditFacility).Patch("/", PatchFacility)
-		r.With(middleware.NotGuest, middleware.CanEditFacility).Post("/reset-api-key", ResetApiKey)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFacility).Put("/", UpdateFacility)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFacility).Patch("/", PatchFacility)
+		r.With(middleware.NoAccessToken, middleware.NotGuest, middleware.RequireUser, middleware.CanEditFacility).Post("/reset-api-key", ResetApiKey)
+
+		r.Route("/api-keys", func(r chi.Router) {
+			api_key.Router(r)
+		})
+
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFacility).Get("/discord", GetDiscordGuild)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFacility).Put("/discord", UpdateDiscordGuild)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFacility).Delete("/discord", DeleteDiscordGuild)
+
+		r.Route("/email-templates", func(r chi.Router) {
+			email_template.Router(r)
+		})
 
 		r.Route("/event-templates", func(r chi.Router) {
 			event.TemplateRouter(r)
 		})
 
+		r.Get("/events.ics", event.GetEventsCalendar)
 		r.Route("/events", func(r chi.Router) {
 			event.EventRouter(r)
 		})
@@ -53,6 +70,14 @@ func Router(r chi.Router) {
 			news.Router(r)
 		})
 
+		r.Route("/oauth-clients", func(r chi.Router) {
+			oauth_client.Router(r)
+		})
+
+		r.Route("/promotions", func(r chi.Router) {
+			promotion.FacilityRouter(r)
+		})
+
 		r.Route("/roster", func(r chi.Router) {
 			roster.Router(r)
 		})
@@ -60,6 +85,10 @@ func Router(r chi.Router) {
 		r.Route("/roster-request", func(r chi.Router) {
 			roster_request.Router(r)
 		})
+
+		r.Route("/training", func(r chi.Router) {
+			training.Router(r)
+		})
 	})
 }
 
@@ -78,7 +107,7 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.FacilityKey{}, fac)
+		ctx := utils.WithResource(r, utils.FacilityKey{}, fac)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
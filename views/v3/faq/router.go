@@ -1,7 +1,7 @@
 package faq
 
 import (
-	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
@@ -13,14 +13,14 @@ import (
 func Router(r chi.Router) {
 	r.Get("/", ListFAQ)
 
-	r.With(middleware.NotGuest, middleware.CanEditFAQ).Post("/", CreateFAQ)
+	r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFAQ).Post("/", CreateFAQ)
 
 	r.Route("/{FAQID}", func(r chi.Router) {
 		r.Use(Ctx)
 
-		r.With(middleware.NotGuest, middleware.CanEditFAQ).Put("/", UpdateFAQ)
-		r.With(middleware.NotGuest, middleware.CanEditFAQ).Patch("/", PatchFAQ)
-		r.With(middleware.NotGuest, middleware.CanEditFAQ).Delete("/", DeleteFAQ)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFAQ).Put("/", UpdateFAQ)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFAQ).Patch("/", PatchFAQ)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditFAQ).Delete("/", DeleteFAQ)
 	})
 }
 
@@ -44,7 +44,7 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.FAQKey{}, faq)
+		ctx := utils.WithResource(r, utils.FAQKey{}, faq)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/notify"
 	"github.com/VATUSA/primary-api/pkg/utils"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
@@ -233,6 +234,7 @@ func UpdateFeedback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	f := utils.GetFeedbackCtx(r)
+	oldStatus := f.Status
 	f.PilotCID = data.PilotCID
 	f.Callsign = data.Callsign
 	f.ControllerCID = data.ControllerCID
@@ -247,6 +249,8 @@ func UpdateFeedback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	notifyFeedbackAccepted(f, oldStatus)
+
 	render.Status(r, http.StatusNoContent)
 }
 
@@ -265,6 +269,7 @@ func UpdateFeedback(w http.ResponseWriter, r *http.Request) {
 // @Router /facility/{FacilityID}/feedback/{id} [patch]
 func PatchFeedback(w http.ResponseWriter, r *http.Request) {
 	f := utils.GetFeedbackCtx(r)
+	oldStatus := f.Status
 	data := &Request{}
 	if err := data.Bind(r); err != nil {
 		utils.Render(w, r, utils.ErrInvalidRequest(err))
@@ -305,6 +310,8 @@ func PatchFeedback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	notifyFeedbackAccepted(f, oldStatus)
+
 	render.Status(r, http.StatusNoContent)
 }
 
@@ -357,3 +364,27 @@ func GetUserFeedback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// notifyFeedbackAccepted notifies the controller when feedback about them is accepted
+func notifyFeedbackAccepted(f *models.Feedback, oldStatus types.StatusType) {
+	if oldStatus == types.Accepted || f.Status != types.Accepted {
+		return
+	}
+
+	notify.Send(&models.Notification{
+		CID:      f.ControllerCID,
+		Category: notify.CategoryFeedback,
+		Title:    "New Feedback",
+		Body:     "You received new feedback from " + f.Callsign + " for your session on " + f.Position + ".",
+		ExpireAt: time.Now().Add(30 * 24 * time.Hour),
+		Template: notify.TemplateFeedbackAccepted,
+		TemplateData: map[string]string{
+			"callsign": f.Callsign,
+			"position": f.Position,
+			"rating":   string(f.Rating),
+			"feedback": f.Feedback,
+			"comment":  f.Comment,
+		},
+		Facility: f.Facility,
+	})
+}
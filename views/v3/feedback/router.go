@@ -1,7 +1,7 @@
 package feedback
 
 import (
-	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
@@ -11,6 +11,8 @@ import (
 )
 
 func Router(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadFeedbackScope, constants.WriteFeedbackScope))
+
 	r.With(middleware.NotGuest, middleware.CanViewFeedback).Get("/", ListFeedback)
 	r.With(middleware.NotGuest, middleware.CanLeaveFeedback).Post("/", CreateFeedback)
 
@@ -42,7 +44,7 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.FeedbackKey{}, feedback)
+		ctx := utils.WithResource(r, utils.FeedbackKey{}, feedback)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -1,7 +1,7 @@
 package news
 
 import (
-	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
@@ -13,14 +13,14 @@ import (
 func Router(r chi.Router) {
 	r.Get("/", ListNews)
 
-	r.With(middleware.NotGuest, middleware.CanEditNews).Post("/", CreateNews)
+	r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditNews).Post("/", CreateNews)
 
 	r.Route("/{NewsID}", func(r chi.Router) {
 		r.Use(Ctx)
 
-		r.With(middleware.NotGuest, middleware.CanEditNews).Put("/", UpdateNews)
-		r.With(middleware.NotGuest, middleware.CanEditNews).Patch("/", PatchNews)
-		r.With(middleware.NotGuest, middleware.CanEditNews).Delete("/", DeleteNews)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditNews).Put("/", UpdateNews)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditNews).Patch("/", PatchNews)
+		r.With(middleware.RequireScope(constants.ReadFacilityScope, constants.WriteFacilityScope), middleware.NotGuest, middleware.CanEditNews).Delete("/", DeleteNews)
 	})
 }
 
@@ -44,7 +44,7 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.NewsKey{}, news)
+		ctx := utils.WithResource(r, utils.NewsKey{}, news)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
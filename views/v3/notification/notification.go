@@ -3,6 +3,7 @@ package notification
 import (
 	"encoding/json"
 	"errors"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	"github.com/VATUSA/primary-api/pkg/utils"
 	"github.com/go-chi/render"
@@ -31,6 +32,9 @@ func (req *Request) Bind(r *http.Request) error {
 
 type Response struct {
 	*models.Notification
+	// DeliveryErrors holds the last error of each failed delivery by delivery ID. It is only shown to staff who can
+	// view the user's notifications, as the errors can expose mail and Discord internals.
+	DeliveryErrors map[uint]string `json:"delivery_errors,omitempty" example:"1:dial tcp: connection refused"`
 }
 
 func NewNotificationResponse(n *models.Notification) *Response {
@@ -41,6 +45,19 @@ func (res *Response) Render(w http.ResponseWriter, r *http.Request) error {
 	if res.Notification == nil {
 		return errors.New("notification not found")
 	}
+
+	if target := utils.GetUserCtx(r); target != nil &&
+		utils.GetPrincipal(r).CanForUser(constants.ViewNotificationsPermission, target) {
+		for _, delivery := range res.Deliveries {
+			if delivery.LastError == "" {
+				continue
+			}
+			if res.DeliveryErrors == nil {
+				res.DeliveryErrors = map[uint]string{}
+			}
+			res.DeliveryErrors[delivery.ID] = delivery.LastError
+		}
+	}
 	return nil
 }
 
@@ -53,18 +70,30 @@ func NewNotificationListResponse(notifications []models.Notification) []render.R
 }
 
 // ListNotifications godoc
-// @Summary List all notifications
-// @Description List all notifications
+// @Summary List notifications
+// @Description List a user's active notifications, newest first. To get the next page pass the created_at and id of
+// @Description the last notification as before and before_id.
 // @Tags notification
 // @Accept  json
 // @Produce  json
-// @Param cid path int true "CID"
+// @Param CID path int true "CID"
+// @Param before query string false "Only notifications created before this RFC 3339 timestamp"
+// @Param before_id query int false "With before, also notifications created at before with a lower ID"
+// @Param limit query int false "Page size, default 25, max 100"
+// @Param unread query bool false "Only unread notifications"
 // @Success 200 {object} []Response
-// @Failure 422 {object} utils.ErrResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
 // @Failure 500 {object} utils.ErrResponse
-// @Router /user/{cid}/notification [get]
+// @Router /user/{CID}/notifications [get]
 func ListNotifications(w http.ResponseWriter, r *http.Request) {
-	notifications, err := models.GetAllNotifications()
+	cursor, limit, unread, err := parsePage(r)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	notifications, err := models.GetActiveNotificationsPage(utils.GetUserCtx(r).CID, cursor, limit, unread)
 	if err != nil {
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
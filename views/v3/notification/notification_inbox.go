@@ -0,0 +1,248 @@
+package notification
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultPageSize = 25
+	maxPageSize     = 100
+)
+
+type DismissRequest struct {
+	IDs []uint `json:"ids" example:"1,2,3"`
+	All bool   `json:"all" example:"false"`
+}
+
+func (req *DismissRequest) Validate() error {
+	if len(req.IDs) == 0 && !req.All {
+		return errors.New("ids or all is required")
+	}
+	return nil
+}
+
+func (req *DismissRequest) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+type UnreadCountResponse struct {
+	Unread        int64 `json:"unread" example:"3"`
+	Notifications int64 `json:"notifications" example:"2"`
+	Broadcasts    int64 `json:"broadcasts" example:"1"`
+}
+
+func (res *UnreadCountResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+type UpdatedResponse struct {
+	Updated int64 `json:"updated" example:"3"`
+}
+
+func (res *UpdatedResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// GetUnreadCount godoc
+// @Summary Get unread notification count
+// @Description Get the number of a user's active notifications and broadcasts that have not been read
+// @Tags notification
+// @Accept  json
+// @Produce  json
+// @Param CID path int true "CID"
+// @Success 200 {object} UnreadCountResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{CID}/notifications/unread-count [get]
+func GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserCtx(r)
+
+	count, err := models.CountUnreadNotifications(user.CID)
+	if err != nil {
+		log.WithError(err).Errorf("Error counting unread notifications for %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	broadcasts, err := models.GetUserBroadcasts(user)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting broadcasts for %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	var unreadBroadcasts int64
+	for _, b := range broadcasts {
+		if b.ReadAt == nil {
+			unreadBroadcasts++
+		}
+	}
+
+	utils.Render(w, r, &UnreadCountResponse{
+		Unread:        count + unreadBroadcasts,
+		Notifications: count,
+		Broadcasts:    unreadBroadcasts,
+	})
+}
+
+// ReadAllNotifications godoc
+// @Summary Mark all notifications read
+// @Description Mark all of a user's active notifications and broadcasts as read
+// @Tags notification
+// @Accept  json
+// @Produce  json
+// @Param CID path int true "CID"
+// @Success 200 {object} UpdatedResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{CID}/notifications/read-all [post]
+func ReadAllNotifications(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserCtx(r)
+
+	updated, err := models.MarkNotificationsRead(user.CID, nil)
+	if err != nil {
+		log.WithError(err).Errorf("Error marking notifications read for %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	broadcasts, err := models.GetUserBroadcasts(user)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting broadcasts for %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	var unread []uint
+	for _, b := range broadcasts {
+		if b.ReadAt == nil {
+			unread = append(unread, b.ID)
+		}
+	}
+	if err := models.MarkBroadcastsRead(user.CID, unread); err != nil {
+		log.WithError(err).Errorf("Error marking broadcasts read for %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+	updated += int64(len(unread))
+
+	utils.Render(w, r, &UpdatedResponse{Updated: updated})
+}
+
+// DismissNotifications godoc
+// @Summary Dismiss notifications
+// @Description Dismiss several of a user's notifications, or all of them with all=true. Dismissed notifications are
+// @Description no longer listed.
+// @Tags notification
+// @Accept  json
+// @Produce  json
+// @Param CID path int true "CID"
+// @Param dismiss body DismissRequest true "Notifications to dismiss"
+// @Success 200 {object} UpdatedResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{CID}/notifications/dismiss [post]
+func DismissNotifications(w http.ResponseWriter, r *http.Request) {
+	data := &DismissRequest{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	ids := data.IDs
+	if data.All {
+		ids = nil
+	}
+
+	user := utils.GetUserCtx(r)
+	updated, err := models.DismissNotifications(user.CID, ids)
+	if err != nil {
+		log.WithError(err).Errorf("Error dismissing notifications for %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, &UpdatedResponse{Updated: updated})
+}
+
+// ReadNotification godoc
+// @Summary Mark a notification read
+// @Description Mark a single notification as read
+// @Tags notification
+// @Accept  json
+// @Produce  json
+// @Param CID path int true "CID"
+// @Param NotificationID path int true "Notification ID"
+// @Success 200 {object} Response
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{CID}/notifications/{NotificationID}/read [post]
+func ReadNotification(w http.ResponseWriter, r *http.Request) {
+	n := utils.GetNotificationCtx(r)
+
+	if n.ReadAt == nil {
+		if _, err := models.MarkNotificationsRead(n.CID, []uint{n.ID}); err != nil {
+			log.WithError(err).Errorf("Error marking notification %d read", n.ID)
+			utils.Render(w, r, utils.ErrInternalServer)
+			return
+		}
+		now := time.Now()
+		n.ReadAt = &now
+	}
+
+	utils.Render(w, r, NewNotificationResponse(n))
+}
+
+// parsePage reads the before, before_id, limit and unread query parameters used to page through notifications
+func parsePage(r *http.Request) (cursor models.NotificationCursor, limit int, unread bool, err error) {
+	limit = defaultPageSize
+	q := r.URL.Query()
+
+	if v := q.Get("before"); v != "" {
+		if cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return cursor, limit, unread, errors.New("before must be an RFC 3339 timestamp")
+		}
+	}
+
+	if v := q.Get("before_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || cursor.CreatedAt.IsZero() {
+			return cursor, limit, unread, errors.New("before_id must be a notification ID and needs before")
+		}
+		cursor.ID = uint(id)
+	}
+
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			return cursor, limit, unread, errors.New("limit must be a positive integer")
+		}
+		if limit > maxPageSize {
+			limit = maxPageSize
+		}
+	}
+
+	if v := q.Get("unread"); v != "" {
+		if unread, err = strconv.ParseBool(v); err != nil {
+			return cursor, limit, unread, errors.New("unread must be a boolean")
+		}
+	}
+
+	return cursor, limit, unread, nil
+}
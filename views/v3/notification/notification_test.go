@@ -0,0 +1,56 @@
+package notification
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/database/dbtest"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListNotificationsDeliveryErrors(t *testing.T) {
+	dbtest.Open(t, &models.Notification{}, &models.NotificationDelivery{})
+
+	owner := &models.User{CID: 1000001, Roster: []models.Roster{{CID: 1000001, Facility: "ZDV", Home: true}}}
+	n := &models.Notification{CID: owner.CID, Title: "Upcoming Training Session", ExpireAt: time.Now().Add(time.Hour)}
+	if err := n.Create(); err != nil {
+		t.Fatal(err)
+	}
+	delivery := &models.NotificationDelivery{NotificationID: n.ID, CID: owner.CID, Channel: types.ChannelEmail,
+		Status: types.DeliveryFailed, Attempts: 5, LastError: "dial tcp 10.0.0.25:587: connection refused"}
+	if err := delivery.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		principal *models.User
+		want      bool
+	}{
+		{name: "owner", principal: owner},
+		{name: "VATUSA staff", principal: &models.User{CID: 1293257, Roster: []models.Roster{{CID: 1293257, Facility: "ZHQ"}}},
+			want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v3/user/1000001/notifications", nil)
+			ctx := context.WithValue(r.Context(), utils.UserKey{}, owner)
+			ctx = context.WithValue(ctx, utils.XUser{}, tt.principal)
+			w := httptest.NewRecorder()
+			ListNotifications(w, r.WithContext(ctx))
+			if w.Code != http.StatusOK {
+				t.Fatalf("ListNotifications() returned %d: %s", w.Code, w.Body.String())
+			}
+
+			if got := strings.Contains(w.Body.String(), "connection refused"); got != tt.want {
+				t.Errorf("delivery error shown = %v, want %v: %s", got, tt.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -1,7 +1,7 @@
 package notification
 
 import (
-	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
@@ -11,11 +11,18 @@ import (
 )
 
 func Router(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadNotificationsScope, constants.WriteNotificationsScope))
+
 	r.With(middleware.NotGuest, middleware.CanViewNotifications).Get("/", ListNotifications)
+	r.With(middleware.NotGuest, middleware.CanViewNotifications).Get("/unread-count", GetUnreadCount)
+	r.With(middleware.NotGuest, middleware.CanManageOwnNotifications).Post("/read-all", ReadAllNotifications)
+	r.With(middleware.NotGuest, middleware.CanManageOwnNotifications).Post("/dismiss", DismissNotifications)
 
 	r.Route("/{NotificationID}", func(r chi.Router) {
 		r.Use(Ctx)
 
+		r.With(middleware.NotGuest, middleware.CanManageOwnNotifications).Post("/read", ReadNotification)
+
 		r.With(middleware.NotGuest, middleware.CanEditNotifications).Put("/", UpdateNotification)
 		r.With(middleware.NotGuest, middleware.CanEditNotifications).Patch("/", PatchNotification)
 		r.With(middleware.NotGuest, middleware.CanEditNotifications).Delete("/", DeleteNotification)
@@ -42,7 +49,13 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.NotificationKey{}, notification)
+		// Notifications are only reachable under the user they belong to
+		if user := utils.GetUserCtx(r); user == nil || user.CID != notification.CID {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		ctx := utils.WithResource(r, utils.NotificationKey{}, notification)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
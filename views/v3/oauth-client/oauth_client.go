@@ -0,0 +1,296 @@
+package oauth_client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	gonanoid "github.com/matoous/go-nanoid"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+type Request struct {
+	Name         string   `json:"name" example:"Denver ARTCC Website"`
+	RedirectURIs []string `json:"redirect_uris" example:"https://zdvartcc.org/login/callback"`
+	Public       bool     `json:"public" example:"false"`
+}
+
+func (req *Request) Validate() error {
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(req.RedirectURIs) == 0 {
+		return errors.New("at least one redirect URI is required")
+	}
+	for _, uri := range req.RedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateRedirectURI only allows absolute https URIs, and http for local development
+func validateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("redirect URI %q must be an absolute URL", uri)
+	}
+	if u.Fragment != "" {
+		return fmt.Errorf("redirect URI %q must not contain a fragment", uri)
+	}
+
+	local := u.Hostname() == "localhost" || u.Hostname() == "127.0.0.1"
+	if u.Scheme != "https" && !(u.Scheme == "http" && local) {
+		return fmt.Errorf("redirect URI %q must use https", uri)
+	}
+
+	return nil
+}
+
+func (req *Request) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+type Response struct {
+	*models.OAuthClient
+}
+
+func (res *Response) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.OAuthClient == nil {
+		return errors.New("oauth client not found")
+	}
+	return nil
+}
+
+// SecretResponse includes the client secret, which is only ever shown when it is generated
+type SecretResponse struct {
+	*models.OAuthClient
+	ClientSecret string `json:"client_secret,omitempty" example:"Ab12Cd34..."`
+}
+
+func (res *SecretResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// ListOAuthClients godoc
+// @Summary List OAuth clients
+// @Description List the facility's OAuth clients, which sign users in with their VATUSA login
+// @Tags oauth-client
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Success 200 {object} []Response
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/oauth-clients [get]
+func ListOAuthClients(w http.ResponseWriter, r *http.Request) {
+	fac := utils.GetFacilityCtx(r)
+
+	clients, err := models.GetOAuthClientsByFacility(fac.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting OAuth clients for %s", fac.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	list := []render.Renderer{}
+	for idx := range clients {
+		list = append(list, &Response{OAuthClient: &clients[idx]})
+	}
+
+	if err := render.RenderList(w, r, list); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// CreateOAuthClient godoc
+// @Summary Register an OAuth client
+// @Description Register a website to sign users in with the API's OAuth2 provider. Confidential clients get a
+// @Description client_secret, which is only shown in this response. Public clients have no secret and must use PKCE.
+// @Tags oauth-client
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param oauth_client body Request true "OAuth Client"
+// @Success 201 {object} SecretResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/oauth-clients [post]
+func CreateOAuthClient(w http.ResponseWriter, r *http.Request) {
+	data := &Request{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	fac := utils.GetFacilityCtx(r)
+	id, err := gonanoid.Generate(alphabet, 16)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	client := &models.OAuthClient{
+		ClientID:     strings.ToLower(string(fac.ID)) + "_" + id,
+		Facility:     fac.ID,
+		Name:         data.Name,
+		RedirectURIs: data.RedirectURIs,
+		Public:       data.Public,
+		CreatedBy:    utils.GetXUser(r).CID,
+	}
+
+	secret := ""
+	if !client.Public {
+		secret, err = gonanoid.Generate(alphabet, 48)
+		if err != nil {
+			utils.Render(w, r, utils.ErrInternalServer)
+			return
+		}
+		client.SecretHash = models.HashToken(secret)
+	}
+
+	if err := client.Create(); err != nil {
+		log.WithError(err).Errorf("Error creating OAuth client for %s", fac.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Response(r, http.StatusCreated)
+	utils.Render(w, r, &SecretResponse{OAuthClient: client, ClientSecret: secret})
+}
+
+// GetOAuthClient godoc
+// @Summary Get an OAuth client
+// @Description Get an OAuth client
+// @Tags oauth-client
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param ClientID path string true "Client ID"
+// @Success 200 {object} Response
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/oauth-clients/{ClientID} [get]
+func GetOAuthClient(w http.ResponseWriter, r *http.Request) {
+	utils.Render(w, r, &Response{OAuthClient: utils.GetOAuthClientCtx(r)})
+}
+
+// UpdateOAuthClient godoc
+// @Summary Update an OAuth client
+// @Description Update the name and redirect URIs of an OAuth client. Whether it is public cannot be changed.
+// @Tags oauth-client
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param ClientID path string true "Client ID"
+// @Param oauth_client body Request true "OAuth Client"
+// @Success 200 {object} Response
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/oauth-clients/{ClientID} [put]
+func UpdateOAuthClient(w http.ResponseWriter, r *http.Request) {
+	data := &Request{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	client := utils.GetOAuthClientCtx(r)
+	client.Name = data.Name
+	client.RedirectURIs = data.RedirectURIs
+	if err := client.Update(); err != nil {
+		log.WithError(err).Errorf("Error updating OAuth client %s", client.ClientID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, &Response{OAuthClient: client})
+}
+
+// ResetOAuthClientSecret godoc
+// @Summary Reset an OAuth client's secret
+// @Description Generate a new secret for a confidential OAuth client. The old secret stops working immediately.
+// @Tags oauth-client
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param ClientID path string true "Client ID"
+// @Success 200 {object} SecretResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/oauth-clients/{ClientID}/reset-secret [post]
+func ResetOAuthClientSecret(w http.ResponseWriter, r *http.Request) {
+	client := utils.GetOAuthClientCtx(r)
+	if client.Public {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("public clients have no secret")))
+		return
+	}
+
+	secret, err := gonanoid.Generate(alphabet, 48)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	client.SecretHash = models.HashToken(secret)
+	if err := client.Update(); err != nil {
+		log.WithError(err).Errorf("Error resetting secret of OAuth client %s", client.ClientID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, &SecretResponse{OAuthClient: client, ClientSecret: secret})
+}
+
+// DeleteOAuthClient godoc
+// @Summary Delete an OAuth client
+// @Description Delete an OAuth client and revoke every refresh token issued to it
+// @Tags oauth-client
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param ClientID path string true "Client ID"
+// @Success 204
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/oauth-clients/{ClientID} [delete]
+func DeleteOAuthClient(w http.ResponseWriter, r *http.Request) {
+	client := utils.GetOAuthClientCtx(r)
+	if err := client.Delete(); err != nil {
+		log.WithError(err).Errorf("Error deleting OAuth client %s", client.ClientID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,44 @@
+package oauth_client
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+// Router serves a facility's OAuth clients, which only logged-in facility staff can manage
+func Router(r chi.Router) {
+	r.Use(middleware.NoAccessToken, middleware.NotGuest, middleware.RequireUser, middleware.CanEditFacility)
+
+	r.Get("/", ListOAuthClients)
+	r.Post("/", CreateOAuthClient)
+
+	r.Route("/{ClientID}", func(r chi.Router) {
+		r.Use(Ctx)
+
+		r.Get("/", GetOAuthClient)
+		r.Put("/", UpdateOAuthClient)
+		r.Delete("/", DeleteOAuthClient)
+		r.Post("/reset-secret", ResetOAuthClientSecret)
+	})
+}
+
+func Ctx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := &models.OAuthClient{ClientID: chi.URLParam(r, "ClientID")}
+		if client.ClientID == "" {
+			utils.Render(w, r, utils.ErrBadRequest)
+			return
+		}
+
+		if err := client.Get(); err != nil || client.Facility != utils.GetFacilityCtx(r).ID {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		ctx := utils.WithResource(r, utils.OAuthClientKey{}, client)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
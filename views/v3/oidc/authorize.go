@@ -0,0 +1,280 @@
+package oidc
+
+import (
+	"embed"
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/config"
+	"github.com/VATUSA/primary-api/pkg/cookie"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/oauth"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	gonanoid "github.com/matoous/go-nanoid"
+	log "github.com/sirupsen/logrus"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	alphabet     = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	codeLifetime = 5 * time.Minute
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+var consentTemplate = template.Must(template.ParseFS(templateFS, "templates/consent.html"))
+
+// scopeDescriptions tell users what a client receives for each scope on the consent page
+var scopeDescriptions = map[string]string{
+	oauth.ScopeOpenID:  "Your CID and controller rating",
+	oauth.ScopeProfile: "Your name",
+	oauth.ScopeEmail:   "Your email address",
+	oauth.ScopeRoster:  "The facilities you are on the roster of and your staff roles",
+}
+
+// authorizeRequest is a validated authorization request
+type authorizeRequest struct {
+	client      *models.OAuthClient
+	redirectURI string
+	state       string
+	scopes      []string
+	nonce       string
+	challenge   string
+}
+
+// parseAuthorizeRequest validates the authorization request in params. Errors are sent to the client's redirect URI
+// once it is known to be registered, and false is returned.
+func parseAuthorizeRequest(w http.ResponseWriter, r *http.Request, params url.Values) (*authorizeRequest, bool) {
+	client := &models.OAuthClient{ClientID: params.Get("client_id")}
+	if client.ClientID == "" || client.Get() != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("unknown client_id")))
+		return nil, false
+	}
+
+	redirectURI := params.Get("redirect_uri")
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !client.AllowsRedirect(redirectURI) {
+		// Never redirect to an unregistered URI, even to report the error
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("redirect_uri is not registered for this client")))
+		return nil, false
+	}
+
+	state := params.Get("state")
+	if params.Get("response_type") != "code" {
+		redirectError(w, r, redirectURI, state, "unsupported_response_type", "only the code response type is supported")
+		return nil, false
+	}
+
+	scopes := oauth.ParseScope(params.Get("scope"))
+	if !slices.Contains(scopes, oauth.ScopeOpenID) {
+		redirectError(w, r, redirectURI, state, "invalid_scope", "the openid scope is required")
+		return nil, false
+	}
+
+	challenge := params.Get("code_challenge")
+	if challenge == "" || params.Get("code_challenge_method") != "S256" {
+		redirectError(w, r, redirectURI, state, "invalid_request", "a code_challenge with the S256 method is required")
+		return nil, false
+	}
+
+	return &authorizeRequest{
+		client:      client,
+		redirectURI: redirectURI,
+		state:       state,
+		scopes:      scopes,
+		nonce:       params.Get("nonce"),
+		challenge:   challenge,
+	}, true
+}
+
+// GetAuthorize godoc
+// @Summary Authorize a client
+// @Description Start an OpenID Connect login for a facility website. Users without a VATUSA session are sent through
+// @Description the VATSIM login first and brought back here. Users are asked to approve a client the first time it
+// @Description requests a scope. PKCE with S256 is required for every client.
+// @Tags oauth
+// @Produce  html
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param scope query string true "Space separated scopes, must include openid"
+// @Param state query string false "Returned to the client unchanged"
+// @Param nonce query string false "Included in the ID token"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Success 200 "Consent page"
+// @Success 302
+// @Failure 400 {object} utils.ErrResponse
+// @Router /oauth/authorize [get]
+func GetAuthorize(w http.ResponseWriter, r *http.Request) {
+	req, ok := parseAuthorizeRequest(w, r, r.URL.Query())
+	if !ok {
+		return
+	}
+
+	user := utils.GetXUser(r)
+	if user == nil {
+		// Come back here once the VATSIM login has set the session cookie
+		login := config.Cfg.API.BaseURL + "/v3/user/login?redirect=" + url.QueryEscape(config.Cfg.API.BaseURL+r.URL.RequestURI())
+		utils.TempRedirect(w, r, login)
+		return
+	}
+
+	consent, err := models.GetOAuthConsent(user.CID, req.client.ClientID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting consent of %d for %s", user.CID, req.client.ClientID)
+		redirectError(w, r, req.redirectURI, req.state, "server_error", "")
+		return
+	}
+
+	if !consent.Covers(req.scopes) {
+		renderConsent(w, r, req, user)
+		return
+	}
+
+	issueCode(w, r, req, user)
+}
+
+// PostAuthorize godoc
+// @Summary Approve or deny a client
+// @Description Submitted by the consent page of GET /oauth/authorize with the original authorization request. An
+// @Description approval is recorded so the user isn't asked again for the same scopes.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Param consent formData string true "Token from the consent page"
+// @Param approve formData bool true "Whether the user approved the client"
+// @Success 302
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Router /oauth/authorize [post]
+func PostAuthorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	user := utils.GetXUser(r)
+	if user == nil {
+		utils.Render(w, r, utils.ErrForbidden)
+		return
+	}
+
+	req, ok := parseAuthorizeRequest(w, r, r.PostForm)
+	if !ok {
+		return
+	}
+
+	// Only the consent page shown to this user for this request can approve it
+	if !validConsentToken(r.PostForm.Get("consent"), req, user) {
+		utils.Render(w, r, utils.ErrForbidden)
+		return
+	}
+
+	if r.PostForm.Get("approve") != "true" {
+		redirectError(w, r, req.redirectURI, req.state, "access_denied", "the user denied the request")
+		return
+	}
+
+	consent, err := models.GetOAuthConsent(user.CID, req.client.ClientID)
+	if err == nil {
+		err = consent.Grant(req.scopes)
+	}
+	if err != nil {
+		log.WithError(err).Errorf("Error saving consent of %d for %s", user.CID, req.client.ClientID)
+		redirectError(w, r, req.redirectURI, req.state, "server_error", "")
+		return
+	}
+
+	issueCode(w, r, req, user)
+}
+
+// validConsentToken reports whether token was issued by the consent page shown to user for req
+func validConsentToken(token string, req *authorizeRequest, user *models.User) bool {
+	values := map[string]string{}
+	if err := cookie.CookieStore.Decode("oauth_consent", token, &values); err != nil {
+		return false
+	}
+	return values["cid"] == strconv.FormatUint(uint64(user.CID), 10) && values["client_id"] == req.client.ClientID &&
+		values["scope"] == strings.Join(req.scopes, " ")
+}
+
+func renderConsent(w http.ResponseWriter, r *http.Request, req *authorizeRequest, user *models.User) {
+	token, err := cookie.CookieStore.Encode("oauth_consent", map[string]string{
+		"cid":       strconv.FormatUint(uint64(user.CID), 10),
+		"client_id": req.client.ClientID,
+		"scope":     strings.Join(req.scopes, " "),
+	})
+	if err != nil {
+		log.WithError(err).Error("Error encoding consent token")
+		redirectError(w, r, req.redirectURI, req.state, "server_error", "")
+		return
+	}
+
+	scopes := make([]string, 0, len(req.scopes))
+	for _, s := range req.scopes {
+		scopes = append(scopes, scopeDescriptions[s])
+	}
+
+	name := user.FirstName
+	if user.PrefNameEnabled && user.PreferredName != "" {
+		name = user.PreferredName
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// The page must not be framed, or another site could trick users into approving
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+
+	err = consentTemplate.Execute(w, map[string]interface{}{
+		"Client":  req.client.Name,
+		"Name":    name + " " + user.LastName,
+		"CID":     user.CID,
+		"Scopes":  scopes,
+		"Action":  config.Cfg.API.BaseURL + r.URL.Path,
+		"Params":  r.URL.Query(),
+		"Consent": token,
+	})
+	if err != nil {
+		log.WithError(err).Error("Error rendering consent page")
+	}
+}
+
+// issueCode sends a new authorization code for user to the client's redirect URI
+func issueCode(w http.ResponseWriter, r *http.Request, req *authorizeRequest, user *models.User) {
+	code, err := gonanoid.Generate(alphabet, 48)
+	if err != nil {
+		redirectError(w, r, req.redirectURI, req.state, "server_error", "")
+		return
+	}
+
+	authCode := &models.OAuthAuthorizationCode{
+		CodeHash:      models.HashToken(code),
+		ClientID:      req.client.ClientID,
+		CID:           user.CID,
+		RedirectURI:   req.redirectURI,
+		Scope:         strings.Join(req.scopes, " "),
+		Nonce:         req.nonce,
+		CodeChallenge: req.challenge,
+		AuthTime:      user.LastLogin,
+		ExpiresAt:     time.Now().Add(codeLifetime),
+	}
+	if err := authCode.Create(); err != nil {
+		log.WithError(err).Errorf("Error creating authorization code for %s", req.client.ClientID)
+		redirectError(w, r, req.redirectURI, req.state, "server_error", "")
+		return
+	}
+
+	params := url.Values{"code": {code}}
+	if req.state != "" {
+		params.Set("state", req.state)
+	}
+	http.Redirect(w, r, withQuery(req.redirectURI, params), http.StatusFound)
+}
@@ -0,0 +1,174 @@
+package oidc
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/config"
+	"github.com/VATUSA/primary-api/pkg/cookie"
+	"github.com/VATUSA/primary-api/pkg/database/dbtest"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/gorilla/securecookie"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var consentField = regexp.MustCompile(`name="consent" value="([^"]+)"`)
+
+func setupAuthorize(t *testing.T) {
+	t.Helper()
+
+	dbtest.Open(t, &models.OAuthClient{}, &models.OAuthConsent{}, &models.OAuthAuthorizationCode{})
+
+	previousCfg, previousStore := config.Cfg, cookie.CookieStore
+	config.Cfg = &config.Config{API: &config.APIConfig{BaseURL: "https://api.vatusa.net"}}
+	cookie.CookieStore = securecookie.New(securecookie.GenerateRandomKey(64), securecookie.GenerateRandomKey(32))
+	t.Cleanup(func() { config.Cfg, cookie.CookieStore = previousCfg, previousStore })
+
+	client := &models.OAuthClient{
+		ClientID:     "zdv",
+		Facility:     "ZDV",
+		Name:         "Denver ARTCC Website",
+		RedirectURIs: []string{"https://zdvartcc.org/callback"},
+	}
+	if err := client.Create(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func authorizeParams(scope string) url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"zdv"},
+		"redirect_uri":          {"https://zdvartcc.org/callback"},
+		"scope":                 {scope},
+		"state":                 {"xyz"},
+		"code_challenge":        {"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+func asUser(r *http.Request, cid uint) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), utils.XUser{}, &models.User{CID: cid, FirstName: "Raaj", LastName: "Patel"}))
+}
+
+func getAuthorize(cid uint, params url.Values) *httptest.ResponseRecorder {
+	r := asUser(httptest.NewRequest(http.MethodGet, "/v3/oauth/authorize?"+params.Encode(), nil), cid)
+	w := httptest.NewRecorder()
+	GetAuthorize(w, r)
+	return w
+}
+
+func postAuthorize(cid uint, params url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/v3/oauth/authorize", strings.NewReader(params.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r = asUser(r, cid)
+	w := httptest.NewRecorder()
+	PostAuthorize(w, r)
+	return w
+}
+
+// consentPage asserts that w is the consent page and returns its consent token
+func consentPage(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("expected the consent page, got %d %s: %s", w.Code, w.Header().Get("Location"), w.Body.String())
+	}
+	if w.Header().Get("X-Frame-Options") != "DENY" {
+		t.Error("consent page can be framed")
+	}
+	match := consentField.FindStringSubmatch(w.Body.String())
+	if match == nil {
+		t.Fatalf("consent page has no consent token:\n%s", w.Body.String())
+	}
+	return html.UnescapeString(match[1])
+}
+
+// redirectQuery asserts that w redirects to the client and returns the query it was given
+func redirectQuery(t *testing.T, w *httptest.ResponseRecorder) url.Values {
+	t.Helper()
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	if w.Code != http.StatusFound || err != nil || !strings.HasPrefix(location.String(), "https://zdvartcc.org/callback?") {
+		t.Fatalf("expected a redirect to the client, got %d %s", w.Code, w.Header().Get("Location"))
+	}
+	return location.Query()
+}
+
+func TestAuthorizeConsent(t *testing.T) {
+	setupAuthorize(t)
+
+	// The first request asks the user
+	params := authorizeParams("openid profile")
+	token := consentPage(t, getAuthorize(1293257, params))
+
+	// Approving issues a code
+	approve := authorizeParams("openid profile")
+	approve.Set("consent", token)
+	approve.Set("approve", "true")
+	if query := redirectQuery(t, postAuthorize(1293257, approve)); query.Get("code") == "" || query.Get("state") != "xyz" {
+		t.Errorf("approval redirected with %v, want a code and the state", query)
+	}
+
+	// The approval is remembered, including for a subset of the scopes
+	for _, scope := range []string{"openid profile", "openid"} {
+		if query := redirectQuery(t, getAuthorize(1293257, authorizeParams(scope))); query.Get("code") == "" {
+			t.Errorf("approved scope %q redirected with %v, want a code", scope, query)
+		}
+	}
+
+	// A scope that wasn't approved asks again
+	consentPage(t, getAuthorize(1293257, authorizeParams("openid profile email")))
+
+	// Another user is asked on their own
+	consentPage(t, getAuthorize(1000000, params))
+}
+
+func TestAuthorizeDeny(t *testing.T) {
+	setupAuthorize(t)
+
+	token := consentPage(t, getAuthorize(1293257, authorizeParams("openid email")))
+
+	deny := authorizeParams("openid email")
+	deny.Set("consent", token)
+	deny.Set("approve", "false")
+	if query := redirectQuery(t, postAuthorize(1293257, deny)); query.Get("error") != "access_denied" || query.Get("code") != "" {
+		t.Errorf("denial redirected with %v, want access_denied", query)
+	}
+
+	consentPage(t, getAuthorize(1293257, authorizeParams("openid email")))
+}
+
+func TestAuthorizeConsentToken(t *testing.T) {
+	setupAuthorize(t)
+
+	token := consentPage(t, getAuthorize(1293257, authorizeParams("openid")))
+
+	tests := []struct {
+		name  string
+		cid   uint
+		token string
+		scope string
+	}{
+		{name: "missing token", cid: 1293257, token: "", scope: "openid"},
+		{name: "forged token", cid: 1293257, token: "forged", scope: "openid"},
+		{name: "another user", cid: 1000000, token: token, scope: "openid"},
+		{name: "more scopes than shown", cid: 1293257, token: token, scope: "openid email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := authorizeParams(tt.scope)
+			params.Set("consent", tt.token)
+			params.Set("approve", "true")
+			if w := postAuthorize(tt.cid, params); w.Code != http.StatusForbidden {
+				t.Errorf("approval returned %d %s, want 403", w.Code, w.Header().Get("Location"))
+			}
+		})
+	}
+}
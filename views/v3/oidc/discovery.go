@@ -0,0 +1,114 @@
+package oidc
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/oauth"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type Configuration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// GetConfiguration godoc
+// @Summary OpenID Connect discovery
+// @Description The provider's OpenID Connect configuration
+// @Tags oauth
+// @Produce  json
+// @Success 200 {object} Configuration
+// @Router /oauth/.well-known/openid-configuration [get]
+func GetConfiguration(w http.ResponseWriter, r *http.Request) {
+	issuer := oauth.Provider.Issuer
+	render.JSON(w, r, Configuration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/authorize",
+		TokenEndpoint:                     issuer + "/token",
+		UserinfoEndpoint:                  issuer + "/userinfo",
+		RevocationEndpoint:                issuer + "/revoke",
+		JWKSURI:                           issuer + "/jwks",
+		ScopesSupported:                   oauth.SupportedScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{
+			"sub", "cid", "rating", "rating_short", "name", "given_name", "family_name", "email", "rosters", "roles",
+		},
+	})
+}
+
+// GetJWKS godoc
+// @Summary JSON Web Key Set
+// @Description The public keys ID and access tokens are signed with
+// @Tags oauth
+// @Produce  json
+// @Success 200 {object} oauth.JWKS
+// @Router /oauth/jwks [get]
+func GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	render.JSON(w, r, oauth.Provider.JWKS())
+}
+
+type UserInfoResponse struct {
+	Subject string `json:"sub" example:"1293257"`
+	oauth.UserClaims
+}
+
+func (res *UserInfoResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// GetUserInfo godoc
+// @Summary OpenID Connect userinfo
+// @Description The claims of the user an OAuth access token was issued for
+// @Tags oauth
+// @Produce  json
+// @Param Authorization header string true "Bearer access token"
+// @Success 200 {object} UserInfoResponse
+// @Failure 401 {object} utils.ErrResponse
+// @Router /oauth/userinfo [get]
+func GetUserInfo(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	claims := oauth.AccessTokenClaims{}
+	if !ok || oauth.Provider.Verify(token, &claims) != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		utils.Render(w, r, utils.ErrUnauthorized)
+		return
+	}
+
+	cid, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		utils.Render(w, r, utils.ErrUnauthorized)
+		return
+	}
+
+	user := &models.User{CID: uint(cid)}
+	if err := user.Get(); err != nil {
+		utils.Render(w, r, utils.ErrUnauthorized)
+		return
+	}
+
+	utils.Render(w, r, &UserInfoResponse{
+		Subject:    claims.Subject,
+		UserClaims: oauth.NewUserClaims(user, strings.Fields(claims.Scope)),
+	})
+}
@@ -0,0 +1,64 @@
+package oidc
+
+import (
+	"github.com/go-chi/render"
+	"net/http"
+	"net/url"
+)
+
+// ErrorResponse is an error in the format RFC 6749 requires of the token endpoint
+type ErrorResponse struct {
+	HTTPStatusCode int    `json:"-"`
+	Error          string `json:"error" example:"invalid_grant"`
+	Description    string `json:"error_description,omitempty" example:"code has expired"`
+}
+
+func (e *ErrorResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "no-store")
+	if e.HTTPStatusCode == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="vatusa"`)
+	}
+	render.Status(r, e.HTTPStatusCode)
+	return nil
+}
+
+func errInvalidRequest(description string) *ErrorResponse {
+	return &ErrorResponse{HTTPStatusCode: http.StatusBadRequest, Error: "invalid_request", Description: description}
+}
+
+func errInvalidGrant(description string) *ErrorResponse {
+	return &ErrorResponse{HTTPStatusCode: http.StatusBadRequest, Error: "invalid_grant", Description: description}
+}
+
+var (
+	errInvalidClient        = &ErrorResponse{HTTPStatusCode: http.StatusUnauthorized, Error: "invalid_client"}
+	errUnsupportedGrantType = &ErrorResponse{HTTPStatusCode: http.StatusBadRequest, Error: "unsupported_grant_type"}
+	errServerError          = &ErrorResponse{HTTPStatusCode: http.StatusInternalServerError, Error: "server_error"}
+)
+
+// redirectError sends an authorization error back to the client's redirect URI
+func redirectError(w http.ResponseWriter, r *http.Request, redirectURI, state, code, description string) {
+	params := url.Values{"error": {code}}
+	if description != "" {
+		params.Set("error_description", description)
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+	http.Redirect(w, r, withQuery(redirectURI, params), http.StatusFound)
+}
+
+// withQuery adds params to uri, keeping any query it already has
+func withQuery(uri string, params url.Values) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
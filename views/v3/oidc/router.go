@@ -0,0 +1,19 @@
+package oidc
+
+import (
+	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+// Router serves the OAuth2 and OpenID Connect provider facility websites sign their users in with
+func Router(r chi.Router) {
+	r.Get("/.well-known/openid-configuration", GetConfiguration)
+	r.Get("/jwks", GetJWKS)
+
+	r.With(middleware.NoAccessToken, middleware.NoImpersonation).Get("/authorize", GetAuthorize)
+	r.With(middleware.NoAccessToken, middleware.NoImpersonation).Post("/authorize", PostAuthorize)
+	r.Post("/token", PostToken)
+	r.Post("/revoke", PostRevoke)
+	r.Get("/userinfo", GetUserInfo)
+	r.Post("/userinfo", GetUserInfo)
+}
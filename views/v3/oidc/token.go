@@ -0,0 +1,247 @@
+package oidc
+
+import (
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/oauth"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	gonanoid "github.com/matoous/go-nanoid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	accessTokenLifetime  = time.Hour
+	idTokenLifetime      = time.Hour
+	refreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int    `json:"expires_in" example:"3600"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	Scope        string `json:"scope" example:"openid profile vatusa_roster"`
+}
+
+func (res *TokenResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	return nil
+}
+
+// authenticateClient reads the client's credentials from HTTP basic auth or the form body
+func authenticateClient(r *http.Request) (*models.OAuthClient, bool) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	client := &models.OAuthClient{ClientID: clientID}
+	if clientID == "" || client.Get() != nil || !client.CheckSecret(secret) {
+		return nil, false
+	}
+	return client, true
+}
+
+// PostToken godoc
+// @Summary Exchange a grant for tokens
+// @Description Exchange an authorization code and its PKCE code_verifier, or a refresh token, for an access token,
+// @Description ID token and new refresh token. Refresh tokens can only be used once.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param grant_type formData string true "authorization_code or refresh_token"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI the code was sent to"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param scope formData string false "Narrower scope for a refresh"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /oauth/token [post]
+func PostToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		utils.Render(w, r, errInvalidRequest("malformed form body"))
+		return
+	}
+
+	client, ok := authenticateClient(r)
+	if !ok {
+		utils.Render(w, r, errInvalidClient)
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		exchangeCode(w, r, client)
+	case "refresh_token":
+		exchangeRefreshToken(w, r, client)
+	default:
+		utils.Render(w, r, errUnsupportedGrantType)
+	}
+}
+
+func exchangeCode(w http.ResponseWriter, r *http.Request, client *models.OAuthClient) {
+	code, err := models.ConsumeOAuthAuthorizationCode(r.PostForm.Get("code"))
+	if err != nil || code.ClientID != client.ClientID {
+		utils.Render(w, r, errInvalidGrant("unknown code"))
+		return
+	}
+
+	if time.Now().After(code.ExpiresAt) {
+		utils.Render(w, r, errInvalidGrant("code has expired"))
+		return
+	}
+
+	if r.PostForm.Get("redirect_uri") != code.RedirectURI {
+		utils.Render(w, r, errInvalidGrant("redirect_uri does not match the authorization request"))
+		return
+	}
+
+	if !oauth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+		utils.Render(w, r, errInvalidGrant("code_verifier does not match the code_challenge"))
+		return
+	}
+
+	issueTokens(w, r, client, code.CID, code.Scope, code.Nonce, code.AuthTime, nil)
+}
+
+func exchangeRefreshToken(w http.ResponseWriter, r *http.Request, client *models.OAuthClient) {
+	token, err := models.GetOAuthRefreshToken(r.PostForm.Get("refresh_token"))
+	if err != nil || token.ClientID != client.ClientID {
+		utils.Render(w, r, errInvalidGrant("unknown refresh token"))
+		return
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		utils.Render(w, r, errInvalidGrant("refresh token has expired"))
+		return
+	}
+
+	scope := token.Scope
+	if requested := r.PostForm.Get("scope"); requested != "" {
+		granted := strings.Fields(token.Scope)
+		for _, s := range strings.Fields(requested) {
+			if !slices.Contains(granted, s) {
+				utils.Render(w, r, &ErrorResponse{HTTPStatusCode: http.StatusBadRequest, Error: "invalid_scope"})
+				return
+			}
+		}
+		scope = strings.Join(oauth.ParseScope(requested), " ")
+	}
+
+	issueTokens(w, r, client, token.CID, scope, "", token.AuthTime, token)
+}
+
+// issueTokens signs new tokens for cid. When previous is set it is the refresh token being exchanged, which is
+// rotated for the new one so it can't be used again.
+func issueTokens(w http.ResponseWriter, r *http.Request, client *models.OAuthClient, cid uint, scope, nonce string, authTime time.Time, previous *models.OAuthRefreshToken) {
+	user := &models.User{CID: cid}
+	if err := user.Get(); err != nil {
+		utils.Render(w, r, errInvalidGrant("user no longer exists"))
+		return
+	}
+
+	scopes := strings.Fields(scope)
+	accessToken, err := oauth.Provider.Sign(oauth.AccessTokenClaims{
+		RegisteredClaims: oauth.Provider.NewRegisteredClaims(cid, client.ClientID, accessTokenLifetime),
+		ClientID:         client.ClientID,
+		Scope:            scope,
+	})
+	if err != nil {
+		log.WithError(err).Error("Error signing access token")
+		utils.Render(w, r, errServerError)
+		return
+	}
+
+	idToken, err := oauth.Provider.Sign(oauth.IDTokenClaims{
+		RegisteredClaims: oauth.Provider.NewRegisteredClaims(cid, client.ClientID, idTokenLifetime),
+		AuthTime:         authTime.Unix(),
+		Nonce:            nonce,
+		UserClaims:       oauth.NewUserClaims(user, scopes),
+	})
+	if err != nil {
+		log.WithError(err).Error("Error signing ID token")
+		utils.Render(w, r, errServerError)
+		return
+	}
+
+	refreshToken, err := gonanoid.Generate(alphabet, 64)
+	if err != nil {
+		utils.Render(w, r, errServerError)
+		return
+	}
+
+	refresh := &models.OAuthRefreshToken{
+		TokenHash: models.HashToken(refreshToken),
+		ClientID:  client.ClientID,
+		CID:       cid,
+		Scope:     scope,
+		AuthTime:  authTime,
+		ExpiresAt: time.Now().Add(refreshTokenLifetime),
+	}
+	if previous != nil {
+		err = previous.Rotate(refresh)
+	} else {
+		err = refresh.Create()
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.Render(w, r, errInvalidGrant("unknown refresh token"))
+		return
+	}
+	if err != nil {
+		log.WithError(err).Errorf("Error creating refresh token for %s", client.ClientID)
+		utils.Render(w, r, errServerError)
+		return
+	}
+
+	utils.Render(w, r, &TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTokenLifetime.Seconds()),
+		RefreshToken: refreshToken,
+		IDToken:      idToken,
+		Scope:        scope,
+	})
+}
+
+// PostRevoke godoc
+// @Summary Revoke a refresh token
+// @Description Revoke a refresh token, for example when the user logs out of the client. Unknown tokens are ignored.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Param token formData string true "Refresh token"
+// @Success 200
+// @Failure 401 {object} ErrorResponse
+// @Router /oauth/revoke [post]
+func PostRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		utils.Render(w, r, errInvalidRequest("malformed form body"))
+		return
+	}
+
+	client, ok := authenticateClient(r)
+	if !ok {
+		utils.Render(w, r, errInvalidClient)
+		return
+	}
+
+	if token, err := models.GetOAuthRefreshToken(r.PostForm.Get("token")); err == nil && token.ClientID == client.ClientID {
+		if err := token.Delete(); err != nil {
+			log.WithError(err).Errorf("Error revoking refresh token %d", token.ID)
+			utils.Render(w, r, errServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,396 @@
+package promotion
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/notify"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	vatsim_api "github.com/VATUSA/primary-api/pkg/vatsim/api"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Request struct {
+	CID     uint   `json:"cid" example:"1293257" validate:"required"`
+	Comment string `json:"comment" example:"Ready for S2, passed the OTS on the first attempt."`
+}
+
+func (req *Request) Validate() error {
+	return validator.New().Struct(req)
+}
+
+func (req *Request) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+type DecisionRequest struct {
+	Comment string `json:"comment" example:"Approved"`
+}
+
+func (req *DecisionRequest) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+type Response struct {
+	*models.Promotion
+}
+
+func NewPromotionResponse(p *models.Promotion) *Response {
+	return &Response{Promotion: p}
+}
+
+func (res *Response) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.Promotion == nil {
+		return errors.New("promotion not found")
+	}
+	return nil
+}
+
+func NewPromotionListResponse(promotions []models.Promotion) []render.Renderer {
+	list := []render.Renderer{}
+	for idx := range promotions {
+		list = append(list, NewPromotionResponse(&promotions[idx]))
+	}
+	return list
+}
+
+type EligibilityResponse struct {
+	*models.PromotionEligibility
+}
+
+func (res *EligibilityResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// RecommendPromotion godoc
+// @Summary Recommend a promotion
+// @Description Recommend a controller on the facility's home roster for promotion to their next rating. The
+// @Description controller must meet every eligibility check, see the eligibility endpoint. Division training staff
+// @Description then approve or reject the promotion.
+// @Tags promotion
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param promotion body Request true "Promotion"
+// @Success 201 {object} Response
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/promotions [post]
+func RecommendPromotion(w http.ResponseWriter, r *http.Request) {
+	req := &Request{}
+	if err := req.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	fac := utils.GetFacilityCtx(r)
+	eligibility, err := models.CheckPromotionEligibility(req.CID, fac)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Render(w, r, utils.ErrInvalidCID)
+			return
+		}
+		log.WithError(err).Errorf("Error checking promotion eligibility of %d at %s", req.CID, fac.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	if !eligibility.Eligible {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New(strings.Join(eligibility.Reasons, "; "))))
+		return
+	}
+
+	promotion := &models.Promotion{
+		CID:              req.CID,
+		Facility:         fac.ID,
+		FromRating:       eligibility.FromRating,
+		ToRating:         eligibility.ToRating,
+		OTSExamID:        eligibility.OTSExamID,
+		RecommendedByCID: utils.GetXUser(r).CID,
+		Comment:          req.Comment,
+		Status:           types.Pending,
+	}
+
+	if err := promotion.Create(); err != nil {
+		log.WithError(err).Errorf("Error creating promotion of %d", req.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	utils.Render(w, r, NewPromotionResponse(promotion))
+}
+
+// GetPromotionEligibility godoc
+// @Summary Check promotion eligibility
+// @Description Check whether a controller can be recommended for promotion at the facility and list every
+// @Description requirement they do not meet yet
+// @Tags promotion
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param cid query int true "CID"
+// @Success 200 {object} EligibilityResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/promotions/eligibility [get]
+func GetPromotionEligibility(w http.ResponseWriter, r *http.Request) {
+	cid, err := strconv.ParseUint(r.URL.Query().Get("cid"), 10, 64)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInvalidCID)
+		return
+	}
+
+	fac := utils.GetFacilityCtx(r)
+	eligibility, err := models.CheckPromotionEligibility(uint(cid), fac)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Render(w, r, utils.ErrInvalidCID)
+			return
+		}
+		log.WithError(err).Errorf("Error checking promotion eligibility of %d at %s", cid, fac.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, &EligibilityResponse{PromotionEligibility: eligibility})
+}
+
+// ListFacilityPromotions godoc
+// @Summary List the facility's promotions
+// @Description List the promotions recommended at the facility, most recent first
+// @Tags promotion
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param status query string false "Status" Enums(pending, accepted, rejected)
+// @Success 200 {object} []Response
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/promotions [get]
+func ListFacilityPromotions(w http.ResponseWriter, r *http.Request) {
+	filter := models.PromotionFilter{
+		Facility: utils.GetFacilityCtx(r).ID,
+		Status:   types.StatusType(r.URL.Query().Get("status")),
+	}
+	listPromotions(w, r, filter)
+}
+
+// ListPromotions godoc
+// @Summary List promotions
+// @Description List promotions across the division, most recent first. Pending promotions are listed by default.
+// @Tags promotion
+// @Accept  json
+// @Produce  json
+// @Param status query string false "Status, pending by default" Enums(pending, accepted, rejected, all)
+// @Param facility query string false "Facility ID"
+// @Param cid query int false "CID"
+// @Success 200 {object} []Response
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /promotions [get]
+func ListPromotions(w http.ResponseWriter, r *http.Request) {
+	filter := models.PromotionFilter{
+		Facility: constants.FacilityID(r.URL.Query().Get("facility")),
+		Status:   types.Pending,
+	}
+
+	switch status := r.URL.Query().Get("status"); status {
+	case "":
+	case "all":
+		filter.Status = types.All
+	default:
+		filter.Status = types.StatusType(status)
+	}
+
+	if cid := r.URL.Query().Get("cid"); cid != "" {
+		cidInt, err := strconv.ParseUint(cid, 10, 64)
+		if err != nil {
+			utils.Render(w, r, utils.ErrInvalidCID)
+			return
+		}
+		filter.CID = uint(cidInt)
+	}
+
+	listPromotions(w, r, filter)
+}
+
+func listPromotions(w http.ResponseWriter, r *http.Request, filter models.PromotionFilter) {
+	promotions, err := models.GetPromotions(filter)
+	if err != nil {
+		log.WithError(err).Error("Error getting promotions")
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	if err := render.RenderList(w, r, NewPromotionListResponse(promotions)); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// GetPromotion godoc
+// @Summary Get a promotion
+// @Description Get a promotion
+// @Tags promotion
+// @Accept  json
+// @Produce  json
+// @Param PromotionID path int true "Promotion ID"
+// @Success 200 {object} Response
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Router /promotions/{PromotionID} [get]
+func GetPromotion(w http.ResponseWriter, r *http.Request) {
+	utils.Render(w, r, NewPromotionResponse(utils.GetPromotionCtx(r)))
+}
+
+// ApprovePromotion godoc
+// @Summary Approve a promotion
+// @Description Approve a pending promotion. The controller must still meet every eligibility check. The rating
+// @Description change is recorded, the controller's rating is updated and pushed to VATSIM, and the controller is
+// @Description notified. Staff cannot approve their own promotion.
+// @Tags promotion
+// @Accept  json
+// @Produce  json
+// @Param PromotionID path int true "Promotion ID"
+// @Param decision body DecisionRequest false "Decision"
+// @Success 200 {object} Response
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /promotions/{PromotionID}/approve [post]
+func ApprovePromotion(w http.ResponseWriter, r *http.Request) {
+	req := &DecisionRequest{}
+	if err := req.Bind(r); err != nil && !errors.Is(err, io.EOF) {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	promotion := utils.GetPromotionCtx(r)
+	approver := utils.GetXUser(r)
+	if promotion.CID == approver.CID {
+		log.Warnf("User %d, attempted to approve their own promotion %d. No permissions.", approver.CID, promotion.ID)
+		utils.Render(w, r, utils.ErrForbidden)
+		return
+	}
+
+	if _, err := promotion.Approve(approver.CID, req.Comment); err != nil {
+		var ineligibleErr *models.PromotionIneligibleError
+		if errors.Is(err, models.ErrPromotionDecided) || errors.Is(err, models.ErrPromotionStale) || errors.As(err, &ineligibleErr) {
+			utils.Render(w, r, utils.ErrInvalidRequest(err))
+			return
+		}
+		log.WithError(err).Errorf("Error approving promotion %d", promotion.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.LogUserChange(r, promotion.CID,
+		fmt.Sprintf("Promoted from %s to %s by promotion %d", promotion.FromRating.Short(), promotion.ToRating.Short(), promotion.ID),
+		models.Changes{{Field: "controller_rating", Old: promotion.FromRating, New: promotion.ToRating}})
+
+	pushRating(r, promotion)
+
+	notify.Send(&models.Notification{
+		CID:      promotion.CID,
+		Category: notify.CategoryTraining,
+		Title:    "Promotion Approved",
+		Body:     fmt.Sprintf("Congratulations! You have been promoted from %s to %s.", promotion.FromRating.Long(), promotion.ToRating.Long()),
+		ExpireAt: time.Now().AddDate(0, 0, 30),
+	})
+
+	utils.Render(w, r, NewPromotionResponse(promotion))
+}
+
+// RejectPromotion godoc
+// @Summary Reject a promotion
+// @Description Reject a pending promotion. The TA who recommended it is notified.
+// @Tags promotion
+// @Accept  json
+// @Produce  json
+// @Param PromotionID path int true "Promotion ID"
+// @Param decision body DecisionRequest false "Decision"
+// @Success 200 {object} Response
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /promotions/{PromotionID}/reject [post]
+func RejectPromotion(w http.ResponseWriter, r *http.Request) {
+	req := &DecisionRequest{}
+	if err := req.Bind(r); err != nil && !errors.Is(err, io.EOF) {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	promotion := utils.GetPromotionCtx(r)
+	if err := promotion.Reject(utils.GetXUser(r).CID, req.Comment); err != nil {
+		if errors.Is(err, models.ErrPromotionDecided) {
+			utils.Render(w, r, utils.ErrInvalidRequest(err))
+			return
+		}
+		log.WithError(err).Errorf("Error rejecting promotion %d", promotion.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	body := fmt.Sprintf("Your recommendation to promote %d to %s was rejected.", promotion.CID, promotion.ToRating.Short())
+	if promotion.DecisionComment != "" {
+		body += " " + promotion.DecisionComment
+	}
+	notify.Send(&models.Notification{
+		CID:      promotion.RecommendedByCID,
+		Category: notify.CategoryTraining,
+		Title:    "Promotion Rejected",
+		Body:     body,
+		ExpireAt: time.Now().AddDate(0, 0, 30),
+	})
+
+	utils.Render(w, r, NewPromotionResponse(promotion))
+}
+
+// pushRating applies the approved rating on VATSIM. Failures are logged and leave PushedAt unset, the promotion
+// itself has already been approved.
+func pushRating(r *http.Request, promotion *models.Promotion) {
+	if vatsim_api.DefaultRatingPusher == nil {
+		log.Warnf("No VATSIM rating pusher configured, rating of %d must be set to %s by hand", promotion.CID, promotion.ToRating.Short())
+		return
+	}
+
+	if err := vatsim_api.DefaultRatingPusher.PushRating(r.Context(), promotion.CID, promotion.ToRating); err != nil {
+		log.WithError(err).Errorf("Error pushing rating of %d to VATSIM for promotion %d", promotion.CID, promotion.ID)
+		return
+	}
+
+	now := time.Now()
+	promotion.PushedAt = &now
+	if err := promotion.Update(); err != nil {
+		log.WithError(err).Errorf("Error recording VATSIM push of promotion %d", promotion.ID)
+	}
+}
@@ -0,0 +1,297 @@
+package promotion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/dbtest"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	vatsim_api "github.com/VATUSA/primary-api/pkg/vatsim/api"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	studentCID = 1000001
+	taCID      = 1000002
+	staffCID   = 1293257
+)
+
+var zdv = &models.Facility{ID: "ZDV"}
+
+// setupPromotion opens a database holding student 1000001, an S2 on the ZDV home roster for 60 days who passed their
+// S3 OTS exam at ZDV yesterday, and so is eligible for promotion
+func setupPromotion(t *testing.T) {
+	t.Helper()
+
+	dbtest.Open(t, &models.Facility{}, &models.User{}, &models.UserFlag{}, &models.Roster{}, &models.UserRole{},
+		&models.Notification{}, &models.RatingChange{}, &models.OTSExam{}, &models.TrainingSession{}, &models.Promotion{},
+		&models.ActionLogEntry{})
+
+	facility := *zdv
+	if err := facility.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	student := &models.User{CID: studentCID, ControllerRating: constants.Student2Rating,
+		CreatedAt: time.Now().AddDate(0, 0, -60)}
+	if err := student.Create(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&models.Roster{CID: studentCID, Facility: zdv.ID, Home: true}).Create(); err != nil {
+		t.Fatal(err)
+	}
+	createOTSExam(t, types.OTSPass, time.Now().AddDate(0, 0, -1))
+}
+
+func createOTSExam(t *testing.T, result types.OTSResult, date time.Time) {
+	t.Helper()
+
+	exam := &models.OTSExam{StudentCID: studentCID, InstructorCID: taCID, Facility: zdv.ID, Position: "DEN_APP",
+		Rating: constants.Student3Rating, Result: result, ExamDate: date}
+	if err := exam.Create(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func withUser(r *http.Request, cid uint) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), utils.XUser{}, &models.User{CID: cid}))
+}
+
+func getEligibility(t *testing.T) *models.PromotionEligibility {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodGet, "/v3/facility/ZDV/promotions/eligibility?cid=1000001", nil)
+	r = withUser(r.WithContext(context.WithValue(r.Context(), utils.FacilityKey{}, zdv)), taCID)
+	w := httptest.NewRecorder()
+	GetPromotionEligibility(w, r)
+
+	eligibility := &models.PromotionEligibility{}
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetPromotionEligibility() returned %d: %s", w.Code, w.Body.String())
+	}
+	if err := json.NewDecoder(w.Body).Decode(eligibility); err != nil {
+		t.Fatal(err)
+	}
+	return eligibility
+}
+
+func recommend(cid uint) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(Request{CID: cid})
+	r := httptest.NewRequest(http.MethodPost, "/v3/facility/ZDV/promotions", strings.NewReader(string(body)))
+	r = withUser(r.WithContext(context.WithValue(r.Context(), utils.FacilityKey{}, zdv)), taCID)
+	w := httptest.NewRecorder()
+	RecommendPromotion(w, r)
+	return w
+}
+
+func TestPromotionEligibility(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(t *testing.T)
+		reason string
+	}{
+		{name: "eligible", setup: func(t *testing.T) {}},
+		{name: "time in rating", reason: "Held S2 for 10 of the required 30 days", setup: func(t *testing.T) {
+			rc := &models.RatingChange{CID: studentCID, OldRating: constants.Student1Rating,
+				NewRating: constants.Student2Rating, CreatedAt: time.Now().AddDate(0, 0, -10)}
+			if err := rc.Create(); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{name: "OTS failed", reason: "Failed the latest S3 OTS exam at ZDV", setup: func(t *testing.T) {
+			if err := database.DB.Where("student_cid = ?", studentCID).Delete(&models.OTSExam{}).Error; err != nil {
+				t.Fatal(err)
+			}
+			createOTSExam(t, types.OTSFail, time.Now().AddDate(0, 0, -1))
+		}},
+		{name: "OTS failed after passing", reason: "Failed the latest S3 OTS exam at ZDV", setup: func(t *testing.T) {
+			createOTSExam(t, types.OTSFail, time.Now())
+		}},
+		{name: "OTS passed before the last rating change", reason: "No passed S3 OTS exam at ZDV", setup: func(t *testing.T) {
+			rc := &models.RatingChange{CID: studentCID, OldRating: constants.Student1Rating,
+				NewRating: constants.Student2Rating, CreatedAt: time.Now().AddDate(0, 0, -40)}
+			if err := rc.Create(); err != nil {
+				t.Fatal(err)
+			}
+			if err := database.DB.Model(&models.OTSExam{}).Where("student_cid = ?", studentCID).
+				Update("exam_date", time.Now().AddDate(0, 0, -50)).Error; err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{name: "pending promotion", reason: "is already pending", setup: func(t *testing.T) {
+			if w := recommend(studentCID); w.Code != http.StatusCreated {
+				t.Fatalf("RecommendPromotion() returned %d: %s", w.Code, w.Body.String())
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupPromotion(t)
+			tt.setup(t)
+
+			eligibility := getEligibility(t)
+			if tt.reason == "" {
+				if !eligibility.Eligible || len(eligibility.Reasons) != 0 {
+					t.Errorf("eligibility = %v %v, want eligible", eligibility.Eligible, eligibility.Reasons)
+				}
+				return
+			}
+
+			if eligibility.Eligible {
+				t.Error("controller is eligible, want ineligible")
+			}
+			if !slices.ContainsFunc(eligibility.Reasons, func(reason string) bool { return strings.Contains(reason, tt.reason) }) {
+				t.Errorf("reasons = %v, want one containing %q", eligibility.Reasons, tt.reason)
+			}
+
+			// An ineligible controller cannot be recommended
+			if w := recommend(studentCID); w.Code != http.StatusBadRequest {
+				t.Errorf("RecommendPromotion() returned %d, want 400", w.Code)
+			}
+		})
+	}
+}
+
+func approve(promotionID uint) *httptest.ResponseRecorder {
+	promotion := &models.Promotion{ID: promotionID}
+	_ = promotion.Get()
+
+	r := httptest.NewRequest(http.MethodPost, "/v3/promotions/1/approve", nil)
+	r = withUser(r.WithContext(context.WithValue(r.Context(), utils.PromotionKey{}, promotion)), staffCID)
+	w := httptest.NewRecorder()
+	ApprovePromotion(w, r)
+	return w
+}
+
+func setupApproval(t *testing.T, pusher *vatsim_api.StubRatingPusher) *models.Promotion {
+	t.Helper()
+
+	setupPromotion(t)
+	previous := vatsim_api.DefaultRatingPusher
+	vatsim_api.DefaultRatingPusher = pusher
+	t.Cleanup(func() { vatsim_api.DefaultRatingPusher = previous })
+
+	if w := recommend(studentCID); w.Code != http.StatusCreated {
+		t.Fatalf("RecommendPromotion() returned %d: %s", w.Code, w.Body.String())
+	}
+	promotions, err := models.GetPromotions(models.PromotionFilter{CID: studentCID, Status: types.Pending})
+	if err != nil || len(promotions) != 1 {
+		t.Fatalf("GetPromotions() = %v, %v, want the recommended promotion", promotions, err)
+	}
+	return &promotions[0]
+}
+
+func TestApprovePromotion(t *testing.T) {
+	pusher := &vatsim_api.StubRatingPusher{}
+	promotion := setupApproval(t, pusher)
+
+	if w := approve(promotion.ID); w.Code != http.StatusOK {
+		t.Fatalf("ApprovePromotion() returned %d: %s", w.Code, w.Body.String())
+	}
+
+	want := []vatsim_api.PushedRating{{CID: studentCID, Rating: constants.Student3Rating}}
+	if got := pusher.Pushed(); !slices.Equal(got, want) {
+		t.Errorf("pushed ratings = %v, want %v", got, want)
+	}
+
+	if err := promotion.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if promotion.Status != types.Accepted || promotion.PushedAt == nil {
+		t.Errorf("promotion status = %s, pushed at %v, want accepted and pushed", promotion.Status, promotion.PushedAt)
+	}
+	student := &models.User{CID: studentCID}
+	if err := student.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if student.ControllerRating != constants.Student3Rating {
+		t.Errorf("controller rating = %s, want S3", student.ControllerRating.Short())
+	}
+
+	// Approving again is refused and pushes nothing more
+	if w := approve(promotion.ID); w.Code != http.StatusBadRequest {
+		t.Errorf("second ApprovePromotion() returned %d, want 400", w.Code)
+	}
+	if got := pusher.Pushed(); !slices.Equal(got, want) {
+		t.Errorf("pushed ratings after a second approval = %v, want %v", got, want)
+	}
+}
+
+func TestApprovePromotionIneligible(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T)
+		reason string
+	}{
+		{name: "restricted from training", reason: "Restricted from training", change: func(t *testing.T) {
+			until := time.Now().AddDate(0, 1, 0)
+			flag := &models.UserFlag{CID: studentCID, NoTraining: true, NoTrainingUntil: &until,
+				NoTrainingReason: "Missed three sessions"}
+			if err := database.DB.Save(flag).Error; err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{name: "failed a later OTS exam", reason: "Failed the latest S3 OTS exam at ZDV", change: func(t *testing.T) {
+			createOTSExam(t, types.OTSFail, time.Now())
+		}},
+		{name: "left the roster", reason: "Not on the ZDV home roster", change: func(t *testing.T) {
+			if err := database.DB.Where("cid = ?", studentCID).Delete(&models.Roster{}).Error; err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pusher := &vatsim_api.StubRatingPusher{}
+			promotion := setupApproval(t, pusher)
+			tt.change(t)
+
+			w := approve(promotion.ID)
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.reason) {
+				t.Errorf("ApprovePromotion() returned %d %s, want 400 with %q", w.Code, w.Body.String(), tt.reason)
+			}
+			if got := pusher.Pushed(); len(got) != 0 {
+				t.Errorf("pushed ratings = %v, want none", got)
+			}
+
+			if err := promotion.Get(); err != nil {
+				t.Fatal(err)
+			}
+			student := &models.User{CID: studentCID}
+			if err := student.Get(); err != nil {
+				t.Fatal(err)
+			}
+			if promotion.Status != types.Pending || student.ControllerRating != constants.Student2Rating {
+				t.Errorf("promotion status = %s, rating = %s, want pending and S2", promotion.Status,
+					student.ControllerRating.Short())
+			}
+		})
+	}
+}
+
+func TestApprovePromotionPushFailure(t *testing.T) {
+	pusher := &vatsim_api.StubRatingPusher{Err: errors.New("VATSIM is down")}
+	promotion := setupApproval(t, pusher)
+
+	if w := approve(promotion.ID); w.Code != http.StatusOK {
+		t.Fatalf("ApprovePromotion() returned %d: %s", w.Code, w.Body.String())
+	}
+
+	if err := promotion.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if promotion.Status != types.Accepted || promotion.PushedAt != nil {
+		t.Errorf("promotion status = %s, pushed at %v, want accepted and not pushed", promotion.Status, promotion.PushedAt)
+	}
+}
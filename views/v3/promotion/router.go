@@ -0,0 +1,54 @@
+package promotion
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+// Router serves division training staff deciding promotions, mounted under /promotions
+func Router(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadTrainingScope, constants.WriteTrainingScope), middleware.NotGuest, middleware.CanApprovePromotions)
+
+	r.Get("/", ListPromotions)
+
+	r.Route("/{PromotionID}", func(r chi.Router) {
+		r.Use(Ctx)
+
+		r.Get("/", GetPromotion)
+		r.Post("/approve", ApprovePromotion)
+		r.Post("/reject", RejectPromotion)
+	})
+}
+
+// FacilityRouter serves the TA recommending promotions, mounted under /facility/{FacilityID}/promotions
+func FacilityRouter(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadTrainingScope, constants.WriteTrainingScope))
+
+	r.With(middleware.NotGuest, middleware.CanViewTraining).Get("/", ListFacilityPromotions)
+	r.With(middleware.NotGuest, middleware.CanRecommendPromotion).Get("/eligibility", GetPromotionEligibility)
+	r.With(middleware.NotGuest, middleware.RequireUser, middleware.CanRecommendPromotion).Post("/", RecommendPromotion)
+}
+
+func Ctx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "PromotionID"), 10, 64)
+		if err != nil {
+			utils.Render(w, r, utils.ErrBadRequest)
+			return
+		}
+
+		promotion := &models.Promotion{ID: uint(id)}
+		if err := promotion.Get(); err != nil {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		ctx := utils.WithResource(r, utils.PromotionKey{}, promotion)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -1,7 +1,6 @@
 package rating_change
 
 import (
-	"context"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
@@ -42,7 +41,7 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.RatingChangeKey{}, ratingChange)
+		ctx := utils.WithResource(r, utils.RatingChangeKey{}, ratingChange)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,217 @@
+package role
+
+import (
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type Response struct {
+	ID           constants.RoleID       `json:"id" example:"ATM"`
+	Name         string                 `json:"name" example:"Air Traffic Manager"`
+	Groups       []constants.GroupID    `json:"groups" example:"fac_mgmt"`
+	RolesCanAdd  []constants.RoleID     `json:"roles_can_add" example:"ATM"`
+	GroupsCanAdd []constants.GroupID    `json:"groups_can_add" example:"div_mgmt"`
+	Permissions  []constants.Permission `json:"permissions" example:"roster.edit"`
+}
+
+func (res *Response) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func NewRoleResponse(id constants.RoleID) *Response {
+	role := constants.Roles[id]
+	return &Response{
+		ID:           id,
+		Name:         id.DisplayName(),
+		Groups:       nonNil(role.Groups),
+		RolesCanAdd:  nonNil(role.RolesCanAdd),
+		GroupsCanAdd: nonNil(role.GroupsCanAdd),
+		Permissions:  id.Permissions(),
+	}
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+type HeldRole struct {
+	Role     constants.RoleID     `json:"role" example:"ATM"`
+	Facility constants.FacilityID `json:"facility" example:"ZDV"`
+}
+
+type AssignableByResponse struct {
+	Role     *Response            `json:"role"`
+	CID      uint                 `json:"cid" example:"1293257"`
+	Facility constants.FacilityID `json:"facility" example:"ZDV"`
+	Allowed  bool                 `json:"allowed" example:"true"`
+	// Grants are the held roles that allow assigning the role
+	Grants []utils.HeldRoleGrant `json:"grants"`
+	// Considered are the held roles that were checked, the ones at Facility and at ZHQ
+	Considered  []HeldRole `json:"considered"`
+	Explanation string     `json:"explanation" example:"ATM at ZDV is in group fac_mgmt, which can assign DATM."`
+}
+
+func (res *AssignableByResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// ListRoles godoc
+// @Summary List roles
+// @Description List every role with its display name, groups, who can assign it and the permissions it grants
+// @Tags role
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []Response
+// @Router /roles [get]
+func ListRoles(w http.ResponseWriter, r *http.Request) {
+	ids := make([]constants.RoleID, 0, len(constants.Roles))
+	for id := range constants.Roles {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	list := []render.Renderer{}
+	for _, id := range ids {
+		list = append(list, NewRoleResponse(id))
+	}
+
+	if err := render.RenderList(w, r, list); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// GetRole godoc
+// @Summary Get a role
+// @Description Get a role with its display name, groups, who can assign it and the permissions it grants
+// @Tags role
+// @Accept  json
+// @Produce  json
+// @Param RoleID path string true "Role ID"
+// @Success 200 {object} Response
+// @Failure 400 {object} utils.ErrResponse
+// @Router /roles/{RoleID} [get]
+func GetRole(w http.ResponseWriter, r *http.Request) {
+	utils.Render(w, r, NewRoleResponse(utils.GetRoleCtx(r)))
+}
+
+// GetAssignableBy godoc
+// @Summary Explain who can assign a role
+// @Description Explain whether a user can assign the role at a facility, and which held role or group grants it. Roles
+// @Description held at the facility and at ZHQ count. cid defaults to the logged-in user, checking anyone else needs
+// @Description permission to view them. facility defaults to ZHQ.
+// @Tags role
+// @Accept  json
+// @Produce  json
+// @Param RoleID path string true "Role ID"
+// @Param cid query int false "CID of the assigning user"
+// @Param facility query string false "Facility the role would be assigned at"
+// @Success 200 {object} AssignableByResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Router /roles/{RoleID}/assignable-by [get]
+func GetAssignableBy(w http.ResponseWriter, r *http.Request) {
+	roleID := utils.GetRoleCtx(r)
+
+	facility := constants.FacilityID(r.URL.Query().Get("facility"))
+	if facility == "" {
+		facility = "ZHQ"
+	}
+	if !facility.IsValidFacility() {
+		utils.Render(w, r, utils.ErrInvalidFacility)
+		return
+	}
+
+	user := utils.GetXUser(r)
+	if cid := r.URL.Query().Get("cid"); cid != "" {
+		id, err := strconv.ParseUint(cid, 10, 64)
+		if err != nil {
+			utils.Render(w, r, utils.ErrInvalidCID)
+			return
+		}
+
+		if uint(id) != user.CID {
+			target := &models.User{CID: uint(id)}
+			if err := target.Get(); err != nil {
+				utils.Render(w, r, utils.ErrNotFound)
+				return
+			}
+
+			if !utils.GetPrincipal(r).CanForUser(constants.ViewUserPermission, target) {
+				utils.Render(w, r, utils.ErrForbidden)
+				return
+			}
+			user = target
+		}
+	}
+
+	considered := []HeldRole{}
+	for _, roster := range user.Roster {
+		if roster.Facility != facility && roster.Facility != "ZHQ" {
+			continue
+		}
+		for _, role := range roster.Roles {
+			considered = append(considered, HeldRole{Role: role.RoleID, Facility: roster.Facility})
+		}
+	}
+
+	grants := utils.AddRoleGrants(user, roleID, facility)
+	utils.Render(w, r, &AssignableByResponse{
+		Role:        NewRoleResponse(roleID),
+		CID:         user.CID,
+		Facility:    facility,
+		Allowed:     len(grants) > 0,
+		Grants:      grants,
+		Considered:  considered,
+		Explanation: explain(roleID, facility, grants, considered),
+	})
+}
+
+func explain(roleID constants.RoleID, facility constants.FacilityID, grants []utils.HeldRoleGrant, considered []HeldRole) string {
+	if len(grants) > 0 {
+		reasons := []string{}
+		for _, grant := range grants {
+			if grant.Group != "" {
+				reasons = append(reasons, fmt.Sprintf("%s at %s is in group %s, which can assign %s.", grant.Role, grant.Facility, grant.Group, roleID))
+			} else {
+				reasons = append(reasons, fmt.Sprintf("%s at %s can assign %s.", grant.Role, grant.Facility, roleID))
+			}
+		}
+		return strings.Join(reasons, " ")
+	}
+
+	role := constants.Roles[roleID]
+	required := []string{}
+	for _, id := range role.RolesCanAdd {
+		required = append(required, "role "+string(id))
+	}
+	for _, group := range role.GroupsCanAdd {
+		required = append(required, "a role in group "+string(group))
+	}
+
+	requirement := fmt.Sprintf("%s cannot be assigned by anyone.", roleID)
+	if len(required) > 0 {
+		requirement = fmt.Sprintf("Assigning %s requires %s.", roleID, strings.Join(required, " or "))
+	}
+
+	if len(considered) == 0 {
+		return fmt.Sprintf("No roles are held at %s or ZHQ. %s", facility, requirement)
+	}
+
+	held := []string{}
+	for _, h := range considered {
+		held = append(held, fmt.Sprintf("%s at %s", h.Role, h.Facility))
+	}
+	return fmt.Sprintf("None of the held roles (%s) can assign %s. %s", strings.Join(held, ", "), roleID, requirement)
+}
@@ -0,0 +1,34 @@
+package role
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+func Router(r chi.Router) {
+	r.Get("/", ListRoles)
+
+	r.Route("/{RoleID}", func(r chi.Router) {
+		r.Use(Ctx)
+
+		r.Get("/", GetRole)
+		r.With(middleware.NotGuest, middleware.RequireUser).Get("/assignable-by", GetAssignableBy)
+	})
+}
+
+func Ctx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := constants.RoleID(chi.URLParam(r, "RoleID"))
+		if !role.IsValidRole() {
+			utils.Render(w, r, utils.ErrInvalidRole)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), utils.RoleKey{}, role)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
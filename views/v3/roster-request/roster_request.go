@@ -3,13 +3,17 @@ package roster_request
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/notify"
+	"github.com/VATUSA/primary-api/pkg/stream"
 	"github.com/VATUSA/primary-api/pkg/utils"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"time"
 )
 
 type Request struct {
@@ -76,6 +80,7 @@ func NewRosterRequestListResponse(rosterReq []models.RosterRequest) []render.Ren
 // @Param roster_request body Request true "Roster Request"
 // @Success 201 {object} Response
 // @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
 // @Failure 500 {object} utils.ErrResponse
 // @Router /facility/{FacilityID}/roster-request [post]
 func CreateRosterRequest(w http.ResponseWriter, r *http.Request) {
@@ -106,10 +111,18 @@ func CreateRosterRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := rosterRequest.Create(); err != nil {
+		var restrictionErr *models.RestrictionError
+		if errors.As(err, &restrictionErr) {
+			utils.Render(w, r, utils.ErrRestricted(err))
+			return
+		}
 		utils.Render(w, r, utils.ErrInvalidRequest(err))
 		return
 	}
 
+	notifyRosterRequest(rosterRequest, notify.TemplateRosterRequestReceived, "Roster Request Received",
+		fmt.Sprintf("Your %s request to %s has been received.", rosterRequest.RequestType, rosterRequest.Facility))
+
 	render.Status(r, http.StatusCreated)
 	utils.Render(w, r, NewRosterRequestResponse(rosterRequest))
 }
@@ -194,6 +207,7 @@ func ListRosterRequest(w http.ResponseWriter, r *http.Request) {
 // @Param roster_request body Request true "Roster Request"
 // @Success 200 {object} Response
 // @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
 // @Failure 404 {object} utils.ErrResponse
 // @Failure 500 {object} utils.ErrResponse
 // @Router /facility/{FacilityID}/roster-request/{id} [patch]
@@ -205,6 +219,7 @@ func PatchRosterRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	req := utils.GetRosterRequestCtx(r)
+	oldStatus := req.Status
 
 	if data.RequestType != "" {
 		req.RequestType = data.RequestType
@@ -228,9 +243,16 @@ func PatchRosterRequest(w http.ResponseWriter, r *http.Request) {
 			}
 
 			if err := roster.Create(); err != nil {
+				var restrictionErr *models.RestrictionError
+				if errors.As(err, &restrictionErr) {
+					utils.Render(w, r, utils.ErrRestricted(err))
+					return
+				}
 				utils.Render(w, r, utils.ErrInvalidRequest(err))
 				return
 			}
+
+			utils.LogUserChange(r, roster.CID, fmt.Sprintf("Added to the %s roster by roster request %d", roster.Facility, req.ID), models.Diff(nil, roster))
 		}
 		req.Status = data.Status
 	}
@@ -244,6 +266,8 @@ func PatchRosterRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	notifyRosterRequestDecided(req, oldStatus)
+
 	utils.Render(w, r, NewRosterRequestResponse(req))
 }
 
@@ -258,6 +282,7 @@ func PatchRosterRequest(w http.ResponseWriter, r *http.Request) {
 // @Param roster_request body Request true "Roster Request"
 // @Success 200 {object} Response
 // @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
 // @Failure 404 {object} utils.ErrResponse
 // @Failure 500 {object} utils.ErrResponse
 // @Router /facility/{FacilityID}/roster-request/{id} [put]
@@ -292,11 +317,19 @@ func UpdateRosterRequest(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if err := roster.Create(); err != nil {
+			var restrictionErr *models.RestrictionError
+			if errors.As(err, &restrictionErr) {
+				utils.Render(w, r, utils.ErrRestricted(err))
+				return
+			}
 			utils.Render(w, r, utils.ErrInvalidRequest(err))
 			return
 		}
+
+		utils.LogUserChange(r, roster.CID, fmt.Sprintf("Added to the %s roster by roster request %d", roster.Facility, req.ID), models.Diff(nil, roster))
 	}
 
+	oldStatus := req.Status
 	req.RequestType = data.RequestType
 	req.Status = data.Status
 	req.Reason = data.Reason
@@ -306,6 +339,8 @@ func UpdateRosterRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	notifyRosterRequestDecided(req, oldStatus)
+
 	utils.Render(w, r, NewRosterRequestResponse(req))
 }
 
@@ -330,3 +365,36 @@ func DeleteRosterRequest(w http.ResponseWriter, r *http.Request) {
 
 	render.Status(r, http.StatusNoContent)
 }
+
+// notifyRosterRequestDecided notifies the requester when their request moves out of pending
+func notifyRosterRequestDecided(req *models.RosterRequest, oldStatus types.StatusType) {
+	if oldStatus != types.Pending || (req.Status != types.Accepted && req.Status != types.Rejected) {
+		return
+	}
+
+	title := "Roster Request Accepted"
+	if req.Status == types.Rejected {
+		title = "Roster Request Rejected"
+	}
+
+	notifyRosterRequest(req, notify.TemplateRosterRequestDecided, title,
+		fmt.Sprintf("Your %s request to %s was %s.", req.RequestType, req.Facility, req.Status))
+	stream.Publish(req.CID, stream.TypeRosterRequest, req)
+}
+
+func notifyRosterRequest(req *models.RosterRequest, template, title, body string) {
+	notify.Send(&models.Notification{
+		CID:      req.CID,
+		Category: notify.CategoryRoster,
+		Title:    title,
+		Body:     body,
+		ExpireAt: time.Now().Add(30 * 24 * time.Hour),
+		Template: template,
+		TemplateData: map[string]string{
+			"request_type": string(req.RequestType),
+			"status":       string(req.Status),
+			"reason":       req.Reason,
+		},
+		Facility: req.Facility,
+	})
+}
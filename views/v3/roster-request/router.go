@@ -1,7 +1,7 @@
 package roster_request
 
 import (
-	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
@@ -11,6 +11,8 @@ import (
 )
 
 func Router(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadRosterScope, constants.WriteRosterScope))
+
 	r.With(middleware.NotGuest, middleware.CanEditRosterRequest).Get("/", ListRosterRequest)
 	r.With(middleware.NotGuest).Post("/", CreateRosterRequest)
 
@@ -43,7 +45,7 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.RosterRequestKey{}, rosterRequest)
+		ctx := utils.WithResource(r, utils.RosterRequestKey{}, rosterRequest)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
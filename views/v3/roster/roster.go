@@ -3,12 +3,16 @@ package roster
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/notify"
 	"github.com/VATUSA/primary-api/pkg/utils"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type Request struct {
@@ -30,6 +34,30 @@ func (req *Request) Bind(r *http.Request) error {
 	return nil
 }
 
+// StatusRequest changes the status of a roster entry. A leave of absence needs its start and end.
+type StatusRequest struct {
+	Status   string     `json:"status" example:"loa" validate:"required,oneof=active loa"`
+	LOAStart *time.Time `json:"loa_start" example:"2021-01-01T00:00:00Z" validate:"required_if=Status loa"`
+	LOAEnd   *time.Time `json:"loa_end" example:"2021-03-01T00:00:00Z" validate:"required_if=Status loa"`
+}
+
+func (req *StatusRequest) Validate() error {
+	if err := validator.New().Struct(req); err != nil {
+		return err
+	}
+	if req.Status == "loa" && !req.LOAEnd.After(*req.LOAStart) {
+		return errors.New("loa_end must be after loa_start")
+	}
+	return nil
+}
+
+func (req *StatusRequest) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
 type Response struct {
 	*models.Roster
 	FirstName string `json:"first_name"`
@@ -77,6 +105,7 @@ func NewRosterListResponse(rosters []models.Roster) []render.Renderer {
 // @Param roster body Request true "Roster"
 // @Success 201 {object} Response
 // @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
 // @Failure 500 {object} utils.ErrResponse
 // @Router /facility/{FacilityID}/roster [post]
 func CreateRoster(w http.ResponseWriter, r *http.Request) {
@@ -118,10 +147,17 @@ func CreateRoster(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := roster.Create(); err != nil {
+		var restrictionErr *models.RestrictionError
+		if errors.As(err, &restrictionErr) {
+			utils.Render(w, r, utils.ErrRestricted(err))
+			return
+		}
 		utils.Render(w, r, utils.ErrInvalidRequest(err))
 		return
 	}
 
+	utils.LogUserChange(r, roster.CID, fmt.Sprintf("Added to the %s roster", roster.Facility), models.Diff(nil, roster))
+
 	render.Status(r, http.StatusCreated)
 	utils.Render(w, r, NewRosterResponse(roster))
 }
@@ -190,9 +226,79 @@ func DeleteRoster(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	utils.LogUserChange(r, roster.CID, fmt.Sprintf("Removed from the %s roster", roster.Facility), models.Diff(roster, nil))
+
 	render.Status(r, http.StatusNoContent)
 }
 
+// UpdateRosterStatus godoc
+// @Summary Change a roster status
+// @Description Set a roster entry active or on a leave of absence. The controller is emailed when their leave of
+// @Description absence is approved.
+// @Tags roster
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param id path int true "Roster ID"
+// @Param status body StatusRequest true "Status"
+// @Success 200 {object} Response
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/roster/{id} [patch]
+func UpdateRosterStatus(w http.ResponseWriter, r *http.Request) {
+	data := &StatusRequest{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	roster := utils.GetRosterCtx(r)
+	if roster.Facility != utils.GetFacilityCtx(r).ID {
+		utils.Render(w, r, utils.ErrNotFound)
+		return
+	}
+
+	before := *roster
+	roster.Status = data.Status
+	roster.LOAStart, roster.LOAEnd = nil, nil
+	if data.Status == "loa" {
+		roster.LOAStart, roster.LOAEnd = data.LOAStart, data.LOAEnd
+	}
+
+	if err := roster.Update(); err != nil {
+		log.WithError(err).Errorf("Error updating roster %d", roster.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.LogUserChange(r, roster.CID, "", models.Diff(&before, roster))
+
+	if roster.Status == "loa" && before.Status != "loa" {
+		start, end := roster.LOAStart.Format("2006-01-02"), roster.LOAEnd.Format("2006-01-02")
+		notify.Send(&models.Notification{
+			CID:      roster.CID,
+			Category: notify.CategoryRoster,
+			Title:    "Leave of Absence Approved",
+			Body:     fmt.Sprintf("Your leave of absence from %s between %s and %s has been approved.", roster.Facility, start, end),
+			ExpireAt: time.Now().AddDate(0, 0, 7),
+			Template: notify.TemplateLOAApproved,
+			TemplateData: map[string]string{
+				"start": start,
+				"end":   end,
+			},
+			Facility: roster.Facility,
+		})
+	}
+
+	utils.Render(w, r, NewRosterResponse(roster))
+}
+
 // GetUserRosters godoc
 // @Summary Get rosters by user
 // @Description Get rosters by user
@@ -0,0 +1,102 @@
+package roster
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database"
+	"github.com/VATUSA/primary-api/pkg/database/dbtest"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/notify"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func patchStatus(t *testing.T, roster *models.Roster, facility string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	loaded := &models.Roster{ID: roster.ID}
+	if err := loaded.Get(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPatch, "/v3/facility/"+facility+"/roster/1", strings.NewReader(body))
+	ctx := context.WithValue(r.Context(), utils.RosterKey{}, loaded)
+	ctx = context.WithValue(ctx, utils.FacilityKey{}, &models.Facility{ID: constants.FacilityID(facility)})
+	ctx = context.WithValue(ctx, utils.XUser{}, &models.User{CID: 1000000})
+	w := httptest.NewRecorder()
+	UpdateRosterStatus(w, r.WithContext(ctx))
+	return w
+}
+
+func loaNotifications(t *testing.T) []models.Notification {
+	t.Helper()
+
+	var notifications []models.Notification
+	if err := database.DB.Where("template = ?", notify.TemplateLOAApproved).Find(&notifications).Error; err != nil {
+		t.Fatal(err)
+	}
+	return notifications
+}
+
+func TestUpdateRosterStatus(t *testing.T) {
+	dbtest.Open(t, &models.User{}, &models.UserFlag{}, &models.Roster{}, &models.UserRole{}, &models.Notification{},
+		&models.ActionLogEntry{})
+
+	if err := (&models.User{CID: 1293257}).Create(); err != nil {
+		t.Fatal(err)
+	}
+	roster := &models.Roster{CID: 1293257, Facility: "ZDV", Home: true, Status: "active"}
+	if err := roster.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A leave of absence needs valid dates, and can only be set through the roster's own facility
+	for _, body := range []string{
+		`{"status": "loa"}`,
+		`{"status": "loa", "loa_start": "2021-03-01T00:00:00Z", "loa_end": "2021-01-01T00:00:00Z"}`,
+		`{"status": "retired"}`,
+	} {
+		if w := patchStatus(t, roster, "ZDV", body); w.Code != http.StatusBadRequest {
+			t.Errorf("UpdateRosterStatus(%s) returned %d, want 400", body, w.Code)
+		}
+	}
+	loa := `{"status": "loa", "loa_start": "2021-01-01T00:00:00Z", "loa_end": "2021-03-01T00:00:00Z"}`
+	if w := patchStatus(t, roster, "ZLC", loa); w.Code != http.StatusNotFound {
+		t.Errorf("UpdateRosterStatus() through another facility returned %d, want 404", w.Code)
+	}
+	if got := loaNotifications(t); len(got) != 0 {
+		t.Fatalf("rejected changes sent %d LOA notifications", len(got))
+	}
+
+	// Approving the leave of absence emails the controller once
+	if w := patchStatus(t, roster, "ZDV", loa); w.Code != http.StatusOK {
+		t.Fatalf("UpdateRosterStatus() returned %d: %s", w.Code, w.Body.String())
+	}
+	notifications := loaNotifications(t)
+	if len(notifications) != 1 || notifications[0].CID != 1293257 || notifications[0].Facility != "ZDV" ||
+		notifications[0].TemplateData["start"] != "2021-01-01" || notifications[0].TemplateData["end"] != "2021-03-01" {
+		t.Fatalf("LOA notifications = %+v, want one for 1293257 from 2021-01-01 to 2021-03-01", notifications)
+	}
+
+	extend := `{"status": "loa", "loa_start": "2021-01-01T00:00:00Z", "loa_end": "2021-04-01T00:00:00Z"}`
+	if w := patchStatus(t, roster, "ZDV", extend); w.Code != http.StatusOK {
+		t.Fatalf("UpdateRosterStatus() returned %d: %s", w.Code, w.Body.String())
+	}
+	if got := loaNotifications(t); len(got) != 1 {
+		t.Errorf("changing the dates of a leave of absence sent %d LOA notifications, want 1", len(got))
+	}
+
+	// Returning from the leave of absence clears its dates
+	if w := patchStatus(t, roster, "ZDV", `{"status": "active"}`); w.Code != http.StatusOK {
+		t.Fatalf("UpdateRosterStatus() returned %d: %s", w.Code, w.Body.String())
+	}
+	if err := roster.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if roster.Status != "active" || roster.LOAStart != nil || roster.LOAEnd != nil {
+		t.Errorf("roster = %s from %v to %v, want active without dates", roster.Status, roster.LOAStart, roster.LOAEnd)
+	}
+}
@@ -1,7 +1,7 @@
 package roster
 
 import (
-	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
@@ -11,10 +11,13 @@ import (
 )
 
 func Router(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadRosterScope, constants.WriteRosterScope))
+
 	r.With(middleware.NotGuest, middleware.CanEditRoster).Post("/", CreateRoster)
 	r.Get("/", GetRosterByFacility)
 	r.Route("/{RosterID}", func(r chi.Router) {
 		r.Use(Ctx)
+		r.With(middleware.NotGuest, middleware.CanEditRoster).Patch("/", UpdateRosterStatus)
 		r.With(middleware.NotGuest, middleware.CanEditRoster).Delete("/", DeleteRoster)
 	})
 }
@@ -39,7 +42,7 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.RosterKey{}, roster)
+		ctx := utils.WithResource(r, utils.RosterKey{}, roster)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -2,8 +2,14 @@ package v3
 
 import (
 	"github.com/VATUSA/primary-api/pkg/config"
+	audit_log "github.com/VATUSA/primary-api/views/v3/audit-log"
+	"github.com/VATUSA/primary-api/views/v3/broadcast"
+	"github.com/VATUSA/primary-api/views/v3/discord"
 	"github.com/VATUSA/primary-api/views/v3/event"
 	"github.com/VATUSA/primary-api/views/v3/facility"
+	"github.com/VATUSA/primary-api/views/v3/oidc"
+	"github.com/VATUSA/primary-api/views/v3/promotion"
+	"github.com/VATUSA/primary-api/views/v3/role"
 	"github.com/VATUSA/primary-api/views/v3/user"
 	"github.com/go-chi/chi/v5"
 )
@@ -18,6 +24,31 @@ func Router(r chi.Router, cfg *config.Config) {
 			facility.Router(r)
 		})
 
+		r.Route("/broadcasts", func(r chi.Router) {
+			broadcast.Router(r)
+		})
+
+		r.Route("/discord", func(r chi.Router) {
+			discord.Router(r)
+		})
+
+		r.Route("/oauth", func(r chi.Router) {
+			oidc.Router(r)
+		})
+
+		r.Route("/roles", func(r chi.Router) {
+			role.Router(r)
+		})
+
+		r.Route("/audit-log", func(r chi.Router) {
+			audit_log.Router(r)
+		})
+
+		r.Route("/promotions", func(r chi.Router) {
+			promotion.Router(r)
+		})
+
 		r.Get("/events", event.GetAllEvents)
+		r.Get("/events.ics", event.GetAllEventsCalendar)
 	})
 }
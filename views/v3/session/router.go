@@ -0,0 +1,37 @@
+package session
+
+import (
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+// Router serves the logged-in user's sessions. Access tokens cannot be used to manage sessions.
+func Router(r chi.Router) {
+	r.Use(middleware.NoAccessToken, middleware.NotGuest, middleware.RequireUser, middleware.NoImpersonation)
+
+	r.Get("/", ListSessions)
+	r.With(Ctx).Delete("/{SessionID}", DeleteSession)
+}
+
+func Ctx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "SessionID"), 10, 64)
+		if err != nil {
+			utils.Render(w, r, utils.ErrBadRequest)
+			return
+		}
+
+		session := &models.Session{ID: uint(id)}
+		if err := session.Get(); err != nil || session.CID != utils.GetXUser(r).CID {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		ctx := utils.WithResource(r, utils.SessionKey{}, session)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
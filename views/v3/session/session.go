@@ -0,0 +1,85 @@
+package session
+
+import (
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/cookie"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type Response struct {
+	*models.Session
+	Current bool `json:"current" example:"true"`
+}
+
+func (res *Response) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.Session == nil {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+// ListSessions godoc
+// @Summary List sessions
+// @Description List the devices the logged-in user is signed in on. The session making the request is marked current.
+// @Tags session
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []Response
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/sessions [get]
+func ListSessions(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetXUser(r)
+
+	sessions, err := models.GetActiveSessionsByCID(user.CID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting sessions for %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	current := utils.GetXSession(r)
+	list := []render.Renderer{}
+	for idx := range sessions {
+		list = append(list, &Response{
+			Session: &sessions[idx],
+			Current: current != nil && current.ID == sessions[idx].ID,
+		})
+	}
+
+	if err := render.RenderList(w, r, list); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// DeleteSession godoc
+// @Summary Revoke a session
+// @Description Sign out of a device. Revoking the current session logs the user out.
+// @Tags session
+// @Accept  json
+// @Produce  json
+// @Param SessionID path int true "Session ID"
+// @Success 204
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/sessions/{SessionID} [delete]
+func DeleteSession(w http.ResponseWriter, r *http.Request) {
+	session := utils.GetSessionCtx(r)
+	if err := session.Delete(); err != nil {
+		log.WithError(err).Errorf("Error deleting session %d", session.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	if current := utils.GetXSession(r); current != nil && current.ID == session.ID {
+		cookie.ClearSession(w)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,363 @@
+package training
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/notify"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+type OTSRequest struct {
+	StudentCID uint `json:"student_cid" example:"1293257" validate:"required"`
+	// InstructorCID defaults to the logged-in user
+	InstructorCID uint                `json:"instructor_cid" example:"1293257"`
+	Position      string              `json:"position" example:"DEN_APP" validate:"required"`
+	Rating        constants.ATCRating `json:"rating" example:"4" validate:"required"`
+	Result        types.OTSResult     `json:"result" example:"pass" validate:"required"`
+	ExamDate      time.Time           `json:"exam_date" example:"2021-01-01T00:00:00Z" validate:"required"`
+	Notes         string              `json:"notes" example:"Handled the traffic load well."`
+}
+
+func (req *OTSRequest) Validate() error {
+	if err := validator.New().Struct(req); err != nil {
+		return err
+	}
+
+	if !isOTSRating(req.Rating) {
+		return errors.New("rating must be a controller rating from S1 to C3")
+	}
+	if !req.Result.IsValid() {
+		return errors.New("result must be pass or fail")
+	}
+	return nil
+}
+
+func (req *OTSRequest) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+type OTSResponse struct {
+	*models.OTSExam
+}
+
+func NewOTSExamResponse(o *models.OTSExam) *OTSResponse {
+	return &OTSResponse{OTSExam: o}
+}
+
+func (res *OTSResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.OTSExam == nil {
+		return errors.New("ots exam not found")
+	}
+	return nil
+}
+
+func NewOTSExamListResponse(exams []models.OTSExam) []render.Renderer {
+	list := []render.Renderer{}
+	for idx := range exams {
+		list = append(list, NewOTSExamResponse(&exams[idx]))
+	}
+	return list
+}
+
+// isOTSRating checks rating is one students are examined for
+func isOTSRating(rating constants.ATCRating) bool {
+	return rating >= constants.Student1Rating && rating <= constants.SeniorControllerRating && rating.IsValidRating()
+}
+
+// CreateOTSExam godoc
+// @Summary Record an OTS exam
+// @Description Record the result of an over-the-shoulder exam at the facility. Students restricted from training
+// @Description cannot have exams recorded.
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param exam body OTSRequest true "OTS Exam"
+// @Success 201 {object} OTSResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/training/ots [post]
+func CreateOTSExam(w http.ResponseWriter, r *http.Request) {
+	req := &OTSRequest{}
+	if err := req.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	instructorCID, err := resolveInstructor(r, req.StudentCID, req.InstructorCID)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	exam := &models.OTSExam{
+		StudentCID:    req.StudentCID,
+		InstructorCID: instructorCID,
+		Facility:      utils.GetFacilityCtx(r).ID,
+		Position:      req.Position,
+		Rating:        req.Rating,
+		Result:        req.Result,
+		ExamDate:      req.ExamDate,
+		Notes:         req.Notes,
+	}
+
+	if err := exam.Create(); err != nil {
+		var restrictionErr *models.RestrictionError
+		if errors.As(err, &restrictionErr) {
+			utils.Render(w, r, utils.ErrRestricted(err))
+			return
+		}
+		log.WithError(err).Errorf("Error creating OTS exam for %d", exam.StudentCID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	notify.Send(&models.Notification{
+		CID:      exam.StudentCID,
+		Category: notify.CategoryTraining,
+		Title:    "OTS Exam Recorded",
+		Body: fmt.Sprintf("Your %s OTS exam on %s at %s has been recorded as a %s.", exam.Rating.Short(), exam.Position,
+			exam.Facility, exam.Result),
+		ExpireAt: time.Now().AddDate(0, 0, 30),
+	})
+
+	render.Status(r, http.StatusCreated)
+	utils.Render(w, r, NewOTSExamResponse(exam))
+}
+
+// ListOTSExams godoc
+// @Summary List the facility's OTS exams
+// @Description List the OTS exams conducted at the facility, most recent first
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param cid query int false "Student CID"
+// @Param instructor_cid query int false "Instructor CID"
+// @Param position query string false "Position"
+// @Success 200 {object} []OTSResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/training/ots [get]
+func ListOTSExams(w http.ResponseWriter, r *http.Request) {
+	filter, err := facilityFilter(r)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	exams, err := models.GetOTSExams(filter)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting OTS exams of %s", filter.Facility)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	if err := render.RenderList(w, r, NewOTSExamListResponse(exams)); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// GetOTSExam godoc
+// @Summary Get an OTS exam
+// @Description Get an OTS exam conducted at the facility
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param OTSExamID path int true "OTS Exam ID"
+// @Success 200 {object} OTSResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/training/ots/{OTSExamID} [get]
+func GetOTSExam(w http.ResponseWriter, r *http.Request) {
+	utils.Render(w, r, NewOTSExamResponse(utils.GetOTSExamCtx(r)))
+}
+
+// UpdateOTSExam godoc
+// @Summary Update an OTS exam
+// @Description Update an OTS exam. The student and facility cannot be changed.
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param OTSExamID path int true "OTS Exam ID"
+// @Param exam body OTSRequest true "OTS Exam"
+// @Success 200 {object} OTSResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/training/ots/{OTSExamID} [put]
+func UpdateOTSExam(w http.ResponseWriter, r *http.Request) {
+	exam := utils.GetOTSExamCtx(r)
+
+	req := &OTSRequest{}
+	if err := req.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	req.StudentCID = exam.StudentCID
+	if err := req.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	instructorCID, err := resolveInstructor(r, exam.StudentCID, req.InstructorCID)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	exam.InstructorCID = instructorCID
+	exam.Position = req.Position
+	exam.Rating = req.Rating
+	exam.Result = req.Result
+	exam.ExamDate = req.ExamDate
+	exam.Notes = req.Notes
+
+	if err := exam.Update(); err != nil {
+		log.WithError(err).Errorf("Error updating OTS exam %d", exam.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, NewOTSExamResponse(exam))
+}
+
+// PatchOTSExam godoc
+// @Summary Patch an OTS exam
+// @Description Patch an OTS exam. The student and facility cannot be changed.
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param OTSExamID path int true "OTS Exam ID"
+// @Param exam body OTSRequest true "OTS Exam"
+// @Success 200 {object} OTSResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/training/ots/{OTSExamID} [patch]
+func PatchOTSExam(w http.ResponseWriter, r *http.Request) {
+	exam := utils.GetOTSExamCtx(r)
+
+	req := &OTSRequest{}
+	if err := req.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if req.InstructorCID != 0 {
+		instructorCID, err := resolveInstructor(r, exam.StudentCID, req.InstructorCID)
+		if err != nil {
+			utils.Render(w, r, utils.ErrInvalidRequest(err))
+			return
+		}
+		exam.InstructorCID = instructorCID
+	}
+	if req.Position != "" {
+		exam.Position = req.Position
+	}
+	if req.Rating != 0 {
+		if !isOTSRating(req.Rating) {
+			utils.Render(w, r, utils.ErrInvalidRequest(errors.New("rating must be a controller rating from S1 to C3")))
+			return
+		}
+		exam.Rating = req.Rating
+	}
+	if req.Result != "" {
+		if !req.Result.IsValid() {
+			utils.Render(w, r, utils.ErrInvalidRequest(errors.New("result must be pass or fail")))
+			return
+		}
+		exam.Result = req.Result
+	}
+	if !req.ExamDate.IsZero() {
+		exam.ExamDate = req.ExamDate
+	}
+	if req.Notes != "" {
+		exam.Notes = req.Notes
+	}
+
+	if err := exam.Update(); err != nil {
+		log.WithError(err).Errorf("Error updating OTS exam %d", exam.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, NewOTSExamResponse(exam))
+}
+
+// DeleteOTSExam godoc
+// @Summary Delete an OTS exam
+// @Description Delete an OTS exam
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param OTSExamID path int true "OTS Exam ID"
+// @Success 204
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/training/ots/{OTSExamID} [delete]
+func DeleteOTSExam(w http.ResponseWriter, r *http.Request) {
+	exam := utils.GetOTSExamCtx(r)
+	if err := exam.Delete(); err != nil {
+		log.WithError(err).Errorf("Error deleting OTS exam %d", exam.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUserOTSExams godoc
+// @Summary Get a user's OTS exams
+// @Description Get the OTS exams of a student across every facility, most recent first
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param CID path int true "CID"
+// @Success 200 {object} []OTSResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{CID}/training/ots [get]
+func GetUserOTSExams(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserCtx(r)
+
+	exams, err := models.GetOTSExams(models.TrainingFilter{StudentCID: user.CID})
+	if err != nil {
+		log.WithError(err).Errorf("Error getting OTS exams of %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	if err := render.RenderList(w, r, NewOTSExamListResponse(exams)); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
@@ -0,0 +1,90 @@
+package training
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+// Router serves a facility's training history, mounted under /facility/{FacilityID}/training
+func Router(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadTrainingScope, constants.WriteTrainingScope))
+
+	r.Route("/sessions", func(r chi.Router) {
+		r.With(middleware.NotGuest, middleware.CanViewTraining).Get("/", ListTrainingSessions)
+		r.With(middleware.NotGuest, middleware.CanEditTraining).Post("/", CreateTrainingSession)
+
+		r.Route("/{TrainingSessionID}", func(r chi.Router) {
+			r.Use(SessionCtx, middleware.NotGuest)
+
+			r.With(middleware.CanViewTraining).Get("/", GetTrainingSession)
+			r.With(middleware.CanEditTraining).Put("/", UpdateTrainingSession)
+			r.With(middleware.CanEditTraining).Patch("/", PatchTrainingSession)
+			r.With(middleware.CanEditTraining).Delete("/", DeleteTrainingSession)
+		})
+	})
+
+	r.Route("/ots", func(r chi.Router) {
+		r.With(middleware.NotGuest, middleware.CanViewTraining).Get("/", ListOTSExams)
+		r.With(middleware.NotGuest, middleware.CanConductOTS).Post("/", CreateOTSExam)
+
+		r.Route("/{OTSExamID}", func(r chi.Router) {
+			r.Use(OTSCtx, middleware.NotGuest)
+
+			r.With(middleware.CanViewTraining).Get("/", GetOTSExam)
+			r.With(middleware.CanConductOTS).Put("/", UpdateOTSExam)
+			r.With(middleware.CanConductOTS).Patch("/", PatchOTSExam)
+			r.With(middleware.CanConductOTS).Delete("/", DeleteOTSExam)
+		})
+	})
+}
+
+// UserRouter serves a student's training history across facilities, mounted under /user/{CID}/training
+func UserRouter(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadTrainingScope, constants.WriteTrainingScope), middleware.NotGuest, middleware.CanViewUserTraining)
+
+	r.Get("/sessions", GetUserTrainingSessions)
+	r.Get("/ots", GetUserOTSExams)
+}
+
+func SessionCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "TrainingSessionID"), 10, 64)
+		if err != nil {
+			utils.Render(w, r, utils.ErrBadRequest)
+			return
+		}
+
+		session := &models.TrainingSession{ID: uint(id)}
+		if err := session.Get(); err != nil || session.Facility != utils.GetFacilityCtx(r).ID {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		ctx := utils.WithResource(r, utils.TrainingSessionKey{}, session)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func OTSCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "OTSExamID"), 10, 64)
+		if err != nil {
+			utils.Render(w, r, utils.ErrBadRequest)
+			return
+		}
+
+		exam := &models.OTSExam{ID: uint(id)}
+		if err := exam.Get(); err != nil || exam.Facility != utils.GetFacilityCtx(r).ID {
+			utils.Render(w, r, utils.ErrNotFound)
+			return
+		}
+
+		ctx := utils.WithResource(r, utils.OTSExamKey{}, exam)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
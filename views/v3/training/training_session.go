@@ -0,0 +1,402 @@
+package training
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/notify"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type SessionRequest struct {
+	StudentCID uint `json:"student_cid" example:"1293257" validate:"required"`
+	// InstructorCID defaults to the logged-in user
+	InstructorCID uint                      `json:"instructor_cid" example:"1293257"`
+	Position      string                    `json:"position" example:"DEN_APP" validate:"required"`
+	Type          types.TrainingSessionType `json:"type" example:"sweatbox" validate:"required"`
+	SessionDate   time.Time                 `json:"session_date" example:"2021-01-01T00:00:00Z" validate:"required"`
+	Duration      uint                      `json:"duration" example:"90" validate:"required"`
+	Score         uint                      `json:"score" example:"4" validate:"max=5"`
+	Notes         string                    `json:"notes" example:"Good progress on vectoring for the ILS."`
+}
+
+func (req *SessionRequest) Validate() error {
+	if err := validator.New().Struct(req); err != nil {
+		return err
+	}
+
+	if !req.Type.IsValid() {
+		return errors.New("type must be one of classroom, sweatbox or live")
+	}
+	return nil
+}
+
+func (req *SessionRequest) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+type SessionResponse struct {
+	*models.TrainingSession
+}
+
+func NewTrainingSessionResponse(ts *models.TrainingSession) *SessionResponse {
+	return &SessionResponse{TrainingSession: ts}
+}
+
+func (res *SessionResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.TrainingSession == nil {
+		return errors.New("training session not found")
+	}
+	return nil
+}
+
+func NewTrainingSessionListResponse(sessions []models.TrainingSession) []render.Renderer {
+	list := []render.Renderer{}
+	for idx := range sessions {
+		list = append(list, NewTrainingSessionResponse(&sessions[idx]))
+	}
+	return list
+}
+
+// CreateTrainingSession godoc
+// @Summary Record a training session
+// @Description Record a training session at the facility. Students restricted from training cannot have sessions
+// @Description recorded.
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param session body SessionRequest true "Training Session"
+// @Success 201 {object} SessionResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/training/sessions [post]
+func CreateTrainingSession(w http.ResponseWriter, r *http.Request) {
+	req := &SessionRequest{}
+	if err := req.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	instructorCID, err := resolveInstructor(r, req.StudentCID, req.InstructorCID)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	session := &models.TrainingSession{
+		StudentCID:    req.StudentCID,
+		InstructorCID: instructorCID,
+		Facility:      utils.GetFacilityCtx(r).ID,
+		Position:      req.Position,
+		Type:          req.Type,
+		SessionDate:   req.SessionDate,
+		Duration:      req.Duration,
+		Score:         req.Score,
+		Notes:         req.Notes,
+	}
+
+	if err := session.Create(); err != nil {
+		var restrictionErr *models.RestrictionError
+		if errors.As(err, &restrictionErr) {
+			utils.Render(w, r, utils.ErrRestricted(err))
+			return
+		}
+		log.WithError(err).Errorf("Error creating training session for %d", session.StudentCID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	notify.Send(&models.Notification{
+		CID:      session.StudentCID,
+		Category: notify.CategoryTraining,
+		Title:    "Training Session Recorded",
+		Body:     fmt.Sprintf("Your %s session on %s at %s has been recorded.", session.Type, session.Position, session.Facility),
+		ExpireAt: time.Now().AddDate(0, 0, 30),
+	})
+
+	render.Status(r, http.StatusCreated)
+	utils.Render(w, r, NewTrainingSessionResponse(session))
+}
+
+// ListTrainingSessions godoc
+// @Summary List the facility's training sessions
+// @Description List the training sessions recorded at the facility, most recent first
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param cid query int false "Student CID"
+// @Param instructor_cid query int false "Instructor CID"
+// @Param position query string false "Position"
+// @Success 200 {object} []SessionResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/training/sessions [get]
+func ListTrainingSessions(w http.ResponseWriter, r *http.Request) {
+	filter, err := facilityFilter(r)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	sessions, err := models.GetTrainingSessions(filter)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting training sessions of %s", filter.Facility)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	if err := render.RenderList(w, r, NewTrainingSessionListResponse(sessions)); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// GetTrainingSession godoc
+// @Summary Get a training session
+// @Description Get a training session recorded at the facility
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param TrainingSessionID path int true "Training Session ID"
+// @Success 200 {object} SessionResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/training/sessions/{TrainingSessionID} [get]
+func GetTrainingSession(w http.ResponseWriter, r *http.Request) {
+	utils.Render(w, r, NewTrainingSessionResponse(utils.GetTrainingSessionCtx(r)))
+}
+
+// UpdateTrainingSession godoc
+// @Summary Update a training session
+// @Description Update a training session. The student and facility cannot be changed.
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param TrainingSessionID path int true "Training Session ID"
+// @Param session body SessionRequest true "Training Session"
+// @Success 200 {object} SessionResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/training/sessions/{TrainingSessionID} [put]
+func UpdateTrainingSession(w http.ResponseWriter, r *http.Request) {
+	session := utils.GetTrainingSessionCtx(r)
+
+	req := &SessionRequest{}
+	if err := req.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	req.StudentCID = session.StudentCID
+	if err := req.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	instructorCID, err := resolveInstructor(r, session.StudentCID, req.InstructorCID)
+	if err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	session.InstructorCID = instructorCID
+	session.Position = req.Position
+	session.Type = req.Type
+	session.SessionDate = req.SessionDate
+	session.Duration = req.Duration
+	session.Score = req.Score
+	session.Notes = req.Notes
+
+	if err := session.Update(); err != nil {
+		log.WithError(err).Errorf("Error updating training session %d", session.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, NewTrainingSessionResponse(session))
+}
+
+// PatchTrainingSession godoc
+// @Summary Patch a training session
+// @Description Patch a training session. The student and facility cannot be changed.
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param TrainingSessionID path int true "Training Session ID"
+// @Param session body SessionRequest true "Training Session"
+// @Success 200 {object} SessionResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/training/sessions/{TrainingSessionID} [patch]
+func PatchTrainingSession(w http.ResponseWriter, r *http.Request) {
+	session := utils.GetTrainingSessionCtx(r)
+
+	req := &SessionRequest{}
+	if err := req.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	if req.InstructorCID != 0 {
+		instructorCID, err := resolveInstructor(r, session.StudentCID, req.InstructorCID)
+		if err != nil {
+			utils.Render(w, r, utils.ErrInvalidRequest(err))
+			return
+		}
+		session.InstructorCID = instructorCID
+	}
+	if req.Position != "" {
+		session.Position = req.Position
+	}
+	if req.Type != "" {
+		if !req.Type.IsValid() {
+			utils.Render(w, r, utils.ErrInvalidRequest(errors.New("type must be one of classroom, sweatbox or live")))
+			return
+		}
+		session.Type = req.Type
+	}
+	if !req.SessionDate.IsZero() {
+		session.SessionDate = req.SessionDate
+	}
+	if req.Duration != 0 {
+		session.Duration = req.Duration
+	}
+	if req.Score != 0 {
+		if req.Score > 5 {
+			utils.Render(w, r, utils.ErrInvalidRequest(errors.New("score must be between 1 and 5")))
+			return
+		}
+		session.Score = req.Score
+	}
+	if req.Notes != "" {
+		session.Notes = req.Notes
+	}
+
+	if err := session.Update(); err != nil {
+		log.WithError(err).Errorf("Error updating training session %d", session.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, NewTrainingSessionResponse(session))
+}
+
+// DeleteTrainingSession godoc
+// @Summary Delete a training session
+// @Description Delete a training session
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param FacilityID path string true "Facility ID"
+// @Param TrainingSessionID path int true "Training Session ID"
+// @Success 204
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /facility/{FacilityID}/training/sessions/{TrainingSessionID} [delete]
+func DeleteTrainingSession(w http.ResponseWriter, r *http.Request) {
+	session := utils.GetTrainingSessionCtx(r)
+	if err := session.Delete(); err != nil {
+		log.WithError(err).Errorf("Error deleting training session %d", session.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUserTrainingSessions godoc
+// @Summary Get a user's training sessions
+// @Description Get the training sessions of a student across every facility, most recent first
+// @Tags training
+// @Accept  json
+// @Produce  json
+// @Param CID path int true "CID"
+// @Success 200 {object} []SessionResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{CID}/training/sessions [get]
+func GetUserTrainingSessions(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserCtx(r)
+
+	sessions, err := models.GetTrainingSessions(models.TrainingFilter{StudentCID: user.CID})
+	if err != nil {
+		log.WithError(err).Errorf("Error getting training sessions of %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	if err := render.RenderList(w, r, NewTrainingSessionListResponse(sessions)); err != nil {
+		utils.Render(w, r, utils.ErrRender(err))
+		return
+	}
+}
+
+// resolveInstructor defaults the instructor to the logged-in user and checks they are someone other than the student
+func resolveInstructor(r *http.Request, studentCID, instructorCID uint) (uint, error) {
+	if instructorCID == 0 {
+		if user := utils.GetXUser(r); user != nil {
+			instructorCID = user.CID
+		}
+	}
+
+	switch {
+	case instructorCID == 0:
+		return 0, errors.New("instructor_cid is required")
+	case instructorCID == studentCID:
+		return 0, errors.New("the instructor cannot be the student")
+	case !models.IsValidUser(studentCID):
+		return 0, errors.New("student not found")
+	case !models.IsValidUser(instructorCID):
+		return 0, errors.New("instructor not found")
+	}
+	return instructorCID, nil
+}
+
+// facilityFilter reads the optional cid, instructor_cid and position filters of the facility's history
+func facilityFilter(r *http.Request) (models.TrainingFilter, error) {
+	filter := models.TrainingFilter{
+		Facility: utils.GetFacilityCtx(r).ID,
+		Position: r.URL.Query().Get("position"),
+	}
+
+	for param, dst := range map[string]*uint{"cid": &filter.StudentCID, "instructor_cid": &filter.InstructorCID} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		cid, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s", param)
+		}
+		*dst = uint(cid)
+	}
+	return filter, nil
+}
@@ -1,7 +1,7 @@
 package user_flag
 
 import (
-	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
@@ -10,7 +10,7 @@ import (
 )
 
 func Router(r chi.Router) {
-	r.Use(middleware.NotGuest, Ctx)
+	r.Use(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope), middleware.NotGuest, Ctx)
 	r.With(middleware.CanViewUserFlag).Get("/", GetUserFlag)
 	r.With(middleware.CanEditUserFlag).Put("/", UpdateUserFlag)
 	r.With(middleware.CanEditUserFlag).Patch("/", PatchUserFlag)
@@ -27,7 +27,7 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.UserFlagKey{}, userFlag)
+		ctx := utils.WithResource(r, utils.UserFlagKey{}, userFlag)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
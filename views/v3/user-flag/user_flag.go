@@ -7,23 +7,43 @@ import (
 	"github.com/VATUSA/primary-api/pkg/utils"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
 	"net/http"
+	"time"
 )
 
 type Request struct {
-	NoStaffRole              bool `json:"no_staff_role" example:"false"`
-	NoStaffLogEntryID        uint `json:"no_staff_log_entry_id" example:"1"`
-	NoVisiting               bool `json:"no_visiting" example:"false"`
-	NoVisitingLogEntryID     uint `json:"no_visiting_log_entry_id" example:"1"`
-	NoTransferring           bool `json:"no_transferring" example:"false"`
-	NoTransferringLogEntryID uint `json:"no_transferring_log_entry_id" example:"1"`
-	NoTraining               bool `json:"no_training" example:"false"`
-	NoTrainingLogEntryID     uint `json:"no_training_log_entry_id" example:"1"`
-	UsedTransferOverride     bool `json:"used_transfer_override" example:"false"`
+	NoStaffRole              bool       `json:"no_staff_role" example:"false"`
+	NoStaffLogEntryID        uint       `json:"no_staff_log_entry_id" example:"1"`
+	NoStaffRoleUntil         *time.Time `json:"no_staff_role_until" example:"2021-01-01T00:00:00Z"`
+	NoStaffRoleReason        string     `json:"no_staff_role_reason" example:"Suspended"`
+	NoVisiting               bool       `json:"no_visiting" example:"false"`
+	NoVisitingLogEntryID     uint       `json:"no_visiting_log_entry_id" example:"1"`
+	NoVisitingUntil          *time.Time `json:"no_visiting_until" example:"2021-01-01T00:00:00Z"`
+	NoVisitingReason         string     `json:"no_visiting_reason" example:"Removed from ZDV visiting roster"`
+	NoTransferring           bool       `json:"no_transferring" example:"false"`
+	NoTransferringLogEntryID uint       `json:"no_transferring_log_entry_id" example:"1"`
+	NoTransferringUntil      *time.Time `json:"no_transferring_until" example:"2021-01-01T00:00:00Z"`
+	NoTransferringReason     string     `json:"no_transferring_reason" example:"Transferred within the last 90 days"`
+	NoTraining               bool       `json:"no_training" example:"false"`
+	NoTrainingLogEntryID     uint       `json:"no_training_log_entry_id" example:"1"`
+	NoTrainingUntil          *time.Time `json:"no_training_until" example:"2021-01-01T00:00:00Z"`
+	NoTrainingReason         string     `json:"no_training_reason" example:"Missed three sessions"`
+	UsedTransferOverride     bool       `json:"used_transfer_override" example:"false"`
 }
 
 func (req *Request) Validate() error {
-	return validator.New().Struct(req)
+	if err := validator.New().Struct(req); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, until := range []*time.Time{req.NoStaffRoleUntil, req.NoVisitingUntil, req.NoTransferringUntil, req.NoTrainingUntil} {
+		if until != nil && !until.After(now) {
+			return errors.New("restriction expiry must be in the future")
+		}
+	}
+	return nil
 }
 
 func (req *Request) Bind(r *http.Request) error {
@@ -100,14 +120,23 @@ func UpdateUserFlag(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userFlag := utils.GetUserFlagCtx(r)
+	before := *userFlag
 	userFlag.NoStaffRole = req.NoStaffRole
 	userFlag.NoStaffLogEntryID = req.NoStaffLogEntryID
+	userFlag.NoStaffRoleUntil = req.NoStaffRoleUntil
+	userFlag.NoStaffRoleReason = req.NoStaffRoleReason
 	userFlag.NoVisiting = req.NoVisiting
 	userFlag.NoVisitingLogEntryID = req.NoVisitingLogEntryID
+	userFlag.NoVisitingUntil = req.NoVisitingUntil
+	userFlag.NoVisitingReason = req.NoVisitingReason
 	userFlag.NoTransferring = req.NoTransferring
 	userFlag.NoTransferringLogEntryID = req.NoTransferringLogEntryID
+	userFlag.NoTransferringUntil = req.NoTransferringUntil
+	userFlag.NoTransferringReason = req.NoTransferringReason
 	userFlag.NoTraining = req.NoTraining
 	userFlag.NoTrainingLogEntryID = req.NoTrainingLogEntryID
+	userFlag.NoTrainingUntil = req.NoTrainingUntil
+	userFlag.NoTrainingReason = req.NoTrainingReason
 	userFlag.UsedTransferOverride = req.UsedTransferOverride
 
 	if err := userFlag.Update(); err != nil {
@@ -115,6 +144,10 @@ func UpdateUserFlag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := models.RecordRestrictionsLifted(&before, userFlag, utils.GetPrincipal(r).Name()); err != nil {
+		log.WithError(err).Errorf("Error recording lifted restrictions of %d", userFlag.CID)
+	}
+
 	utils.Render(w, r, NewUserFlagResponse(userFlag))
 }
 
@@ -138,6 +171,11 @@ func PatchUserFlag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := req.Validate(); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
 	userFlag := utils.GetUserFlagCtx(r)
 	if req.NoStaffRole {
 		userFlag.NoStaffRole = req.NoStaffRole
@@ -145,24 +183,48 @@ func PatchUserFlag(w http.ResponseWriter, r *http.Request) {
 	if req.NoStaffLogEntryID != 0 {
 		userFlag.NoStaffLogEntryID = req.NoStaffLogEntryID
 	}
+	if req.NoStaffRoleUntil != nil {
+		userFlag.NoStaffRoleUntil = req.NoStaffRoleUntil
+	}
+	if req.NoStaffRoleReason != "" {
+		userFlag.NoStaffRoleReason = req.NoStaffRoleReason
+	}
 	if req.NoVisiting {
 		userFlag.NoVisiting = req.NoVisiting
 	}
 	if req.NoVisitingLogEntryID != 0 {
 		userFlag.NoVisitingLogEntryID = req.NoVisitingLogEntryID
 	}
+	if req.NoVisitingUntil != nil {
+		userFlag.NoVisitingUntil = req.NoVisitingUntil
+	}
+	if req.NoVisitingReason != "" {
+		userFlag.NoVisitingReason = req.NoVisitingReason
+	}
 	if req.NoTransferring {
 		userFlag.NoTransferring = req.NoTransferring
 	}
 	if req.NoTransferringLogEntryID != 0 {
 		userFlag.NoTransferringLogEntryID = req.NoTransferringLogEntryID
 	}
+	if req.NoTransferringUntil != nil {
+		userFlag.NoTransferringUntil = req.NoTransferringUntil
+	}
+	if req.NoTransferringReason != "" {
+		userFlag.NoTransferringReason = req.NoTransferringReason
+	}
 	if req.NoTraining {
 		userFlag.NoTraining = req.NoTraining
 	}
 	if req.NoTrainingLogEntryID != 0 {
 		userFlag.NoTrainingLogEntryID = req.NoTrainingLogEntryID
 	}
+	if req.NoTrainingUntil != nil {
+		userFlag.NoTrainingUntil = req.NoTrainingUntil
+	}
+	if req.NoTrainingReason != "" {
+		userFlag.NoTrainingReason = req.NoTrainingReason
+	}
 	if req.UsedTransferOverride {
 		userFlag.UsedTransferOverride = req.UsedTransferOverride
 	}
@@ -193,5 +255,9 @@ func DeleteUserFlag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := models.RecordRestrictionsLifted(userFlag, nil, utils.GetPrincipal(r).Name()); err != nil {
+		log.WithError(err).Errorf("Error recording lifted restrictions of %d", userFlag.CID)
+	}
+
 	render.Status(r, http.StatusNoContent)
 }
@@ -1,7 +1,6 @@
 package user_notification
 
 import (
-	"context"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
@@ -25,7 +24,7 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.UserNotificationKey{}, userNotification)
+		ctx := utils.WithResource(r, utils.UserNotificationKey{}, userNotification)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -1,7 +1,6 @@
 package user_role
 
 import (
-	"context"
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	middleware "github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
@@ -11,6 +10,8 @@ import (
 )
 
 func Router(r chi.Router) {
+	r.Use(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope))
+
 	r.With(middleware.NotGuest).Get("/", GetSelfRoles)
 
 	r.Route("/{RoleID}", func(r chi.Router) {
@@ -36,7 +37,7 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.UserRoleKey{}, role)
+		ctx := utils.WithResource(r, utils.UserRoleKey{}, role)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -3,8 +3,10 @@ package user_role
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/notify"
 	"github.com/VATUSA/primary-api/pkg/utils"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
@@ -108,6 +110,7 @@ func GetSelfRoles(w http.ResponseWriter, r *http.Request) {
 // @Param user_role body Request true "User Role"
 // @Success 201 {object} Response
 // @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
 // @Failure 500 {object} utils.ErrResponse
 // @Router /user/{cid}/roles [post]
 func CreateUserRoles(w http.ResponseWriter, r *http.Request) {
@@ -133,24 +136,37 @@ func CreateUserRoles(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := userRole.Create(); err != nil {
+		var restrictionErr *models.RestrictionError
+		if errors.As(err, &restrictionErr) {
+			utils.Render(w, r, utils.ErrRestricted(err))
+			return
+		}
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
 	}
 
+	changes := models.Diff(nil, userRole)
+	entry := fmt.Sprintf("Assigned %s at %s to %d", userRole.RoleID, userRole.FacilityID, userRole.CID)
+	utils.LogUserChange(r, userRole.CID, entry, changes)
+	utils.LogFacilityChange(r, userRole.FacilityID, entry, changes)
+
 	utils.Render(w, r, NewUserRoleResponse(userRole.RoleID, userRole.FacilityID, userRole.CreatedAt))
 
 	// Create notification
 	notification := &models.Notification{
 		CID:      user.CID,
-		Category: "Administration",
+		Category: notify.CategoryAdministration,
 		Title:    "Role Added",
 		Body:     "You have been added to the " + string(userRole.RoleID) + " role at " + string(userRole.FacilityID),
 		ExpireAt: time.Now().Add(7 * 24 * time.Hour),
+		Template: notify.TemplateRoleAssigned,
+		TemplateData: map[string]string{
+			"role": string(userRole.RoleID),
+		},
+		Facility: userRole.FacilityID,
 	}
 
-	if err := notification.Create(); err != nil {
-		log.WithError(err).Errorf("Error creating notification for user %d", user.CID)
-	}
+	notify.Send(notification)
 }
 
 // DeleteUserRoles godoc
@@ -179,18 +195,21 @@ func DeleteUserRoles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	changes := models.Diff(role, nil)
+	entry := fmt.Sprintf("Removed %s at %s from %d", role.RoleID, role.FacilityID, role.CID)
+	utils.LogUserChange(r, role.CID, entry, changes)
+	utils.LogFacilityChange(r, role.FacilityID, entry, changes)
+
 	w.WriteHeader(http.StatusNoContent)
 
 	// Create notification
 	notification := &models.Notification{
 		CID:      user.CID,
-		Category: "Administration",
+		Category: notify.CategoryAdministration,
 		Title:    "Role Removed",
 		Body:     "You have been removed from the " + string(role.RoleID) + " role at " + string(role.FacilityID),
 		ExpireAt: time.Now().Add(7 * 24 * time.Hour),
 	}
 
-	if err := notification.Create(); err != nil {
-		log.WithError(err).Errorf("Error creating notification for user %d", user.CID)
-	}
+	notify.Send(notification)
 }
@@ -0,0 +1,81 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/config"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	gonanoid "github.com/matoous/go-nanoid"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// CalendarResponse is the calendar feed link of the current user. Only a hash of the feed token is stored, so the
+// URL is only included right after it was created.
+type CalendarResponse struct {
+	Enabled bool   `json:"enabled" example:"true"`
+	URL     string `json:"url,omitempty" example:"https://api.vatusa.net/v3/user/calendar/abc123.ics"`
+}
+
+func (res *CalendarResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if res.URL != "" && !res.Enabled {
+		return errors.New("calendar url of a disabled feed")
+	}
+	return nil
+}
+
+// GetCalendarLink godoc
+// @Summary Get your calendar feed link status
+// @Description Get whether you have a private iCalendar feed URL of your event assignments. The URL itself is only
+// @Description shown once when it is created, use the reset endpoint to create a new one.
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} CalendarResponse
+// @Failure 401 {object} utils.ErrResponse
+// @Router /user/calendar [get]
+func GetCalendarLink(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetXUser(r)
+
+	utils.Render(w, r, &CalendarResponse{Enabled: user.CalendarTokenHash != nil})
+}
+
+// ResetCalendarLink godoc
+// @Summary Create or reset your calendar feed link
+// @Description Create a private iCalendar feed URL of your event assignments, replacing the previous one which stops
+// @Description working. The URL is only shown in this response.
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} CalendarResponse
+// @Failure 401 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/calendar/reset [post]
+func ResetCalendarLink(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetXUser(r)
+
+	token, err := resetCalendarToken(user)
+	if err != nil {
+		log.WithError(err).Errorf("Error resetting calendar token for user %d", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, &CalendarResponse{
+		Enabled: true,
+		URL:     fmt.Sprintf("%s/v3/user/calendar/%s.ics", config.Cfg.API.BaseURL, token),
+	})
+}
+
+// resetCalendarToken creates a new calendar feed token for user and stores its hash
+func resetCalendarToken(user *models.User) (string, error) {
+	token, err := gonanoid.Generate("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789", 48)
+	if err != nil {
+		return "", err
+	}
+
+	hash := models.HashToken(token)
+	user.CalendarTokenHash = &hash
+	return token, user.Update()
+}
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"github.com/VATUSA/primary-api/pkg/config"
 	"github.com/VATUSA/primary-api/pkg/cookie"
+	"github.com/VATUSA/primary-api/pkg/database/models"
 	"github.com/VATUSA/primary-api/pkg/oauth"
 	"github.com/VATUSA/primary-api/pkg/utils"
 	gonanoid "github.com/matoous/go-nanoid"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"net/http"
+	"net/url"
 )
 
 func GetDiscordLink(w http.ResponseWriter, r *http.Request) {
@@ -119,23 +121,38 @@ func GetDiscordCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user := utils.GetXUser(r)
-	user.DiscordID = discordResp.ID
-	if err := user.Update(); err != nil {
+	if err := linkDiscordAccount(r, utils.GetXUser(r), discordResp.ID); err != nil {
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
 	}
 
-	redirect := session["redirect"]
-	if redirect == "" {
-		redirect = "https://vatusa.net"
-	} else {
-		redirect = fmt.Sprintf("%s?name=%s#discord", redirect, discordResp.Username)
+	redirect := defaultRedirect
+	if session["redirect"] != "" && safeRedirect(session["redirect"]) == session["redirect"] {
+		redirect = fmt.Sprintf("%s?name=%s#discord", session["redirect"], url.QueryEscape(discordResp.Username))
 	}
 
 	utils.TempRedirect(w, r, redirect)
 }
 
+// linkDiscordAccount links discordID to user. The mapped roles are revoked from a previously linked account and
+// granted to the new one.
+func linkDiscordAccount(r *http.Request, user *models.User, discordID string) error {
+	before := *user
+	previousID := user.DiscordID
+	user.DiscordID = discordID
+	if err := user.Update(); err != nil {
+		return err
+	}
+
+	utils.LogUserChange(r, user.CID, "Linked Discord account", models.Diff(&before, user))
+
+	if previousID != "" && previousID != user.DiscordID {
+		revokeDiscordRoles(user.CID, previousID)
+	}
+	syncDiscordRoles(user.CID)
+	return nil
+}
+
 // UnlinkDiscord godoc
 // @Summary Unlink your Discord account
 // @Description Unlink your Discord account from your VATUSA account
@@ -156,11 +173,17 @@ func UnlinkDiscord(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := *user
+	discordID := user.DiscordID
 	user.DiscordID = ""
 	if err := user.Update(); err != nil {
 		utils.Render(w, r, utils.ErrInternalServer)
 		return
 	}
 
+	utils.LogUserChange(r, user.CID, "Unlinked Discord account", models.Diff(&before, user))
+
+	revokeDiscordRoles(user.CID, discordID)
+
 	utils.Response(r, http.StatusOK)
 }
@@ -0,0 +1,87 @@
+package user
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/discord"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+const discordSyncTimeout = time.Minute
+
+type DiscordRolesResponse struct {
+	DiscordID string               `json:"discord_id" example:"1234567890"`
+	Guilds    []discord.GuildRoles `json:"guilds"`
+}
+
+func (res *DiscordRolesResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// GetDiscordRoles godoc
+// @Summary Get a user's Discord roles
+// @Description Get the mapped roles a user should hold in every configured Discord guild, for bots applying roles
+// @Description themselves. Roles not listed in a guild's mappings are not managed by VATUSA.
+// @Tags discord
+// @Accept  json
+// @Produce  json
+// @Param CID path int true "CID"
+// @Success 200 {object} DiscordRolesResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/{CID}/discord/roles [get]
+func GetDiscordRoles(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserCtx(r)
+
+	guilds := []discord.GuildRoles{}
+	if user.DiscordID != "" {
+		var err error
+		if guilds, err = discord.DesiredGuildRoles(user); err != nil {
+			log.WithError(err).Errorf("Error computing Discord roles for user %d", user.CID)
+			utils.Render(w, r, utils.ErrInternalServer)
+			return
+		}
+	}
+
+	utils.Render(w, r, &DiscordRolesResponse{DiscordID: user.DiscordID, Guilds: guilds})
+}
+
+// syncDiscordRoles syncs the user's roles in the background once they link their Discord account
+func syncDiscordRoles(cid uint) {
+	if discord.DefaultSyncer == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), discordSyncTimeout)
+		defer cancel()
+
+		user := &models.User{CID: cid}
+		if err := user.Get(); err != nil {
+			log.WithError(err).Errorf("Error loading user %d for Discord role sync", cid)
+			return
+		}
+		if err := discord.DefaultSyncer.SyncUser(ctx, user); err != nil {
+			log.WithError(err).Errorf("Error syncing Discord roles for user %d", cid)
+		}
+	}()
+}
+
+// revokeDiscordRoles removes the mapped roles from an unlinked Discord account in the background
+func revokeDiscordRoles(cid uint, discordID string) {
+	if discord.DefaultSyncer == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), discordSyncTimeout)
+		defer cancel()
+
+		if err := discord.DefaultSyncer.RevokeUser(ctx, discordID); err != nil {
+			log.WithError(err).Errorf("Error revoking Discord roles of user %d", cid)
+		}
+	}()
+}
@@ -0,0 +1,102 @@
+package user
+
+import (
+	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/database/dbtest"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/database/types"
+	"github.com/VATUSA/primary-api/pkg/discord"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// setupDiscordSync links user 1293257, a C1, to Discord account "old" and syncs roles through a fake Discord where
+// both "old" and "new" are members of the ZDV guild, which maps the C1 rating to role "c1"
+func setupDiscordSync(t *testing.T) (*discord.Fake, *models.User) {
+	t.Helper()
+
+	dbtest.Open(t, &models.User{}, &models.UserFlag{}, &models.Roster{}, &models.UserRole{}, &models.ActionLogEntry{},
+		&models.DiscordGuild{}, &models.DiscordRoleMapping{})
+
+	guild := &models.DiscordGuild{
+		Facility: "ZDV",
+		GuildID:  "zdv",
+		Roles:    []models.DiscordRoleMapping{{Source: types.DiscordSourceRating, Value: "C1", DiscordRoleID: "c1"}},
+	}
+	if err := guild.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	user := &models.User{CID: 1293257, ControllerRating: constants.ControllerRating, DiscordID: "old"}
+	if err := user.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &discord.Fake{Members: map[string]map[string][]string{
+		"zdv": {"old": {"c1", "unmanaged"}, "new": {}},
+	}}
+
+	previous := discord.DefaultSyncer
+	discord.DefaultSyncer = discord.NewSyncer(fake)
+	t.Cleanup(func() { discord.DefaultSyncer = previous })
+
+	return fake, user
+}
+
+// waitForRoles waits for the background sync to leave discordID holding want in the ZDV guild
+func waitForRoles(t *testing.T, fake *discord.Fake, discordID string, want ...string) {
+	t.Helper()
+
+	var got []string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		got, _ = fake.GetMemberRoles(context.Background(), "zdv", discordID)
+		slices.Sort(got)
+		if slices.Equal(got, want) {
+			return
+		}
+	}
+	t.Errorf("roles of %s = %v, want %v", discordID, got, want)
+}
+
+func TestLinkDiscordAccountRelink(t *testing.T) {
+	fake, user := setupDiscordSync(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/v3/user/discord/callback", nil)
+	if err := linkDiscordAccount(r, user, "new"); err != nil {
+		t.Fatalf("linkDiscordAccount() error = %v", err)
+	}
+
+	waitForRoles(t, fake, "old", "unmanaged")
+	waitForRoles(t, fake, "new", "c1")
+
+	stored := &models.User{CID: user.CID}
+	if err := stored.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if stored.DiscordID != "new" {
+		t.Errorf("DiscordID = %q, want %q", stored.DiscordID, "new")
+	}
+}
+
+func TestUnlinkDiscord(t *testing.T) {
+	fake, user := setupDiscordSync(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/v3/user/discord/unlink", nil)
+	r = r.WithContext(context.WithValue(r.Context(), utils.XUser{}, user))
+	UnlinkDiscord(httptest.NewRecorder(), r)
+
+	waitForRoles(t, fake, "old", "unmanaged")
+
+	stored := &models.User{CID: user.CID}
+	if err := stored.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if stored.DiscordID != "" {
+		t.Errorf("DiscordID = %q, want it cleared", stored.DiscordID)
+	}
+}
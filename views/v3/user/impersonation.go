@@ -0,0 +1,161 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/VATUSA/primary-api/pkg/database/models"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// impersonationLifetime is how long an impersonation lasts before the session returns to the staff member
+const impersonationLifetime = time.Hour
+
+type ImpersonationRequest struct {
+	CID      uint   `json:"cid" example:"1293257"`
+	ReadOnly bool   `json:"read_only" example:"true"`
+	Reason   string `json:"reason" example:"Ticket #1234, roster request not showing"`
+}
+
+func (req *ImpersonationRequest) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+type ImpersonationResponse struct {
+	Impersonating bool       `json:"impersonating" example:"true"`
+	CID           uint       `json:"cid,omitempty" example:"1293257"`
+	Impersonator  uint       `json:"impersonator,omitempty" example:"876594"`
+	ReadOnly      bool       `json:"read_only" example:"true"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty" example:"2021-01-01T00:00:00Z"`
+}
+
+func (res *ImpersonationResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func NewImpersonationResponse(session *models.Session) *ImpersonationResponse {
+	if session == nil || !session.IsImpersonating() {
+		return &ImpersonationResponse{}
+	}
+
+	return &ImpersonationResponse{
+		Impersonating: true,
+		CID:           *session.ImpersonatingCID,
+		Impersonator:  session.CID,
+		ReadOnly:      session.ImpersonationReadOnly,
+		ExpiresAt:     session.ImpersonationExpiresAt,
+	}
+}
+
+// GetImpersonation godoc
+// @Summary Get impersonation status
+// @Description Get whether the current session is impersonating another user
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} ImpersonationResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Router /user/impersonation [get]
+func GetImpersonation(w http.ResponseWriter, r *http.Request) {
+	utils.Render(w, r, NewImpersonationResponse(utils.GetXSession(r)))
+}
+
+// StartImpersonation godoc
+// @Summary Start impersonating a user
+// @Description Make the current session act as another user for an hour, with that user's permissions. Every request
+// @Description made while impersonating is logged, and read_only blocks everything but viewing. Starting and stopping
+// @Description is recorded in the user's action log. Only support services, the technical manager and division
+// @Description development can impersonate, and never another user who can impersonate.
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param impersonation body ImpersonationRequest true "Impersonation"
+// @Success 200 {object} ImpersonationResponse
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 404 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/impersonation [post]
+func StartImpersonation(w http.ResponseWriter, r *http.Request) {
+	data := &ImpersonationRequest{}
+	if err := data.Bind(r); err != nil {
+		utils.Render(w, r, utils.ErrInvalidRequest(err))
+		return
+	}
+
+	staff := utils.GetXUser(r)
+	session := utils.GetXSession(r)
+	if session == nil || !utils.CanImpersonate(staff) {
+		utils.Render(w, r, utils.ErrForbidden)
+		return
+	}
+
+	if data.CID == staff.CID {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("you cannot impersonate yourself")))
+		return
+	}
+
+	target := &models.User{CID: data.CID}
+	if err := target.Get(); err != nil {
+		utils.Render(w, r, utils.ErrNotFound)
+		return
+	}
+
+	if utils.CanImpersonate(target) {
+		utils.Render(w, r, utils.ErrForbidden)
+		return
+	}
+
+	until := time.Now().Add(impersonationLifetime)
+	if session.ExpiresAt.Before(until) {
+		until = session.ExpiresAt
+	}
+
+	if err := session.StartImpersonation(target.CID, data.ReadOnly, until, data.Reason); err != nil {
+		log.WithError(err).Errorf("Error starting impersonation of %d by %d", target.CID, staff.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	log.Infof("%d started impersonating %d", staff.CID, target.CID)
+
+	// StartImpersonation only updates the row, reload it for the response
+	if err := session.Get(); err != nil {
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	utils.Render(w, r, NewImpersonationResponse(session))
+}
+
+// StopImpersonation godoc
+// @Summary Stop impersonating
+// @Description Return the current session to the staff member's own user
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Success 204
+// @Failure 400 {object} utils.ErrResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/impersonation [delete]
+func StopImpersonation(w http.ResponseWriter, r *http.Request) {
+	session := utils.GetXSession(r)
+	if session == nil || !session.IsImpersonating() {
+		utils.Render(w, r, utils.ErrInvalidRequest(errors.New("not impersonating")))
+		return
+	}
+
+	if err := session.EndImpersonation("stopped"); err != nil {
+		log.WithError(err).Errorf("Error stopping impersonation of session %d", session.ID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
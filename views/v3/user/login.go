@@ -123,25 +123,6 @@ func GetLoginCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if encoded, err := cookie.CookieStore.Encode("VATUSA", map[string]string{
-		"cid": user.CID,
-	}); err == nil {
-		usa := &http.Cookie{
-			Name:     "VATUSA",
-			Value:    encoded,
-			Path:     "/",
-			MaxAge:   60 * 60 * 24, // 24 hours
-			HttpOnly: true,
-			Secure:   true,
-			Domain:   config.Cfg.Cookie.Domain,
-		}
-
-		http.SetCookie(w, usa)
-	} else {
-		utils.Render(w, r, utils.ErrInternalServerWithErr(err))
-		return
-	}
-
 	// Create user if they don't exist
 	intCID, err := strconv.ParseInt(user.CID, 10, 64)
 	if err != nil {
@@ -152,6 +133,11 @@ func GetLoginCallback(w http.ResponseWriter, r *http.Request) {
 		CID: uint(intCID),
 	}
 
+	if err := startSession(w, r, dbUser.CID); err != nil {
+		utils.Render(w, r, utils.ErrInternalServerWithErr(err))
+		return
+	}
+
 	if err := dbUser.Get(); err != nil {
 		dbUser.FirstName = user.Personal.FirstName
 		dbUser.LastName = user.Personal.LastName
@@ -242,10 +228,7 @@ func GetLoginCallback(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	redirect := session["redirect"]
-	if redirect == "" {
-		redirect = "https://vatusa.net"
-	}
+	redirect := safeRedirect(session["redirect"])
 
 	// Delete the session cookie
 	http.SetCookie(w, &http.Cookie{
@@ -320,16 +303,51 @@ func exchangeToken(ctx context.Context, oauthConfig *oauth2.Config, code string)
 	return token, nil
 }
 
+// startSession creates a server-side session for cid and points the VATUSA cookie at it
+func startSession(w http.ResponseWriter, r *http.Request, cid uint) error {
+	token, err := gonanoid.Generate("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789", 64)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		TokenHash:  models.HashToken(token),
+		CID:        cid,
+		UserAgent:  truncate(r.UserAgent(), 512),
+		IP:         utils.ClientIP(r),
+		LastSeenAt: now,
+		LastSeenIP: utils.ClientIP(r),
+		ExpiresAt:  now.Add(cookie.SessionLifetime),
+	}
+	if err := session.Create(); err != nil {
+		return err
+	}
+
+	return cookie.SetSession(w, token)
+}
+
+// truncate cuts s to at most length characters, never splitting a multi-byte character
+func truncate(s string, length int) string {
+	for i := range s {
+		if length == 0 {
+			return s[:i]
+		}
+		length--
+	}
+	return s
+}
+
 func GetLogout(w http.ResponseWriter, r *http.Request) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     "VATUSA",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		Domain:   config.Cfg.Cookie.Domain,
-		Expires:  time.Unix(0, 0),
-	})
+	if session := utils.GetXSession(r); session != nil {
+		if err := session.Delete(); err != nil {
+			log.WithError(err).Errorf("Error deleting session %d", session.ID)
+			utils.Render(w, r, utils.ErrInternalServer)
+			return
+		}
+	}
+
+	cookie.ClearSession(w)
 
 	utils.Response(r, http.StatusNoContent)
 }
@@ -0,0 +1,35 @@
+package user
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s      string
+		length int
+		want   string
+	}{
+		{s: "Mozilla/5.0", length: 512, want: "Mozilla/5.0"},
+		{s: "Mozilla/5.0", length: 7, want: "Mozilla"},
+		{s: "Mozilla/5.0", length: 0, want: ""},
+		{s: "", length: 3, want: ""},
+		{s: "héllo", length: 2, want: "hé"},
+		{s: "日本語", length: 3, want: "日本語"},
+		{s: "日本語", length: 2, want: "日本"},
+		{s: "a😀b", length: 2, want: "a😀"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got := truncate(tt.s, tt.length)
+			if got != tt.want {
+				t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.length, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncate(%q, %d) = %q is not valid UTF-8", tt.s, tt.length, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package user
+
+import (
+	"github.com/VATUSA/primary-api/pkg/constants"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	"net/http"
+)
+
+type PermissionsResponse struct {
+	// Division holds permissions that apply at every facility
+	Division   []constants.Permission                          `json:"division" example:"facility.edit"`
+	Facilities map[constants.FacilityID][]constants.Permission `json:"facilities"`
+}
+
+func (res *PermissionsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// GetPermissions godoc
+// @Summary Get your permissions
+// @Description Get what the logged-in user, or the facility API key, can do at each facility, so front-ends can hide
+// @Description what isn't allowed. Permissions in division apply at every facility.
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} PermissionsResponse
+// @Failure 403 {object} utils.ErrResponse
+// @Router /user/permissions [get]
+func GetPermissions(w http.ResponseWriter, r *http.Request) {
+	division, facilities := utils.GetPrincipal(r).Permissions()
+	utils.Render(w, r, &PermissionsResponse{Division: division, Facilities: facilities})
+}
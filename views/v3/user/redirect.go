@@ -0,0 +1,27 @@
+package user
+
+import (
+	"github.com/VATUSA/primary-api/pkg/config"
+	"net/url"
+	"strings"
+)
+
+const defaultRedirect = "https://vatusa.net"
+
+// safeRedirect returns redirect when it points at the API or one of the VATUSA sites allowed to call it, and the
+// VATUSA website otherwise, so the login can't be used to send users to an arbitrary site
+func safeRedirect(redirect string) string {
+	u, err := url.Parse(redirect)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return defaultRedirect
+	}
+
+	origins := append([]string{defaultRedirect, config.Cfg.API.BaseURL}, strings.Split(config.Cfg.Cors.AllowedOrigins, ",")...)
+	for _, origin := range origins {
+		o, err := url.Parse(strings.TrimSpace(origin))
+		if err == nil && o.Host != "" && strings.EqualFold(o.Scheme, u.Scheme) && strings.EqualFold(o.Host, u.Host) {
+			return redirect
+		}
+	}
+	return defaultRedirect
+}
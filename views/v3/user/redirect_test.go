@@ -0,0 +1,40 @@
+package user
+
+import (
+	"github.com/VATUSA/primary-api/pkg/config"
+	"testing"
+)
+
+func TestSafeRedirect(t *testing.T) {
+	previous := config.Cfg
+	config.Cfg = &config.Config{
+		API:  &config.APIConfig{BaseURL: "https://api.vatusa.net"},
+		Cors: &config.CorsConfig{AllowedOrigins: "https://my.vatusa.net, http://localhost:3000"},
+	}
+	t.Cleanup(func() { config.Cfg = previous })
+
+	tests := []struct {
+		redirect string
+		want     string
+	}{
+		{redirect: "", want: defaultRedirect},
+		{redirect: "https://vatusa.net/profile", want: "https://vatusa.net/profile"},
+		{redirect: "https://my.vatusa.net/roster?facility=ZDV", want: "https://my.vatusa.net/roster?facility=ZDV"},
+		{redirect: "http://localhost:3000/", want: "http://localhost:3000/"},
+		{redirect: "https://api.vatusa.net/v3/oauth/authorize?client_id=zdv", want: "https://api.vatusa.net/v3/oauth/authorize?client_id=zdv"},
+		{redirect: "https://evil.example.com/", want: defaultRedirect},
+		{redirect: "https://my.vatusa.net.evil.example.com/", want: defaultRedirect},
+		{redirect: "http://my.vatusa.net/", want: defaultRedirect},
+		{redirect: "//evil.example.com/", want: defaultRedirect},
+		{redirect: "/profile", want: defaultRedirect},
+		{redirect: "javascript:alert(1)", want: defaultRedirect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.redirect, func(t *testing.T) {
+			if got := safeRedirect(tt.redirect); got != tt.want {
+				t.Errorf("safeRedirect(%q) = %q, want %q", tt.redirect, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,16 +1,21 @@
 package user
 
 import (
-	"context"
+	"github.com/VATUSA/primary-api/pkg/constants"
 	"github.com/VATUSA/primary-api/pkg/database/models"
 	"github.com/VATUSA/primary-api/pkg/go-chi/middleware/auth"
 	"github.com/VATUSA/primary-api/pkg/utils"
+	access_token "github.com/VATUSA/primary-api/views/v3/access-token"
 	action_log "github.com/VATUSA/primary-api/views/v3/action-log"
+	"github.com/VATUSA/primary-api/views/v3/broadcast"
 	disciplinary_log "github.com/VATUSA/primary-api/views/v3/disciplinary-log"
+	"github.com/VATUSA/primary-api/views/v3/event"
 	"github.com/VATUSA/primary-api/views/v3/feedback"
 	"github.com/VATUSA/primary-api/views/v3/notification"
 	rating_change "github.com/VATUSA/primary-api/views/v3/rating-change"
 	"github.com/VATUSA/primary-api/views/v3/roster"
+	"github.com/VATUSA/primary-api/views/v3/session"
+	"github.com/VATUSA/primary-api/views/v3/training"
 	user_flag "github.com/VATUSA/primary-api/views/v3/user-flag"
 	user_notification "github.com/VATUSA/primary-api/views/v3/user-notification"
 	user_role "github.com/VATUSA/primary-api/views/v3/user-role"
@@ -25,18 +30,45 @@ func Router(r chi.Router) {
 	r.Get("/login", GetLogin)
 	r.Get("/login/callback", GetLoginCallback)
 
-	r.With(middleware.NotGuest).Get("/discord", GetDiscordLink)
-	r.With(middleware.NotGuest).Get("/discord/callback", GetDiscordCallback)
-	r.With(middleware.NotGuest).Get("/discord/unlink", UnlinkDiscord)
+	r.With(middleware.NotGuest, middleware.NoImpersonation).Get("/discord", GetDiscordLink)
+	r.With(middleware.NotGuest, middleware.NoImpersonation).Get("/discord/callback", GetDiscordCallback)
+	r.With(middleware.NotGuest, middleware.NoImpersonation).Get("/discord/unlink", UnlinkDiscord)
 
-	r.With(middleware.NotGuest).Get("/", GetSelf)
+	r.With(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope), middleware.NotGuest).Get("/", GetSelf)
+	r.With(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope), middleware.NotGuest).Get("/permissions", GetPermissions)
+
+	r.With(middleware.NotGuest, middleware.NoImpersonation).Get("/calendar", GetCalendarLink)
+	r.With(middleware.NotGuest, middleware.NoImpersonation).Post("/calendar/reset", ResetCalendarLink)
+	r.Get("/calendar/{Token}.ics", event.GetUserCalendar)
+
+	r.With(middleware.NotGuest).Get("/stream", GetStream)
+
+	r.Route("/tokens", func(r chi.Router) {
+		access_token.Router(r)
+	})
+
+	r.Route("/sessions", func(r chi.Router) {
+		session.Router(r)
+	})
+
+	r.Route("/impersonation", func(r chi.Router) {
+		r.Use(middleware.NoAccessToken, middleware.NotGuest, middleware.RequireUser)
+
+		r.Get("/", GetImpersonation)
+		r.With(middleware.NoImpersonation).Post("/", StartImpersonation)
+		r.Delete("/", StopImpersonation)
+	})
 
 	r.Route("/{CID}", func(r chi.Router) {
 		r.Use(Ctx)
 
-		r.With(middleware.NotGuest, middleware.CanViewUser).Get("/", GetUser)
-		r.With(middleware.NotGuest, middleware.CanEditUser).Put("/", UpdateUser)
-		r.With(middleware.NotGuest, middleware.CanEditUser).Patch("/", PatchUser)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope))
+
+			r.With(middleware.NotGuest, middleware.CanViewUser).Get("/", GetUser)
+			r.With(middleware.NotGuest, middleware.CanEditUser).Put("/", UpdateUser)
+			r.With(middleware.NotGuest, middleware.CanEditUser).Patch("/", PatchUser)
+		})
 
 		r.Route("/action-log", func(r chi.Router) {
 			action_log.Router(r)
@@ -46,12 +78,18 @@ func Router(r chi.Router) {
 			disciplinary_log.Router(r)
 		})
 
-		r.With(middleware.NotGuest, middleware.CanViewUser).Get("/feedback", feedback.GetUserFeedback)
+		r.With(middleware.RequireScope(constants.ReadUsersScope, constants.WriteUsersScope), middleware.NotGuest, middleware.CanViewUser).Get("/discord/roles", GetDiscordRoles)
+
+		r.With(middleware.RequireScope(constants.ReadFeedbackScope, constants.WriteFeedbackScope), middleware.NotGuest, middleware.CanViewUser).Get("/feedback", feedback.GetUserFeedback)
 
 		r.Route("/notifications", func(r chi.Router) {
 			notification.Router(r)
 		})
 
+		r.Route("/broadcasts", func(r chi.Router) {
+			broadcast.UserRouter(r)
+		})
+
 		r.Route("/notification-settings", func(r chi.Router) {
 			user_notification.Router(r)
 		})
@@ -60,7 +98,11 @@ func Router(r chi.Router) {
 			rating_change.Router(r)
 		})
 
-		r.With(middleware.NotGuest, middleware.CanViewUser).Get("/roster", roster.GetUserRosters)
+		r.With(middleware.RequireScope(constants.ReadRosterScope, constants.WriteRosterScope), middleware.NotGuest, middleware.CanViewUser).Get("/roster", roster.GetUserRosters)
+
+		r.Route("/training", func(r chi.Router) {
+			training.UserRouter(r)
+		})
 
 		r.Route("/user-flag", func(r chi.Router) {
 			user_flag.Router(r)
@@ -93,7 +135,7 @@ func Ctx(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), utils.UserKey{}, user)
+		ctx := utils.WithResource(r, utils.UserKey{}, user)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
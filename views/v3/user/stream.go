@@ -0,0 +1,74 @@
+package user
+
+import (
+	"fmt"
+	"github.com/VATUSA/primary-api/pkg/stream"
+	"github.com/VATUSA/primary-api/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// streamHeartbeat keeps idle streams from being closed by proxies and notices disconnected clients
+const streamHeartbeat = 25 * time.Second
+
+// GetStream godoc
+// @Summary Stream your updates
+// @Description Server-sent events stream of your new notifications, roster request decisions and event assignment
+// @Description changes. Each event's name is its type and its data is the JSON encoded resource. Browsers connect
+// @Description with EventSource using withCredentials so the session cookie is sent.
+// @Tags user
+// @Produce  text/event-stream
+// @Success 200 {string} string
+// @Failure 401 {object} utils.ErrResponse
+// @Failure 500 {object} utils.ErrResponse
+// @Router /user/stream [get]
+func GetStream(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetXUser(r)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok || stream.Default == nil {
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	events, err := stream.Default.Subscribe(r.Context(), user.CID)
+	if err != nil {
+		log.WithError(err).Errorf("Error subscribing user %d to stream", user.CID)
+		utils.Render(w, r, utils.ErrInternalServer)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Stop nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Tell the client how long to wait before reconnecting, and flush the headers so it knows it is connected
+	_, _ = fmt.Fprint(w, "retry: 5000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, e.Data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
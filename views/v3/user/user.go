@@ -116,6 +116,7 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := *user
 	user.PreferredName = req.PreferredName
 	user.PreferredOIs = strings.ToUpper(req.PreferredOIs)
 	user.DiscordID = req.DiscordID
@@ -125,6 +126,8 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	utils.LogUserChange(r, user.CID, "", models.Diff(&before, user))
+
 	utils.Render(w, r, NewUserResponse(user))
 }
 
@@ -150,6 +153,7 @@ func PatchUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := *user
 	if req.PreferredName != "" {
 		if req.PreferredName == "-" {
 			req.PreferredName = ""
@@ -168,5 +172,7 @@ func PatchUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	utils.LogUserChange(r, user.CID, "", models.Diff(&before, user))
+
 	utils.Render(w, r, NewUserResponse(user))
 }